@@ -48,7 +48,16 @@ func DoSetup() {
 
 	utils.CheckGpexpandRunning(utils.BackupPreventedByGpexpandMessage)
 	timestamp := history.CurrentTimestamp()
-	createBackupLockFile(timestamp)
+	if resumeTimestamp := MustGetFlagString(options.RESUME); resumeTimestamp != "" {
+		timestamp = resumeTimestamp
+	}
+	dryRun := MustGetFlagBool(options.DRY_RUN)
+	if !dryRun {
+		createBackupLockFile(timestamp)
+	}
+	if MustGetFlagBool(options.TRACK_HEAP_CHANGES) && !MustGetFlagBool(options.METADATA_ONLY) && !MustGetFlagBool(options.DATA_ONLY) {
+		readHeapChangeMarkers()
+	}
 	initializeConnectionPool(timestamp)
 	gplog.Info("Greenplum Database Version = %s", connectionPool.Version.VersionString)
 
@@ -73,7 +82,9 @@ func DoSetup() {
 	clusterConfigConn.Close()
 
 	globalFPInfo = filepath.NewFilePathInfo(globalCluster, MustGetFlagString(options.BACKUP_DIR), timestamp, segPrefix, MustGetFlagBool(options.SINGLE_BACKUP_DIR))
-	if MustGetFlagBool(options.METADATA_ONLY) {
+	if dryRun {
+		gplog.Info("Performing dry run, no backup files will be written")
+	} else if MustGetFlagBool(options.METADATA_ONLY) {
 		_, err = globalCluster.ExecuteLocalCommand(fmt.Sprintf("mkdir -p %s", globalFPInfo.GetDirForContent(-1)))
 		gplog.FatalOnError(err)
 	} else {
@@ -82,6 +93,8 @@ func DoSetup() {
 	globalTOC = &toc.TOC{}
 	globalTOC.InitializeMetadataEntryMap()
 	utils.InitializePipeThroughParameters(!MustGetFlagBool(options.NO_COMPRESSION), MustGetFlagString(options.COMPRESSION_TYPE), MustGetFlagInt(options.COMPRESSION_LEVEL))
+	err = utils.InitializeEncryption(MustGetFlagString(options.ENCRYPTION_KEY_FILE))
+	gplog.FatalOnError(err)
 	getQuotedRoleNames(connectionPool)
 
 	pluginConfigFlag := MustGetFlagString(options.PLUGIN_CONFIG)
@@ -96,9 +109,16 @@ func DoSetup() {
 		gplog.Debug("Plugin config path: %s", pluginConfig.ConfigPath)
 	}
 
+	priorityTables = opts.PriorityRelations
+	tablePredicates = opts.TablePredicates
+	maskingRules = opts.MaskingRules
+	maxBandwidth, maxBandwidthPerHost = opts.MaxBandwidth, opts.MaxBandwidthPerHost
 	initializeBackupReport(*opts)
+	if MustGetFlagString(options.RESUME) != "" {
+		prepareBackupForResume()
+	}
 
-	if pluginConfigFlag != "" {
+	if pluginConfigFlag != "" && !dryRun {
 		backupReport.PluginVersion = pluginConfig.CheckPluginExistsOnAllHosts(globalCluster)
 		pluginConfig.CopyPluginConfigToAllHosts(globalCluster)
 		pluginConfig.SetupPluginForBackup(globalCluster, globalFPInfo)
@@ -113,13 +133,14 @@ func DoBackup() {
 	pluginConfigFlag := MustGetFlagString(options.PLUGIN_CONFIG)
 	targetBackupTimestamp := ""
 	var targetBackupFPInfo filepath.FilePathInfo
-	if MustGetFlagBool(options.INCREMENTAL) {
+	if MustGetFlagBool(options.INCREMENTAL) || MustGetFlagBool(options.DIFFERENTIAL) {
 		targetBackupTimestamp = GetTargetBackupTimestamp()
 
 		targetBackupFPInfo = filepath.NewFilePathInfo(globalCluster, globalFPInfo.UserSpecifiedBackupDir,
 			targetBackupTimestamp, globalFPInfo.UserSpecifiedSegPrefix, globalFPInfo.SingleBackupDir)
 
-		if pluginConfigFlag != "" {
+		// A dry run must not read from the plugin storage, so it only uses any local copies of these files
+		if pluginConfigFlag != "" && !MustGetFlagBool(options.DRY_RUN) {
 			// These files need to be downloaded from the remote system into the local filesystem
 			pluginConfig.MustRestoreFile(targetBackupFPInfo.GetConfigFilePath())
 			pluginConfig.MustRestoreFile(targetBackupFPInfo.GetTOCFilePath())
@@ -128,23 +149,25 @@ func DoBackup() {
 	}
 
 	gplog.Info("Gathering table state information")
-	metadataTables, dataTables := RetrieveAndProcessTables()
-	dataTables, numExtOrForeignTables := GetBackupDataSet(dataTables)
+	metadataTables, retrievedDataTables := RetrieveAndProcessTables()
+	dataTables, numExtOrForeignTables := GetBackupDataSet(retrievedDataTables)
 	if len(dataTables) == 0 && !backupReport.MetadataOnly {
 		gplog.Warn("No tables in backup set contain data. Performing metadata-only backup instead.")
 		backupReport.MetadataOnly = true
 	}
+	if len(tablePredicates) > 0 {
+		backupReport.PartialTables = GetTablePredicatesForBackup(dataTables)
+	}
+	if len(maskingRules) > 0 {
+		LogMaskedColumnsForBackup(dataTables)
+	}
 	// This must be a full backup with --leaf-parition-data to query for incremental metadata
 	if !(MustGetFlagBool(options.METADATA_ONLY) || MustGetFlagBool(options.DATA_ONLY)) && MustGetFlagBool(options.LEAF_PARTITION_DATA) {
-		backupIncrementalMetadata()
+		backupIncrementalMetadata(dataTables)
 	} else {
 		gplog.Verbose("Skipping query for incremental metadata.")
 	}
 
-	metadataFilename := globalFPInfo.GetMetadataFilePath()
-	gplog.Info("Metadata will be written to %s", metadataFilename)
-	metadataFile := utils.NewFileWithByteCountFromFile(metadataFilename)
-
 	/*
 	 * We check this in the backup report rather than the flag because we
 	 * perform a metadata only backup if the database contains no tables
@@ -154,16 +177,37 @@ func DoBackup() {
 	if !backupReport.MetadataOnly {
 		targetBackupRestorePlan := make([]history.RestorePlanEntry, 0)
 		if targetBackupTimestamp != "" {
-			gplog.Info("Basing incremental backup off of backup with timestamp = %s", targetBackupTimestamp)
+			if backupReport.Differential {
+				gplog.Info("Basing differential backup off of full backup with timestamp = %s", targetBackupTimestamp)
+			} else {
+				gplog.Info("Basing incremental backup off of backup with timestamp = %s", targetBackupTimestamp)
+			}
 
-			targetBackupTOC := toc.NewTOC(targetBackupFPInfo.GetTOCFilePath())
-			targetBackupRestorePlan = history.ReadConfigFile(targetBackupFPInfo.GetConfigFilePath()).RestorePlan
-			backupSetTables = FilterTablesForIncremental(targetBackupTOC, globalTOC, dataTables)
+			if MustGetFlagBool(options.DRY_RUN) && !(utils.FileExists(targetBackupFPInfo.GetConfigFilePath()) && utils.FileExists(targetBackupFPInfo.GetTOCFilePath())) {
+				gplog.Warn("The config and TOC files of backup %s are not available locally, so the dry run plan includes the data of every table", targetBackupTimestamp)
+			} else {
+				targetBackupConfig := history.ReadConfigFile(targetBackupFPInfo.GetConfigFilePath())
+				if targetBackupConfig.EncryptionFingerprint != backupReport.EncryptionFingerprint {
+					gplog.Fatal(fmt.Errorf("Backup with timestamp %s was not encrypted with the same key.  All backups in an incremental backup set must use the same --encryption-key-file.", targetBackupTimestamp), "")
+				}
+				targetBackupTOC := toc.NewTOC(targetBackupFPInfo.GetTOCFilePath())
+				targetBackupRestorePlan = targetBackupConfig.RestorePlan
+				backupSetTables = FilterTablesForIncremental(targetBackupTOC, globalTOC, dataTables)
+			}
 		}
 
 		backupReport.RestorePlan = PopulateRestorePlan(backupSetTables, targetBackupRestorePlan, dataTables)
 	}
 
+	if MustGetFlagBool(options.DRY_RUN) {
+		printDryRunPlan(targetBackupTimestamp, metadataTables, retrievedDataTables, backupSetTables)
+		return
+	}
+
+	metadataFilename := globalFPInfo.GetMetadataFilePath()
+	gplog.Info("Metadata will be written to %s", metadataFilename)
+	metadataFile := utils.NewFileWithByteCountFromFile(metadataFilename)
+
 	// As soon as all necessary data is available, capture the backup into history database
 	if !MustGetFlagBool(options.NO_HISTORY) {
 		historyDBName := globalFPInfo.GetBackupHistoryDatabasePath()
@@ -171,6 +215,14 @@ func DoBackup() {
 		if err != nil {
 			gplog.FatalOnError(err)
 		} else {
+			if MustGetFlagString(options.RESUME) != "" {
+				// Replace the entry of the failed backup with the one for this run
+				err = history.DeleteBackupHistory(historyDB, globalFPInfo.Timestamp)
+				if err != nil {
+					historyDB.Close()
+					gplog.FatalOnError(err)
+				}
+			}
 			err = history.StoreBackupHistory(historyDB, &backupReport.BackupConfig)
 			historyDB.Close()
 			gplog.FatalOnError(err)
@@ -191,6 +243,9 @@ func DoBackup() {
 
 	if !backupReport.MetadataOnly {
 		backupData(backupSetTables)
+		if backupReport.Resumed() {
+			storeDataSnapshotsInHistory()
+		}
 	}
 
 	printDataBackupWarnings(numExtOrForeignTables)
@@ -290,20 +345,47 @@ func backupPredata(metadataFile *utils.FileWithByteCount, tables []Table, tableO
 }
 
 func backupData(tables []Table) {
+	snapshotTimestamp := history.CurrentTimestamp()
+	tableSizes := GetTableSizes(connectionPool, tables)
+	if MustGetFlagString(options.RESUME) != "" {
+		var resumedEntries []ResumeStateEntry
+		tables, resumedEntries = FilterTablesForResume(tables, resumeStateEntries)
+		gplog.Info("Skipping data backup of %d table(s) completed by a previous run", len(resumedEntries))
+		addResumedDataEntriesToTOC(resumedEntries)
+		backupReport.DataSnapshots = ConstructDataSnapshots(resumedEntries, tables, snapshotTimestamp, backupSnapshot)
+	}
 	if len(tables) == 0 {
 		// No incremental data changes to backup
 		gplog.Info("No tables to backup")
+		if MustGetFlagString(options.RESUME) != "" {
+			// Only the tables completed by a previous run have data entries
+			AddTableDataSizesToTOC(tableSizes)
+			AddTableDataChecksumsToTOC()
+		}
 		gplog.Info("Data backup complete")
 		return
 	}
+	// gpbackup_helper compresses the table data and computes its checksums in all backups
+	utils.VerifyHelperVersionOnSegments(version, globalCluster)
+	if utils.GetEncryptionKey() != nil {
+		utils.WriteEncryptionKeyToSegments(globalCluster, globalFPInfo)
+	}
 	if MustGetFlagBool(options.SINGLE_DATA_FILE) {
 		gplog.Verbose("Initializing pipes and gpbackup_helper on segments for single data file backup")
-		utils.VerifyHelperVersionOnSegments(version, globalCluster)
 		oidList := make([]string, 0, len(tables))
 		for _, table := range tables {
 			oidList = append(oidList, fmt.Sprintf("%d", table.Oid))
 		}
 		utils.WriteOidListToSegments(oidList, globalCluster, globalFPInfo, "oid")
+		dataStreams := MustGetFlagInt(options.DATA_STREAMS)
+		if dataStreams > 1 {
+			streams := AssignTablesToDataStreams(tables, tableSizes, dataStreams)
+			streamList := make([]string, 0, len(tables))
+			for _, table := range tables {
+				streamList = append(streamList, fmt.Sprintf("%d %d", table.Oid, streams[table.Oid]))
+			}
+			utils.WriteOidListToSegments(streamList, globalCluster, globalFPInfo, "stream")
+		}
 		compressStr := fmt.Sprintf(" --compression-level %d --compression-type %s", MustGetFlagInt(options.COMPRESSION_LEVEL), MustGetFlagString(options.COMPRESSION_TYPE))
 		if MustGetFlagBool(options.NO_COMPRESSION) {
 			compressStr = " --compression-level 0"
@@ -311,11 +393,24 @@ func backupData(tables []Table) {
 		initialPipes := CreateInitialSegmentPipes(oidList, globalCluster, connectionPool, globalFPInfo)
 		// Do not pass through the --on-error-continue flag or the resizeClusterMap because neither apply to gpbackup
 		utils.StartGpbackupHelpers(globalCluster, globalFPInfo, "--backup-agent",
-			MustGetFlagString(options.PLUGIN_CONFIG), compressStr, false, false, &wasTerminated, initialPipes, true, false, 0, 0)
+			MustGetFlagString(options.PLUGIN_CONFIG), compressStr, false, false, &wasTerminated, initialPipes, true, false, 0, 0,
+			utils.GetStreamBandwidth(globalCluster, maxBandwidth, maxBandwidthPerHost, dataStreams), dataStreams)
+	}
+	if !MustGetFlagBool(options.SINGLE_DATA_FILE) {
+		tables = ScheduleTablesForBackup(tables, tableSizes, priorityTables)
+		// Record each table as it completes, so that a failed backup can be resumed
+		var err error
+		resumeStateFile, err = OpenResumeStateFile(globalFPInfo.GetResumeStateFilePath(), snapshotTimestamp, backupSnapshot)
+		gplog.FatalOnError(err)
+		defer resumeStateFile.Close()
 	}
 	gplog.Info("Writing data to file")
-	rowsCopiedMaps := BackupDataForAllTables(tables)
+	rowsCopiedMaps := BackupDataForAllTables(tables, tableSizes)
 	AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+	AddTableDataSizesToTOC(tableSizes)
+	if !MustGetFlagBool(options.SINGLE_DATA_FILE) && !wasTerminated {
+		AddTableDataChecksumsToTOC()
+	}
 	if MustGetFlagBool(options.SINGLE_DATA_FILE) && MustGetFlagString(options.PLUGIN_CONFIG) != "" {
 		pluginConfig.BackupSegmentTOCs(globalCluster, globalFPInfo)
 	}
@@ -364,7 +459,9 @@ func DoTeardown() {
 		DoCleanup(backupFailed)
 
 		errorCode := gplog.GetErrorCode()
-		if errorCode == 0 {
+		if errorCode == 0 && MustGetFlagBool(options.DRY_RUN) {
+			gplog.Info("Dry run completed successfully")
+		} else if errorCode == 0 {
 			gplog.Info("Backup completed successfully")
 		}
 		os.Exit(errorCode)
@@ -400,8 +497,9 @@ func DoTeardown() {
 	/*
 	 * Only create a report file if we fail after the cluster is initialized
 	 * and a backup directory exists in which to create the report file.
+	 * A dry run never creates one.
 	 */
-	if globalFPInfo.Timestamp != "" {
+	if globalFPInfo.Timestamp != "" && !MustGetFlagBool(options.DRY_RUN) {
 		_, statErr := os.Stat(globalFPInfo.GetDirForContent(-1))
 		if statErr != nil { // Even if this isn't os.IsNotExist, don't try to write a report file in case of further errors
 			return
@@ -469,7 +567,7 @@ func DoCleanup(backupFailed bool) {
 		cancelBlockedQueries(globalFPInfo.Timestamp)
 	}
 	if globalFPInfo.Timestamp != "" {
-		if MustGetFlagBool(options.SINGLE_DATA_FILE) {
+		if MustGetFlagBool(options.SINGLE_DATA_FILE) && !MustGetFlagBool(options.DRY_RUN) {
 			// Copy sessions must be terminated before cleaning up gpbackup_helper processes to avoid a potential deadlock
 			// If the terminate query is sent via a connection with an active COPY command, and the COPY's pipe is cleaned up, the COPY query will hang.
 			// This results in the DoCleanup function passed to the signal handler to never return, blocking the os.Exit call
@@ -480,6 +578,9 @@ func DoCleanup(backupFailed bool) {
 			// We can have helper processes hanging around even without failures, so call this cleanup routine whether successful or not.
 			utils.CleanUpSegmentHelperProcesses(globalCluster, globalFPInfo, "backup")
 			utils.CleanUpHelperFilesOnAllHosts(globalCluster, globalFPInfo)
+		} else if (utils.GetEncryptionKey() != nil || (backupReport != nil && !backupReport.MetadataOnly)) && !MustGetFlagBool(options.DRY_RUN) {
+			// The progress files and the copy of the encryption key on each segment must not outlive the backup
+			utils.CleanUpHelperFilesOnAllHosts(globalCluster, globalFPInfo)
 		}
 
 		// The gpbackup_history entry is written to the DB with an "In Progress" status and a preliminary EndTime value
@@ -487,7 +588,7 @@ func DoCleanup(backupFailed bool) {
 		// failure; in either case, update the end time to the actual value. Between our signal handler and recovering
 		// panics, there should be no way for gpbackup to exit that leaves the entry in the initial status.
 
-		if !MustGetFlagBool(options.NO_HISTORY) {
+		if !MustGetFlagBool(options.NO_HISTORY) && !MustGetFlagBool(options.DRY_RUN) {
 			var statusString string
 			if backupFailed {
 				statusString = history.BackupStatusFailed
@@ -7,14 +7,18 @@ package backup
 import (
 	"errors"
 	"fmt"
+	"path"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
 	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/jackc/pgconn"
 	"gopkg.in/cheggaaa/pb.v1"
@@ -22,6 +26,8 @@ import (
 
 var (
 	tableDelim = ","
+	// How often the progress of the backup in bytes is gathered from the segments
+	dataProgressInterval = 5 * time.Second
 )
 
 func ConstructTableAttributesList(columnDefs []ColumnDefinition) string {
@@ -40,6 +46,45 @@ func ConstructTableAttributesList(columnDefs []ColumnDefinition) string {
 	return ""
 }
 
+/*
+ * Returns the list of columns to select when the data of a table is copied out
+ * with a query, in the same order as ConstructTableAttributesList so that the
+ * data can be copied back in.  Masked columns are selected as the result of
+ * their masking expression instead, and the second return value reports
+ * whether the table has any.
+ */
+func constructTableSelectList(table Table) (string, bool) {
+	columns := make([]string, 0)
+	isMasked := false
+	for _, col := range table.ColumnDefs {
+		if col.AttGenerated != "" {
+			continue
+		}
+		if rule := getColumnMaskingRule(table, col); rule != "" {
+			columns = append(columns, fmt.Sprintf("%s AS %s", maskingRules[rule], col.Name))
+			isMasked = true
+		} else {
+			columns = append(columns, col.Name)
+		}
+	}
+	if len(columns) == 0 {
+		return "*", false
+	}
+	return strings.Join(columns, ", "), isMasked
+}
+
+// Returns the key of the masking rule for the column, if any
+func getColumnMaskingRule(table Table, column ColumnDefinition) string {
+	for _, fqn := range utils.UserFQNVariants(table.Schema, table.Name) {
+		for _, columnName := range []string{column.Name, utils.UnquoteIdent(column.Name)} {
+			if _, ok := maskingRules[fqn+"."+columnName]; ok {
+				return fqn + "." + columnName
+			}
+		}
+	}
+	return ""
+}
+
 func AddTableDataEntriesToTOC(tables []Table, rowsCopiedMaps []map[uint32]int64) {
 	for _, table := range tables {
 		if !table.SkipDataBackup() {
@@ -50,8 +95,151 @@ func AddTableDataEntriesToTOC(tables []Table, rowsCopiedMaps []map[uint32]int64)
 					break
 				}
 			}
-			attributes := ConstructTableAttributesList(table.ColumnDefs)
-			globalTOC.AddCoordinatorDataEntry(table.Schema, table.Name, table.Oid, attributes, rowsCopied, table.PartitionLevelInfo.RootName, table.DistPolicy.Policy, table.DistPolicy.DistByEnum)
+			globalTOC.DataEntries = append(globalTOC.DataEntries, constructCoordinatorDataEntry(table, rowsCopied))
+		}
+	}
+}
+
+/*
+ * Restores schedule the largest tables first, so record the estimated size of
+ * each table, including the ones backed up by a previous run of a resumed
+ * backup.
+ */
+func AddTableDataSizesToTOC(tableSizes map[uint32]int64) {
+	for i := range globalTOC.DataEntries {
+		entry := &globalTOC.DataEntries[i]
+		if size, ok := tableSizes[entry.Oid]; ok {
+			entry.DataSize = size
+		}
+	}
+}
+
+/*
+ * Multiple-data-file backups copy the largest tables first, or the tables in
+ * the priority file if there is one, to make the most of parallel jobs.  The
+ * data of a single-data-file backup is copied over a single connection, so
+ * the order in which its tables are copied makes no difference.
+ */
+func ScheduleTablesForBackup(tables []Table, tableSizes map[uint32]int64, priorityTables []string) []Table {
+	copyTasks := make([]utils.CopyTask, len(tables))
+	for i, table := range tables {
+		copyTasks[i] = utils.CopyTask{Schema: table.Schema, Name: table.Name, Size: tableSizes[table.Oid]}
+	}
+	scheduledTables := make([]Table, 0, len(tables))
+	for _, i := range utils.ScheduleCopyTasks(copyTasks, priorityTables) {
+		scheduledTables = append(scheduledTables, tables[i])
+	}
+	return scheduledTables
+}
+
+func constructCoordinatorDataEntry(table Table, rowsCopied int64) toc.CoordinatorDataEntry {
+	attributes := ConstructTableAttributesList(table.ColumnDefs)
+	entry := toc.NewCoordinatorDataEntry(table.Schema, table.Name, table.Oid, attributes, rowsCopied, table.PartitionLevelInfo.RootName, table.DistPolicy.Policy, table.DistPolicy.DistByEnum)
+	entry.Predicate = getTablePredicate(table)
+	if MustGetFlagString(options.DATA_FORMAT) == utils.DATA_FORMAT_BINARY {
+		entry.DataFormat = utils.DATA_FORMAT_BINARY
+	}
+	if MustGetFlagBool(options.REPOSITORY) {
+		entry.ChunkList = path.Base(globalFPInfo.GetTableBackupFilePathForCopyCommand(table.Oid, utils.ChunkListExtension, false))
+	}
+	return entry
+}
+
+// Returns the WHERE clause from the table predicate file for the table, if any
+func getTablePredicate(table Table) string {
+	for _, fqn := range utils.UserFQNVariants(table.Schema, table.Name) {
+		if predicate, ok := tablePredicates[fqn]; ok {
+			return predicate
+		}
+	}
+	return ""
+}
+
+/*
+ * Returns the predicates of the tables in the backup set, keyed by table FQN,
+ * for the backup report.  A table in the predicate file that has no data to
+ * back up is most likely misspelled, so warn about it.
+ */
+func GetTablePredicatesForBackup(tables []Table) map[string]string {
+	backupPredicates := make(map[string]string)
+	matchedFqns := make(map[string]bool)
+	for _, table := range tables {
+		for _, fqn := range utils.UserFQNVariants(table.Schema, table.Name) {
+			if predicate, ok := tablePredicates[fqn]; ok {
+				backupPredicates[table.FQN()] = predicate
+				matchedFqns[fqn] = true
+				break
+			}
+		}
+	}
+	for fqn := range tablePredicates {
+		if !matchedFqns[fqn] {
+			gplog.Warn("Table %s in the table predicate file does not have any data to back up", fqn)
+		}
+	}
+	return backupPredicates
+}
+
+/*
+ * Each segment appends the checksum of every table it backs up to its checksum
+ * file, including the tables backed up by any previous run of a resumed backup,
+ * so the checksums of all data entries can be collected once the data is done.
+ */
+func AddTableDataChecksumsToTOC() {
+	if len(globalTOC.DataEntries) == 0 {
+		return
+	}
+	remoteOutput := globalCluster.GenerateAndExecuteCommand("Collecting table data checksums from segments", cluster.ON_SEGMENTS, func(contentID int) string {
+		checksumFile := globalFPInfo.GetSegmentChecksumFilePath(contentID)
+		return fmt.Sprintf("if [[ -f %[1]s ]]; then cat %[1]s; fi", checksumFile)
+	})
+	globalCluster.CheckClusterError(remoteOutput, "Unable to collect table data checksums", func(contentID int) string {
+		return fmt.Sprintf("Unable to read checksum file %s", globalFPInfo.GetSegmentChecksumFilePath(contentID))
+	})
+
+	numMissing := 0
+	for _, cmd := range remoteOutput.Commands {
+		checksums := utils.ParseChecksumEntries(cmd.Stdout)
+		for i := range globalTOC.DataEntries {
+			entry := &globalTOC.DataEntries[i]
+			checksum, ok := checksums[entry.Oid]
+			if !ok {
+				gplog.Verbose("No checksum recorded for table %s on segment %d", utils.MakeFQN(entry.Schema, entry.Name), cmd.Content)
+				numMissing++
+				continue
+			}
+			if entry.Checksums == nil {
+				entry.Checksums = make(map[int]string)
+			}
+			entry.Checksums[cmd.Content] = checksum
+		}
+	}
+	if numMissing > 0 {
+		gplog.Warn("No checksum was recorded for %d table data file(s), so they cannot be verified on restore. See %s for a complete list.", numMissing, gplog.GetLogFilePath())
+	}
+}
+
+/*
+ * Logs the columns whose data will be masked.  A rule in the masking rules file
+ * that matches no column with data to back up is most likely misspelled, or is
+ * for a generated column whose data is never backed up, so warn about it.
+ */
+func LogMaskedColumnsForBackup(tables []Table) {
+	matchedRules := make(map[string]bool)
+	for _, table := range tables {
+		for _, col := range table.ColumnDefs {
+			if col.AttGenerated != "" {
+				continue
+			}
+			if rule := getColumnMaskingRule(table, col); rule != "" {
+				gplog.Verbose("Masking data of column %s.%s with: %s", table.FQN(), col.Name, maskingRules[rule])
+				matchedRules[rule] = true
+			}
+		}
+	}
+	for rule := range maskingRules {
+		if !matchedRules[rule] {
+			gplog.Warn("Column %s in the masking rules file does not have any data to back up", rule)
 		}
 	}
 }
@@ -64,7 +252,7 @@ type BackupProgressCounters struct {
 
 func CopyTableOut(connectionPool *dbconn.DBConn, table Table, destinationToWrite string, connNum int) (int64, error) {
 	checkPipeExistsCommand := ""
-	customPipeThroughCommand := utils.GetPipeThroughProgram().OutputCommand
+	customPipeThroughCommand := ""
 	sendToDestinationCommand := ">"
 	if MustGetFlagBool(options.SINGLE_DATA_FILE) {
 		/*
@@ -74,9 +262,19 @@ func CopyTableOut(connectionPool *dbconn.DBConn, table Table, destinationToWrite
 		 * of the data is backed up.
 		 */
 		checkPipeExistsCommand = fmt.Sprintf("(test -p \"%s\" || (echo \"Pipe not found %s\">&2; exit 1)) && ", destinationToWrite, destinationToWrite)
+		// The helper agent compresses the data and computes the checksums for single data file backups
 		customPipeThroughCommand = "cat -"
-	} else if MustGetFlagString(options.PLUGIN_CONFIG) != "" {
-		sendToDestinationCommand = fmt.Sprintf("| %s backup_data %s", pluginConfig.ExecutablePath, pluginConfig.ConfigPath)
+	} else {
+		// Each job copies the data of a different table at the same time, so they share the bandwidth
+		copyBandwidth := utils.GetStreamBandwidth(globalCluster, maxBandwidth, maxBandwidthPerHost, MustGetFlagInt(options.JOBS))
+		chunkDir := ""
+		if MustGetFlagBool(options.REPOSITORY) {
+			chunkDir = globalFPInfo.GetChunkDirForCopyCommand()
+		}
+		customPipeThroughCommand = utils.GetBackupFilterCommand(globalFPInfo.GetSegmentChecksumFilePathForCopyCommand(), globalFPInfo.GetSegmentHelperFilePathForCopyCommand("progress"), utils.GetEncryptionKeyFileForCopyCommand(globalFPInfo), chunkDir, table.Oid, copyBandwidth)
+		if MustGetFlagString(options.PLUGIN_CONFIG) != "" {
+			sendToDestinationCommand = fmt.Sprintf("| %s backup_data %s", pluginConfig.ExecutablePath, pluginConfig.ConfigPath)
+		}
 	}
 
 	copyCommand := fmt.Sprintf("PROGRAM '%s%s %s %s'", checkPipeExistsCommand, customPipeThroughCommand, sendToDestinationCommand, destinationToWrite)
@@ -87,7 +285,18 @@ func CopyTableOut(connectionPool *dbconn.DBConn, table Table, destinationToWrite
 		columnNames = ConstructTableAttributesList(table.ColumnDefs)
 	}
 
-	query := fmt.Sprintf("COPY %s%s TO %s WITH CSV DELIMITER '%s' ON SEGMENT IGNORE EXTERNAL PARTITIONS;", table.FQN(), columnNames, copyCommand, tableDelim)
+	formatOptions := utils.GetCopyFormatOptions(MustGetFlagString(options.DATA_FORMAT), tableDelim)
+	query := fmt.Sprintf("COPY %s%s TO %s WITH %s ON SEGMENT IGNORE EXTERNAL PARTITIONS;", table.FQN(), columnNames, copyCommand, formatOptions)
+	selectList, isMasked := constructTableSelectList(table)
+	predicate := getTablePredicate(table)
+	if predicate != "" || isMasked {
+		// IGNORE EXTERNAL PARTITIONS cannot be used here, see ValidateTablePredicates and ValidateMaskingRules
+		whereClause := ""
+		if predicate != "" {
+			whereClause = fmt.Sprintf(" WHERE %s", predicate)
+		}
+		query = fmt.Sprintf("COPY (SELECT %s FROM %s%s) TO %s WITH %s ON SEGMENT;", selectList, table.FQN(), whereClause, copyCommand, formatOptions)
+	}
 	gplog.Verbose("Worker %d: %s", connNum, query)
 	result, err := connectionPool.Exec(query, connNum)
 	if err != nil {
@@ -98,6 +307,36 @@ func CopyTableOut(connectionPool *dbconn.DBConn, table Table, destinationToWrite
 	return numRows, nil
 }
 
+/*
+ * The tables of a single data file backup taken with --data-streams are spread
+ * over the data streams by size, from the largest table down, each to the
+ * stream with the least data so far or, of streams with as much data, the
+ * fewest tables, so that the streams finish at about the same time.  Returns
+ * the stream of each table, keyed by oid.
+ */
+func AssignTablesToDataStreams(tables []Table, tableSizes map[uint32]int64, numStreams int) map[uint32]int {
+	tablesBySize := make([]Table, len(tables))
+	copy(tablesBySize, tables)
+	sort.SliceStable(tablesBySize, func(i, j int) bool {
+		return tableSizes[tablesBySize[i].Oid] > tableSizes[tablesBySize[j].Oid]
+	})
+	streamSizes := make([]int64, numStreams)
+	streamTables := make([]int, numStreams)
+	streams := make(map[uint32]int, len(tables))
+	for _, table := range tablesBySize {
+		stream := 0
+		for i := 1; i < numStreams; i++ {
+			if streamSizes[i] < streamSizes[stream] || (streamSizes[i] == streamSizes[stream] && streamTables[i] < streamTables[stream]) {
+				stream = i
+			}
+		}
+		streams[table.Oid] = stream
+		streamSizes[stream] += tableSizes[table.Oid]
+		streamTables[stream]++
+	}
+	return streams
+}
+
 func BackupSingleTableData(table Table, rowsCopiedMap map[uint32]int64, counters *BackupProgressCounters, whichConn int) error {
 	logMessage := fmt.Sprintf("Worker %d: Writing data for table %s to file", whichConn, table.FQN())
 	// Avoid race condition by incrementing counters in call to sprintf
@@ -112,18 +351,67 @@ func BackupSingleTableData(table Table, rowsCopiedMap map[uint32]int64, counters
 	destinationToWrite := ""
 	if MustGetFlagBool(options.SINGLE_DATA_FILE) {
 		destinationToWrite = fmt.Sprintf("%s_%d", globalFPInfo.GetSegmentPipePathForCopyCommand(), table.Oid)
+	} else if MustGetFlagBool(options.REPOSITORY) {
+		// The data file of each table is the list of the chunks its data is stored in
+		destinationToWrite = globalFPInfo.GetTableBackupFilePathForCopyCommand(table.Oid, utils.ChunkListExtension, false)
 	} else {
 		destinationToWrite = globalFPInfo.GetTableBackupFilePathForCopyCommand(table.Oid, utils.GetPipeThroughProgram().Extension, false)
 	}
-	rowsCopied, err := CopyTableOut(connectionPool, table, destinationToWrite, whichConn)
+	rowsCopied, err := copyTableOutWithStallRetries(table, destinationToWrite, whichConn)
 	if err != nil {
 		return err
 	}
 	rowsCopiedMap[table.Oid] = rowsCopied
+	if resumeStateFile != nil {
+		err = resumeStateFile.RecordTable(constructCoordinatorDataEntry(table, rowsCopied))
+		if err != nil {
+			return err
+		}
+	}
 	counters.ProgressBar.Increment()
 	return nil
 }
 
+/*
+ * The progress bar counts the tables backed up, so the number of bytes backed
+ * up, the throughput and the estimated time left are gathered from the
+ * segments periodically and shown after it, or logged when logging verbosely.
+ * The same progress is used to notice stalled tables.  Gathering the progress
+ * runs a command on every host, so it is only done periodically when it is
+ * shown or there is a stall timeout.  Returns a function that stops reporting.
+ */
+func startDataProgressReporting(progress *utils.DataProgress, progressBar utils.ProgressBar) func() {
+	isVerbose := gplog.GetVerbosity() > gplog.LOGINFO
+	bar, isBar := progressBar.(*pb.ProgressBar)
+	showBar := isBar && !bar.NotPrint
+	if !isVerbose && !showBar && dataStallWatchdog == nil {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(dataProgressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				segmentProgress := utils.GetDataProgressOnSegments(globalCluster, globalFPInfo)
+				progress.Update(segmentProgress)
+				for _, stalled := range dataStallWatchdog.Check(segmentProgress, globalCluster.ContentIDs, time.Now()) {
+					cancelStalledCopy(stalled, dataStallWatchdog.timeout)
+				}
+				if isVerbose {
+					gplog.Verbose("Data backed up: %s", progress)
+				} else if showBar {
+					bar.Postfix(fmt.Sprintf(" (%s)", progress))
+				}
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
 /*
 * Iterate through tables, backup data from each table in set.
 * If supported by the database, a synchronized database snapshot is used to sync
@@ -138,10 +426,17 @@ func BackupSingleTableData(table Table, rowsCopiedMap map[uint32]int64, counters
 * FIXME: Simplify BackupDataForAllTables by having one function for snapshot workflow and
 * another without, then extract common portions into their own functions.
  */
-func BackupDataForAllTables(tables []Table) []map[uint32]int64 {
+func BackupDataForAllTables(tables []Table, tableSizes map[uint32]int64) []map[uint32]int64 {
 	counters := BackupProgressCounters{NumRegTables: 0, TotalRegTables: int64(len(tables))}
 	counters.ProgressBar = utils.NewProgressBar(int(counters.TotalRegTables), "Tables backed up: ", utils.PB_INFO)
 	counters.ProgressBar.Start()
+	totalBytes := int64(0)
+	for _, table := range tables {
+		totalBytes += tableSizes[table.Oid]
+	}
+	dataProgress := utils.NewDataProgress(totalBytes)
+	dataStallWatchdog = NewStallWatchdog(time.Duration(MustGetFlagInt(options.STALL_TIMEOUT)) * time.Second)
+	stopDataProgressReporting := startDataProgressReporting(dataProgress, counters.ProgressBar)
 	rowsCopiedMaps := make([]map[uint32]int64, connectionPool.NumConns)
 	/*
 	 * We break when an interrupt is received and rely on
@@ -328,7 +623,13 @@ func BackupDataForAllTables(tables []Table) []map[uint32]int64 {
 		gplog.Fatal(agentErr, "")
 	}
 
+	stopDataProgressReporting()
+	dataProgress.Update(utils.GetDataProgressOnSegments(globalCluster, globalFPInfo))
+	if backupReport != nil {
+		backupReport.SegmentDataBytes = dataProgress.GetSegmentBytes()
+	}
 	counters.ProgressBar.Finish()
+	gplog.Verbose("Data backed up: %s", dataProgress)
 	return rowsCopiedMaps
 }
 
@@ -2,10 +2,12 @@ package backup_test
 
 import (
 	"fmt"
+	"os"
 	"regexp"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/filepath"
 	"github.com/greenplum-db/gpbackup/history"
 	"github.com/greenplum-db/gpbackup/options"
 	"github.com/greenplum-db/gpbackup/report"
@@ -15,6 +17,7 @@ import (
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("backup/data tests", func() {
@@ -70,12 +73,105 @@ var _ = Describe("backup/data tests", func() {
 			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
 			Expect(tocfile.DataEntries).To(BeNil())
 		})
+		It("records the predicate of a table in the table predicate file", func() {
+			backup.SetTablePredicates(map[string]string{"public.table": "a > 1"})
+			defer backup.SetTablePredicates(nil)
+			tables := []backup.Table{table}
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+			expectedDataEntries := []toc.CoordinatorDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)", Predicate: "a > 1"}}
+			Expect(tocfile.DataEntries).To(Equal(expectedDataEntries))
+		})
+		It("records the format of table data backed up in binary format", func() {
+			_ = cmdFlags.Set(options.DATA_FORMAT, "binary")
+			tables := []backup.Table{table}
+			backup.AddTableDataEntriesToTOC(tables, rowsCopiedMaps)
+			expectedDataEntries := []toc.CoordinatorDataEntry{{Schema: "public", Name: "table", Oid: 1, AttributeString: "(a)", DataFormat: "binary"}}
+			Expect(tocfile.DataEntries).To(Equal(expectedDataEntries))
+		})
+	})
+	Describe("GetTablePredicatesForBackup", func() {
+		AfterEach(func() {
+			backup.SetTablePredicates(nil)
+		})
+		It("returns the predicates of the tables being backed up", func() {
+			backup.SetTablePredicates(map[string]string{"public.table1": "a > 1", "Schema.Table": "b = 'x'", "public.missing": "c < 2"})
+			tables := []backup.Table{
+				{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "table1"}},
+				{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "table2"}},
+				{Relation: backup.Relation{Oid: 3, Schema: `"Schema"`, Name: `"Table"`}},
+			}
+			predicates := backup.GetTablePredicatesForBackup(tables)
+			Expect(predicates).To(Equal(map[string]string{"public.table1": "a > 1", `"Schema"."Table"`: "b = 'x'"}))
+			Expect(logfile).To(Say("Table public.missing in the table predicate file does not have any data to back up"))
+		})
+	})
+	Describe("AddTableDataSizesToTOC", func() {
+		It("records the size of each table with a data entry", func() {
+			tocfile := &toc.TOC{DataEntries: []toc.CoordinatorDataEntry{
+				{Schema: "public", Name: "table1", Oid: 1},
+				{Schema: "public", Name: "table2", Oid: 2},
+			}}
+			backup.SetTOC(tocfile)
+			backup.AddTableDataSizesToTOC(map[uint32]int64{1: 1024, 3: 2048})
+			Expect(tocfile.DataEntries[0].DataSize).To(Equal(int64(1024)))
+			Expect(tocfile.DataEntries[1].DataSize).To(Equal(int64(0)))
+		})
+	})
+	Describe("ScheduleTablesForBackup", func() {
+		tbl1 := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "table1"}}
+		tbl2 := backup.Table{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "table2"}}
+		tbl3 := backup.Table{Relation: backup.Relation{Oid: 3, Schema: "public", Name: "table3"}}
+		tableSizes := map[uint32]int64{1: 10, 2: 1000, 3: 100}
+
+		It("schedules the largest tables first", func() {
+			tables := backup.ScheduleTablesForBackup([]backup.Table{tbl1, tbl2, tbl3}, tableSizes, []string{})
+			Expect(tables).To(Equal([]backup.Table{tbl2, tbl3, tbl1}))
+		})
+		It("schedules priority tables before all other tables", func() {
+			tables := backup.ScheduleTablesForBackup([]backup.Table{tbl1, tbl2, tbl3}, tableSizes, []string{"public.table1"})
+			Expect(tables).To(Equal([]backup.Table{tbl1, tbl2, tbl3}))
+		})
+	})
+	Describe("AssignTablesToDataStreams", func() {
+		tbl1 := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "table1"}}
+		tbl2 := backup.Table{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "table2"}}
+		tbl3 := backup.Table{Relation: backup.Relation{Oid: 3, Schema: "public", Name: "table3"}}
+		tbl4 := backup.Table{Relation: backup.Relation{Oid: 4, Schema: "public", Name: "table4"}}
+
+		It("assigns each table to the stream with the least data so far, from the largest table down", func() {
+			tableSizes := map[uint32]int64{1: 300, 2: 1000, 3: 600, 4: 400}
+			streams := backup.AssignTablesToDataStreams([]backup.Table{tbl1, tbl2, tbl3, tbl4}, tableSizes, 2)
+			Expect(streams).To(Equal(map[uint32]int{2: 0, 3: 1, 4: 1, 1: 0}))
+		})
+		It("assigns tables of the same size round-robin", func() {
+			streams := backup.AssignTablesToDataStreams([]backup.Table{tbl1, tbl2, tbl3, tbl4}, map[uint32]int64{}, 3)
+			Expect(streams).To(Equal(map[uint32]int{1: 0, 2: 1, 3: 2, 4: 0}))
+		})
+		It("assigns every table to the first stream if there is only one", func() {
+			streams := backup.AssignTablesToDataStreams([]backup.Table{tbl1, tbl2}, map[uint32]int64{1: 10, 2: 20}, 1)
+			Expect(streams).To(Equal(map[uint32]int{1: 0, 2: 0}))
+		})
 	})
 	Describe("CopyTableOut", func() {
 		testTable := backup.Table{Relation: backup.Relation{SchemaOid: 2345, Oid: 3456, Schema: "public", Name: "foo"}}
+		backupFilterCommand := fmt.Sprintf("%s/bin/gpbackup_helper --backup-filter --checksum-file <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_20170101010101_<SEGID>_checksums --progress-file <SEG_DATA_DIR>/gpbackup_<SEGID>_20170101010101_progress_0 --oid 3456 --content <SEGID>", os.Getenv("GPHOME"))
+		BeforeEach(func() {
+			backup.SetFPInfo(filepath.FilePathInfo{Timestamp: "20170101010101", BaseDataDir: "<SEG_DATA_DIR>"})
+		})
 		It("will back up a table to its own file with gzip compression", func() {
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", OutputCommand: "gzip -c -8", InputCommand: "gzip -d -c", Extension: ".gz"})
-			execStr := regexp.QuoteMeta("COPY public.foo TO PROGRAM 'gzip -c -8 > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.gz' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;")
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", Level: 8, Extension: ".gz"})
+			execStr := regexp.QuoteMeta(fmt.Sprintf("COPY public.foo TO PROGRAM '%s --compression-type gzip --compression-level 8 > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.gz' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;", backupFilterCommand))
+			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
+			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.gz"
+
+			_, err := backup.CopyTableOut(connectionPool, testTable, filename, defaultConnNum)
+
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+		It("will back up a table to its own file in binary format", func() {
+			_ = cmdFlags.Set(options.DATA_FORMAT, "binary")
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", Level: 8, Extension: ".gz"})
+			execStr := regexp.QuoteMeta(fmt.Sprintf("COPY public.foo TO PROGRAM '%s --compression-type gzip --compression-level 8 > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.gz' WITH BINARY ON SEGMENT IGNORE EXTERNAL PARTITIONS;", backupFilterCommand))
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.gz"
 
@@ -87,8 +183,8 @@ var _ = Describe("backup/data tests", func() {
 			_ = cmdFlags.Set(options.PLUGIN_CONFIG, "/tmp/plugin_config")
 			pluginConfig := utils.PluginConfig{ExecutablePath: "/tmp/fake-plugin.sh", ConfigPath: "/tmp/plugin_config"}
 			backup.SetPluginConfig(&pluginConfig)
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", OutputCommand: "gzip -c -8", InputCommand: "gzip -d -c", Extension: ".gz"})
-			execStr := regexp.QuoteMeta("COPY public.foo TO PROGRAM 'gzip -c -8 | /tmp/fake-plugin.sh backup_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;")
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", Level: 8, Extension: ".gz"})
+			execStr := regexp.QuoteMeta(fmt.Sprintf("COPY public.foo TO PROGRAM '%s --compression-type gzip --compression-level 8 | /tmp/fake-plugin.sh backup_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;", backupFilterCommand))
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
@@ -97,8 +193,8 @@ var _ = Describe("backup/data tests", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 		It("will back up a table to its own file with zstd compression", func() {
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "zstd", OutputCommand: "zstd --compress -3 -c", InputCommand: "zstd --decompress -c", Extension: ".zst"})
-			execStr := regexp.QuoteMeta("COPY public.foo TO PROGRAM 'zstd --compress -3 -c > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.zst' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;")
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "zstd", Level: 3, Extension: ".zst"})
+			execStr := regexp.QuoteMeta(fmt.Sprintf("COPY public.foo TO PROGRAM '%s --compression-type zstd --compression-level 3 > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.zst' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;", backupFilterCommand))
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.zst"
 
@@ -110,8 +206,8 @@ var _ = Describe("backup/data tests", func() {
 			_ = cmdFlags.Set(options.PLUGIN_CONFIG, "/tmp/plugin_config")
 			pluginConfig := utils.PluginConfig{ExecutablePath: "/tmp/fake-plugin.sh", ConfigPath: "/tmp/plugin_config"}
 			backup.SetPluginConfig(&pluginConfig)
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "zstd", OutputCommand: "zstd --compress -3 -c", InputCommand: "zstd --decompress -c", Extension: ".zst"})
-			execStr := regexp.QuoteMeta("COPY public.foo TO PROGRAM 'zstd --compress -3 -c | /tmp/fake-plugin.sh backup_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;")
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "zstd", Level: 3, Extension: ".zst"})
+			execStr := regexp.QuoteMeta(fmt.Sprintf("COPY public.foo TO PROGRAM '%s --compression-type zstd --compression-level 3 | /tmp/fake-plugin.sh backup_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;", backupFilterCommand))
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
@@ -120,8 +216,8 @@ var _ = Describe("backup/data tests", func() {
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 		It("will back up a table to its own file without compression", func() {
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", OutputCommand: "cat -", InputCommand: "cat -", Extension: ""})
-			execStr := regexp.QuoteMeta("COPY public.foo TO PROGRAM 'cat - > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;")
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", Level: 0, Extension: ""})
+			execStr := regexp.QuoteMeta(fmt.Sprintf("COPY public.foo TO PROGRAM '%s --compression-level 0 > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;", backupFilterCommand))
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
 
@@ -133,8 +229,8 @@ var _ = Describe("backup/data tests", func() {
 			_ = cmdFlags.Set(options.PLUGIN_CONFIG, "/tmp/plugin_config")
 			pluginConfig := utils.PluginConfig{ExecutablePath: "/tmp/fake-plugin.sh", ConfigPath: "/tmp/plugin_config"}
 			backup.SetPluginConfig(&pluginConfig)
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", OutputCommand: "cat -", InputCommand: "cat -", Extension: ""})
-			execStr := regexp.QuoteMeta("COPY public.foo TO PROGRAM 'cat - | /tmp/fake-plugin.sh backup_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;")
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", Level: 0, Extension: ""})
+			execStr := regexp.QuoteMeta(fmt.Sprintf("COPY public.foo TO PROGRAM '%s --compression-level 0 | /tmp/fake-plugin.sh backup_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;", backupFilterCommand))
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
@@ -142,6 +238,19 @@ var _ = Describe("backup/data tests", func() {
 
 			Expect(err).ShouldNot(HaveOccurred())
 		})
+		It("will back up a table to its own file with limited bandwidth", func() {
+			backup.SetMaxBandwidth(1048576, false)
+			defer backup.SetMaxBandwidth(0, false)
+			_ = cmdFlags.Set(options.JOBS, "4")
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", Level: 0, Extension: ""})
+			execStr := regexp.QuoteMeta(fmt.Sprintf("COPY public.foo TO PROGRAM '%s --compression-level 0 --max-bandwidth 262144 > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;", backupFilterCommand))
+			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
+			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
+
+			_, err := backup.CopyTableOut(connectionPool, testTable, filename, defaultConnNum)
+
+			Expect(err).ShouldNot(HaveOccurred())
+		})
 		It("will back up a table to a single file", func() {
 			_ = cmdFlags.Set(options.SINGLE_DATA_FILE, "true")
 			execStr := regexp.QuoteMeta(`COPY public.foo TO PROGRAM '(test -p "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456" || (echo "Pipe not found <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456">&2; exit 1)) && cat - > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT IGNORE EXTERNAL PARTITIONS;`)
@@ -152,6 +261,54 @@ var _ = Describe("backup/data tests", func() {
 
 			Expect(err).ShouldNot(HaveOccurred())
 		})
+		It("will back up only the rows of a table matching its predicate", func() {
+			backup.SetTablePredicates(map[string]string{"public.foo": "i > 1"})
+			defer backup.SetTablePredicates(nil)
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", Level: 0, Extension: ""})
+			execStr := regexp.QuoteMeta(fmt.Sprintf("COPY (SELECT * FROM public.foo WHERE i > 1) TO PROGRAM '%s --compression-level 0 > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT;", backupFilterCommand))
+			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
+			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
+
+			_, err := backup.CopyTableOut(connectionPool, testTable, filename, defaultConnNum)
+
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+		It("will back up the masked data of a table's masked columns", func() {
+			backup.SetMaskingRules(map[string]string{"public.foo.b": "md5(b)", "public.foo.c": "NULL"})
+			backup.SetTablePredicates(map[string]string{"public.foo": "a > 1"})
+			defer backup.SetMaskingRules(nil)
+			defer backup.SetTablePredicates(nil)
+			maskedTable := testTable
+			maskedTable.ColumnDefs = []backup.ColumnDefinition{{Name: "a"}, {Name: "b"}, {Name: "gen", AttGenerated: "STORED"}, {Name: "c"}}
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", Level: 0, Extension: ""})
+			execStr := regexp.QuoteMeta(fmt.Sprintf("COPY (SELECT a, md5(b) AS b, NULL AS c FROM public.foo WHERE a > 1) TO PROGRAM '%s --compression-level 0 > <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456' WITH CSV DELIMITER ',' ON SEGMENT;", backupFilterCommand))
+			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
+			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
+
+			_, err := backup.CopyTableOut(connectionPool, maskedTable, filename, defaultConnNum)
+
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+	})
+	Describe("LogMaskedColumnsForBackup", func() {
+		AfterEach(func() {
+			backup.SetMaskingRules(nil)
+		})
+		It("warns about masking rules that do not match a column with data to back up", func() {
+			backup.SetMaskingRules(map[string]string{"public.foo.a": "NULL", `"Schema"."Table".Col`: "md5(\"Col\")", "public.foo.gen": "NULL", "public.foo.missing": "NULL"})
+			tables := []backup.Table{
+				{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "foo"},
+					TableDefinition: backup.TableDefinition{ColumnDefs: []backup.ColumnDefinition{{Name: "a"}, {Name: "gen", AttGenerated: "STORED"}}}},
+				{Relation: backup.Relation{Oid: 2, Schema: `"Schema"`, Name: `"Table"`},
+					TableDefinition: backup.TableDefinition{ColumnDefs: []backup.ColumnDefinition{{Name: `"Col"`}}}},
+			}
+			backup.LogMaskedColumnsForBackup(tables)
+			Expect(string(logfile.Contents())).To(ContainSubstring("Masking data of column public.foo.a with: NULL"))
+			Expect(string(logfile.Contents())).To(ContainSubstring(`Masking data of column "Schema"."Table"."Col" with: md5("Col")`))
+			Expect(string(logfile.Contents())).To(ContainSubstring("Column public.foo.gen in the masking rules file does not have any data to back up"))
+			Expect(string(logfile.Contents())).To(ContainSubstring("Column public.foo.missing in the masking rules file does not have any data to back up"))
+			Expect(string(logfile.Contents())).ToNot(ContainSubstring("Column public.foo.a in the masking rules file"))
+		})
 	})
 	Describe("BackupSingleTableData", func() {
 		var (
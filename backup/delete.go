@@ -0,0 +1,131 @@
+package backup
+
+/*
+ * This file contains functions related to deleting a backup with the
+ * --delete-backup flag, and to removing the repository chunks that no
+ * remaining backup uses once it is deleted.
+ */
+
+import (
+	"fmt"
+	"os"
+	"path"
+	gofilepath "path/filepath"
+	"runtime/debug"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/filepath"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/pkg/errors"
+)
+
+func DoDeleteBackup() {
+	SetLoggerVerbosity()
+	gplog.Verbose("Backup Command: %s", os.Args)
+	gplog.Info("gpbackup version = %s", GetVersion())
+
+	timestamp := MustGetFlagString(options.DELETE_BACKUP)
+	gplog.Info("Deleting backup %s", timestamp)
+
+	clusterConfigConn := dbconn.NewDBConnFromEnvironment(MustGetFlagString(options.DBNAME))
+	clusterConfigConn.MustConnect(1)
+	segConfig := cluster.MustGetSegmentConfiguration(clusterConfigConn)
+	deleteCluster := cluster.NewCluster(segConfig)
+	segPrefix := ""
+	if !MustGetFlagBool(options.SINGLE_BACKUP_DIR) {
+		segPrefix = filepath.GetSegPrefix(clusterConfigConn)
+	}
+	clusterConfigConn.Close()
+
+	fpInfo := filepath.NewFilePathInfo(deleteCluster, MustGetFlagString(options.BACKUP_DIR), timestamp, segPrefix, MustGetFlagBool(options.SINGLE_BACKUP_DIR))
+	configFilename := fpInfo.GetConfigFilePath()
+	if _, err := os.Stat(configFilename); err != nil {
+		gplog.Fatal(errors.Errorf("Backup %s does not exist in %s", timestamp, MustGetFlagString(options.BACKUP_DIR)), "")
+	}
+	backupConfig := history.ReadConfigFile(configFilename)
+	backupsDir := path.Dir(path.Dir(fpInfo.GetDirForContent(-1)))
+	if dependentBackups := GetDependentBackups(backupsDir, timestamp); len(dependentBackups) > 0 {
+		gplog.Fatal(errors.Errorf("Backup %s cannot be deleted, as the following backups are based off of it: %s.  Please delete them first.",
+			timestamp, strings.Join(dependentBackups, ", ")), "")
+	}
+
+	remoteOutput := deleteCluster.GenerateAndExecuteCommand("Removing backup directories", cluster.ON_SEGMENTS|cluster.INCLUDE_COORDINATOR, func(contentID int) string {
+		return fmt.Sprintf("rm -rf %s", fpInfo.GetDirForContent(contentID))
+	})
+	deleteCluster.CheckClusterError(remoteOutput, "Unable to remove backup directories", func(contentID int) string {
+		return fmt.Sprintf("Unable to remove backup directory %s", fpInfo.GetDirForContent(contentID))
+	})
+
+	if !MustGetFlagBool(options.NO_HISTORY) {
+		historyDB, err := history.InitializeHistoryDatabase(fpInfo.GetBackupHistoryDatabasePath())
+		gplog.FatalOnError(err)
+		err = history.MarkBackupDeleted(historyDB, timestamp, history.CurrentTimestamp())
+		historyDB.Close()
+		if err != nil {
+			gplog.Warn("Unable to record the deletion of backup %s in the history database: %v", timestamp, err)
+		}
+	}
+
+	if backupConfig.Repository {
+		// The chunk lists of the deleted backup are gone, so its chunks that no other backup lists can be removed
+		remoteOutput = deleteCluster.GenerateAndExecuteCommand("Removing repository chunks no backup uses", cluster.ON_SEGMENTS, func(contentID int) string {
+			return GetCollectChunksCommand(fpInfo.GetChunkDir(contentID), contentID)
+		})
+		deleteCluster.CheckClusterError(remoteOutput, "Unable to remove repository chunks", func(contentID int) string {
+			return fmt.Sprintf("Unable to remove repository chunks in %s", fpInfo.GetChunkDir(contentID))
+		})
+	}
+}
+
+/*
+ * Returns the timestamps of the backups in the backups directory whose restore
+ * plan includes the given backup, as an incremental or differential backup
+ * cannot be restored without the backups it is based off of.
+ */
+func GetDependentBackups(backupsDir string, timestamp string) []string {
+	configFilenames, err := gofilepath.Glob(path.Join(backupsDir, "*", "*", "gpbackup_*_config.yaml"))
+	gplog.FatalOnError(err)
+	dependentBackups := make([]string, 0)
+	for _, configFilename := range configFilenames {
+		backupConfig := history.ReadConfigFile(configFilename)
+		if backupConfig.Timestamp == timestamp {
+			continue
+		}
+		for _, restorePlanEntry := range backupConfig.RestorePlan {
+			if restorePlanEntry.Timestamp == timestamp {
+				dependentBackups = append(dependentBackups, backupConfig.Timestamp)
+				break
+			}
+		}
+	}
+	return dependentBackups
+}
+
+func GetCollectChunksCommand(chunkDir string, contentID int) string {
+	return fmt.Sprintf("%s/bin/gpbackup_helper --collect-chunks --chunk-dir %s --content %d", operating.System.Getenv("GPHOME"), chunkDir, contentID)
+}
+
+func DoDeleteTeardown() {
+	defer func() {
+		errorCode := gplog.GetErrorCode()
+		if errorCode == 0 {
+			gplog.Info("Backup deletion completed successfully")
+		}
+		os.Exit(errorCode)
+	}()
+
+	if err := recover(); err != nil {
+		// gplog's Fatal will cause a panic with error code 2
+		if gplog.GetErrorCode() != 2 {
+			gplog.Error(fmt.Sprintf("%v: %s", err, debug.Stack()))
+			gplog.SetErrorCode(2)
+		} else {
+			fmt.Println(err)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package backup_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/history"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/delete tests", func() {
+	Describe("GetDependentBackups", func() {
+		var backupsDir string
+
+		writeConfig := func(timestamp string, restorePlanTimestamps ...string) {
+			config := history.BackupConfig{Timestamp: timestamp, RestorePlan: []history.RestorePlanEntry{}}
+			for _, restorePlanTimestamp := range append(restorePlanTimestamps, timestamp) {
+				config.RestorePlan = append(config.RestorePlan, history.RestorePlanEntry{Timestamp: restorePlanTimestamp, TableFQNs: []string{}})
+			}
+			backupDir := path.Join(backupsDir, timestamp[0:8], timestamp)
+			Expect(os.MkdirAll(backupDir, 0755)).To(Succeed())
+			history.WriteConfigFile(&config, path.Join(backupDir, "gpbackup_"+timestamp+"_config.yaml"))
+		}
+
+		BeforeEach(func() {
+			var err error
+			backupsDir, err = ioutil.TempDir("", "gpbackup_delete_test")
+			Expect(err).ToNot(HaveOccurred())
+			writeConfig("20220101010101")
+			writeConfig("20220102010101", "20220101010101")
+			writeConfig("20220103010101", "20220101010101", "20220102010101")
+			writeConfig("20220104010101")
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(backupsDir)
+		})
+
+		It("returns the backups whose restore plan includes the backup", func() {
+			Expect(backup.GetDependentBackups(backupsDir, "20220101010101")).To(Equal([]string{"20220102010101", "20220103010101"}))
+			Expect(backup.GetDependentBackups(backupsDir, "20220102010101")).To(Equal([]string{"20220103010101"}))
+		})
+		It("returns no backups for a backup nothing is based off of", func() {
+			Expect(backup.GetDependentBackups(backupsDir, "20220103010101")).To(BeEmpty())
+			Expect(backup.GetDependentBackups(backupsDir, "20220104010101")).To(BeEmpty())
+		})
+	})
+	Describe("GetCollectChunksCommand", func() {
+		It("removes the chunks no backup uses from the chunk directory of a segment", func() {
+			Expect(backup.GetCollectChunksCommand("/backups/gpseg0/chunks", 0)).To(HaveSuffix("/bin/gpbackup_helper --collect-chunks --chunk-dir /backups/gpseg0/chunks --content 0"))
+		})
+	})
+})
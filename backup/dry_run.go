@@ -0,0 +1,180 @@
+package backup
+
+/*
+ * This file contains structs and functions related to printing the plan for a
+ * backup with the --dry-run flag instead of taking it.
+ */
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+type DryRunTable struct {
+	Name string
+	Size int64
+}
+
+type DryRunSkippedTable struct {
+	Name   string
+	Reason string
+}
+
+type DryRunPlan struct {
+	Timestamp      string
+	DatabaseName   string
+	BackupType     string
+	BaseTimestamp  string
+	MetadataTables []string
+	DataTables     []DryRunTable
+	SkippedTables  []DryRunSkippedTable
+	TotalDataSize  int64
+	RestorePlan    []history.RestorePlanEntry
+}
+
+func GetBackupType(backupConfig *history.BackupConfig) string {
+	switch {
+	case backupConfig.MetadataOnly:
+		return "metadata-only"
+	case backupConfig.DataOnly:
+		return "data-only"
+	case backupConfig.Differential:
+		return "differential"
+	case backupConfig.Incremental:
+		return "incremental"
+	default:
+		return "full"
+	}
+}
+
+/*
+ * The skipped tables are the external and foreign tables whose data is never
+ * backed up, and the data tables are the ones whose data would be backed up
+ * by this run, so for an incremental or differential backup they only include
+ * the tables that changed since the base backup.
+ */
+func ConstructDryRunPlan(backupConfig *history.BackupConfig, baseTimestamp string, metadataTables []Table,
+	skippedTables []Table, dataTables []Table, tableSizes map[uint32]int64) DryRunPlan {
+	plan := DryRunPlan{
+		Timestamp:      backupConfig.Timestamp,
+		DatabaseName:   backupConfig.DatabaseName,
+		BackupType:     GetBackupType(backupConfig),
+		BaseTimestamp:  baseTimestamp,
+		MetadataTables: make([]string, 0),
+		DataTables:     make([]DryRunTable, 0),
+		SkippedTables:  make([]DryRunSkippedTable, 0),
+		RestorePlan:    make([]history.RestorePlanEntry, 0),
+	}
+	if !backupConfig.DataOnly {
+		for _, table := range metadataTables {
+			plan.MetadataTables = append(plan.MetadataTables, table.FQN())
+		}
+	}
+	if backupConfig.MetadataOnly {
+		return plan
+	}
+	for _, table := range skippedTables {
+		reason := "foreign table"
+		if table.IsExternal {
+			reason = "external table"
+		}
+		plan.SkippedTables = append(plan.SkippedTables, DryRunSkippedTable{Name: table.FQN(), Reason: reason})
+	}
+	for _, table := range dataTables {
+		size := tableSizes[table.Oid]
+		plan.DataTables = append(plan.DataTables, DryRunTable{Name: table.FQN(), Size: size})
+		plan.TotalDataSize += size
+	}
+	if backupConfig.RestorePlan != nil {
+		plan.RestorePlan = backupConfig.RestorePlan
+	}
+	return plan
+}
+
+func printDryRunPlan(baseTimestamp string, metadataTables []Table, retrievedDataTables []Table, backupSetTables []Table) {
+	skippedTables := make([]Table, 0)
+	for _, table := range retrievedDataTables {
+		if table.SkipDataBackup() {
+			skippedTables = append(skippedTables, table)
+		}
+	}
+	tableSizes := make(map[uint32]int64)
+	if !backupReport.MetadataOnly {
+		gplog.Info("Estimating size of table data")
+		tableSizes = GetTableSizes(connectionPool, backupSetTables)
+	}
+	plan := ConstructDryRunPlan(&backupReport.BackupConfig, baseTimestamp, metadataTables, skippedTables, backupSetTables, tableSizes)
+
+	planFilename := MustGetFlagString(options.DRY_RUN_FILE)
+	if planFilename == "" {
+		err := WriteDryRunPlan(os.Stdout, plan, MustGetFlagString(options.DRY_RUN_FORMAT))
+		gplog.FatalOnError(err)
+		return
+	}
+	planFile, err := os.Create(planFilename)
+	gplog.FatalOnError(err, planFilename)
+	err = WriteDryRunPlan(planFile, plan, MustGetFlagString(options.DRY_RUN_FORMAT))
+	gplog.FatalOnError(err, planFilename)
+	err = planFile.Close()
+	gplog.FatalOnError(err, planFilename)
+	gplog.Info("Dry run plan written to %s", planFilename)
+}
+
+func WriteDryRunPlan(writer io.Writer, plan DryRunPlan, format string) error {
+	switch format {
+	case "json":
+		contents, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(writer, "%s\n", contents)
+		return err
+	case "text":
+		return writeDryRunPlanText(writer, plan)
+	default:
+		return errors.Errorf("Unrecognized dry run format %s", format)
+	}
+}
+
+func writeDryRunPlanText(writer io.Writer, plan DryRunPlan) error {
+	tabWriter := tabwriter.NewWriter(writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tabWriter, "Backup timestamp:\t%s\n", plan.Timestamp)
+	fmt.Fprintf(tabWriter, "Database:\t%s\n", plan.DatabaseName)
+	fmt.Fprintf(tabWriter, "Backup type:\t%s\n", plan.BackupType)
+	if plan.BaseTimestamp != "" {
+		fmt.Fprintf(tabWriter, "Base backup timestamp:\t%s\n", plan.BaseTimestamp)
+	}
+	fmt.Fprintf(tabWriter, "Tables with metadata:\t%d\n", len(plan.MetadataTables))
+	fmt.Fprintf(tabWriter, "Tables with data:\t%d\n", len(plan.DataTables))
+	fmt.Fprintf(tabWriter, "Tables skipped:\t%d\n", len(plan.SkippedTables))
+	fmt.Fprintf(tabWriter, "Estimated data size:\t%s\n", utils.FormatByteSize(plan.TotalDataSize))
+
+	if len(plan.DataTables) > 0 {
+		fmt.Fprintf(tabWriter, "\nTable data to back up:\n")
+		for _, table := range plan.DataTables {
+			fmt.Fprintf(tabWriter, "  %s\t%s\n", table.Name, utils.FormatByteSize(table.Size))
+		}
+	}
+	if len(plan.SkippedTables) > 0 {
+		fmt.Fprintf(tabWriter, "\nTable data skipped:\n")
+		for _, table := range plan.SkippedTables {
+			fmt.Fprintf(tabWriter, "  %s\t%s\n", table.Name, table.Reason)
+		}
+	}
+	if len(plan.RestorePlan) > 0 {
+		fmt.Fprintf(tabWriter, "\nRestore plan:\n")
+		for _, entry := range plan.RestorePlan {
+			fmt.Fprintf(tabWriter, "  %s\t%d table(s)\n", entry.Timestamp, len(entry.TableFQNs))
+		}
+	}
+	return tabWriter.Flush()
+}
@@ -0,0 +1,123 @@
+package backup_test
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/history"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/dry_run tests", func() {
+	tbl1 := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "table1"}}
+	tbl2 := backup.Table{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "table2"}}
+	extTbl := backup.Table{Relation: backup.Relation{Oid: 3, Schema: "public", Name: "ext_table"},
+		TableDefinition: backup.TableDefinition{IsExternal: true}}
+	foreignTbl := backup.Table{Relation: backup.Relation{Oid: 4, Schema: "public", Name: "foreign_table"},
+		TableDefinition: backup.TableDefinition{ForeignDef: backup.ForeignTableDefinition{Oid: 4, Server: "fs"}}}
+	tableSizes := map[uint32]int64{1: 1024, 2: 3072}
+
+	var backupConfig *history.BackupConfig
+	BeforeEach(func() {
+		backupConfig = &history.BackupConfig{
+			Timestamp:    "20220101010101",
+			DatabaseName: "testdb",
+			RestorePlan: []history.RestorePlanEntry{
+				{Timestamp: "20220101010101", TableFQNs: []string{"public.table1", "public.table2"}},
+			},
+		}
+	})
+
+	Describe("ConstructDryRunPlan", func() {
+		It("includes the metadata, data and skipped tables of a full backup", func() {
+			plan := backup.ConstructDryRunPlan(backupConfig, "", []backup.Table{tbl1, tbl2, extTbl, foreignTbl},
+				[]backup.Table{extTbl, foreignTbl}, []backup.Table{tbl1, tbl2}, tableSizes)
+
+			Expect(plan.BackupType).To(Equal("full"))
+			Expect(plan.MetadataTables).To(Equal([]string{"public.table1", "public.table2", "public.ext_table", "public.foreign_table"}))
+			Expect(plan.DataTables).To(Equal([]backup.DryRunTable{{Name: "public.table1", Size: 1024}, {Name: "public.table2", Size: 3072}}))
+			Expect(plan.SkippedTables).To(Equal([]backup.DryRunSkippedTable{
+				{Name: "public.ext_table", Reason: "external table"},
+				{Name: "public.foreign_table", Reason: "foreign table"},
+			}))
+			Expect(plan.TotalDataSize).To(Equal(int64(4096)))
+			Expect(plan.RestorePlan).To(Equal(backupConfig.RestorePlan))
+		})
+		It("only includes the changed tables of an incremental backup", func() {
+			backupConfig.Incremental = true
+			plan := backup.ConstructDryRunPlan(backupConfig, "20211231010101", []backup.Table{tbl1, tbl2},
+				[]backup.Table{}, []backup.Table{tbl2}, tableSizes)
+
+			Expect(plan.BackupType).To(Equal("incremental"))
+			Expect(plan.BaseTimestamp).To(Equal("20211231010101"))
+			Expect(plan.DataTables).To(Equal([]backup.DryRunTable{{Name: "public.table2", Size: 3072}}))
+			Expect(plan.TotalDataSize).To(Equal(int64(3072)))
+		})
+		It("does not include any data for a metadata-only backup", func() {
+			backupConfig.MetadataOnly = true
+			backupConfig.RestorePlan = nil
+			plan := backup.ConstructDryRunPlan(backupConfig, "", []backup.Table{tbl1, extTbl},
+				[]backup.Table{extTbl}, []backup.Table{}, map[uint32]int64{})
+
+			Expect(plan.BackupType).To(Equal("metadata-only"))
+			Expect(plan.MetadataTables).To(Equal([]string{"public.table1", "public.ext_table"}))
+			Expect(plan.DataTables).To(BeEmpty())
+			Expect(plan.SkippedTables).To(BeEmpty())
+			Expect(plan.RestorePlan).To(BeEmpty())
+		})
+		It("does not include any metadata for a data-only backup", func() {
+			backupConfig.DataOnly = true
+			plan := backup.ConstructDryRunPlan(backupConfig, "", []backup.Table{tbl1, tbl2},
+				[]backup.Table{}, []backup.Table{tbl1, tbl2}, tableSizes)
+
+			Expect(plan.BackupType).To(Equal("data-only"))
+			Expect(plan.MetadataTables).To(BeEmpty())
+			Expect(plan.DataTables).To(HaveLen(2))
+		})
+	})
+	Describe("WriteDryRunPlan", func() {
+		var plan backup.DryRunPlan
+		BeforeEach(func() {
+			backupConfig.Differential = true
+			plan = backup.ConstructDryRunPlan(backupConfig, "20211231010101", []backup.Table{tbl1, tbl2, extTbl},
+				[]backup.Table{extTbl}, []backup.Table{tbl1, tbl2}, tableSizes)
+		})
+		It("writes the plan as text", func() {
+			buffer := &bytes.Buffer{}
+			Expect(backup.WriteDryRunPlan(buffer, plan, "text")).To(Succeed())
+			Expect(buffer.String()).To(Equal(`Backup timestamp:       20220101010101
+Database:               testdb
+Backup type:            differential
+Base backup timestamp:  20211231010101
+Tables with metadata:   3
+Tables with data:       2
+Tables skipped:         1
+Estimated data size:    4.0 KB
+
+Table data to back up:
+  public.table1  1.0 KB
+  public.table2  3.0 KB
+
+Table data skipped:
+  public.ext_table  external table
+
+Restore plan:
+  20220101010101  2 table(s)
+`))
+		})
+		It("writes the plan as JSON", func() {
+			buffer := &bytes.Buffer{}
+			Expect(backup.WriteDryRunPlan(buffer, plan, "json")).To(Succeed())
+			var resultPlan backup.DryRunPlan
+			Expect(json.Unmarshal(buffer.Bytes(), &resultPlan)).To(Succeed())
+			Expect(resultPlan).To(Equal(plan))
+		})
+		It("returns an error for an unrecognized format", func() {
+			err := backup.WriteDryRunPlan(&bytes.Buffer{}, plan, "yaml")
+			Expect(err).To(MatchError("Unrecognized dry run format yaml"))
+		})
+	})
+})
@@ -48,6 +48,15 @@ var (
 	filterRelationClause string
 	quotedRoleNames      map[string]string
 	backupSnapshot       string
+	resumeStateFile      *ResumeStateFile
+	resumeStateEntries   []ResumeStateEntry
+	priorityTables       []string
+	tablePredicates      map[string]string
+	maskingRules         map[string]string
+	maxBandwidth         int64
+	maxBandwidthPerHost  bool
+	heapChangeMarkers    map[string]toc.HeapEntry
+	heapStatsEpoch       string
 	/*
 	 * Used for synchronizing DoCleanup.  In DoInit() we increment the group
 	 * and then wait for at least one DoCleanup to finish, either in DoTeardown
@@ -126,6 +135,19 @@ func SetQuotedRoleNames(quotedRoles map[string]string) {
 	quotedRoleNames = quotedRoles
 }
 
+func SetTablePredicates(predicates map[string]string) {
+	tablePredicates = predicates
+}
+
+func SetMaskingRules(rules map[string]string) {
+	maskingRules = rules
+}
+
+func SetMaxBandwidth(bandwidth int64, perHost bool) {
+	maxBandwidth = bandwidth
+	maxBandwidthPerHost = perHost
+}
+
 // Util functions to enable ease of access to global flag values
 
 func FlagChanged(flagName string) bool {
@@ -1,6 +1,7 @@
 package backup
 
 import (
+	"database/sql"
 	"fmt"
 	"path"
 
@@ -15,17 +16,33 @@ import (
 
 func FilterTablesForIncremental(lastBackupTOC, currentTOC *toc.TOC, tables []Table) []Table {
 	var filteredTables []Table
+	// The heap tuple counters only show which tables changed if both backups read them in the same epoch
+	heapCountersComparable := currentTOC.IncrementalMetadata.HeapStatsEpoch != "" &&
+		currentTOC.IncrementalMetadata.HeapStatsEpoch == lastBackupTOC.IncrementalMetadata.HeapStatsEpoch
+	if !heapCountersComparable && len(currentTOC.IncrementalMetadata.Heap) > 0 {
+		gplog.Verbose("Heap table statistics were reset or not tracked since the last backup, so all heap tables will be backed up")
+	}
 	for _, table := range tables {
 		currentAOEntry, isAOTable := currentTOC.IncrementalMetadata.AO[table.FQN()]
-		if !isAOTable {
-			filteredTables = append(filteredTables, table)
+		if isAOTable {
+			previousAOEntry := lastBackupTOC.IncrementalMetadata.AO[table.FQN()]
+			if previousAOEntry.Modcount != currentAOEntry.Modcount || previousAOEntry.LastDDLTimestamp != currentAOEntry.LastDDLTimestamp {
+				filteredTables = append(filteredTables, table)
+			}
 			continue
 		}
-		previousAOEntry := lastBackupTOC.IncrementalMetadata.AO[table.FQN()]
 
-		if previousAOEntry.Modcount != currentAOEntry.Modcount || previousAOEntry.LastDDLTimestamp != currentAOEntry.LastDDLTimestamp {
-			filteredTables = append(filteredTables, table)
+		currentHeapEntry, isHeapTable := currentTOC.IncrementalMetadata.Heap[table.FQN()]
+		if isHeapTable {
+			// Backups taken before heap tables were tracked have no previous entry, so the table is always included
+			previousHeapEntry, hasPreviousEntry := lastBackupTOC.IncrementalMetadata.Heap[table.FQN()]
+			if !heapCountersComparable || !hasPreviousEntry || previousHeapEntry != currentHeapEntry {
+				filteredTables = append(filteredTables, table)
+			}
+			continue
 		}
+
+		filteredTables = append(filteredTables, table)
 	}
 
 	return filteredTables
@@ -67,7 +84,18 @@ func GetLatestMatchingBackupConfig(historyDBPath string, currentBackupConfig *hi
 	// iterate through them querying and checking one at a time. this is necessary due to the
 	// impracticality of checking the include and exclude sets directly in a query
 
-	historyDB, _ := history.InitializeHistoryDatabase(historyDBPath)
+	var historyDB *sql.DB
+	if MustGetFlagBool(options.DRY_RUN) {
+		// A dry run must not create or migrate the history database, and without one there is no matching backup
+		var err error
+		historyDB, err = history.OpenHistoryDatabaseReadOnly(historyDBPath)
+		if err != nil {
+			return nil
+		}
+	} else {
+		historyDB, _ = history.InitializeHistoryDatabase(historyDBPath)
+	}
+	defer historyDB.Close()
 
 	whereClause := fmt.Sprintf(`backup_dir = '%s' AND database_name = '%s' AND leaf_partition_data = %v
 		AND plugin = '%s' AND single_data_file = %v AND compressed = %v AND date_deleted = '' AND status = '%s'`,
@@ -78,6 +106,10 @@ func GetLatestMatchingBackupConfig(historyDBPath string, currentBackupConfig *hi
 		MustGetFlagBool(options.SINGLE_DATA_FILE),
 		currentBackupConfig.Compressed,
         history.BackupStatusSucceed)
+	if currentBackupConfig.Differential {
+		// A differential backup is always based off of the most recent full backup
+		whereClause += " AND incremental = 0"
+	}
 
 	getBackupTimetampsQuery := fmt.Sprintf(`
 		SELECT timestamp
@@ -128,7 +160,23 @@ func matchesIncrementalFlags(backupConfig *history.BackupConfig, currentBackupCo
 		utils.NewIncludeSet(backupConfig.IncludeRelations).Equals(utils.NewIncludeSet(currentBackupConfig.IncludeRelations)) &&
 		utils.NewIncludeSet(backupConfig.IncludeSchemas).Equals(utils.NewIncludeSet(MustGetFlagStringArray(options.INCLUDE_SCHEMA))) &&
 		utils.NewIncludeSet(backupConfig.ExcludeRelations).Equals(utils.NewIncludeSet(MustGetFlagStringArray(options.EXCLUDE_RELATION))) &&
-		utils.NewIncludeSet(backupConfig.ExcludeSchemas).Equals(utils.NewIncludeSet(MustGetFlagStringArray(options.EXCLUDE_SCHEMA)))
+		utils.NewIncludeSet(backupConfig.ExcludeSchemas).Equals(utils.NewIncludeSet(MustGetFlagStringArray(options.EXCLUDE_SCHEMA))) &&
+		// A table backed up with a different predicate holds different rows, even if it was not modified
+		matchesStringMaps(backupConfig.TablePredicates, currentBackupConfig.TablePredicates) &&
+		// Restoring a backup set with different masking rules would mix masked and unmasked data
+		matchesStringMaps(backupConfig.MaskingRules, currentBackupConfig.MaskingRules)
+}
+
+func matchesStringMaps(values map[string]string, currentValues map[string]string) bool {
+	if len(values) != len(currentValues) {
+		return false
+	}
+	for key, value := range values {
+		if currentValue, ok := currentValues[key]; !ok || currentValue != value {
+			return false
+		}
+	}
+	return true
 }
 
 func PopulateRestorePlan(changedTables []Table,
@@ -9,6 +9,7 @@ import (
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/filepath"
 	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
 	"github.com/greenplum-db/gpbackup/report"
 	"github.com/greenplum-db/gpbackup/testutils"
 	"github.com/greenplum-db/gpbackup/toc"
@@ -24,6 +25,13 @@ var _ = Describe("backup/incremental tests", func() {
 			Modcount:         0,
 			LastDDLTimestamp: "00000",
 		}
+		defaultHeapEntry := toc.HeapEntry{
+			Relfilenode:      16384,
+			TuplesInserted:   10,
+			TuplesUpdated:    0,
+			TuplesDeleted:    0,
+			LastDDLTimestamp: "00000",
+		}
 		prevTOC := toc.TOC{
 			IncrementalMetadata: toc.IncrementalEntries{
 				AO: map[string]toc.AOEntry{
@@ -31,6 +39,13 @@ var _ = Describe("backup/incremental tests", func() {
 					"public.ao_changed_timestamp": defaultEntry,
 					"public.ao_unchanged":         defaultEntry,
 				},
+				Heap: map[string]toc.HeapEntry{
+					"public.heap_changed_relfilenode": defaultHeapEntry,
+					"public.heap_changed_counters":    defaultHeapEntry,
+					"public.heap_changed_timestamp":   defaultHeapEntry,
+					"public.heap_unchanged":           defaultHeapEntry,
+				},
+				HeapStatsEpoch: "0:2022-01-01 00:00:00+00:,1:2022-01-01 00:00:00+00:",
 			},
 		}
 
@@ -47,6 +62,27 @@ var _ = Describe("backup/incremental tests", func() {
 					},
 					"public.ao_unchanged": defaultEntry,
 				},
+				Heap: map[string]toc.HeapEntry{
+					"public.heap_changed_relfilenode": {
+						Relfilenode:      16400,
+						TuplesInserted:   10,
+						LastDDLTimestamp: "00000",
+					},
+					"public.heap_changed_counters": {
+						Relfilenode:      16384,
+						TuplesInserted:   10,
+						TuplesDeleted:    3,
+						LastDDLTimestamp: "00000",
+					},
+					"public.heap_changed_timestamp": {
+						Relfilenode:      16384,
+						TuplesInserted:   10,
+						LastDDLTimestamp: "00001",
+					},
+					"public.heap_unchanged":  defaultHeapEntry,
+					"public.heap_no_history": defaultHeapEntry,
+				},
+				HeapStatsEpoch: "0:2022-01-01 00:00:00+00:,1:2022-01-01 00:00:00+00:",
 			},
 		}
 
@@ -54,16 +90,26 @@ var _ = Describe("backup/incremental tests", func() {
 		tblAOChangedModcount := backup.Table{Relation: backup.Relation{Schema: "public", Name: "ao_changed_modcount"}}
 		tblAOChangedTS := backup.Table{Relation: backup.Relation{Schema: "public", Name: "ao_changed_timestamp"}}
 		tblAOUnchanged := backup.Table{Relation: backup.Relation{Schema: "public", Name: "ao_unchanged"}}
+		tblHeapChangedRelfilenode := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_changed_relfilenode"}}
+		tblHeapChangedCounters := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_changed_counters"}}
+		tblHeapChangedTS := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_changed_timestamp"}}
+		tblHeapUnchanged := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_unchanged"}}
+		tblHeapNoHistory := backup.Table{Relation: backup.Relation{Schema: "public", Name: "heap_no_history"}}
 		tables := []backup.Table{
 			tblHeap,
 			tblAOChangedModcount,
 			tblAOChangedTS,
 			tblAOUnchanged,
+			tblHeapChangedRelfilenode,
+			tblHeapChangedCounters,
+			tblHeapChangedTS,
+			tblHeapUnchanged,
+			tblHeapNoHistory,
 		}
 
 		filteredTables := backup.FilterTablesForIncremental(&prevTOC, &currTOC, tables)
 
-		It("Should include the heap table in the filtered list", func() {
+		It("Should include a table without incremental metadata in the filtered list", func() {
 			Expect(filteredTables).To(ContainElement(tblHeap))
 		})
 
@@ -78,11 +124,58 @@ var _ = Describe("backup/incremental tests", func() {
 		It("Should NOT include the unmodified AO table", func() {
 			Expect(filteredTables).To(Not(ContainElement(tblAOUnchanged)))
 		})
+
+		It("Should include the heap table having a modified relfilenode", func() {
+			Expect(filteredTables).To(ContainElement(tblHeapChangedRelfilenode))
+		})
+
+		It("Should include the heap table having modified tuple counters", func() {
+			Expect(filteredTables).To(ContainElement(tblHeapChangedCounters))
+		})
+
+		It("Should include the heap table having a modified last DDL timestamp", func() {
+			Expect(filteredTables).To(ContainElement(tblHeapChangedTS))
+		})
+
+		It("Should include the heap table missing from the previous backup's incremental metadata", func() {
+			Expect(filteredTables).To(ContainElement(tblHeapNoHistory))
+		})
+
+		It("Should NOT include the unmodified heap table", func() {
+			Expect(filteredTables).To(Not(ContainElement(tblHeapUnchanged)))
+		})
+
+		It("Should include the unmodified heap table if the statistics were reset since the last backup", func() {
+			resetTOC := currTOC
+			resetTOC.IncrementalMetadata.HeapStatsEpoch = "0:2022-01-01 00:00:00+00:,1:2022-01-01 00:00:00+00:2022-01-02 00:00:00+00"
+			Expect(backup.FilterTablesForIncremental(&prevTOC, &resetTOC, tables)).To(ContainElement(tblHeapUnchanged))
+		})
+
+		It("Should include the unmodified heap table if the statistics are not tracked", func() {
+			untrackedTOC := currTOC
+			untrackedTOC.IncrementalMetadata.HeapStatsEpoch = ""
+			untrackedPrevTOC := prevTOC
+			untrackedPrevTOC.IncrementalMetadata.HeapStatsEpoch = ""
+			filteredUntracked := backup.FilterTablesForIncremental(&untrackedPrevTOC, &untrackedTOC, tables)
+			Expect(filteredUntracked).To(ContainElement(tblHeapUnchanged))
+			Expect(filteredUntracked).To(Not(ContainElement(tblAOUnchanged)))
+		})
 	})
 
 	Describe("GetLatestMatchingBackupConfig", func() {
 		historyDBPath := "/tmp/hist.db"
 		contents := []history.BackupConfig{
+			{
+				DatabaseName:     "test2",
+				Timestamp:        "timestamp5",
+				Status:           history.BackupStatusSucceed,
+				ExcludeRelations: []string{},
+				ExcludeSchemas:   []string{},
+				IncludeRelations: []string{},
+				IncludeSchemas:   []string{},
+				RestorePlan:      []history.RestorePlanEntry{},
+				TablePredicates:  map[string]string{"public.sales": "id > 100"},
+			},
 			{
 				DatabaseName:     "test2",
 				Timestamp:        "timestamp4",
@@ -97,6 +190,7 @@ var _ = Describe("backup/incremental tests", func() {
 				DatabaseName:     "test1",
 				Timestamp:        "timestamp3",
 				Status:           history.BackupStatusSucceed,
+				Incremental:      true,
 				ExcludeRelations: []string{},
 				ExcludeSchemas:   []string{},
 				IncludeRelations: []string{},
@@ -140,14 +234,36 @@ var _ = Describe("backup/incremental tests", func() {
 			currentBackupConfig := history.BackupConfig{DatabaseName: "test1"}
 			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(historyDBPath, &currentBackupConfig)
 			// endtime is set dynamically on storage, so force it to match
-			contents[1].EndTime = latestBackupHistoryEntry.EndTime
-			structmatcher.ExpectStructsToMatch(contents[1], latestBackupHistoryEntry)
+			contents[2].EndTime = latestBackupHistoryEntry.EndTime
+			structmatcher.ExpectStructsToMatch(contents[2], latestBackupHistoryEntry)
 		})
 		It("Should return the latest matching backup's timestamp that did not fail", func() {
 			currentBackupConfig := history.BackupConfig{DatabaseName: "test2"}
 			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(historyDBPath, &currentBackupConfig)
-			contents[2].EndTime = latestBackupHistoryEntry.EndTime
-			structmatcher.ExpectStructsToMatch(contents[2], latestBackupHistoryEntry)
+			contents[3].EndTime = latestBackupHistoryEntry.EndTime
+			structmatcher.ExpectStructsToMatch(contents[3], latestBackupHistoryEntry)
+		})
+		It("Should return the latest full backup's timestamp when taking a differential backup", func() {
+			currentBackupConfig := history.BackupConfig{DatabaseName: "test1", Differential: true}
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(historyDBPath, &currentBackupConfig)
+			contents[4].EndTime = latestBackupHistoryEntry.EndTime
+			structmatcher.ExpectStructsToMatch(contents[4], latestBackupHistoryEntry)
+		})
+		It("Should return the latest matching backup's timestamp taken with the same table predicates", func() {
+			currentBackupConfig := history.BackupConfig{DatabaseName: "test2", TablePredicates: map[string]string{"public.sales": "id > 100"}}
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(historyDBPath, &currentBackupConfig)
+			contents[0].EndTime = latestBackupHistoryEntry.EndTime
+			structmatcher.ExpectStructsToMatch(contents[0], latestBackupHistoryEntry)
+		})
+		It("should return nil with no backup taken with the same masking rules", func() {
+			currentBackupConfig := history.BackupConfig{DatabaseName: "test1", MaskingRules: map[string]string{"public.customers.ssn": "'xxx-xx-xxxx'"}}
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(historyDBPath, &currentBackupConfig)
+			Expect(latestBackupHistoryEntry).To(BeNil())
+		})
+		It("should return nil with no backup taken with the same table predicates", func() {
+			currentBackupConfig := history.BackupConfig{DatabaseName: "test2", TablePredicates: map[string]string{"public.sales": "id > 200"}}
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(historyDBPath, &currentBackupConfig)
+			Expect(latestBackupHistoryEntry).To(BeNil())
 		})
 		It("should return nil with no matching Dbname", func() {
 			currentBackupConfig := history.BackupConfig{DatabaseName: "test3"}
@@ -160,6 +276,22 @@ var _ = Describe("backup/incremental tests", func() {
 			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(historyDBPath, &currentBackupConfig)
 			Expect(latestBackupHistoryEntry).To(BeNil())
 		})
+		It("Should return the latest matching backup's timestamp in a dry run", func() {
+			_ = cmdFlags.Set(options.DRY_RUN, "true")
+			currentBackupConfig := history.BackupConfig{DatabaseName: "test1"}
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(historyDBPath, &currentBackupConfig)
+			contents[2].EndTime = latestBackupHistoryEntry.EndTime
+			structmatcher.ExpectStructsToMatch(contents[2], latestBackupHistoryEntry)
+		})
+		It("should return nil without creating the history in a dry run with no history", func() {
+			_ = cmdFlags.Set(options.DRY_RUN, "true")
+			currentBackupConfig := history.BackupConfig{DatabaseName: "test1"}
+			os.Remove(historyDBPath)
+			latestBackupHistoryEntry := backup.GetLatestMatchingBackupConfig(historyDBPath, &currentBackupConfig)
+			Expect(latestBackupHistoryEntry).To(BeNil())
+			_, err := os.Stat(historyDBPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
 	})
 
 	Describe("PopulateRestorePlan", func() {
@@ -214,3 +214,27 @@ func GetExternalPartitionInfo(connectionPool *dbconn.DBConn) ([]PartitionInfo, m
 
 	return extPartitions, partInfoMap
 }
+
+/*
+ * Returns the partition tables that have any external leaf partitions.  In
+ * GPDB 7+ the leaf partitions are backed up as separate tables, so none are
+ * returned.
+ */
+func GetPartitionRootsWithExternalPartitions(connectionPool *dbconn.DBConn) []Relation {
+	results := make([]Relation, 0)
+	if connectionPool.Version.AtLeast("7") {
+		return results
+	}
+
+	query := `
+	SELECT DISTINCT quote_ident(n.nspname) AS schema,
+		quote_ident(c.relname) AS name
+	FROM pg_partition p
+		JOIN pg_partition_rule r ON p.oid = r.paroid
+		JOIN pg_exttable e ON r.parchildrelid = e.reloid
+		JOIN pg_class c ON p.parrelid = c.oid
+		JOIN pg_namespace n ON c.relnamespace = n.oid`
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	return results
+}
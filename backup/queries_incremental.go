@@ -2,6 +2,7 @@ package backup
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
@@ -176,3 +177,141 @@ func getLastDDLTimestamps(connectionPool *dbconn.DBConn) map[string]string {
 	}
 	return resultMap
 }
+
+/*
+ * Heap tables have no equivalent of the AO modcount, so we build a change marker
+ * out of the relfilenode (which changes on TRUNCATE, VACUUM FULL, CLUSTER, and
+ * table rewrites), the per-segment tuple counters from the statistics collector,
+ * and the last DDL timestamp. Any difference in the marker between two backups
+ * causes the table to be included in the incremental backup set. The counters
+ * are only compared between backups with the same GetHeapStatsEpoch.  This runs
+ * before the table filters of the backup are processed, so only the schema
+ * flags narrow down the heap tables queried; see readHeapChangeMarkers.
+ */
+func GetHeapIncrementalMetadata(connectionPool *dbconn.DBConn) map[string]toc.HeapEntry {
+	gplog.Verbose("Querying heap table change markers")
+
+	before7Query := fmt.Sprintf(`
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS heaptablefqn,
+			c.relfilenode,
+			coalesce(segstats.tuplesinserted, 0) AS tuplesinserted,
+			coalesce(segstats.tuplesupdated, 0) AS tuplesupdated,
+			coalesce(segstats.tuplesdeleted, 0) AS tuplesdeleted,
+			coalesce(lastop.lastddltimestamp, '') AS lastddltimestamp
+		FROM pg_class c
+			JOIN pg_namespace n ON c.relnamespace = n.oid
+			LEFT JOIN ( SELECT s.oid,
+					pg_catalog.sum(pg_stat_get_tuples_inserted(s.oid)) AS tuplesinserted,
+					pg_catalog.sum(pg_stat_get_tuples_updated(s.oid)) AS tuplesupdated,
+					pg_catalog.sum(pg_stat_get_tuples_deleted(s.oid)) AS tuplesdeleted
+				FROM gp_dist_random('pg_class') s
+				WHERE s.relkind = 'r'
+					AND s.relstorage = 'h'
+				GROUP BY s.oid
+			) segstats ON c.oid = segstats.oid
+			LEFT JOIN ( SELECT lo.objid,
+					MAX(lo.statime)::text AS lastddltimestamp
+				FROM pg_stat_last_operation lo
+				WHERE lo.staactionname IN ('CREATE', 'ALTER', 'TRUNCATE')
+				GROUP BY lo.objid
+			) lastop ON c.oid = lastop.objid
+		WHERE c.relkind = 'r'
+			AND c.relstorage = 'h'
+			AND %s`, SchemaFilterClause("n"))
+
+	atLeast7Query := fmt.Sprintf(`
+		SELECT quote_ident(n.nspname) || '.' || quote_ident(c.relname) AS heaptablefqn,
+			c.relfilenode,
+			coalesce(segstats.tuplesinserted, 0) AS tuplesinserted,
+			coalesce(segstats.tuplesupdated, 0) AS tuplesupdated,
+			coalesce(segstats.tuplesdeleted, 0) AS tuplesdeleted,
+			coalesce(lastop.lastddltimestamp, '') AS lastddltimestamp
+		FROM pg_class c
+			JOIN pg_namespace n ON c.relnamespace = n.oid
+			JOIN pg_am a ON c.relam = a.oid
+			LEFT JOIN ( SELECT s.oid,
+					pg_catalog.sum(pg_stat_get_tuples_inserted(s.oid)) AS tuplesinserted,
+					pg_catalog.sum(pg_stat_get_tuples_updated(s.oid)) AS tuplesupdated,
+					pg_catalog.sum(pg_stat_get_tuples_deleted(s.oid)) AS tuplesdeleted
+				FROM gp_dist_random('pg_class') s
+				WHERE s.relkind = 'r'
+				GROUP BY s.oid
+			) segstats ON c.oid = segstats.oid
+			LEFT JOIN ( SELECT lo.objid,
+					MAX(lo.statime)::text AS lastddltimestamp
+				FROM pg_stat_last_operation lo
+				WHERE lo.staactionname IN ('CREATE', 'ALTER', 'TRUNCATE')
+				GROUP BY lo.objid
+			) lastop ON c.oid = lastop.objid
+		WHERE c.relkind = 'r'
+			AND a.amname = 'heap'
+			AND %s`, SchemaFilterClause("n"))
+
+	query := ""
+	if connectionPool.Version.Before("7") {
+		query = before7Query
+	} else {
+		query = atLeast7Query
+	}
+
+	var results []struct {
+		HeapTableFQN     string
+		Relfilenode      uint32
+		TuplesInserted   int64
+		TuplesUpdated    int64
+		TuplesDeleted    int64
+		LastDDLTimestamp string
+	}
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	heapTableEntries := make(map[string]toc.HeapEntry)
+	for _, result := range results {
+		heapTableEntries[result.HeapTableFQN] = toc.HeapEntry{
+			Relfilenode:      result.Relfilenode,
+			TuplesInserted:   result.TuplesInserted,
+			TuplesUpdated:    result.TuplesUpdated,
+			TuplesDeleted:    result.TuplesDeleted,
+			LastDDLTimestamp: result.LastDDLTimestamp,
+		}
+	}
+	return heapTableEntries
+}
+
+/*
+ * The tuple counters used by GetHeapIncrementalMetadata are lost whenever the
+ * statistics collector of a segment is reset, whether explicitly or by crash
+ * recovery, and are never incremented while track_counts is off.  The epoch
+ * records each segment's postmaster start time and statistics reset time, so
+ * that counters read in different epochs are never compared, and is empty if
+ * any segment is not tracking counts.
+ */
+func GetHeapStatsEpoch(connectionPool *dbconn.DBConn) string {
+	gplog.Verbose("Querying heap table statistics epoch")
+
+	query := `
+	SELECT d.gp_segment_id AS contentid,
+		current_setting('track_counts') AS trackcounts,
+		pg_postmaster_start_time()::text AS starttime,
+		coalesce(pg_stat_get_db_stat_reset_time(d.oid)::text, '') AS resettime
+	FROM gp_dist_random('pg_database') d
+	WHERE d.datname = current_database()
+	ORDER BY d.gp_segment_id`
+
+	var results []struct {
+		ContentID   int
+		TrackCounts string
+		StartTime   string
+		ResetTime   string
+	}
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	segmentEpochs := make([]string, 0, len(results))
+	for _, result := range results {
+		if result.TrackCounts != "on" {
+			gplog.Warn("track_counts is off on segment %d, so all heap tables will be included in this and the next incremental backup", result.ContentID)
+			return ""
+		}
+		segmentEpochs = append(segmentEpochs, fmt.Sprintf("%d:%s:%s", result.ContentID, result.StartTime, result.ResetTime))
+	}
+	return strings.Join(segmentEpochs, ",")
+}
@@ -540,3 +540,54 @@ func GenerateTableBatches(tables []Relation, batchSize int) []string {
 
 	return batches
 }
+
+/*
+ * The sizes are estimates of the on-disk size of the table data across all
+ * segments, for reporting the scope of a backup before it is taken.  A parent
+ * partition table's data is stored in its partitions, so its size includes
+ * the size of all of them.
+ */
+func GetTableSizes(connectionPool *dbconn.DBConn, tables []Table) map[uint32]int64 {
+	tableSizes := make(map[uint32]int64)
+	if len(tables) == 0 {
+		return tableSizes
+	}
+	oidList := make([]string, 0, len(tables))
+	for _, table := range tables {
+		oidList = append(oidList, fmt.Sprintf("%d", table.Oid))
+	}
+
+	before7Query := fmt.Sprintf(`
+	SELECT c.oid,
+		(pg_relation_size(c.oid) + coalesce((SELECT sum(pg_relation_size(r.parchildrelid))
+			FROM pg_partition p
+				JOIN pg_partition_rule r ON r.paroid = p.oid
+			WHERE p.parrelid = c.oid), 0))::bigint AS size
+	FROM pg_class c
+	WHERE c.oid IN (%s)`, strings.Join(oidList, ", "))
+
+	atLeast7Query := fmt.Sprintf(`
+	SELECT c.oid,
+		coalesce((SELECT sum(pg_relation_size(t.relid)) FROM pg_partition_tree(c.oid) t),
+			pg_relation_size(c.oid))::bigint AS size
+	FROM pg_class c
+	WHERE c.oid IN (%s)`, strings.Join(oidList, ", "))
+
+	query := ""
+	if connectionPool.Version.Before("7") {
+		query = before7Query
+	} else {
+		query = atLeast7Query
+	}
+
+	results := make([]struct {
+		Oid  uint32
+		Size int64
+	}, 0)
+	err := connectionPool.Select(&results, query)
+	gplog.FatalOnError(err)
+	for _, result := range results {
+		tableSizes[result.Oid] = result.Size
+	}
+	return tableSizes
+}
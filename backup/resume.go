@@ -0,0 +1,264 @@
+package backup
+
+/*
+ * This file contains structs and functions related to resuming a failed backup
+ * with the --resume flag.
+ */
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+type ResumeStateEntry struct {
+	SnapshotTimestamp string
+	SnapshotID        string
+	DataEntry         toc.CoordinatorDataEntry
+}
+
+/*
+ * The resume state file holds one JSON-encoded ResumeStateEntry per line for
+ * each table whose data has been completely backed up.  The file is only ever
+ * appended to, so if gpbackup dies while writing an entry, only the last line
+ * can be incomplete and it is ignored when the file is read back in.
+ */
+type ResumeStateFile struct {
+	file              *os.File
+	mutex             sync.Mutex
+	snapshotTimestamp string
+	snapshotID        string
+}
+
+func OpenResumeStateFile(filename string, snapshotTimestamp string, snapshotID string) (*ResumeStateFile, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &ResumeStateFile{file: file, snapshotTimestamp: snapshotTimestamp, snapshotID: snapshotID}, nil
+}
+
+func (stateFile *ResumeStateFile) RecordTable(dataEntry toc.CoordinatorDataEntry) error {
+	line, err := json.Marshal(ResumeStateEntry{
+		SnapshotTimestamp: stateFile.snapshotTimestamp,
+		SnapshotID:        stateFile.snapshotID,
+		DataEntry:         dataEntry,
+	})
+	if err != nil {
+		return err
+	}
+
+	stateFile.mutex.Lock()
+	defer stateFile.mutex.Unlock()
+	_, err = stateFile.file.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+	return stateFile.file.Sync()
+}
+
+func (stateFile *ResumeStateFile) Close() error {
+	return stateFile.file.Close()
+}
+
+func ReadResumeStateFile(filename string) ([]ResumeStateEntry, error) {
+	entries := make([]ResumeStateEntry, 0)
+	contents, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		// The previous backup failed before any table data was backed up
+		return entries, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry ResumeStateEntry
+		err = json.Unmarshal([]byte(line), &entry)
+		if err != nil {
+			gplog.Verbose("Ignoring incomplete resume state entry: %s", line)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+/*
+ * Split the tables into those that still need their data backed up and the
+ * state entries of those that were completed by a previous run.  A table is
+ * only considered complete if it still has the same oid, as the data files are
+ * named by oid and a dropped and recreated table has to be backed up again.
+ * Its columns must also be unchanged, as the restore copies the data back in
+ * with the attribute list recorded in its data entry.
+ */
+func FilterTablesForResume(tables []Table, stateEntries []ResumeStateEntry) ([]Table, []ResumeStateEntry) {
+	completedEntries := make(map[uint32]ResumeStateEntry, len(stateEntries))
+	for _, entry := range stateEntries {
+		completedEntries[entry.DataEntry.Oid] = entry
+	}
+
+	remainingTables := make([]Table, 0)
+	resumedEntries := make([]ResumeStateEntry, 0)
+	for _, table := range tables {
+		entry, ok := completedEntries[table.Oid]
+		if ok && entry.DataEntry.Schema == table.Schema && entry.DataEntry.Name == table.Name &&
+			entry.DataEntry.AttributeString == ConstructTableAttributesList(table.ColumnDefs) {
+			resumedEntries = append(resumedEntries, entry)
+		} else {
+			remainingTables = append(remainingTables, table)
+		}
+	}
+	return remainingTables, resumedEntries
+}
+
+// Group the tables by the snapshot their data was backed up under, oldest snapshot first
+func ConstructDataSnapshots(resumedEntries []ResumeStateEntry, remainingTables []Table, snapshotTimestamp string, snapshotID string) []history.DataSnapshotEntry {
+	dataSnapshots := make([]history.DataSnapshotEntry, 0)
+	snapshotIndexes := make(map[string]int)
+	for _, entry := range resumedEntries {
+		index, ok := snapshotIndexes[entry.SnapshotTimestamp]
+		if !ok {
+			index = len(dataSnapshots)
+			snapshotIndexes[entry.SnapshotTimestamp] = index
+			dataSnapshots = append(dataSnapshots, history.DataSnapshotEntry{
+				Timestamp:  entry.SnapshotTimestamp,
+				SnapshotID: entry.SnapshotID,
+				TableFQNs:  make([]string, 0),
+			})
+		}
+		tableFQN := utils.MakeFQN(entry.DataEntry.Schema, entry.DataEntry.Name)
+		dataSnapshots[index].TableFQNs = append(dataSnapshots[index].TableFQNs, tableFQN)
+	}
+
+	currentSnapshot := history.DataSnapshotEntry{
+		Timestamp:  snapshotTimestamp,
+		SnapshotID: snapshotID,
+		TableFQNs:  make([]string, 0, len(remainingTables)),
+	}
+	for _, table := range remainingTables {
+		currentSnapshot.TableFQNs = append(currentSnapshot.TableFQNs, table.FQN())
+	}
+	return append(dataSnapshots, currentSnapshot)
+}
+
+func addResumedDataEntriesToTOC(resumedEntries []ResumeStateEntry) {
+	for _, entry := range resumedEntries {
+		globalTOC.DataEntries = append(globalTOC.DataEntries, entry.DataEntry)
+
+		/*
+		 * The incremental metadata was gathered under the current snapshot, so it
+		 * may be newer than the data backed up by a previous run.  Drop it so the
+		 * next incremental backup always picks these tables up again.
+		 */
+		tableFQN := utils.MakeFQN(entry.DataEntry.Schema, entry.DataEntry.Name)
+		delete(globalTOC.IncrementalMetadata.AO, tableFQN)
+		delete(globalTOC.IncrementalMetadata.Heap, tableFQN)
+	}
+}
+
+/*
+ * Every setting that changes how table data is written must match, as the data
+ * of the tables completed by previous runs is kept as it is.
+ */
+func matchesResumeFlags(backupConfig *history.BackupConfig, currentBackupConfig *history.BackupConfig) bool {
+	return matchesIncrementalFlags(backupConfig, currentBackupConfig) &&
+		backupConfig.CompressionType == currentBackupConfig.CompressionType &&
+		backupConfig.DataOnly == currentBackupConfig.DataOnly &&
+		backupConfig.Incremental == currentBackupConfig.Incremental &&
+		backupConfig.Differential == currentBackupConfig.Differential &&
+		backupConfig.WithoutGlobals == currentBackupConfig.WithoutGlobals &&
+		backupConfig.WithStatistics == currentBackupConfig.WithStatistics &&
+		backupConfig.EncryptionFingerprint == currentBackupConfig.EncryptionFingerprint &&
+		backupConfig.DataFormat == currentBackupConfig.DataFormat &&
+		backupConfig.DataStreams == currentBackupConfig.DataStreams &&
+		backupConfig.Repository == currentBackupConfig.Repository
+}
+
+func getConfigOfBackupToResume() *history.BackupConfig {
+	configFilename := globalFPInfo.GetConfigFilePath()
+	if _, err := os.Stat(configFilename); err == nil {
+		return history.ReadConfigFile(configFilename)
+	}
+
+	// If gpbackup was killed, the config file was never written, so fall back to the history database
+	historyDBPath := globalFPInfo.GetBackupHistoryDatabasePath()
+	if _, err := os.Stat(historyDBPath); err == nil {
+		historyDB, err := history.InitializeHistoryDatabase(historyDBPath)
+		gplog.FatalOnError(err)
+		defer historyDB.Close()
+		backupConfig, err := history.GetBackupConfig(globalFPInfo.Timestamp, historyDB)
+		if err == nil {
+			return backupConfig
+		}
+	}
+	return nil
+}
+
+/*
+ * Check that the backup being resumed failed and was taken with the same flags,
+ * then read back the state of its data backup and remove the coordinator files
+ * that will be written again by this run.
+ */
+func prepareBackupForResume() {
+	backupConfig := getConfigOfBackupToResume()
+	if backupConfig == nil {
+		gplog.Fatal(errors.Errorf("Unable to find a backup with timestamp %s to resume.", globalFPInfo.Timestamp), "")
+	}
+	if backupConfig.Status == history.BackupStatusSucceed {
+		gplog.Fatal(errors.Errorf("The backup with timestamp %s completed successfully and cannot be resumed.", globalFPInfo.Timestamp), "")
+	}
+	/*
+	 * A different version of gpbackup may not have recorded every setting that
+	 * affects the backed up data, so the flags could not be fully compared.
+	 */
+	if backupConfig.BackupVersion != backupReport.BackupConfig.BackupVersion {
+		gplog.Fatal(errors.Errorf("The backup with timestamp %s was taken with gpbackup version %s and cannot be resumed by version %s.",
+			globalFPInfo.Timestamp, backupConfig.BackupVersion, backupReport.BackupConfig.BackupVersion), "")
+	}
+	if backupConfig.EncryptionFingerprint != backupReport.EncryptionFingerprint {
+		gplog.Fatal(errors.Errorf("Backup with timestamp %s was not encrypted with the same key.  A backup must be resumed with the --encryption-key-file it was started with.", globalFPInfo.Timestamp), "")
+	}
+	if !matchesResumeFlags(backupConfig, &backupReport.BackupConfig) {
+		gplog.Fatal(errors.Errorf("The flags of the backup with timestamp = %s do not match "+
+			"that of the current one. Please refer to the report to view the flags supplied for the "+
+			"previous backup.", globalFPInfo.Timestamp), "")
+	}
+
+	var err error
+	resumeStateEntries, err = ReadResumeStateFile(globalFPInfo.GetResumeStateFilePath())
+	gplog.FatalOnError(err)
+	gplog.Info("Resuming backup with timestamp = %s, data for %d table(s) was previously backed up", globalFPInfo.Timestamp, len(resumeStateEntries))
+
+	staleFilenames := []string{globalFPInfo.GetMetadataFilePath(), globalFPInfo.GetTOCFilePath(),
+		globalFPInfo.GetStatisticsFilePath(), globalFPInfo.GetConfigFilePath(),
+		globalFPInfo.GetBackupReportFilePath(), globalFPInfo.GetPluginConfigPath()}
+	for _, filename := range staleFilenames {
+		err = os.Remove(filename)
+		if err != nil && !os.IsNotExist(err) {
+			gplog.FatalOnError(err)
+		}
+	}
+}
+
+func storeDataSnapshotsInHistory() {
+	if MustGetFlagBool(options.NO_HISTORY) {
+		return
+	}
+	historyDB, err := history.InitializeHistoryDatabase(globalFPInfo.GetBackupHistoryDatabasePath())
+	gplog.FatalOnError(err)
+	defer historyDB.Close()
+	err = history.StoreDataSnapshots(historyDB, globalFPInfo.Timestamp, backupReport.DataSnapshots)
+	gplog.FatalOnError(err)
+}
@@ -0,0 +1,118 @@
+package backup_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/history"
+	"github.com/greenplum-db/gpbackup/toc"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/resume tests", func() {
+	tbl1 := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "table1"},
+		TableDefinition: backup.TableDefinition{ColumnDefs: []backup.ColumnDefinition{{Name: "i"}, {Name: "j"}}}}
+	tbl2 := backup.Table{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "table2"},
+		TableDefinition: backup.TableDefinition{ColumnDefs: []backup.ColumnDefinition{{Name: "k"}}}}
+	tbl3 := backup.Table{Relation: backup.Relation{Oid: 3, Schema: "public", Name: "table3"}}
+	entry1 := backup.ResumeStateEntry{
+		SnapshotTimestamp: "20220101010101",
+		SnapshotID:        "00000005-00000002-1",
+		DataEntry:         toc.CoordinatorDataEntry{Schema: "public", Name: "table1", Oid: 1, AttributeString: "(i,j)", RowsCopied: 10},
+	}
+	entry2 := backup.ResumeStateEntry{
+		SnapshotTimestamp: "20220101020202",
+		SnapshotID:        "00000007-00000003-1",
+		DataEntry:         toc.CoordinatorDataEntry{Schema: "public", Name: "table2", Oid: 2, AttributeString: "(k)", RowsCopied: 20},
+	}
+
+	Describe("ResumeStateFile", func() {
+		stateFilename := "/tmp/gpbackup_resume_state"
+
+		BeforeEach(func() {
+			_ = os.Remove(stateFilename)
+		})
+		AfterEach(func() {
+			_ = os.Remove(stateFilename)
+		})
+
+		It("reads back the entries recorded by previous runs", func() {
+			stateFile, err := backup.OpenResumeStateFile(stateFilename, entry1.SnapshotTimestamp, entry1.SnapshotID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateFile.RecordTable(entry1.DataEntry)).To(Succeed())
+			Expect(stateFile.Close()).To(Succeed())
+
+			stateFile, err = backup.OpenResumeStateFile(stateFilename, entry2.SnapshotTimestamp, entry2.SnapshotID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateFile.RecordTable(entry2.DataEntry)).To(Succeed())
+			Expect(stateFile.Close()).To(Succeed())
+
+			entries, err := backup.ReadResumeStateFile(stateFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(Equal([]backup.ResumeStateEntry{entry1, entry2}))
+		})
+		It("ignores an entry that was only partially written", func() {
+			stateFile, err := backup.OpenResumeStateFile(stateFilename, entry1.SnapshotTimestamp, entry1.SnapshotID)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateFile.RecordTable(entry1.DataEntry)).To(Succeed())
+			Expect(stateFile.Close()).To(Succeed())
+			contents, _ := ioutil.ReadFile(stateFilename)
+			_ = ioutil.WriteFile(stateFilename, append(contents, []byte(`{"SnapshotTimestamp":"2022`)...), 0644)
+
+			entries, err := backup.ReadResumeStateFile(stateFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(Equal([]backup.ResumeStateEntry{entry1}))
+		})
+		It("returns no entries if no table data was backed up", func() {
+			entries, err := backup.ReadResumeStateFile(stateFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+		})
+	})
+	Describe("FilterTablesForResume", func() {
+		It("skips tables that were completed by a previous run", func() {
+			remainingTables, resumedEntries := backup.FilterTablesForResume([]backup.Table{tbl1, tbl2, tbl3}, []backup.ResumeStateEntry{entry1, entry2})
+			Expect(remainingTables).To(Equal([]backup.Table{tbl3}))
+			Expect(resumedEntries).To(Equal([]backup.ResumeStateEntry{entry1, entry2}))
+		})
+		It("backs up a table again if it was recreated since the previous run", func() {
+			recreatedTbl1 := backup.Table{Relation: backup.Relation{Oid: 4, Schema: "public", Name: "table1"}}
+			remainingTables, resumedEntries := backup.FilterTablesForResume([]backup.Table{recreatedTbl1, tbl2}, []backup.ResumeStateEntry{entry1, entry2})
+			Expect(remainingTables).To(Equal([]backup.Table{recreatedTbl1}))
+			Expect(resumedEntries).To(Equal([]backup.ResumeStateEntry{entry2}))
+		})
+		It("does not resume a table whose oid was reused by a different table", func() {
+			otherTbl := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "other"}}
+			remainingTables, resumedEntries := backup.FilterTablesForResume([]backup.Table{otherTbl}, []backup.ResumeStateEntry{entry1})
+			Expect(remainingTables).To(Equal([]backup.Table{otherTbl}))
+			Expect(resumedEntries).To(BeEmpty())
+		})
+		It("backs up a table again if its columns changed since the previous run", func() {
+			alteredTbl1 := backup.Table{Relation: tbl1.Relation,
+				TableDefinition: backup.TableDefinition{ColumnDefs: []backup.ColumnDefinition{{Name: "i"}, {Name: "j"}, {Name: "k"}}}}
+			remainingTables, resumedEntries := backup.FilterTablesForResume([]backup.Table{alteredTbl1, tbl2}, []backup.ResumeStateEntry{entry1, entry2})
+			Expect(remainingTables).To(Equal([]backup.Table{alteredTbl1}))
+			Expect(resumedEntries).To(Equal([]backup.ResumeStateEntry{entry2}))
+		})
+	})
+	Describe("ConstructDataSnapshots", func() {
+		It("groups tables by the snapshot their data was backed up under", func() {
+			dataSnapshots := backup.ConstructDataSnapshots([]backup.ResumeStateEntry{entry1, entry2}, []backup.Table{tbl3}, "20220101030303", "00000009-00000004-1")
+			Expect(dataSnapshots).To(Equal([]history.DataSnapshotEntry{
+				{Timestamp: "20220101010101", SnapshotID: "00000005-00000002-1", TableFQNs: []string{"public.table1"}},
+				{Timestamp: "20220101020202", SnapshotID: "00000007-00000003-1", TableFQNs: []string{"public.table2"}},
+				{Timestamp: "20220101030303", SnapshotID: "00000009-00000004-1", TableFQNs: []string{"public.table3"}},
+			}))
+		})
+		It("records the current run even if no tables were left to back up", func() {
+			dataSnapshots := backup.ConstructDataSnapshots([]backup.ResumeStateEntry{entry1}, []backup.Table{}, "20220101030303", "")
+			Expect(dataSnapshots).To(Equal([]history.DataSnapshotEntry{
+				{Timestamp: "20220101010101", SnapshotID: "00000005-00000002-1", TableFQNs: []string{"public.table1"}},
+				{Timestamp: "20220101030303", SnapshotID: "", TableFQNs: []string{}},
+			}))
+		})
+	})
+})
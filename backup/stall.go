@@ -0,0 +1,220 @@
+package backup
+
+/*
+ * This file contains structs and functions related to noticing table data
+ * backups that have stalled and canceling their COPY commands.
+ */
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/dbconn"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/utils"
+)
+
+var dataStallWatchdog *StallWatchdog
+
+type tableWatch struct {
+	table       Table
+	destination string
+	started     time.Time
+	lastBytes   map[int]int64
+	lastChanged map[int]time.Time
+	stalled     bool
+}
+
+type StalledTable struct {
+	Table       Table
+	Destination string
+	Segments    []int
+}
+
+/*
+ * A StallWatchdog watches the tables whose data is being copied out, and
+ * reports a table as stalled once a segment has backed up no data of it for
+ * the stall timeout.  A segment that has backed up all of the data of a table
+ * cannot stall, and a segment that has not started backing it up counts as
+ * having backed up none.  A segment with no progress at all, such as one on a
+ * host that could not be reached, is skipped until its progress is gathered.
+ * A nil StallWatchdog watches nothing.
+ */
+type StallWatchdog struct {
+	timeout time.Duration
+	mutex   sync.Mutex
+	tables  map[uint32]*tableWatch
+}
+
+func NewStallWatchdog(timeout time.Duration) *StallWatchdog {
+	if timeout <= 0 {
+		return nil
+	}
+	return &StallWatchdog{timeout: timeout, tables: make(map[uint32]*tableWatch)}
+}
+
+func (watchdog *StallWatchdog) StartTable(table Table, destination string, now time.Time) {
+	if watchdog == nil {
+		return
+	}
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+	watchdog.tables[table.Oid] = &tableWatch{
+		table:       table,
+		destination: destination,
+		started:     now,
+		lastBytes:   make(map[int]int64),
+		lastChanged: make(map[int]time.Time),
+	}
+}
+
+// Stops watching the table and returns whether it was reported as stalled
+func (watchdog *StallWatchdog) FinishTable(oid uint32) bool {
+	if watchdog == nil {
+		return false
+	}
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+	watch, ok := watchdog.tables[oid]
+	if !ok {
+		return false
+	}
+	delete(watchdog.tables, oid)
+	return watch.stalled
+}
+
+/*
+ * Compares the progress of each segment on each table being watched with the
+ * progress it had last time, and returns the tables that have newly stalled,
+ * along with the segments on which they stalled.  Each table is only reported
+ * once per attempt to back it up.
+ */
+func (watchdog *StallWatchdog) Check(progress map[int]map[uint32]utils.TableProgress, contentIDs []int, now time.Time) []StalledTable {
+	if watchdog == nil {
+		return nil
+	}
+	watchdog.mutex.Lock()
+	defer watchdog.mutex.Unlock()
+	stalledTables := make([]StalledTable, 0)
+	for oid, watch := range watchdog.tables {
+		if watch.stalled {
+			continue
+		}
+		stalledSegments := make([]int, 0)
+		for _, contentID := range contentIDs {
+			if progress[contentID] == nil {
+				continue
+			}
+			tableProgress := progress[contentID][oid]
+			if tableProgress.Done {
+				continue
+			}
+			lastBytes, seen := watch.lastBytes[contentID]
+			if !seen || tableProgress.Bytes != lastBytes {
+				watch.lastBytes[contentID] = tableProgress.Bytes
+				if seen || tableProgress.Bytes > 0 {
+					watch.lastChanged[contentID] = now
+				} else {
+					// No data backed up yet, so the segment has made no progress since the table was started
+					watch.lastChanged[contentID] = watch.started
+				}
+			}
+			if now.Sub(watch.lastChanged[contentID]) >= watchdog.timeout {
+				stalledSegments = append(stalledSegments, contentID)
+			}
+		}
+		if len(stalledSegments) > 0 {
+			watch.stalled = true
+			stalledTables = append(stalledTables, StalledTable{Table: watch.table, Destination: watch.destination, Segments: stalledSegments})
+		}
+	}
+	sort.Slice(stalledTables, func(i, j int) bool {
+		return stalledTables[i].Table.Oid < stalledTables[j].Table.Oid
+	})
+	return stalledTables
+}
+
+/*
+ * Cancels the COPY command backing up the data of a stalled table, which is
+ * the only query of this backup whose program ends with its destination.  The
+ * connection running the COPY is busy, so the cancel is sent over a new one.
+ */
+func cancelStalledCopy(stalled StalledTable, timeout time.Duration) {
+	segments := make([]string, len(stalled.Segments))
+	for i, contentID := range stalled.Segments {
+		segments[i] = fmt.Sprintf("%d", contentID)
+	}
+	gplog.Warn("Table %s (oid %d) has backed up no data on segment(s) %s for %s; canceling its COPY", stalled.Table.FQN(), stalled.Table.Oid, strings.Join(segments, ", "), timeout)
+
+	conn := dbconn.NewDBConnFromEnvironment(MustGetFlagString(options.DBNAME))
+	err := conn.Connect(1)
+	if err != nil {
+		gplog.Warn("Unable to connect to the database to cancel the COPY of table %s: %v", stalled.Table.FQN(), err)
+		return
+	}
+	defer conn.Close()
+	_, err = conn.Exec(CancelCopyQuery(conn, fmt.Sprintf("gpbackup_%s", globalFPInfo.Timestamp), stalled.Destination))
+	if err != nil {
+		gplog.Warn("Unable to cancel the COPY of table %s: %v", stalled.Table.FQN(), err)
+	}
+}
+
+func CancelCopyQuery(conn *dbconn.DBConn, appName string, destination string) string {
+	pidColumn, queryColumn := "pid", "query"
+	if conn.Version.Before("6") {
+		pidColumn, queryColumn = "procpid", "current_query"
+	}
+	return fmt.Sprintf(`SELECT
+	pg_cancel_backend(%[1]s)
+FROM pg_stat_activity
+WHERE application_name = '%[3]s'
+AND %[2]s LIKE 'COPY %%%[4]s''%%'
+AND %[1]s <> pg_backend_pid()`, pidColumn, queryColumn, appName, destination)
+}
+
+/*
+ * Copies out the data of a table, backing it up again if its COPY is canceled
+ * for stalling and there are stall retries left.  A COPY run in a transaction
+ * is run in a savepoint, so the transaction survives the cancel.
+ */
+func copyTableOutWithStallRetries(table Table, destinationToWrite string, whichConn int) (int64, error) {
+	retries := 0
+	if dataStallWatchdog != nil {
+		retries = MustGetFlagInt(options.STALL_RETRIES)
+	}
+	useSavepoint := retries > 0 && connectionPool.Tx[whichConn] != nil
+	for attempt := 0; ; attempt++ {
+		if useSavepoint {
+			_, err := connectionPool.Exec("SAVEPOINT gpbackup_copy", whichConn)
+			if err != nil {
+				return 0, err
+			}
+		}
+		dataStallWatchdog.StartTable(table, destinationToWrite, time.Now())
+		rowsCopied, err := CopyTableOut(connectionPool, table, destinationToWrite, whichConn)
+		stalled := dataStallWatchdog.FinishTable(table.Oid)
+		if err == nil {
+			if useSavepoint {
+				_, err = connectionPool.Exec("RELEASE SAVEPOINT gpbackup_copy", whichConn)
+			}
+			return rowsCopied, err
+		}
+		if !stalled {
+			return 0, err
+		}
+		if attempt >= retries {
+			return 0, fmt.Errorf("Backup of table %s stalled: %v", table.FQN(), err)
+		}
+		if useSavepoint {
+			_, rollbackErr := connectionPool.Exec("ROLLBACK TO SAVEPOINT gpbackup_copy", whichConn)
+			if rollbackErr != nil {
+				return 0, rollbackErr
+			}
+		}
+		gplog.Warn("Worker %d: Backing up data for table %s again after it stalled (retry %d of %d)", whichConn, table.FQN(), attempt+1, retries)
+	}
+}
@@ -0,0 +1,99 @@
+package backup_test
+
+import (
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	"github.com/greenplum-db/gpbackup/backup"
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("backup/stall tests", func() {
+	tbl1 := backup.Table{Relation: backup.Relation{Oid: 1, Schema: "public", Name: "table1"}}
+	tbl2 := backup.Table{Relation: backup.Relation{Oid: 2, Schema: "public", Name: "table2"}}
+	contentIDs := []int{-1, 0, 1}
+	start := time.Date(2017, 1, 1, 1, 1, 1, 0, time.UTC)
+
+	Describe("StallWatchdog", func() {
+		var watchdog *backup.StallWatchdog
+
+		BeforeEach(func() {
+			watchdog = backup.NewStallWatchdog(time.Minute)
+			watchdog.StartTable(tbl1, "/data/file_1", start)
+		})
+		It("does not watch anything without a stall timeout", func() {
+			watchdog = backup.NewStallWatchdog(0)
+			Expect(watchdog).To(BeNil())
+			watchdog.StartTable(tbl1, "/data/file_1", start)
+			Expect(watchdog.Check(nil, contentIDs, start.Add(time.Hour))).To(BeEmpty())
+			Expect(watchdog.FinishTable(1)).To(BeFalse())
+		})
+		It("does not report a table backing up data on every segment", func() {
+			progress := map[int]map[uint32]utils.TableProgress{0: {1: {Bytes: 100}}, 1: {1: {Bytes: 100}}}
+			Expect(watchdog.Check(progress, contentIDs, start.Add(30*time.Second))).To(BeEmpty())
+			progress = map[int]map[uint32]utils.TableProgress{0: {1: {Bytes: 200}}, 1: {1: {Bytes: 200}}}
+			Expect(watchdog.Check(progress, contentIDs, start.Add(80*time.Second))).To(BeEmpty())
+			Expect(watchdog.FinishTable(1)).To(BeFalse())
+		})
+		It("reports a table that has backed up no data on a segment for the stall timeout", func() {
+			progress := map[int]map[uint32]utils.TableProgress{0: {1: {Bytes: 100}}, 1: {1: {Bytes: 100}}}
+			Expect(watchdog.Check(progress, contentIDs, start.Add(30*time.Second))).To(BeEmpty())
+			progress = map[int]map[uint32]utils.TableProgress{0: {1: {Bytes: 200}}, 1: {1: {Bytes: 100}}}
+			Expect(watchdog.Check(progress, contentIDs, start.Add(80*time.Second))).To(BeEmpty())
+			stalledTables := watchdog.Check(progress, contentIDs, start.Add(90*time.Second))
+			Expect(stalledTables).To(Equal([]backup.StalledTable{{Table: tbl1, Destination: "/data/file_1", Segments: []int{1}}}))
+			Expect(watchdog.FinishTable(1)).To(BeTrue())
+		})
+		It("reports a table that has not started backing up data on a segment for the stall timeout", func() {
+			watchdog.StartTable(tbl2, "/data/file_2", start.Add(30*time.Second))
+			progress := map[int]map[uint32]utils.TableProgress{0: {1: {Bytes: 100, Done: true}, 2: {Bytes: 100}}, 1: {1: {Bytes: 100, Done: true}}}
+			Expect(watchdog.Check(progress, contentIDs, start.Add(60*time.Second))).To(BeEmpty())
+			stalledTables := watchdog.Check(progress, contentIDs, start.Add(90*time.Second))
+			Expect(stalledTables).To(Equal([]backup.StalledTable{{Table: tbl2, Destination: "/data/file_2", Segments: []int{1}}}))
+		})
+		It("does not report a table that has backed up all of its data on a segment", func() {
+			progress := map[int]map[uint32]utils.TableProgress{0: {1: {Bytes: 100, Done: true}}, 1: {1: {Bytes: 0, Done: true}}}
+			Expect(watchdog.Check(progress, contentIDs, start.Add(90*time.Second))).To(BeEmpty())
+		})
+		It("does not report a table on a segment whose progress could not be gathered", func() {
+			progress := map[int]map[uint32]utils.TableProgress{0: {1: {Bytes: 100, Done: true}}}
+			Expect(watchdog.Check(progress, contentIDs, start.Add(90*time.Second))).To(BeEmpty())
+		})
+		It("reports a stalled table only once", func() {
+			progress := map[int]map[uint32]utils.TableProgress{0: {}, 1: {}}
+			Expect(watchdog.Check(progress, contentIDs, start.Add(90*time.Second))).To(HaveLen(1))
+			Expect(watchdog.Check(progress, contentIDs, start.Add(180*time.Second))).To(BeEmpty())
+		})
+		It("watches a table backed up again from when it was started again", func() {
+			progress := map[int]map[uint32]utils.TableProgress{0: {}, 1: {}}
+			Expect(watchdog.Check(progress, contentIDs, start.Add(90*time.Second))).To(HaveLen(1))
+			Expect(watchdog.FinishTable(1)).To(BeTrue())
+			watchdog.StartTable(tbl1, "/data/file_1", start.Add(100*time.Second))
+			Expect(watchdog.Check(progress, contentIDs, start.Add(130*time.Second))).To(BeEmpty())
+			Expect(watchdog.FinishTable(1)).To(BeFalse())
+		})
+	})
+	Describe("CancelCopyQuery", func() {
+		It("cancels the COPY of this backup writing to the destination", func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+			Expect(backup.CancelCopyQuery(connectionPool, "gpbackup_20170101010101", "/data/file_1")).To(Equal(`SELECT
+	pg_cancel_backend(pid)
+FROM pg_stat_activity
+WHERE application_name = 'gpbackup_20170101010101'
+AND query LIKE 'COPY %/data/file_1''%'
+AND pid <> pg_backend_pid()`))
+		})
+		It("cancels the COPY of this backup writing to the destination in GPDB 5", func() {
+			testhelper.SetDBVersion(connectionPool, "5.1.0")
+			Expect(backup.CancelCopyQuery(connectionPool, "gpbackup_20170101010101", "/data/file_1")).To(Equal(`SELECT
+	pg_cancel_backend(procpid)
+FROM pg_stat_activity
+WHERE application_name = 'gpbackup_20170101010101'
+AND current_query LIKE 'COPY %/data/file_1''%'
+AND procpid <> pg_backend_pid()`))
+		})
+	})
+})
@@ -2,6 +2,7 @@ package backup
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
@@ -31,6 +32,8 @@ func ValidateAndProcessFilterLists(opts *options.Options) {
 	ValidateTablesExist(connectionPool, opts.GetExcludedTables(), true)
 	ValidateSchemasExist(connectionPool, opts.GetIncludedSchemas(), false)
 	ValidateSchemasExist(connectionPool, opts.GetExcludedSchemas(), true)
+	ValidateTablePredicates(connectionPool, opts.TablePredicates)
+	ValidateMaskingRules(connectionPool, opts.MaskingRules)
 }
 
 func ValidateSchemasExist(connectionPool *dbconn.DBConn, schemaList []string, excludeSet bool) {
@@ -136,9 +139,56 @@ func ValidateTablesExist(conn *dbconn.DBConn, tableList []string, excludeSet boo
 	}
 }
 
+/*
+ * IGNORE EXTERNAL PARTITIONS cannot be used when copying out a query, so the
+ * data of a partition table with external partitions cannot be filtered
+ * through its root; the predicates have to be given for its leaf partitions
+ * instead, using --leaf-partition-data.
+ */
+func ValidateTablePredicates(conn *dbconn.DBConn, predicates map[string]string) {
+	if len(predicates) == 0 || MustGetFlagBool(options.LEAF_PARTITION_DATA) {
+		return
+	}
+	rootFqns := getPartitionRootsWithExternalPartitionsFqns(conn)
+	for fqn := range predicates {
+		if rootFqns[fqn] {
+			gplog.Fatal(errors.Errorf("Cannot use a table predicate for %s, as it is a partition table with external partitions.  Specify the predicate for its leaf partitions with --leaf-partition-data instead.", fqn), "")
+		}
+	}
+}
+
+/*
+ * Masking rules are applied by copying out a query as well, so like table
+ * predicates they have to be given for the columns of the leaf partitions of
+ * a partition table with external partitions.
+ */
+func ValidateMaskingRules(conn *dbconn.DBConn, rules map[string]string) {
+	if len(rules) == 0 || MustGetFlagBool(options.LEAF_PARTITION_DATA) {
+		return
+	}
+	rootFqns := getPartitionRootsWithExternalPartitionsFqns(conn)
+	for column := range rules {
+		tableFqn := column[:strings.LastIndex(column, ".")]
+		if rootFqns[tableFqn] {
+			gplog.Fatal(errors.Errorf("Cannot use a masking rule for %s, as it is a column of a partition table with external partitions.  Specify the masking rule for the columns of its leaf partitions with --leaf-partition-data instead.", column), "")
+		}
+	}
+}
+
+// Returns every way the user may write the names of the partition tables with external partitions
+func getPartitionRootsWithExternalPartitionsFqns(conn *dbconn.DBConn) map[string]bool {
+	rootFqns := make(map[string]bool)
+	for _, root := range GetPartitionRootsWithExternalPartitions(conn) {
+		for _, fqn := range utils.UserFQNVariants(root.Schema, root.Name) {
+			rootFqns[fqn] = true
+		}
+	}
+	return rootFqns
+}
+
 func validateFlagCombinations(flags *pflag.FlagSet) {
 	options.CheckExclusiveFlags(flags, options.DEBUG, options.QUIET, options.VERBOSE)
-	options.CheckExclusiveFlags(flags, options.DATA_ONLY, options.METADATA_ONLY, options.INCREMENTAL)
+	options.CheckExclusiveFlags(flags, options.DATA_ONLY, options.METADATA_ONLY, options.INCREMENTAL, options.DIFFERENTIAL)
 	options.CheckExclusiveFlags(flags, options.INCLUDE_SCHEMA, options.INCLUDE_SCHEMA_FILE, options.INCLUDE_RELATION, options.INCLUDE_RELATION_FILE)
 	options.CheckExclusiveFlags(flags, options.EXCLUDE_SCHEMA, options.EXCLUDE_SCHEMA_FILE, options.INCLUDE_SCHEMA, options.INCLUDE_SCHEMA_FILE)
 	options.CheckExclusiveFlags(flags, options.EXCLUDE_SCHEMA, options.EXCLUDE_SCHEMA_FILE, options.EXCLUDE_RELATION, options.INCLUDE_RELATION, options.EXCLUDE_RELATION_FILE, options.INCLUDE_RELATION_FILE)
@@ -147,21 +197,73 @@ func validateFlagCombinations(flags *pflag.FlagSet) {
 	options.CheckExclusiveFlags(flags, options.NO_COMPRESSION, options.COMPRESSION_TYPE)
 	options.CheckExclusiveFlags(flags, options.NO_COMPRESSION, options.COMPRESSION_LEVEL)
 	options.CheckExclusiveFlags(flags, options.PLUGIN_CONFIG, options.BACKUP_DIR)
+	options.CheckExclusiveFlags(flags, options.RESUME, options.METADATA_ONLY, options.SINGLE_DATA_FILE)
+	options.CheckExclusiveFlags(flags, options.DRY_RUN, options.RESUME)
+	options.CheckExclusiveFlags(flags, options.PRIORITY_TABLE_FILE, options.SINGLE_DATA_FILE)
+	options.CheckExclusiveFlags(flags, options.TABLE_PREDICATE_FILE, options.METADATA_ONLY)
+	options.CheckExclusiveFlags(flags, options.MASKING_RULES_FILE, options.METADATA_ONLY)
+	// Statistics hold most common values and histogram bounds sampled from the unmasked column data
+	options.CheckExclusiveFlags(flags, options.MASKING_RULES_FILE, options.WITH_STATS)
+	options.CheckExclusiveFlags(flags, options.STALL_TIMEOUT, options.METADATA_ONLY)
+	options.CheckExclusiveFlags(flags, options.DATA_FORMAT, options.METADATA_ONLY)
+	// The data of a table cannot be backed up again to a single data file
+	options.CheckExclusiveFlags(flags, options.STALL_RETRIES, options.SINGLE_DATA_FILE)
+	// Repository chunks are stored on the segments unencrypted and are shared between backups
+	options.CheckExclusiveFlags(flags, options.REPOSITORY, options.SINGLE_DATA_FILE)
+	options.CheckExclusiveFlags(flags, options.REPOSITORY, options.PLUGIN_CONFIG)
+	options.CheckExclusiveFlags(flags, options.REPOSITORY, options.METADATA_ONLY)
+	options.CheckExclusiveFlags(flags, options.REPOSITORY, options.ENCRYPTION_KEY_FILE)
+	// --delete-backup deletes an existing backup instead of taking one, so the flags that only affect taking a backup make no sense with it
+	options.CheckExclusiveFlags(flags, options.DELETE_BACKUP, options.RESUME)
+	options.CheckExclusiveFlags(flags, options.DELETE_BACKUP, options.DRY_RUN)
+	options.CheckExclusiveFlags(flags, options.DELETE_BACKUP, options.REPOSITORY)
 	if FlagChanged(options.COPY_QUEUE_SIZE) && !MustGetFlagBool(options.SINGLE_DATA_FILE) {
 		gplog.Fatal(errors.Errorf("--copy-queue-size must be specified with --single-data-file"), "")
 	}
-	if MustGetFlagString(options.FROM_TIMESTAMP) != "" && !MustGetFlagBool(options.INCREMENTAL) {
-		gplog.Fatal(errors.Errorf("--from-timestamp must be specified with --incremental"), "")
+	if FlagChanged(options.DATA_STREAMS) && !MustGetFlagBool(options.SINGLE_DATA_FILE) {
+		gplog.Fatal(errors.Errorf("--data-streams must be specified with --single-data-file"), "")
+	}
+	if MustGetFlagString(options.FROM_TIMESTAMP) != "" && !(MustGetFlagBool(options.INCREMENTAL) || MustGetFlagBool(options.DIFFERENTIAL)) {
+		gplog.Fatal(errors.Errorf("--from-timestamp must be specified with --incremental or --differential"), "")
 	}
 	if MustGetFlagBool(options.INCREMENTAL) && !MustGetFlagBool(options.LEAF_PARTITION_DATA) {
 		gplog.Fatal(errors.Errorf("--leaf-partition-data must be specified with --incremental"), "")
 	}
+	if MustGetFlagBool(options.DIFFERENTIAL) && !MustGetFlagBool(options.LEAF_PARTITION_DATA) {
+		gplog.Fatal(errors.Errorf("--leaf-partition-data must be specified with --differential"), "")
+	}
+	if MustGetFlagBool(options.TRACK_HEAP_CHANGES) && !MustGetFlagBool(options.LEAF_PARTITION_DATA) {
+		gplog.Fatal(errors.Errorf("--leaf-partition-data must be specified with --track-heap-changes"), "")
+	}
 	if MustGetFlagBool(options.NO_INHERITS) && !(FlagChanged(options.INCLUDE_RELATION) || FlagChanged(options.INCLUDE_RELATION_FILE)) {
 		gplog.Fatal(errors.Errorf("--no-inherits must be specified with either --include-table or --include-table-file"), "")
 	}
+	if FlagChanged(options.DRY_RUN_FORMAT) && !MustGetFlagBool(options.DRY_RUN) {
+		gplog.Fatal(errors.Errorf("--dry-run-format must be specified with --dry-run"), "")
+	}
+	if FlagChanged(options.DRY_RUN_FILE) && !MustGetFlagBool(options.DRY_RUN) {
+		gplog.Fatal(errors.Errorf("--dry-run-file must be specified with --dry-run"), "")
+	}
+	// Log messages are printed to stdout as well, so a plan meant to be parsed is written to its own file
+	if MustGetFlagString(options.DRY_RUN_FORMAT) == "json" && !FlagChanged(options.DRY_RUN_FILE) {
+		gplog.Fatal(errors.Errorf("--dry-run-format json must be specified with --dry-run-file"), "")
+	}
 	if FlagChanged(options.SINGLE_BACKUP_DIR) && !FlagChanged(options.BACKUP_DIR) {
 		gplog.Fatal(errors.Errorf("--single-backup-dir must be specified with --backup-dir"), "")
 	}
+	if MustGetFlagBool(options.REPOSITORY) && !FlagChanged(options.BACKUP_DIR) {
+		gplog.Fatal(errors.Errorf("--repository must be specified with --backup-dir"), "")
+	}
+	if FlagChanged(options.DELETE_BACKUP) && !FlagChanged(options.BACKUP_DIR) {
+		gplog.Fatal(errors.Errorf("--delete-backup must be specified with --backup-dir"), "")
+	}
+	if FlagChanged(options.STALL_RETRIES) && !FlagChanged(options.STALL_TIMEOUT) {
+		gplog.Fatal(errors.Errorf("--stall-retries must be specified with --stall-timeout"), "")
+	}
+	// A masking expression need not have the type of its column, and binary data can only be restored to a column of its own type
+	if MustGetFlagString(options.DATA_FORMAT) == utils.DATA_FORMAT_BINARY && FlagChanged(options.MASKING_RULES_FILE) {
+		gplog.Fatal(errors.Errorf("--data-format binary cannot be used with --masking-rules-file"), "")
+	}
 }
 
 func validateFlagValues() {
@@ -169,16 +271,48 @@ func validateFlagValues() {
 	gplog.FatalOnError(err)
 	err = utils.ValidateFullPath(MustGetFlagString(options.PLUGIN_CONFIG))
 	gplog.FatalOnError(err)
+	err = utils.ValidateFullPath(MustGetFlagString(options.DRY_RUN_FILE))
+	gplog.FatalOnError(err)
 	err = utils.ValidateCompressionTypeAndLevel(MustGetFlagString(options.COMPRESSION_TYPE), MustGetFlagInt(options.COMPRESSION_LEVEL))
 	gplog.FatalOnError(err)
 	if MustGetFlagString(options.FROM_TIMESTAMP) != "" && !filepath.IsValidTimestamp(MustGetFlagString(options.FROM_TIMESTAMP)) {
 		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.",
 			MustGetFlagString(options.FROM_TIMESTAMP)), "")
 	}
+	if MustGetFlagString(options.RESUME) != "" && !filepath.IsValidTimestamp(MustGetFlagString(options.RESUME)) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.",
+			MustGetFlagString(options.RESUME)), "")
+	}
+	if FlagChanged(options.DELETE_BACKUP) && !filepath.IsValidTimestamp(MustGetFlagString(options.DELETE_BACKUP)) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.",
+			MustGetFlagString(options.DELETE_BACKUP)), "")
+	}
+	if format := MustGetFlagString(options.DRY_RUN_FORMAT); format != "text" && format != "json" {
+		gplog.Fatal(errors.Errorf("--dry-run-format %s is invalid. Valid values are 'text', 'json'", format), "")
+	}
+	if format := MustGetFlagString(options.DATA_FORMAT); format != utils.DATA_FORMAT_CSV && format != utils.DATA_FORMAT_BINARY {
+		gplog.Fatal(errors.Errorf("--data-format %s is invalid. Valid values are 'csv', 'binary'", format), "")
+	}
 	if FlagChanged(options.COPY_QUEUE_SIZE) && MustGetFlagInt(options.COPY_QUEUE_SIZE) < 2 {
 		gplog.Fatal(errors.Errorf("--copy-queue-size %d is invalid. Must be at least 2",
 			MustGetFlagInt(options.COPY_QUEUE_SIZE)), "")
 	}
+	if MustGetFlagInt(options.DATA_STREAMS) < 1 {
+		gplog.Fatal(errors.Errorf("--data-streams %d is invalid. Must be at least 1",
+			MustGetFlagInt(options.DATA_STREAMS)), "")
+	}
+	if FlagChanged(options.COPY_QUEUE_SIZE) && MustGetFlagInt(options.COPY_QUEUE_SIZE) < MustGetFlagInt(options.DATA_STREAMS) {
+		gplog.Fatal(errors.Errorf("--copy-queue-size %d is invalid. Must be at least the number of --data-streams",
+			MustGetFlagInt(options.COPY_QUEUE_SIZE)), "")
+	}
+	if MustGetFlagInt(options.STALL_TIMEOUT) < 0 {
+		gplog.Fatal(errors.Errorf("--stall-timeout %d is invalid. Must be at least 0",
+			MustGetFlagInt(options.STALL_TIMEOUT)), "")
+	}
+	if MustGetFlagInt(options.STALL_RETRIES) < 0 {
+		gplog.Fatal(errors.Errorf("--stall-retries %d is invalid. Must be at least 0",
+			MustGetFlagInt(options.STALL_RETRIES)), "")
+	}
 }
 
 func validateFromTimestamp(fromTimestamp string) {
@@ -196,4 +330,8 @@ func validateFromTimestamp(fromTimestamp string) {
 			"that of the current one. Please refer to the report to view the flags supplied for the "+
 			"previous backup.", fromTimestampFPInfo.Timestamp), "")
 	}
+	if MustGetFlagBool(options.DIFFERENTIAL) && fromBackupConfig.Incremental {
+		gplog.Fatal(errors.Errorf("The backup with timestamp = %s is not a full backup. "+
+			"A differential backup must be based off of a full backup.", fromTimestampFPInfo.Timestamp), "")
+	}
 }
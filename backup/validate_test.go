@@ -193,6 +193,49 @@ var _ = Describe("backup/validate tests", func() {
 			})
 		})
 	})
+	Describe("ValidateTablePredicates", func() {
+		var rootRows *sqlmock.Rows
+		BeforeEach(func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+			rootRows = sqlmock.NewRows([]string{"schema", "name"}).AddRow("public", `"Sales"`)
+		})
+		It("passes if there are no table predicates", func() {
+			backup.ValidateTablePredicates(connectionPool, map[string]string{})
+		})
+		It("passes if no predicate is for a partition table with external partitions", func() {
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(rootRows)
+			backup.ValidateTablePredicates(connectionPool, map[string]string{"public.orders": "id > 5"})
+		})
+		It("passes if a predicate is for a partition table with external partitions and --leaf-partition-data is set", func() {
+			_ = cmdFlags.Set(options.LEAF_PARTITION_DATA, "true")
+			backup.ValidateTablePredicates(connectionPool, map[string]string{"public.Sales": "id > 5"})
+		})
+		It("panics if a predicate is for a partition table with external partitions", func() {
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(rootRows)
+			defer testhelper.ShouldPanicWithMessage("Cannot use a table predicate for public.Sales, as it is a partition table with external partitions.")
+			backup.ValidateTablePredicates(connectionPool, map[string]string{"public.Sales": "id > 5"})
+		})
+	})
+	Describe("ValidateMaskingRules", func() {
+		var rootRows *sqlmock.Rows
+		BeforeEach(func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+			rootRows = sqlmock.NewRows([]string{"schema", "name"}).AddRow("public", "sales")
+		})
+		It("passes if no masking rule is for a partition table with external partitions", func() {
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(rootRows)
+			backup.ValidateMaskingRules(connectionPool, map[string]string{"public.customers.email": "md5(email)"})
+		})
+		It("passes if a masking rule is for a partition table with external partitions and --leaf-partition-data is set", func() {
+			_ = cmdFlags.Set(options.LEAF_PARTITION_DATA, "true")
+			backup.ValidateMaskingRules(connectionPool, map[string]string{"public.sales.email": "md5(email)"})
+		})
+		It("panics if a masking rule is for a partition table with external partitions", func() {
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(rootRows)
+			defer testhelper.ShouldPanicWithMessage("Cannot use a masking rule for public.sales.email, as it is a column of a partition table with external partitions.")
+			backup.ValidateMaskingRules(connectionPool, map[string]string{"public.sales.email": "md5(email)"})
+		})
+	})
 	Describe("Validate various flag combinations that are required or exclusive", func() {
 		DescribeTable("Validate various flag combinations that are required or exclusive",
 			func(argString string, valid bool) {
@@ -274,6 +317,101 @@ var _ = Describe("backup/validate tests", func() {
 			Entry("incremental combos", "--incremental --from-timestamp 20211507152558 --leaf-partition-data", true),
 			Entry("incremental combos", "--incremental --leaf-partition-data --data-only", false),
 			Entry("incremental combos", "--incremental --leaf-partition-data --metadata-only", false),
+			Entry("incremental combos", "--incremental --differential --leaf-partition-data", false),
+
+			/*
+			 * Below are various different differential combinations
+			 */
+			Entry("differential combos", "--differential", false),
+			Entry("differential combos", "--differential --leaf-partition-data", true),
+			Entry("differential combos", "--differential --from-timestamp 20211507152558", false),
+			Entry("differential combos", "--differential --from-timestamp 20211507152558 --leaf-partition-data", true),
+			Entry("differential combos", "--differential --leaf-partition-data --data-only", false),
+			Entry("differential combos", "--differential --leaf-partition-data --metadata-only", false),
+
+			/*
+			 * Below are various different heap change tracking combinations
+			 */
+			Entry("track heap changes combos", "--track-heap-changes", false),
+			Entry("track heap changes combos", "--track-heap-changes --leaf-partition-data", true),
+			Entry("track heap changes combos", "--track-heap-changes --incremental --leaf-partition-data", true),
+
+			/*
+			 * Below are various different resume combinations
+			 */
+			Entry("resume combos", "--resume 20220101010101", true),
+			Entry("resume combos", "--resume 20220101010101 --jobs 2", true),
+			Entry("resume combos", "--resume 20220101010101 --metadata-only", false),
+			Entry("resume combos", "--resume 20220101010101 --single-data-file", false),
+
+			/*
+			 * Below are various different dry run combinations
+			 */
+			Entry("dry run combos", "--dry-run", true),
+			Entry("dry run combos", "--dry-run --dry-run-format json", false),
+			Entry("dry run combos", "--dry-run --dry-run-format json --dry-run-file /tmp/plan.json", true),
+			Entry("dry run combos", "--dry-run --dry-run-file /tmp/plan.txt", true),
+			Entry("dry run combos", "--dry-run-file /tmp/plan.txt", false),
+			Entry("dry run combos", "--dry-run --dry-run-format yaml", false),
+			Entry("dry run combos", "--dry-run-format json", false),
+			Entry("dry run combos", "--dry-run --incremental --leaf-partition-data", true),
+			Entry("dry run combos", "--dry-run --resume 20220101010101", false),
+
+			/*
+			 * Below are various different priority table file combinations
+			 */
+			Entry("priority table file combos", "--priority-table-file /tmp/file --jobs 2", true),
+			Entry("priority table file combos", "--priority-table-file /tmp/file --single-data-file", false),
+
+			/*
+			 * Below are various different table predicate file combinations
+			 */
+			Entry("table predicate file combos", "--table-predicate-file /tmp/file --single-data-file", true),
+			Entry("table predicate file combos", "--table-predicate-file /tmp/file --metadata-only", false),
+			Entry("masking rules file combos", "--masking-rules-file /tmp/file --table-predicate-file /tmp/file", true),
+			Entry("masking rules file combos", "--masking-rules-file /tmp/file --metadata-only", false),
+			Entry("masking rules file combos", "--masking-rules-file /tmp/file --with-stats", false),
+
+			/*
+			 * Below are various different data format combinations
+			 */
+			Entry("data format combos", "--data-format binary", true),
+			Entry("data format combos", "--data-format csv --masking-rules-file /tmp/file", true),
+			Entry("data format combos", "--data-format binary --single-data-file", true),
+			Entry("data format combos", "--data-format text", false),
+			Entry("data format combos", "--data-format binary --masking-rules-file /tmp/file", false),
+			Entry("data format combos", "--data-format binary --metadata-only", false),
+
+			/*
+			 * Below are various different stall combinations
+			 */
+			Entry("stall combos", "--stall-timeout 60", true),
+			Entry("stall combos", "--stall-timeout 60 --stall-retries 2", true),
+			Entry("stall combos", "--stall-timeout 60 --single-data-file", true),
+			Entry("stall combos", "--stall-timeout -1", false),
+			Entry("stall combos", "--stall-timeout 60 --stall-retries -1", false),
+			Entry("stall combos", "--stall-retries 2", false),
+			Entry("stall combos", "--stall-timeout 60 --stall-retries 2 --single-data-file", false),
+			Entry("stall combos", "--stall-timeout 60 --metadata-only", false),
+
+			/*
+			 * Below are various different repository combinations
+			 */
+			Entry("repository combos", "--repository --backup-dir /tmp", true),
+			Entry("repository combos", "--repository --backup-dir /tmp --jobs 2 --incremental --leaf-partition-data", true),
+			Entry("repository combos", "--repository", false),
+			Entry("repository combos", "--repository --backup-dir /tmp --single-data-file", false),
+			Entry("repository combos", "--repository --backup-dir /tmp --metadata-only", false),
+			Entry("repository combos", "--repository --backup-dir /tmp --encryption-key-file /tmp/key", false),
+			Entry("repository combos", "--repository --plugin-config /tmp/config", false),
+			Entry("repository combos", "--repository --backup-dir /tmp --resume 20220101010101", true),
+			Entry("repository combos", "--repository --backup-dir /tmp --dry-run", true),
+			Entry("delete backup combos", "--delete-backup 20220101010101 --backup-dir /tmp", true),
+			Entry("delete backup combos", "--delete-backup 20220101010101", false),
+			Entry("delete backup combos", "--delete-backup 2022 --backup-dir /tmp", false),
+			Entry("delete backup combos", "--delete-backup 20220101010101 --backup-dir /tmp --resume 20220101010101", false),
+			Entry("delete backup combos", "--delete-backup 20220101010101 --backup-dir /tmp --dry-run", false),
+			Entry("delete backup combos", "--delete-backup 20220101010101 --backup-dir /tmp --repository", false),
 
 			/*
 			 * Below are various different jobs combinations
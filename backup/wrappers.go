@@ -53,6 +53,9 @@ func initializeConnectionPool(timestamp string) {
 	switch true {
 	case FlagChanged(options.COPY_QUEUE_SIZE):
 		numConns = MustGetFlagInt(options.COPY_QUEUE_SIZE) + 1
+	case FlagChanged(options.DATA_STREAMS):
+		// Each data stream needs a COPY command of its own to write at the same time as the others
+		numConns = MustGetFlagInt(options.DATA_STREAMS) + 1
 	case FlagChanged(options.JOBS):
 		numConns = MustGetFlagInt(options.JOBS) + 1
 	default:
@@ -113,6 +116,7 @@ func NewBackupConfig(dbName string, dbVersion string, backupVersion string, plug
 		DatabaseName:          dbName,
 		DatabaseVersion:       dbVersion,
 		DataOnly:              MustGetFlagBool(options.DATA_ONLY),
+		Differential:          MustGetFlagBool(options.DIFFERENTIAL),
 		ExcludeRelations:      MustGetFlagStringArray(options.EXCLUDE_RELATION),
 		ExcludeSchemaFiltered: len(MustGetFlagStringArray(options.EXCLUDE_SCHEMA)) > 0,
 		ExcludeSchemas:        MustGetFlagStringArray(options.EXCLUDE_SCHEMA),
@@ -121,16 +125,30 @@ func NewBackupConfig(dbName string, dbVersion string, backupVersion string, plug
 		IncludeSchemaFiltered: len(MustGetFlagStringArray(options.INCLUDE_SCHEMA)) > 0,
 		IncludeSchemas:        MustGetFlagStringArray(options.INCLUDE_SCHEMA),
 		IncludeTableFiltered:  len(opts.GetOriginalIncludedTables()) > 0,
-		Incremental:           MustGetFlagBool(options.INCREMENTAL),
+		Incremental:           MustGetFlagBool(options.INCREMENTAL) || MustGetFlagBool(options.DIFFERENTIAL),
 		LeafPartitionData:     MustGetFlagBool(options.LEAF_PARTITION_DATA),
 		MetadataOnly:          MustGetFlagBool(options.METADATA_ONLY),
 		Plugin:                plugin,
+		Repository:            MustGetFlagBool(options.REPOSITORY),
 		SingleDataFile:        MustGetFlagBool(options.SINGLE_DATA_FILE),
 		Timestamp:             timestamp,
 		WithoutGlobals:        MustGetFlagBool(options.WITHOUT_GLOBALS),
 		WithStatistics:        MustGetFlagBool(options.WITH_STATS),
 		Status:                history.BackupStatusInProgress,
 	}
+	if len(opts.TablePredicates) > 0 {
+		backupConfig.TablePredicates = opts.TablePredicates
+	}
+	if len(opts.MaskingRules) > 0 {
+		backupConfig.MaskingRules = opts.MaskingRules
+	}
+	if MustGetFlagBool(options.SINGLE_DATA_FILE) && MustGetFlagInt(options.DATA_STREAMS) > 1 {
+		backupConfig.DataStreams = MustGetFlagInt(options.DATA_STREAMS)
+	}
+	if MustGetFlagString(options.DATA_FORMAT) == utils.DATA_FORMAT_BINARY {
+		backupConfig.DataFormat = utils.DATA_FORMAT_BINARY
+	}
+	backupConfig.EncryptionFingerprint = utils.GetEncryptionKeyFingerprint(utils.GetEncryptionKey())
 
 	return &backupConfig
 }
@@ -754,7 +772,34 @@ func backupTableStatistics(statisticsFile *utils.FileWithByteCount, tables []Tab
 	PrintStatisticsStatements(statisticsFile, globalTOC, tables, attStats, tupleStats)
 }
 
-func backupIncrementalMetadata() {
+/*
+ * The heap tuple counters are not transactional, so they are read on their own
+ * connection before the data snapshot is taken.  A change committed between
+ * the two is then in the data but not the counters, which can only cause the
+ * next incremental backup to back up that table again, while a change
+ * committed after the snapshot is in neither.
+ */
+func readHeapChangeMarkers() {
+	heapConn := dbconn.NewDBConnFromEnvironment(MustGetFlagString(options.DBNAME))
+	heapConn.MustConnect(1)
+	defer heapConn.Close()
+	heapChangeMarkers = GetHeapIncrementalMetadata(heapConn)
+	heapStatsEpoch = GetHeapStatsEpoch(heapConn)
+}
+
+func backupIncrementalMetadata(tables []Table) {
 	aoTableEntries := GetAOIncrementalMetadata(connectionPool)
 	globalTOC.IncrementalMetadata.AO = aoTableEntries
+	if heapChangeMarkers == nil {
+		return
+	}
+	// Heap tables without a marker, such as those created after the markers were read, are always backed up
+	heapTableEntries := make(map[string]toc.HeapEntry)
+	for _, table := range tables {
+		if heapEntry, ok := heapChangeMarkers[table.FQN()]; ok {
+			heapTableEntries[table.FQN()] = heapEntry
+		}
+	}
+	globalTOC.IncrementalMetadata.Heap = heapTableEntries
+	globalTOC.IncrementalMetadata.HeapStatsEpoch = heapStatsEpoch
 }
@@ -134,12 +134,64 @@ func (backupFPInfo *FilePathInfo) GetTableBackupFilePathForCopyCommand(tableOid
 	return path.Join(baseDir, "backups", backupFPInfo.Timestamp[0:8], backupFPInfo.Timestamp, backupFilePath)
 }
 
+/*
+ * The table data of backups taken with --repository is stored in chunks that
+ * are shared by all of the repository backups in the backup directory of a
+ * segment, so the chunk directory sits beside the backups directory.
+ */
+func (backupFPInfo *FilePathInfo) GetChunkDir(contentID int) string {
+	return backupFPInfo.replaceCopyFormatStringsInPath(backupFPInfo.GetChunkDirForCopyCommand(), contentID)
+}
+
+func (backupFPInfo *FilePathInfo) GetChunkDirForCopyCommand() string {
+	baseDir := backupFPInfo.BaseDataDir
+	if backupFPInfo.IsUserSpecifiedBackupDir() {
+		baseDir = backupFPInfo.UserSpecifiedBackupDir
+		if backupFPInfo.UserSpecifiedSegPrefix != "" {
+			baseDir = path.Join(baseDir, fmt.Sprintf("%s<SEGID>", backupFPInfo.UserSpecifiedSegPrefix))
+		}
+	}
+	return path.Join(baseDir, "chunks")
+}
+
+/*
+ * A single data file backup taken with --data-streams writes each stream after
+ * the first to its own data file, named for the stream, so the data file of the
+ * first stream has the same name as that of a backup with a single stream.
+ */
+func GetDataStreamFilePath(dataFile string, stream int) string {
+	if stream == 0 {
+		return dataFile
+	}
+	extension := path.Ext(path.Base(dataFile))
+	return fmt.Sprintf("%s_stream%d%s", strings.TrimSuffix(dataFile, extension), stream, extension)
+}
+
+/*
+ * Each segment records the checksums of the table data it backs up in a file
+ * stored alongside its data files.  The name does not include a PID, so that
+ * a resumed backup keeps appending to the same file, and it puts the timestamp
+ * before the content ID, so that the file is not counted as a data file of the
+ * segment by gprestore.
+ */
+func (backupFPInfo *FilePathInfo) GetSegmentChecksumFilePath(contentID int) string {
+	templateFilePath := backupFPInfo.GetSegmentChecksumFilePathForCopyCommand()
+	return backupFPInfo.replaceCopyFormatStringsInPath(templateFilePath, contentID)
+}
+
+func (backupFPInfo *FilePathInfo) GetSegmentChecksumFilePathForCopyCommand() string {
+	backupDir := path.Dir(backupFPInfo.GetTableBackupFilePathForCopyCommand(0, "", true))
+	return path.Join(backupDir, fmt.Sprintf("gpbackup_%s_<SEGID>_checksums", backupFPInfo.Timestamp))
+}
+
 var metadataFilenameMap = map[string]string{
 	"config":                "config.yaml",
 	"metadata":              "metadata.sql",
 	"statistics":            "statistics.sql",
 	"table of contents":     "toc.yaml",
 	"report":                "report",
+	"resume state":          "resume_state",
+	"restore state":         "restore_state",
 	"plugin_config":         "plugin_config.yaml",
 	"error_tables_metadata": "error_tables_metadata",
 	"error_tables_data":     "error_tables_data",
@@ -175,6 +227,10 @@ func (backupFPInfo *FilePathInfo) GetBackupReportFilePath() string {
 	return backupFPInfo.GetBackupFilePath("report")
 }
 
+func (backupFPInfo *FilePathInfo) GetResumeStateFilePath() string {
+	return backupFPInfo.GetBackupFilePath("resume state")
+}
+
 func (backupFPInfo *FilePathInfo) GetRestoreFilePath(restoreTimestamp string, filetype string) string {
 	return path.Join(backupFPInfo.GetReportDirectoryPath(), fmt.Sprintf("gprestore_%s_%s_%s", backupFPInfo.Timestamp, restoreTimestamp, metadataFilenameMap[filetype]))
 }
@@ -183,6 +239,10 @@ func (backupFPInfo *FilePathInfo) GetRestoreReportFilePath(restoreTimestamp stri
 	return backupFPInfo.GetRestoreFilePath(restoreTimestamp, "report")
 }
 
+func (backupFPInfo *FilePathInfo) GetRestoreStateFilePath(restoreTimestamp string) string {
+	return backupFPInfo.GetRestoreFilePath(restoreTimestamp, "restore state")
+}
+
 func (backupFPInfo *FilePathInfo) GetErrorTablesMetadataFilePath(restoreTimestamp string) string {
 	return backupFPInfo.GetRestoreFilePath(restoreTimestamp, "error_tables_metadata")
 }
@@ -207,6 +267,10 @@ func (backupFPInfo *FilePathInfo) GetSegmentHelperFilePath(contentID int, suffix
 	return path.Join(backupFPInfo.SegDirMap[contentID], fmt.Sprintf("gpbackup_%d_%s_%s_%d", contentID, backupFPInfo.Timestamp, suffix, backupFPInfo.PID))
 }
 
+func (backupFPInfo *FilePathInfo) GetSegmentHelperFilePathForCopyCommand(suffix string) string {
+	return fmt.Sprintf("<SEG_DATA_DIR>/gpbackup_<SEGID>_%s_%s_%d", backupFPInfo.Timestamp, suffix, backupFPInfo.PID)
+}
+
 func (backupFPInfo *FilePathInfo) GetHelperLogPath() string {
 	currentUser, _ := operating.System.CurrentUser()
 	homeDir := currentUser.HomeDir
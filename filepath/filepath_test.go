@@ -158,6 +158,12 @@ var _ = Describe("filepath tests", func() {
 			Expect(fpInfo.GetRestoreReportFilePath("20200101010101")).To(Equal("/bar/foo/gpseg-1/backups/20170101/20170101010101/gprestore_20170101010101_20200101010101_report"))
 		})
 	})
+	Describe("GetRestoreStateFilePath", func() {
+		It("returns the restore state file path of a restore", func() {
+			fpInfo := NewFilePathInfo(c, "", "20170101010101", "gpseg", false)
+			Expect(fpInfo.GetRestoreStateFilePath("20200101010101")).To(Equal("/data/gpseg-1/backups/20170101/20170101010101/gprestore_20170101010101_20200101010101_restore_state"))
+		})
+	})
 	Describe("GetTableBackupFilePath", func() {
 		It("returns table file path", func() {
 			fpInfo := NewFilePathInfo(c, "", "20170101010101", "gpseg", false)
@@ -176,6 +182,39 @@ var _ = Describe("filepath tests", func() {
 			Expect(fpInfo.GetTableBackupFilePath(-1, 1234, "", true)).To(Equal("/foo/bar/gpseg-1/backups/20170101/20170101010101/gpbackup_-1_20170101010101"))
 		})
 	})
+	Describe("GetChunkDir", func() {
+		It("returns the chunk directory of a segment in the user specified path", func() {
+			fpInfo := NewFilePathInfo(c, "/foo/bar", "20170101010101", "gpseg", false)
+			Expect(fpInfo.GetChunkDirForCopyCommand()).To(Equal("/foo/bar/gpseg<SEGID>/chunks"))
+			Expect(fpInfo.GetChunkDir(-1)).To(Equal("/foo/bar/gpseg-1/chunks"))
+		})
+		It("returns the chunk directory shared by the segments of a single backup directory", func() {
+			fpInfo := NewFilePathInfo(c, "/foo/bar", "20170101010101", "", true)
+			Expect(fpInfo.GetChunkDir(-1)).To(Equal("/foo/bar/chunks"))
+		})
+	})
+	Describe("GetDataStreamFilePath", func() {
+		It("returns the data file itself for the first stream", func() {
+			Expect(GetDataStreamFilePath("/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101.gz", 0)).To(Equal("/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101.gz"))
+		})
+		It("returns a data file named for any other stream", func() {
+			Expect(GetDataStreamFilePath("/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101.gz", 2)).To(Equal("/data/gpseg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_stream2.gz"))
+		})
+		It("returns a data file named for a stream of an uncompressed backup in a directory with a dot", func() {
+			Expect(GetDataStreamFilePath("/data/gp.seg0/backups/20170101/20170101010101/gpbackup_0_20170101010101", 1)).To(Equal("/data/gp.seg0/backups/20170101/20170101010101/gpbackup_0_20170101010101_stream1"))
+		})
+	})
+	Describe("GetSegmentChecksumFilePath", func() {
+		It("returns checksum file path", func() {
+			fpInfo := NewFilePathInfo(c, "", "20170101010101", "gpseg", false)
+			Expect(fpInfo.GetSegmentChecksumFilePathForCopyCommand()).To(Equal("<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_20170101010101_<SEGID>_checksums"))
+			Expect(fpInfo.GetSegmentChecksumFilePath(-1)).To(Equal("/data/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_-1_checksums"))
+		})
+		It("returns checksum file path based on user specified path", func() {
+			fpInfo := NewFilePathInfo(c, "/foo/bar", "20170101010101", "gpseg", false)
+			Expect(fpInfo.GetSegmentChecksumFilePath(-1)).To(Equal("/foo/bar/gpseg-1/backups/20170101/20170101010101/gpbackup_20170101010101_-1_checksums"))
+		})
+	})
 	Describe("ParseSegPrefix", func() {
 		AfterEach(func() {
 			operating.System.Glob = path.Glob
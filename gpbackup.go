@@ -17,6 +17,12 @@ func main() {
 		Args:    cobra.NoArgs,
 		Version: GetVersion(),
 		Run: func(cmd *cobra.Command, args []string) {
+			if cmd.Flags().Changed(options.DELETE_BACKUP) {
+				defer DoDeleteTeardown()
+				DoFlagValidation(cmd)
+				DoDeleteBackup()
+				return
+			}
 			defer DoTeardown()
 			DoFlagValidation(cmd)
 			DoSetup()
@@ -8,6 +8,7 @@ import (
 	"os/exec"
 	"strings"
 
+	fp "github.com/greenplum-db/gpbackup/filepath"
 	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/pkg/errors"
@@ -17,42 +18,126 @@ import (
  * Backup specific functions
  */
 
-func doBackupAgent() error {
-	var lastRead uint64
-	var (
-		pipeWriter BackupPipeWriterCloser
-		writeCmd   *exec.Cmd
-	)
-	tocfile := &toc.SegmentTOC{}
-	tocfile.DataEntries = make(map[uint]toc.SegmentDataEntry)
+/*
+ * A compressed single data file starts a new block at the first table boundary
+ * after each minBlockSize bytes of table data, so that a restore of one table
+ * decompresses at most about this much data that it does not need.
+ */
+const minBlockSize = 8 * 1024 * 1024
 
+func doBackupAgent() error {
 	oidList, err := getOidListFromFile(*oidFile)
 	if err != nil {
 		// error logging handled in getOidListFromFile
 		return err
 	}
+	streams := make(map[int]int)
+	if *streamFile != "" {
+		streams, err = getStreamsFromFile(*streamFile)
+		if err != nil {
+			// error logging handled in getStreamsFromFile
+			return err
+		}
+	}
 
 	preloadCreatedPipes(oidList, *copyQueue)
+	pipes := newPipeQueue(oidList, *copyQueue)
+	/*
+	 * Each data stream backs up its tables in the order of the oid list, and
+	 * every data file is written even if its stream has no tables, so that a
+	 * restore finds the same files for every segment.
+	 */
+	streamTOCs := make([]*toc.SegmentTOC, *dataStreams)
+	errChan := make(chan error, *dataStreams)
+	for stream := 0; stream < *dataStreams; stream++ {
+		oidIndexes := make([]int, 0)
+		for i, oid := range oidList {
+			if streams[oid] == stream {
+				oidIndexes = append(oidIndexes, i)
+			}
+		}
+		streamTOCs[stream] = &toc.SegmentTOC{DataEntries: make(map[uint]toc.SegmentDataEntry)}
+		go func(stream int, oidIndexes []int) {
+			errChan <- backupDataStream(stream, oidList, oidIndexes, pipes, streamTOCs[stream])
+		}(stream, oidIndexes)
+	}
+	for stream := 0; stream < *dataStreams; stream++ {
+		err = <-errChan
+		if err != nil {
+			// error logging handled in backupDataStream
+			return err
+		}
+	}
+
+	tocfile := &toc.SegmentTOC{}
+	tocfile.DataEntries = make(map[uint]toc.SegmentDataEntry)
+	for _, streamTOC := range streamTOCs {
+		for oid, entry := range streamTOC.DataEntries {
+			tocfile.DataEntries[oid] = entry
+		}
+		tocfile.Blocks = append(tocfile.Blocks, streamTOC.Blocks...)
+	}
+	err = tocfile.WriteToFileAndMakeReadOnly(*tocFile)
+	if err != nil {
+		// error logging handled in util.go
+		return err
+	}
+	log("Finished writing segment TOC")
+	return nil
+}
+
+/*
+ * Backs up the tables at the given indexes of the oid list to the data file of
+ * the data stream, recording their entries and blocks in the stream TOC.
+ */
+func backupDataStream(stream int, oidList []int, oidIndexes []int, pipes *pipeQueue, tocfile *toc.SegmentTOC) error {
+	var lastRead uint64
+	var blockStart uint64
+	var (
+		pipeWriter  BackupPipeWriterCloser
+		blockWriter BlockBackupPipeWriterCloser
+		writeCmd    *exec.Cmd
+		err         error
+	)
+	streamDataFile := fp.GetDataStreamFilePath(*dataFile, stream)
+	streamRateLimiter := utils.NewRateLimiter(*maxBandwidth)
+	startWriter := func(oid int) error {
+		pipeWriter, writeCmd, err = getBackupPipeWriter(streamDataFile)
+		if err != nil {
+			logError(fmt.Sprintf("Oid %d: Error encountered getting backup pipe writer: %v", oid, err))
+			return err
+		}
+		// The offsets of an encrypted file are not those of the compressed data, so it cannot be read from a block
+		if writer, ok := pipeWriter.(BlockBackupPipeWriterCloser); ok && encryptionKey == nil {
+			blockWriter = writer
+			tocfile.AddSegmentBlock(0, 0, stream)
+		}
+		return nil
+	}
+	if len(oidIndexes) == 0 {
+		err = startWriter(0)
+		if err != nil {
+			return err
+		}
+	}
+
 	var currentPipe string
 	/*
 	 * It is important that we create the reader before creating the writer
 	 * so that we establish a connection to the first pipe (created by gpbackup)
 	 * and properly clean it up if an error occurs while creating the writer.
 	 */
-	for i, oid := range oidList {
-		currentPipe = fmt.Sprintf("%s_%d", *pipeFile, oidList[i])
+	for i, index := range oidIndexes {
+		oid := oidList[index]
+		currentPipe = fmt.Sprintf("%s_%d", *pipeFile, oid)
 		if wasTerminated {
 			logError("Terminated due to user request")
 			return errors.New("Terminated due to user request")
 		}
-		if i < len(oidList)-*copyQueue {
-			nextPipeToCreate := fmt.Sprintf("%s_%d", *pipeFile, oidList[i+*copyQueue])
-			log(fmt.Sprintf("Oid %d: Creating pipe %s\n", oidList[i+*copyQueue], nextPipeToCreate))
-			err := createPipe(nextPipeToCreate)
-			if err != nil {
-				logError(fmt.Sprintf("Oid %d: Failed to create pipe %s\n", oidList[i+*copyQueue], nextPipeToCreate))
-				return err
-			}
+		err = pipes.createPipesForTable(index)
+		if err != nil {
+			// error logging handled in createPipesForTable
+			return err
 		}
 
 		log(fmt.Sprintf("Oid %d: Opening pipe %s", oid, currentPipe))
@@ -62,23 +147,31 @@ func doBackupAgent() error {
 			return err
 		}
 		if i == 0 {
-			pipeWriter, writeCmd, err = getBackupPipeWriter()
+			err = startWriter(oid)
 			if err != nil {
-				logError(fmt.Sprintf("Oid %d: Error encountered getting backup pipe writer: %v", oid, err))
 				return err
 			}
+		} else if blockWriter != nil && lastRead-blockStart >= minBlockSize {
+			fileOffset, err := blockWriter.StartBlock()
+			if err != nil {
+				logError(fmt.Sprintf("Oid %d: Error encountered starting compressed block: %v", oid, err))
+				return errors.Wrap(err, strings.Trim(errBuf.String(), "\x00"))
+			}
+			tocfile.AddSegmentBlock(lastRead, fileOffset, stream)
+			blockStart = lastRead
 		}
 
-		log(fmt.Sprintf("Oid %d: Backing up table with pipe %s", oid, currentPipe))
-		numBytes, err := io.Copy(pipeWriter, reader)
+		log(fmt.Sprintf("Oid %d: Backing up table with pipe %s to data stream %d", oid, currentPipe, stream))
+		checksum := utils.NewChecksum()
+		numBytes, err := io.Copy(pipeWriter, streamRateLimiter.Reader(dataProgress.Reader(io.TeeReader(reader, checksum), oid)))
 		if err != nil {
 			logError(fmt.Sprintf("Oid %d: Error encountered copying bytes from pipeWriter to reader: %v", oid, err))
 			return errors.Wrap(err, strings.Trim(errBuf.String(), "\x00"))
 		}
-		log(fmt.Sprintf("Oid %d: Read %d bytes\n", oid, numBytes))
+		log(fmt.Sprintf("Oid %d: Read %d bytes with checksum %s\n", oid, numBytes, utils.FormatChecksum(checksum)))
 
 		lastProcessed := lastRead + uint64(numBytes)
-		tocfile.AddSegmentDataEntry(uint(oid), lastRead, lastProcessed)
+		tocfile.AddSegmentDataEntry(uint(oid), lastRead, lastProcessed, utils.FormatChecksum(checksum), stream)
 		lastRead = lastProcessed
 
 		_ = readHandle.Close()
@@ -87,6 +180,10 @@ func doBackupAgent() error {
 	}
 
 	_ = pipeWriter.Close()
+	if blockWriter != nil {
+		tocfile.AddSegmentBlock(lastRead, blockWriter.BytesWritten(), stream)
+		log(fmt.Sprintf("Wrote %d compressed blocks to data stream %d", len(tocfile.Blocks)-1, stream))
+	}
 	if *pluginConfigFile != "" {
 		/*
 		 * When using a plugin, the agent may take longer to finish than the
@@ -95,19 +192,13 @@ func doBackupAgent() error {
 		 * finished. We then wait on the gpbackup side until one of those files is
 		 * written to verify the agent completed.
 		 */
-		log("Uploading remaining data to plugin destination")
+		log(fmt.Sprintf("Uploading remaining data of data stream %d to plugin destination", stream))
 		err := writeCmd.Wait()
 		if err != nil {
 			logError(fmt.Sprintf("Error encountered writing either TOC file or error file: %v", err))
 			return errors.Wrap(err, strings.Trim(errBuf.String(), "\x00"))
 		}
 	}
-	err = tocfile.WriteToFileAndMakeReadOnly(*tocFile)
-	if err != nil {
-		// error logging handled in util.go
-		return err
-	}
-	log("Finished writing segment TOC")
 	return nil
 }
 
@@ -124,44 +215,63 @@ func getBackupPipeReader(currentPipe string) (io.Reader, io.ReadCloser, error) {
 	return reader, readHandle, nil
 }
 
-func getBackupPipeWriter() (pipe BackupPipeWriterCloser, writeCmd *exec.Cmd, err error) {
+func getBackupPipeWriter(streamDataFile string) (pipe BackupPipeWriterCloser, writeCmd *exec.Cmd, err error) {
 	var writeHandle io.WriteCloser
 	if *pluginConfigFile != "" {
-		writeCmd, writeHandle, err = startBackupPluginCommand()
+		writeCmd, writeHandle, err = startBackupPluginCommand(streamDataFile)
 	} else {
-		writeHandle, err = os.Create(*dataFile)
+		writeHandle, err = os.Create(streamDataFile)
 	}
 	if err != nil {
 		// error logging handled by calling functions
 		return nil, nil, err
 	}
 
+	pipe, err = newBackupPipeWriterCloser(writeHandle)
+	return pipe, writeCmd, err
+}
+
+func newBackupPipeWriterCloser(writeHandle io.WriteCloser) (BackupPipeWriterCloser, error) {
+	if encryptionKey != nil {
+		// Data is compressed before it is encrypted, as encrypted data does not compress
+		encryptingWriter, err := utils.NewEncryptingWriter(writeHandle, encryptionKey)
+		if err != nil {
+			writeHandle.Close()
+			// error logging handled by calling functions
+			return nil, err
+		}
+		writeHandle = encryptingWriter
+	}
+
 	if *compressionLevel == 0 {
-		pipe = NewCommonBackupPipeWriterCloser(writeHandle)
-		return
+		return NewCommonBackupPipeWriterCloser(writeHandle), nil
 	}
 
 	if *compressionType == "gzip" {
-		pipe, err = NewGZipBackupPipeWriterCloser(writeHandle, *compressionLevel)
-		return
+		return NewGZipBackupPipeWriterCloser(writeHandle, *compressionLevel)
 	}
 	if *compressionType == "zstd" {
-		pipe, err = NewZSTDBackupPipeWriterCloser(writeHandle, *compressionLevel)
-		return
+		return NewZSTDBackupPipeWriterCloser(writeHandle, *compressionLevel)
+	}
+	if *compressionType == "lz4" {
+		return NewLZ4BackupPipeWriterCloser(writeHandle, *compressionLevel)
+	}
+	if *compressionType == "snappy" {
+		return NewSnappyBackupPipeWriterCloser(writeHandle), nil
 	}
 
 	writeHandle.Close()
 	// error logging handled by calling functions
-	return nil, nil, fmt.Errorf("unknown compression type '%s' (compression level %d)", *compressionType, *compressionLevel)
+	return nil, fmt.Errorf("unknown compression type '%s' (compression level %d)", *compressionType, *compressionLevel)
 }
 
-func startBackupPluginCommand() (*exec.Cmd, io.WriteCloser, error) {
+func startBackupPluginCommand(streamDataFile string) (*exec.Cmd, io.WriteCloser, error) {
 	pluginConfig, err := utils.ReadPluginConfig(*pluginConfigFile)
 	if err != nil {
 		// error logging handled by calling functions
 		return nil, nil, err
 	}
-	cmdStr := fmt.Sprintf("%s backup_data %s %s", pluginConfig.ExecutablePath, pluginConfig.ConfigPath, *dataFile)
+	cmdStr := fmt.Sprintf("%s backup_data %s %s", pluginConfig.ExecutablePath, pluginConfig.ConfigPath, streamDataFile)
 	writeCmd := exec.Command("bash", "-c", cmdStr)
 
 	writeHandle, err := writeCmd.StdinPipe()
@@ -3,9 +3,12 @@ package helper
 import (
 	"bufio"
 	"compress/gzip"
+	"fmt"
 	"io"
 
+	"github.com/klauspost/compress/snappy"
 	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 )
 
 type BackupPipeWriterCloser interface {
@@ -13,8 +16,30 @@ type BackupPipeWriterCloser interface {
 	io.Closer
 }
 
+/*
+ * A BlockBackupPipeWriterCloser can end the compressed frame it is writing and
+ * start another, which can be decompressed without any of the data before it.
+ */
+type BlockBackupPipeWriterCloser interface {
+	BackupPipeWriterCloser
+	StartBlock() (uint64, error)
+	BytesWritten() uint64
+}
+
+type byteCountingWriter struct {
+	writer   io.Writer
+	numBytes uint64
+}
+
+func (w *byteCountingWriter) Write(p []byte) (int, error) {
+	numBytes, err := w.writer.Write(p)
+	w.numBytes += uint64(numBytes)
+	return numBytes, err
+}
+
 type CommonBackupPipeWriterCloser struct {
 	writeHandle io.WriteCloser
+	counter     *byteCountingWriter
 	bufIoWriter *bufio.Writer
 	finalWriter io.Writer
 }
@@ -30,9 +55,15 @@ func (cPipe CommonBackupPipeWriterCloser) Close() error {
 	return nil
 }
 
+// Returns the number of bytes written to the underlying handle, including those not yet flushed
+func (cPipe CommonBackupPipeWriterCloser) BytesWritten() uint64 {
+	return cPipe.counter.numBytes + uint64(cPipe.bufIoWriter.Buffered())
+}
+
 func NewCommonBackupPipeWriterCloser(writeHandle io.WriteCloser) (cPipe CommonBackupPipeWriterCloser) {
 	cPipe.writeHandle = writeHandle
-	cPipe.bufIoWriter = bufio.NewWriter(cPipe.writeHandle)
+	cPipe.counter = &byteCountingWriter{writer: writeHandle}
+	cPipe.bufIoWriter = bufio.NewWriter(cPipe.counter)
 	cPipe.finalWriter = cPipe.bufIoWriter
 	return
 }
@@ -52,6 +83,20 @@ func (gzPipe GZipBackupPipeWriterCloser) Close() error {
 	return gzPipe.cPipe.Close()
 }
 
+// Ends the current gzip member and starts another, returning the offset at which it starts
+func (gzPipe GZipBackupPipeWriterCloser) StartBlock() (uint64, error) {
+	err := gzPipe.gzipWriter.Close()
+	if err != nil {
+		return 0, err
+	}
+	gzPipe.gzipWriter.Reset(gzPipe.cPipe.bufIoWriter)
+	return gzPipe.cPipe.BytesWritten(), nil
+}
+
+func (gzPipe GZipBackupPipeWriterCloser) BytesWritten() uint64 {
+	return gzPipe.cPipe.BytesWritten()
+}
+
 func NewGZipBackupPipeWriterCloser(writeHandle io.WriteCloser, compressLevel int) (gzPipe GZipBackupPipeWriterCloser, err error) {
 	gzPipe.cPipe = NewCommonBackupPipeWriterCloser(writeHandle)
 	gzPipe.gzipWriter, err = gzip.NewWriterLevel(gzPipe.cPipe.bufIoWriter, compressLevel)
@@ -76,6 +121,20 @@ func (zstdPipe ZSTDBackupPipeWriterCloser) Close() error {
 	return zstdPipe.cPipe.Close()
 }
 
+// Ends the current zstd frame and starts another, returning the offset at which it starts
+func (zstdPipe ZSTDBackupPipeWriterCloser) StartBlock() (uint64, error) {
+	err := zstdPipe.zstdEncoder.Close()
+	if err != nil {
+		return 0, err
+	}
+	zstdPipe.zstdEncoder.Reset(zstdPipe.cPipe.bufIoWriter)
+	return zstdPipe.cPipe.BytesWritten(), nil
+}
+
+func (zstdPipe ZSTDBackupPipeWriterCloser) BytesWritten() uint64 {
+	return zstdPipe.cPipe.BytesWritten()
+}
+
 func NewZSTDBackupPipeWriterCloser(writeHandle io.WriteCloser, compressLevel int) (zstdPipe ZSTDBackupPipeWriterCloser, err error) {
 	zstdPipe.cPipe = NewCommonBackupPipeWriterCloser(writeHandle)
 	zstdPipe.zstdEncoder, err = zstd.NewWriter(zstdPipe.cPipe.bufIoWriter, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(compressLevel)))
@@ -84,3 +143,59 @@ func NewZSTDBackupPipeWriterCloser(writeHandle io.WriteCloser, compressLevel int
 	}
 	return
 }
+
+type LZ4BackupPipeWriterCloser struct {
+	cPipe     CommonBackupPipeWriterCloser
+	lz4Writer *lz4.Writer
+}
+
+func (lz4Pipe LZ4BackupPipeWriterCloser) Write(p []byte) (n int, err error) {
+	return lz4Pipe.lz4Writer.Write(p)
+}
+
+// Returns errors from underlying common writer only
+func (lz4Pipe LZ4BackupPipeWriterCloser) Close() error {
+	_ = lz4Pipe.lz4Writer.Close()
+	return lz4Pipe.cPipe.Close()
+}
+
+/*
+ * Level 1 is the fast lz4 compressor, and levels 2 through 9 are the levels of
+ * the slower high compression lz4 compressor.
+ */
+func NewLZ4BackupPipeWriterCloser(writeHandle io.WriteCloser, compressLevel int) (lz4Pipe LZ4BackupPipeWriterCloser, err error) {
+	lz4Levels := []lz4.CompressionLevel{lz4.Fast, lz4.Fast, lz4.Level2, lz4.Level3, lz4.Level4, lz4.Level5, lz4.Level6, lz4.Level7, lz4.Level8, lz4.Level9}
+	lz4Pipe.cPipe = NewCommonBackupPipeWriterCloser(writeHandle)
+	if compressLevel < 1 || compressLevel >= len(lz4Levels) {
+		lz4Pipe.cPipe.Close()
+		return lz4Pipe, fmt.Errorf("invalid lz4 compression level %d", compressLevel)
+	}
+	lz4Pipe.lz4Writer = lz4.NewWriter(lz4Pipe.cPipe.bufIoWriter)
+	err = lz4Pipe.lz4Writer.Apply(lz4.CompressionLevelOption(lz4Levels[compressLevel]))
+	if err != nil {
+		lz4Pipe.cPipe.Close()
+	}
+	return
+}
+
+type SnappyBackupPipeWriterCloser struct {
+	cPipe        CommonBackupPipeWriterCloser
+	snappyWriter *snappy.Writer
+}
+
+func (snappyPipe SnappyBackupPipeWriterCloser) Write(p []byte) (n int, err error) {
+	return snappyPipe.snappyWriter.Write(p)
+}
+
+// Returns errors from underlying common writer only
+func (snappyPipe SnappyBackupPipeWriterCloser) Close() error {
+	_ = snappyPipe.snappyWriter.Close()
+	return snappyPipe.cPipe.Close()
+}
+
+// Snappy has no compression levels
+func NewSnappyBackupPipeWriterCloser(writeHandle io.WriteCloser) (snappyPipe SnappyBackupPipeWriterCloser) {
+	snappyPipe.cPipe = NewCommonBackupPipeWriterCloser(writeHandle)
+	snappyPipe.snappyWriter = snappy.NewBufferedWriter(snappyPipe.cPipe.bufIoWriter)
+	return
+}
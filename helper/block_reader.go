@@ -0,0 +1,227 @@
+package helper
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Seekable readers of single data files
+ *
+ * The table data of a single data file can be read from any table boundary if
+ * the file is not compressed, or from the start of any block if the file was
+ * compressed in blocks, so a filtered restore need not read the data of the
+ * tables it does not restore.
+ */
+
+func canSeekInFile(fileToRead string, objToc *toc.SegmentTOC) bool {
+	if encryptionKey != nil {
+		return false
+	}
+	return getCompressionTypeForFile(fileToRead) == "cat" || objToc.HasBlocks()
+}
+
+func openSeekableFile(fileToRead string, objToc *toc.SegmentTOC) (io.ReadSeeker, error) {
+	if getCompressionTypeForFile(fileToRead) == "cat" {
+		return os.Open(fileToRead)
+	}
+	return newBlockReader(fileToRead, objToc)
+}
+
+type decompressionResetter interface {
+	Reset(reader io.Reader) error
+}
+
+/*
+ * A blockReader reads a file compressed in blocks as if it were uncompressed,
+ * seeking forward by starting to decompress at the block holding the data it
+ * seeks to and discarding the data before it in that block.
+ */
+type blockReader struct {
+	file            *os.File
+	bufReader       *bufio.Reader
+	decompressor    io.Reader
+	compressionType string
+	objToc          *toc.SegmentTOC
+	position        uint64
+}
+
+func newBlockReader(filename string, objToc *toc.SegmentTOC) (*blockReader, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	reader := &blockReader{
+		file:            file,
+		bufReader:       bufio.NewReader(file),
+		compressionType: getCompressionTypeForFile(filename),
+		objToc:          objToc,
+	}
+	err = reader.startBlock(objToc.Blocks[0])
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return reader, nil
+}
+
+func (r *blockReader) startBlock(block toc.SegmentBlock) error {
+	_, err := r.file.Seek(int64(block.FileOffset), io.SeekStart)
+	if err != nil {
+		return err
+	}
+	r.bufReader.Reset(r.file)
+	if resetter, ok := r.decompressor.(decompressionResetter); ok {
+		err = resetter.Reset(r.bufReader)
+	} else {
+		r.decompressor, err = getDecompressionReader(r.bufReader, r.compressionType)
+	}
+	if err != nil {
+		return err
+	}
+	r.position = block.DataOffset
+	return nil
+}
+
+func (r *blockReader) Read(p []byte) (int, error) {
+	numBytes, err := r.decompressor.Read(p)
+	r.position += uint64(numBytes)
+	return numBytes, err
+}
+
+// Only seeking forward from the current position is supported
+func (r *blockReader) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekCurrent || offset < 0 {
+		return 0, errors.New("Compressed data can only be seeked forward from the current position")
+	}
+	target := r.position + uint64(offset)
+	block := r.objToc.GetBlockForOffset(target)
+	if block.DataOffset > r.position {
+		err := r.startBlock(block)
+		if err != nil {
+			return 0, err
+		}
+	}
+	_, err := io.CopyN(io.Discard, r, int64(target-r.position))
+	if err != nil {
+		return 0, err
+	}
+	return int64(r.position), nil
+}
+
+/*
+ * A subsetRange is a range of a data file requested from a plugin, and the
+ * range of table data it holds.  The two are the same for an uncompressed file,
+ * while the range of a file compressed in blocks is the blocks holding the data.
+ */
+type subsetRange struct {
+	fileStart uint64
+	fileEnd   uint64
+	dataStart uint64
+	dataEnd   uint64
+}
+
+/*
+ * Returns the ranges of the data file holding the data of the given tables,
+ * in order.  Tables sharing a block share a range, as the plugin must return
+ * each block only once for the blocks to decompress as one stream.
+ */
+func getSubsetRanges(objToc *toc.SegmentTOC, oidList []int) []subsetRange {
+	ranges := make([]subsetRange, 0, len(oidList))
+	for _, oid := range oidList {
+		entry := objToc.DataEntries[uint(oid)]
+		next := subsetRange{entry.StartByte, entry.EndByte, entry.StartByte, entry.EndByte}
+		if objToc.HasBlocks() {
+			startBlock := objToc.GetBlockForOffset(entry.StartByte)
+			endBlock := objToc.GetBlockBoundaryAtOrAfter(entry.EndByte)
+			next = subsetRange{startBlock.FileOffset, endBlock.FileOffset, startBlock.DataOffset, endBlock.DataOffset}
+		}
+		if last := len(ranges) - 1; last >= 0 && next.fileStart >= ranges[last].fileStart && next.fileStart < ranges[last].fileEnd {
+			if next.fileEnd > ranges[last].fileEnd {
+				ranges[last].fileEnd = next.fileEnd
+				ranges[last].dataEnd = next.dataEnd
+			}
+			continue
+		}
+		ranges = append(ranges, next)
+	}
+	return ranges
+}
+
+/*
+ * A subsetReader reads the table data of the ranges of a data file returned
+ * by a plugin, seeking forward by discarding the data up to the position it
+ * seeks to, which may be in a later range.  Like a reader of the whole file,
+ * it starts at position 0, before the first range.
+ */
+type subsetReader struct {
+	reader     *bufio.Reader
+	ranges     []subsetRange
+	rangeIndex int
+	position   uint64
+}
+
+func newSubsetReader(reader *bufio.Reader, ranges []subsetRange) *subsetReader {
+	return &subsetReader{reader: reader, ranges: ranges, rangeIndex: -1}
+}
+
+// Discards the rest of the current range and moves to the next one
+func (r *subsetReader) nextRange() error {
+	if r.rangeIndex >= 0 {
+		_, err := r.reader.Discard(int(r.ranges[r.rangeIndex].dataEnd - r.position))
+		if err != nil {
+			return err
+		}
+	}
+	if r.rangeIndex+1 >= len(r.ranges) {
+		return io.EOF
+	}
+	r.rangeIndex++
+	r.position = r.ranges[r.rangeIndex].dataStart
+	return nil
+}
+
+func (r *subsetReader) Read(p []byte) (int, error) {
+	for r.rangeIndex < 0 || r.position == r.ranges[r.rangeIndex].dataEnd {
+		err := r.nextRange()
+		if err != nil {
+			return 0, err
+		}
+	}
+	if remaining := r.ranges[r.rangeIndex].dataEnd - r.position; uint64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	numBytes, err := r.reader.Read(p)
+	r.position += uint64(numBytes)
+	if err == io.EOF && r.position < r.ranges[r.rangeIndex].dataEnd {
+		err = io.ErrUnexpectedEOF
+	}
+	return numBytes, err
+}
+
+// Only seeking forward from the current position is supported
+func (r *subsetReader) Seek(offset int64, whence int) (int64, error) {
+	if whence != io.SeekCurrent || offset < 0 {
+		return 0, errors.New("Subset data can only be seeked forward from the current position")
+	}
+	target := r.position + uint64(offset)
+	for r.rangeIndex < 0 || target < r.ranges[r.rangeIndex].dataStart || target > r.ranges[r.rangeIndex].dataEnd {
+		err := r.nextRange()
+		if err == io.EOF {
+			return 0, fmt.Errorf("Offset %d is not in the data returned by the plugin", target)
+		} else if err != nil {
+			return 0, err
+		}
+	}
+	_, err := r.reader.Discard(int(target - r.position))
+	if err != nil {
+		return 0, err
+	}
+	r.position = target
+	return int64(r.position), nil
+}
@@ -0,0 +1,57 @@
+package helper
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Checksum specific functions
+ */
+
+func recordChecksumInFile(oid uint32, checksum string) error {
+	/*
+	 * Several COPY commands may append to the checksum file of a segment at
+	 * once, so each entry must be written with a single call.
+	 */
+	handle, err := os.OpenFile(*checksumFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		logError(fmt.Sprintf("Oid %d: Error encountered opening checksum file %s: %v", oid, *checksumFile, err))
+		return err
+	}
+	err = utils.WriteChecksumEntry(handle, oid, checksum)
+	if err != nil {
+		_ = handle.Close()
+		logError(fmt.Sprintf("Oid %d: Error encountered writing checksum file %s: %v", oid, *checksumFile, err))
+		return err
+	}
+	return handle.Close()
+}
+
+func checkChecksum(oid uint32, expectedChecksum string, actualChecksum string) error {
+	if actualChecksum != expectedChecksum {
+		err := errors.Errorf("Checksum mismatch for data of table with oid %d: expected %s, found %s", oid, expectedChecksum, actualChecksum)
+		logError(err.Error())
+		return err
+	}
+	log(fmt.Sprintf("Oid %d: Verified checksum %s", oid, actualChecksum))
+	return nil
+}
+
+/*
+ * In a single-data-file restore the table data has already been written to the
+ * pipe by the time its checksum is known, so a mismatch is reported through a
+ * marker file that the COPY command checks after reading the pipe.  Failing
+ * the COPY command rolls back the data that was loaded.
+ */
+func writeChecksumErrorFile(currentPipe string, checksumErr error) {
+	errorFile := fmt.Sprintf("%s_checksum_error", currentPipe)
+	err := ioutil.WriteFile(errorFile, []byte(checksumErr.Error()+"\n"), 0644)
+	if err != nil {
+		logError(fmt.Sprintf("Error encountered writing checksum error file %s: %v", errorFile, err))
+	}
+}
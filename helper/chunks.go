@@ -0,0 +1,276 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Repository specific functions
+ *
+ * When run with --chunk-dir, the backup filter splits the table data into
+ * chunks and stores each chunk in the chunk directory under the hash of its
+ * data, unless a chunk with that hash is already stored there, and writes the
+ * name of each chunk to stdout instead of the data.  The restore filter reads
+ * those names from stdin and writes the data of the chunks instead.  A chunk
+ * is only listed once it is stored, so a chunk list never names a chunk that
+ * is not in the chunk directory.
+ */
+
+/*
+ * Chunks are stored before the chunk list naming them is written, and a
+ * chunk that is already stored is only touched, so chunks newer than this are
+ * kept in case the backup that stored them has not yet listed them.
+ */
+const chunkCollectionGracePeriod = time.Hour
+
+// A chunk is renamed with this suffix while it is being removed
+const pendingRemovalSuffix = ".remove"
+
+func doBackupFilterToChunks(oid uint32, input io.Reader) (int64, error) {
+	extension := getChunkExtension()
+	chunker := utils.NewChunker(input)
+	numBytes, numChunks, numStored := int64(0), 0, 0
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return numBytes, err
+		}
+		numBytes += int64(len(chunk))
+		chunkName := utils.GetChunkName(chunk, extension)
+		stored, err := storeChunk(chunkName, chunk)
+		if err != nil {
+			return numBytes, errors.Wrapf(err, "Unable to store chunk %s", chunkName)
+		}
+		// Each entry is written straight to the chunk list, so it never names a chunk that was not stored
+		err = utils.WriteChunkListEntry(os.Stdout, chunkName)
+		if err != nil {
+			return numBytes, err
+		}
+		numChunks++
+		if stored {
+			numStored++
+		}
+	}
+	log(fmt.Sprintf("Oid %d: Split %d bytes into %d chunks, of which %d were not already stored", oid, numBytes, numChunks, numStored))
+	return numBytes, nil
+}
+
+// Chunks are compressed the same way as the data files of other backups
+func getChunkExtension() string {
+	utils.InitializePipeThroughParameters(*compressionLevel != 0, *compressionType, *compressionLevel)
+	return utils.GetPipeThroughProgram().Extension
+}
+
+/*
+ * Stores a chunk and returns whether it was not already stored.  The chunk is
+ * written to a temporary file that is renamed once complete, so a chunk that
+ * exists is always complete.
+ */
+func storeChunk(chunkName string, chunk []byte) (bool, error) {
+	chunkPath := utils.GetChunkPath(*chunkDir, chunkName)
+	now := time.Now()
+	err := os.Chtimes(chunkPath, now, now)
+	if err == nil {
+		return false, nil
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	err = os.MkdirAll(path.Dir(chunkPath), 0755)
+	if err != nil {
+		return false, err
+	}
+	handle, err := os.CreateTemp(path.Dir(chunkPath), fmt.Sprintf("%s.*.tmp", chunkName))
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		// The temporary file no longer exists once it has been renamed
+		_ = os.Remove(handle.Name())
+	}()
+	output := &countingWriteCloser{writer: handle}
+	pipe, err := newBackupPipeWriterCloser(output)
+	if err == nil {
+		_, err = pipe.Write(chunk)
+		closeErr := pipe.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}
+	if err == nil {
+		err = output.err
+	}
+	closeErr := handle.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, os.Rename(handle.Name(), chunkPath)
+}
+
+func doRestoreFilterFromChunks(oid uint32, input io.Reader, output io.Writer) (int64, error) {
+	chunkNames, err := utils.ReadChunkList(input)
+	if err != nil {
+		return 0, err
+	}
+	numBytes := int64(0)
+	for _, chunkName := range chunkNames {
+		chunkBytes, err := copyChunk(chunkName, output)
+		numBytes += chunkBytes
+		if err != nil {
+			return numBytes, errors.Wrapf(err, "Unable to read chunk %s", chunkName)
+		}
+	}
+	log(fmt.Sprintf("Oid %d: Read %d chunks", oid, len(chunkNames)))
+	return numBytes, nil
+}
+
+func copyChunk(chunkName string, output io.Writer) (int64, error) {
+	handle, err := os.Open(utils.GetChunkPath(*chunkDir, chunkName))
+	if err != nil {
+		return 0, err
+	}
+	defer handle.Close()
+	reader, err := getDecompressionReader(handle, getCompressionTypeForFile(chunkName))
+	if err != nil {
+		return 0, err
+	}
+	if closer, ok := reader.(interface{ Close() }); ok {
+		defer closer.Close()
+	}
+	return io.Copy(output, reader)
+}
+
+/*
+ * A backup that finds a chunk already stored only touches it, which it may do
+ * after the modification time of the chunk was checked but before the chunk
+ * is removed.  So the chunk is first renamed out of the way, after which a
+ * backup would store it again instead of touching it, and its modification
+ * time is checked again.  A chunk that was touched in the meantime is renamed
+ * back, even over a copy that a backup stored again, as both hold the same
+ * data.
+ *
+ * A chunk left renamed by a collection that failed is removed or renamed back
+ * the same way.  The segments on a host share a chunk directory with
+ * --single-backup-dir, so another segment may remove a chunk first.
+ */
+func removeUnusedChunk(filePath string, referencedChunks map[string]bool, cutoff time.Time) (bool, error) {
+	chunkPath := strings.TrimSuffix(filePath, pendingRemovalSuffix)
+	pendingPath := chunkPath + pendingRemovalSuffix
+	if filePath == chunkPath {
+		err := os.Rename(chunkPath, pendingPath)
+		if os.IsNotExist(err) {
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+	}
+
+	info, err := os.Stat(pendingPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	if referencedChunks[path.Base(chunkPath)] || info.ModTime().After(cutoff) {
+		err = os.Rename(pendingPath, chunkPath)
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	err = os.Remove(pendingPath)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+/*
+ * Removes the chunks that are not named in the chunk list of any backup left
+ * in the backups directory beside the chunk directory, along with temporary
+ * files left behind by backups that failed while storing a chunk.
+ */
+func doCollectChunks() error {
+	if *chunkDir == "" {
+		logError("No chunk directory specified")
+		return errors.New("No chunk directory specified")
+	}
+	backupsDir := path.Join(path.Dir(*chunkDir), "backups")
+	referencedChunks := make(map[string]bool)
+	err := filepath.WalkDir(backupsDir, func(filePath string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filePath == backupsDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() || !strings.HasSuffix(filePath, utils.ChunkListExtension) {
+			return nil
+		}
+		handle, err := os.Open(filePath)
+		if err != nil {
+			return err
+		}
+		defer handle.Close()
+		chunkNames, err := utils.ReadChunkList(handle)
+		if err != nil {
+			return errors.Wrapf(err, "Unable to read chunk list %s", filePath)
+		}
+		for _, chunkName := range chunkNames {
+			referencedChunks[chunkName] = true
+		}
+		return nil
+	})
+	if err != nil {
+		logError(fmt.Sprintf("Error encountered reading chunk lists in %s: %v", backupsDir, err))
+		return err
+	}
+
+	cutoff := time.Now().Add(-chunkCollectionGracePeriod)
+	numKept, numRemoved := 0, 0
+	err = filepath.WalkDir(*chunkDir, func(filePath string, entry os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && filePath == *chunkDir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		if referencedChunks[entry.Name()] {
+			numKept++
+			return nil
+		}
+		removed, err := removeUnusedChunk(filePath, referencedChunks, cutoff)
+		if err != nil {
+			return err
+		}
+		if removed {
+			numRemoved++
+		} else {
+			numKept++
+		}
+		return nil
+	})
+	if err != nil {
+		logError(fmt.Sprintf("Error encountered removing chunks in %s: %v", *chunkDir, err))
+		return err
+	}
+	log(fmt.Sprintf("Kept %d chunks and removed %d chunks no backup uses from %s", numKept, numRemoved, *chunkDir))
+	return nil
+}
@@ -0,0 +1,149 @@
+package helper
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Filter specific functions
+ *
+ * When run with --backup-filter or --restore-filter, the helper is not an
+ * agent but a filter in the pipeline of a multiple-data-file COPY command.  It
+ * copies table data from stdin to stdout while compressing or decompressing
+ * it, encrypting or decrypting it, computing its checksum, limiting its
+ * bandwidth and recording its progress, so it must never log anything to
+ * stdout.
+ */
+
+/*
+ * The pipe writers suppress the errors of the handle they write to when they
+ * are closed, so the first error writing to stdout is kept here instead.
+ */
+type countingWriteCloser struct {
+	writer   io.Writer
+	numBytes int64
+	err      error
+}
+
+func (w *countingWriteCloser) Write(p []byte) (int, error) {
+	numBytes, err := w.writer.Write(p)
+	w.numBytes += int64(numBytes)
+	if err != nil && w.err == nil {
+		w.err = err
+	}
+	return numBytes, err
+}
+
+func (w *countingWriteCloser) Close() error {
+	return nil
+}
+
+type countingReader struct {
+	reader   io.Reader
+	numBytes int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	numBytes, err := r.reader.Read(p)
+	r.numBytes += int64(numBytes)
+	return numBytes, err
+}
+
+func doBackupFilter() error {
+	oid := uint32(*tableOid)
+	if *checksumFile == "" {
+		logError("Oid %d: No checksum file specified", oid)
+		return errors.New("No checksum file specified")
+	}
+
+	checksum := utils.NewChecksum()
+	input := rateLimiter.Reader(dataProgress.Reader(io.TeeReader(bufio.NewReader(os.Stdin), checksum), int(oid)))
+	if *chunkDir != "" {
+		numBytes, err := doBackupFilterToChunks(oid, input)
+		if err != nil {
+			logError(fmt.Sprintf("Oid %d: Error encountered storing table data in chunks: %v", oid, err))
+			return err
+		}
+		actualChecksum := utils.FormatChecksum(checksum)
+		log(fmt.Sprintf("Oid %d: Read %d bytes with checksum %s", oid, numBytes, actualChecksum))
+		return recordChecksumInFile(oid, actualChecksum)
+	}
+
+	output := &countingWriteCloser{writer: os.Stdout}
+	pipe, err := newBackupPipeWriterCloser(output)
+	if err != nil {
+		logError(fmt.Sprintf("Oid %d: Error encountered initializing compression: %v", oid, err))
+		return err
+	}
+	numBytes, err := io.Copy(pipe, input)
+	closeErr := pipe.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = output.err
+	}
+	if err != nil {
+		logError(fmt.Sprintf("Oid %d: Error encountered copying table data: %v", oid, err))
+		return err
+	}
+	actualChecksum := utils.FormatChecksum(checksum)
+	log(fmt.Sprintf("Oid %d: Read %d bytes with checksum %s and wrote %d bytes", oid, numBytes, actualChecksum, output.numBytes))
+
+	return recordChecksumInFile(oid, actualChecksum)
+}
+
+func doRestoreFilter() error {
+	oid := uint32(*tableOid)
+	expectedChecksum := ""
+	if *checksumFile != "" {
+		checksums, err := utils.ReadChecksumFile(*checksumFile)
+		if err != nil {
+			logError(fmt.Sprintf("Oid %d: Error encountered reading checksum file %s: %v", oid, *checksumFile, err))
+			return err
+		}
+		expectedChecksum = checksums[oid]
+	}
+
+	input := &countingReader{reader: bufio.NewReader(os.Stdin)}
+	checksum := utils.NewChecksum()
+	output := bufio.NewWriter(os.Stdout)
+	var numBytes int64
+	var err error
+	if *chunkDir != "" {
+		// The input is the chunk list, and the chunks are read from the chunk directory
+		numBytes, err = doRestoreFilterFromChunks(oid, input, rateLimiter.Writer(io.MultiWriter(output, checksum)))
+	} else {
+		var reader io.Reader
+		reader, err = getDecryptionReader(input)
+		if err == nil {
+			reader, err = getDecompressionReader(reader, *compressionType)
+		}
+		if err != nil {
+			logError(fmt.Sprintf("Oid %d: Error encountered initializing decryption or decompression: %v", oid, err))
+			return err
+		}
+		numBytes, err = io.Copy(io.MultiWriter(output, checksum), rateLimiter.Reader(reader))
+	}
+	if err == nil {
+		err = output.Flush()
+	}
+	if err != nil {
+		logError(fmt.Sprintf("Oid %d: Error encountered copying table data: %v", oid, err))
+		return err
+	}
+	actualChecksum := utils.FormatChecksum(checksum)
+	log(fmt.Sprintf("Oid %d: Read %d bytes and wrote %d bytes with checksum %s", oid, input.numBytes, numBytes, actualChecksum))
+
+	if expectedChecksum == "" {
+		log(fmt.Sprintf("Oid %d: No checksum recorded for table data, skipping verification", oid))
+		return nil
+	}
+	return checkChecksum(oid, expectedChecksum, actualChecksum)
+}
@@ -1,7 +1,6 @@
 package helper
 
 import (
-	"bufio"
 	"bytes"
 	"flag"
 	"fmt"
@@ -25,38 +24,79 @@ import (
  */
 
 var (
-	CleanupGroup  *sync.WaitGroup
-	errBuf        bytes.Buffer
-	version       string
-	wasTerminated bool
-	writeHandle   *os.File
-	writer        *bufio.Writer
-	pipesMap      map[string]bool
+	CleanupGroup   *sync.WaitGroup
+	errBuf         lockedBuffer
+	version        string
+	wasTerminated  bool
+	pipesMap       map[string]bool
+	pipesMutex     sync.Mutex
+	rateLimiter    *utils.RateLimiter
+	encryptionKey  []byte
+	dataProgress   *progressRecorder
+	restoreStreams []*restoreStream
 )
 
+/*
+ * The data streams of a single data file backup are backed up and restored at
+ * the same time, so the plugin commands of all of them write to errBuf.
+ */
+type lockedBuffer struct {
+	mutex  sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Write(p)
+}
+
+func (b *lockedBuffer) Len() int {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.Len()
+}
+
+func (b *lockedBuffer) String() string {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.buffer.String()
+}
+
 /*
  * Command-line flags
  */
 var (
 	backupAgent      *bool
+	backupFilter     *bool
+	checksumFile     *string
+	chunkDir         *string
+	collectChunks    *bool
 	compressionLevel *int
 	compressionType  *string
 	content          *int
 	dataFile         *string
+	dataStreams      *int
+	encryptKeyFile   *string
 	oidFile          *string
 	onErrorContinue  *bool
 	pipeFile         *string
 	pluginConfigFile *string
 	printVersion     *bool
+	progressFile     *string
 	restoreAgent     *bool
+	restoreFilter    *bool
 	tocFile          *string
 	isFiltered       *bool
+	maxBandwidth     *int64
 	copyQueue        *int
 	singleDataFile   *bool
+	streamFile       *string
 	isResizeRestore  *bool
 	origSize         *int
 	destSize         *int
 	replicationFile  *string
+	tableOid         *int
 )
 
 func DoHelper() {
@@ -73,12 +113,23 @@ func DoHelper() {
 	InitializeGlobals()
 	go InitializeSignalHandler()
 
-	if *backupAgent {
+	if *encryptKeyFile != "" {
+		encryptionKey, err = utils.ReadEncryptionKeyFile(*encryptKeyFile)
+	}
+	if err != nil {
+		logError(fmt.Sprintf("Error encountered reading encryption key: %v", err))
+	} else if *backupAgent {
 		err = doBackupAgent()
 	} else if *restoreAgent {
 		err = doRestoreAgent()
+	} else if *backupFilter {
+		err = doBackupFilter()
+	} else if *restoreFilter {
+		err = doRestoreFilter()
+	} else if *collectChunks {
+		err = doCollectChunks()
 	}
-	if err != nil {
+	if err != nil && *pipeFile != "" {
 		// error logging handled in doBackupAgent and doRestoreAgent
 		handle, _ := utils.OpenFileForWrite(fmt.Sprintf("%s_error", *pipeFile))
 		_ = handle.Close()
@@ -91,24 +142,35 @@ func InitializeGlobals() {
 	gplog.InitializeLogging("gpbackup_helper", "")
 
 	backupAgent = flag.Bool("backup-agent", false, "Use gpbackup_helper as an agent for backup")
+	backupFilter = flag.Bool("backup-filter", false, "Use gpbackup_helper as a filter that compresses table data for backup")
+	checksumFile = flag.String("checksum-file", "", "Absolute path to the file containing table data checksums")
+	chunkDir = flag.String("chunk-dir", "", "Absolute path to the directory in which table data backed up with --repository is stored in chunks")
+	collectChunks = flag.Bool("collect-chunks", false, "Remove the chunks in the --chunk-dir that no backup uses")
 	content = flag.Int("content", -2, "Content ID of the corresponding segment")
 	compressionLevel = flag.Int("compression-level", 0, "The level of compression. O indicates no compression. Range of valid values depends on compression type")
-	compressionType = flag.String("compression-type", "gzip", "The type of compression. Valid values are 'gzip' and 'zstd'")
+	compressionType = flag.String("compression-type", "gzip", "The type of compression. Valid values are 'gzip', 'zstd', 'lz4' and 'snappy'")
 	dataFile = flag.String("data-file", "", "Absolute path to the data file")
+	dataStreams = flag.Int("data-streams", 1, "The number of data files to write at the same time for single data file backup")
+	encryptKeyFile = flag.String("encryption-key-file", "", "Absolute path to the file containing the key with which to encrypt or decrypt table data")
 	oidFile = flag.String("oid-file", "", "Absolute path to the file containing a list of oids to restore")
 	onErrorContinue = flag.Bool("on-error-continue", false, "Continue restore even when encountering an error")
 	pipeFile = flag.String("pipe-file", "", "Absolute path to the pipe file")
 	pluginConfigFile = flag.String("plugin-config", "", "The configuration file to use for a plugin")
 	printVersion = flag.Bool("version", false, "Print version number and exit")
+	progressFile = flag.String("progress-file", "", "Absolute path to the file to which to append the number of bytes of table data backed up")
 	restoreAgent = flag.Bool("restore-agent", false, "Use gpbackup_helper as an agent for restore")
+	restoreFilter = flag.Bool("restore-filter", false, "Use gpbackup_helper as a filter that decompresses table data for restore")
 	tocFile = flag.String("toc-file", "", "Absolute path to the table of contents file")
 	isFiltered = flag.Bool("with-filters", false, "Used with table/schema filters")
+	maxBandwidth = flag.Int64("max-bandwidth", 0, "The maximum number of bytes per second at which to copy table data. 0 indicates no limit")
 	copyQueue = flag.Int("copy-queue-size", 1, "Used to know how many COPIES are being queued up")
 	singleDataFile = flag.Bool("single-data-file", false, "Used with single data file restore.")
+	streamFile = flag.String("stream-file", "", "Absolute path to the file containing the data stream of each oid to back up")
 	isResizeRestore = flag.Bool("resize-cluster", false, "Used with resize cluster restore.")
 	origSize = flag.Int("orig-seg-count", 0, "Used with resize restore.  Gives the segment count of the backup.")
 	destSize = flag.Int("dest-seg-count", 0, "Used with resize restore.  Gives the segment count of the current cluster.")
 	replicationFile = flag.String("replication-file", "", "Used with resize restore.  Gives the list of replicated tables.")
+	tableOid = flag.Int("oid", 0, "Oid of the table whose checksum is recorded or verified")
 
 	if *onErrorContinue && !*restoreAgent {
 		fmt.Printf("--on-error-continue flag can only be used with --restore-agent flag")
@@ -126,6 +188,8 @@ func InitializeGlobals() {
 	operating.InitializeSystemFunctions()
 
 	pipesMap = make(map[string]bool, 0)
+	rateLimiter = utils.NewRateLimiter(*maxBandwidth)
+	dataProgress = initializeProgressRecorder(*progressFile)
 }
 
 func InitializeSignalHandler() {
@@ -176,7 +240,9 @@ func createPipe(pipe string) error {
 		return err
 	}
 
+	pipesMutex.Lock()
 	pipesMap[pipe] = true
+	pipesMutex.Unlock()
 	return nil
 }
 
@@ -186,7 +252,41 @@ func deletePipe(pipe string) error {
 		return err
 	}
 
+	pipesMutex.Lock()
 	delete(pipesMap, pipe)
+	pipesMutex.Unlock()
+	return nil
+}
+
+/*
+ * Gpbackup and gprestore start to copy a table only once its pipe exists, and
+ * expect the helper to create the pipe of the table copyQueue tables after
+ * each table it starts.  The data streams start their tables out of order, so
+ * they share a pipeQueue, which creates the pipes of all of the tables up to
+ * copyQueue tables after the last table any of them has started.
+ */
+type pipeQueue struct {
+	mutex    sync.Mutex
+	oidList  []int
+	nextPipe int
+}
+
+func newPipeQueue(oidList []int, queuedPipeCount int) *pipeQueue {
+	return &pipeQueue{oidList: oidList, nextPipe: queuedPipeCount}
+}
+
+func (q *pipeQueue) createPipesForTable(index int) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for ; q.nextPipe < len(q.oidList) && q.nextPipe <= index+*copyQueue; q.nextPipe++ {
+		nextPipeToCreate := fmt.Sprintf("%s_%d", *pipeFile, q.oidList[q.nextPipe])
+		log(fmt.Sprintf("Oid %d: Creating pipe %s\n", q.oidList[q.nextPipe], nextPipeToCreate))
+		err := createPipe(nextPipeToCreate)
+		if err != nil {
+			logError(fmt.Sprintf("Oid %d: Failed to create pipe %s\n", q.oidList[q.nextPipe], nextPipeToCreate))
+			return err
+		}
+	}
 	return nil
 }
 
@@ -214,26 +314,27 @@ func getOidListFromFile(oidFileName string) ([]int, error) {
 	return oidList, nil
 }
 
-func flushAndCloseRestoreWriter(pipeName string, oid int) error {
-	if writer != nil {
-		err := writer.Flush()
-		if err != nil {
-			logError("Oid %d: Failed to flush pipe %s", oid, pipeName)
-			return err
-		}
-		writer = nil
-		log("Oid %d: Successfully flushed pipe %s", oid, pipeName)
+/*
+ * Each line of the stream file holds an oid and the index of the data stream
+ * its table is backed up to.
+ */
+func getStreamsFromFile(streamFileName string) (map[int]int, error) {
+	streamStr, err := operating.System.ReadFile(streamFileName)
+	if err != nil {
+		logError(fmt.Sprintf("Error encountered reading data streams from file: %v", err))
+		return nil, err
 	}
-	if writeHandle != nil {
-		err := writeHandle.Close()
-		if err != nil {
-			logError("Oid %d: Failed to close pipe handle", oid)
-			return err
+	streams := make(map[int]int)
+	for _, line := range strings.Split(strings.TrimSpace(string(streamStr)), "\n") {
+		var oid, stream int
+		_, err = fmt.Sscanf(line, "%d %d", &oid, &stream)
+		if err != nil || stream < 0 || stream >= *dataStreams {
+			logError(fmt.Sprintf("Invalid data stream entry in file %s: %s", streamFileName, line))
+			return nil, fmt.Errorf("Invalid data stream entry: %s", line)
 		}
-		writeHandle = nil
-		log("Oid %d: Successfully closed pipe handle", oid)
+		streams[oid] = stream
 	}
-	return nil
+	return streams, nil
 }
 
 /*
@@ -242,7 +343,7 @@ func flushAndCloseRestoreWriter(pipeName string, oid int) error {
 
 func DoCleanup() {
 	defer CleanupGroup.Done()
-	if wasTerminated {
+	if wasTerminated && *pipeFile != "" {
 		/*
 		 * If the agent dies during the last table copy, it can still report
 		 * success, so we create an error file and check for its presence in
@@ -251,12 +352,23 @@ func DoCleanup() {
 		handle, _ := utils.OpenFileForWrite(fmt.Sprintf("%s_error", *pipeFile))
 		_ = handle.Close()
 	}
-	err := flushAndCloseRestoreWriter("Current writer pipe on cleanup", 0)
-	if err != nil {
-		log("Encountered error during cleanup: %v", err)
+	var err error
+	for _, stream := range restoreStreams {
+		err = stream.flushAndCloseWriter("Current writer pipe on cleanup", 0)
+		if err != nil {
+			log("Encountered error during cleanup: %v", err)
+		}
 	}
 
-	for pipeName, _ := range pipesMap {
+	dataProgress.Close()
+
+	pipesMutex.Lock()
+	pipeNames := make([]string, 0, len(pipesMap))
+	for pipeName := range pipesMap {
+		pipeNames = append(pipeNames, pipeName)
+	}
+	pipesMutex.Unlock()
+	for _, pipeName := range pipeNames {
 		log("Removing pipe %s", pipeName)
 		err = deletePipe(pipeName)
 		if err != nil {
@@ -264,11 +376,13 @@ func DoCleanup() {
 		}
 	}
 
-	skipFiles, _ := filepath.Glob(fmt.Sprintf("%s_skip_*", *pipeFile))
-	for _, skipFile := range skipFiles {
-		err = utils.RemoveFileIfExists(skipFile)
-		if err != nil {
-			log("Encountered error during cleanup skip files: %v", err)
+	if *pipeFile != "" {
+		skipFiles, _ := filepath.Glob(fmt.Sprintf("%s_skip_*", *pipeFile))
+		for _, skipFile := range skipFiles {
+			err = utils.RemoveFileIfExists(skipFile)
+			if err != nil {
+				log("Encountered error during cleanup skip files: %v", err)
+			}
 		}
 	}
 	log("Cleanup complete")
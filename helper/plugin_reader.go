@@ -0,0 +1,93 @@
+package helper
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Resumable plugin restore streams
+ *
+ * The restore_data command of a plugin streams a whole data file, so a stream
+ * that breaks partway through the file would fail the restore.  A plugin that
+ * can return the data of a file from a byte offset is instead started again
+ * with restore_data_from_offset at the last byte of the file it delivered, and
+ * the data read from it continues as if the stream had never broken.
+ */
+
+const pluginRestoreRetries = 3
+
+type pluginRestoreReader struct {
+	pluginConfig *utils.PluginConfig
+	filename     string
+	cmd          *exec.Cmd
+	stdout       io.ReadCloser
+	stderr       *bytes.Buffer
+	offset       uint64
+	retries      int
+}
+
+func startPluginRestoreReader(pluginConfig *utils.PluginConfig, filename string) (*pluginRestoreReader, error) {
+	reader := &pluginRestoreReader{pluginConfig: pluginConfig, filename: filename}
+	return reader, reader.start()
+}
+
+func (r *pluginRestoreReader) start() error {
+	cmdStr := fmt.Sprintf("%s restore_data %s %s", r.pluginConfig.ExecutablePath, r.pluginConfig.ConfigPath, r.filename)
+	if r.offset > 0 {
+		cmdStr = fmt.Sprintf("%s restore_data_from_offset %s %s %d", r.pluginConfig.ExecutablePath, r.pluginConfig.ConfigPath, r.filename, r.offset)
+	}
+	log(cmdStr)
+	r.cmd = exec.Command("bash", "-c", cmdStr)
+	r.stderr = &bytes.Buffer{}
+	r.cmd.Stderr = r.stderr
+	var err error
+	r.stdout, err = r.cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	return r.cmd.Start()
+}
+
+/*
+ * The plugin command has delivered all of the file once it exits successfully,
+ * and any other end of its output is a broken stream.
+ */
+func (r *pluginRestoreReader) Read(p []byte) (int, error) {
+	for {
+		numBytes, err := r.stdout.Read(p)
+		r.offset += uint64(numBytes)
+		if err == nil {
+			return numBytes, nil
+		}
+		waitErr := r.cmd.Wait()
+		if waitErr == nil && err == io.EOF {
+			return numBytes, io.EOF
+		}
+		if waitErr == nil {
+			waitErr = err
+		}
+		stderr := strings.TrimSpace(r.stderr.String())
+		if r.retries >= pluginRestoreRetries {
+			// The existing error handling reports the output of the plugin
+			_, _ = errBuf.Write(r.stderr.Bytes())
+			return numBytes, errors.Wrapf(waitErr, "Plugin stream of %s broke at byte %d after %d retries", r.filename, r.offset, r.retries)
+		}
+		r.retries++
+		log(fmt.Sprintf("Plugin stream of %s broke at byte %d: %v: %s. Resuming from that byte (retry %d of %d)",
+			r.filename, r.offset, waitErr, stderr, r.retries, pluginRestoreRetries))
+		err = r.start()
+		if err != nil {
+			return numBytes, err
+		}
+		if numBytes > 0 {
+			return numBytes, nil
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package helper
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+/*
+ * Progress specific functions
+ *
+ * When given a progress file, the helper appends the number of bytes of table
+ * data it has read for a table to that file about once a second while it
+ * copies the table, and once more, marked done, when it has read all of it,
+ * so that gpbackup can report the progress of the backup in bytes and notice
+ * a table that has stalled.  The last entry for a table is its latest count,
+ * and a table backed up again starts over from a count of 0.
+ * Several COPY commands may append to the progress file of a segment at once,
+ * so each entry is written with a single call.
+ */
+
+const progressInterval = time.Second
+
+type progressRecorder struct {
+	mutex  sync.Mutex
+	handle *os.File
+}
+
+/*
+ * Progress is only informational, so a helper that cannot record it logs why
+ * and copies the table data without recording it.
+ */
+func initializeProgressRecorder(progressFileName string) *progressRecorder {
+	if progressFileName == "" {
+		return nil
+	}
+	handle, err := os.OpenFile(progressFileName, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		log(fmt.Sprintf("Unable to open progress file %s, so progress will not be recorded: %v", progressFileName, err))
+		return nil
+	}
+	return &progressRecorder{handle: handle}
+}
+
+// A nil progressRecorder records nothing, so callers need not check for one
+func (recorder *progressRecorder) record(oid int, numBytes int64, done bool) {
+	if recorder == nil {
+		return
+	}
+	doneStr := ""
+	if done {
+		doneStr = " done"
+	}
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+	_, err := fmt.Fprintf(recorder.handle, "%d %d%s\n", oid, numBytes, doneStr)
+	if err != nil {
+		log(fmt.Sprintf("Oid %d: Unable to record progress: %v", oid, err))
+	}
+}
+
+func (recorder *progressRecorder) Reader(reader io.Reader, oid int) io.Reader {
+	if recorder == nil {
+		return reader
+	}
+	recorder.record(oid, 0, false)
+	return &progressReader{reader: reader, recorder: recorder, oid: oid, lastRecorded: time.Now()}
+}
+
+func (recorder *progressRecorder) Close() {
+	if recorder == nil {
+		return
+	}
+	_ = recorder.handle.Close()
+}
+
+type progressReader struct {
+	reader       io.Reader
+	recorder     *progressRecorder
+	oid          int
+	numBytes     int64
+	lastRecorded time.Time
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	numBytes, err := r.reader.Read(p)
+	r.numBytes += int64(numBytes)
+	if err == io.EOF || time.Since(r.lastRecorded) >= progressInterval {
+		r.recorder.record(r.oid, r.numBytes, err == io.EOF)
+		r.lastRecorded = time.Now()
+	}
+	return numBytes, err
+}
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"compress/gzip"
 	"fmt"
+	"hash"
 	"io"
 	"io/ioutil"
 	"math"
@@ -11,12 +12,16 @@ import (
 	"os/exec"
 	"path"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
+	fp "github.com/greenplum-db/gpbackup/filepath"
 	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/klauspost/compress/snappy"
 	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
 	"github.com/pkg/errors"
 	"golang.org/x/sys/unix"
 )
@@ -38,10 +43,10 @@ var (
 
 /* RestoreReader structure to wrap the underlying reader.
  * readerType identifies how the reader can be used
- * SEEKABLE uses seekReader. Used when restoring from uncompressed data with filters from local filesystem
- * NONSEEKABLE and SUBSET types uses bufReader.
- * SUBSET type applies when restoring using plugin(if compatible) from uncompressed data with filters
- * NONSEEKABLE type applies for every other restore scenario
+ * SEEKABLE and SUBSET types use seekReader.
+ * SEEKABLE type applies when restoring from uncompressed data or data compressed in blocks with filters from local filesystem
+ * SUBSET type applies when restoring using plugin(if compatible) from uncompressed data or data compressed in blocks with filters
+ * NONSEEKABLE type uses bufReader, and applies for every other restore scenario
  */
 type RestoreReader struct {
 	bufReader  *bufio.Reader
@@ -66,49 +71,97 @@ func (r *RestoreReader) positionReader(pos uint64, oid int) error {
 		}
 		log(fmt.Sprintf("Oid %d: Data Reader discarded %d bytes", oid, numDiscarded))
 	case SUBSET:
-		// Do nothing as the stream is pre filtered
+		// The stream is pre filtered, so this only skips data of the ranges it shares with other tables
+		_, err := r.seekReader.Seek(int64(pos), io.SeekCurrent)
+		if err != nil {
+			// Always hard quit if data reader has issues
+			return err
+		}
 	}
 	return nil
 }
 
-func (r *RestoreReader) copyData(num int64) (int64, error) {
+func (r *RestoreReader) copyData(num int64, writer io.Writer, checksum hash.Hash32) (int64, error) {
 	var bytesRead int64
 	var err error
+	dest := writer
+	if checksum != nil {
+		dest = io.MultiWriter(writer, checksum)
+	}
 	switch r.readerType {
-	case SEEKABLE:
-		bytesRead, err = io.CopyN(writer, r.seekReader, num)
-	case NONSEEKABLE, SUBSET:
-		bytesRead, err = io.CopyN(writer, r.bufReader, num)
+	case SEEKABLE, SUBSET:
+		bytesRead, err = io.CopyN(dest, r.seekReader, num)
+	case NONSEEKABLE:
+		bytesRead, err = io.CopyN(dest, r.bufReader, num)
 	}
 	return bytesRead, err
 }
 
-func (r *RestoreReader) copyAllData() (int64, error) {
+func (r *RestoreReader) copyAllData(dest io.Writer) (int64, error) {
 	var bytesRead int64
 	var err error
 	switch r.readerType {
-	case SEEKABLE:
-		bytesRead, err = io.Copy(writer, r.seekReader)
-	case NONSEEKABLE, SUBSET:
-		bytesRead, err = io.Copy(writer, r.bufReader)
+	case SEEKABLE, SUBSET:
+		bytesRead, err = io.Copy(dest, r.seekReader)
+	case NONSEEKABLE:
+		bytesRead, err = io.Copy(dest, r.bufReader)
 	}
 	return bytesRead, err
 }
 
-func doRestoreAgent() error {
-	// We need to track various values separately per content for resize restore
-	var segmentTOC map[int]*toc.SegmentTOC
-	var tocEntries map[int]map[uint]toc.SegmentDataEntry
-	var start map[int]uint64
-	var end map[int]uint64
-	var lastByte map[int]uint64
+/*
+ * A restoreStream restores the tables of one data stream of a single data file
+ * backup, reading the data file of the stream of each content it restores and
+ * writing the data of each table to its pipe in turn.  The data streams are
+ * restored at the same time; any other restore has a single stream.
+ */
+type restoreStream struct {
+	stream           int
+	oidIndexes       []int
+	readers          map[int]*RestoreReader
+	tocEntries       map[int]map[uint]toc.SegmentDataEntry
+	lastByte         map[int]uint64
+	replicatedTables map[int]bool
+	writer           *bufio.Writer
+	writeHandle      *os.File
+	rateLimiter      *utils.RateLimiter
+	lastError        error
+}
 
-	var bytesRead int64
-	var lastError error
-	var replicatedTables map[int]bool
+func newRestoreStream(stream int) *restoreStream {
+	return &restoreStream{
+		stream:      stream,
+		oidIndexes:  make([]int, 0),
+		readers:     make(map[int]*RestoreReader),
+		tocEntries:  make(map[int]map[uint]toc.SegmentDataEntry),
+		lastByte:    make(map[int]uint64),
+		rateLimiter: utils.NewRateLimiter(*maxBandwidth),
+	}
+}
 
-	readers := make(map[int]*RestoreReader)
+func (s *restoreStream) flushAndCloseWriter(pipeName string, oid int) error {
+	if s.writer != nil {
+		err := s.writer.Flush()
+		if err != nil {
+			logError("Oid %d: Failed to flush pipe %s", oid, pipeName)
+			return err
+		}
+		s.writer = nil
+		log("Oid %d: Successfully flushed pipe %s", oid, pipeName)
+	}
+	if s.writeHandle != nil {
+		err := s.writeHandle.Close()
+		if err != nil {
+			logError("Oid %d: Failed to close pipe handle", oid)
+			return err
+		}
+		s.writeHandle = nil
+		log("Oid %d: Successfully closed pipe handle", oid)
+	}
+	return nil
+}
 
+func doRestoreAgent() error {
 	oidList, err := getOidListFromFile(*oidFile)
 	if err != nil {
 		return err
@@ -126,13 +179,15 @@ func doRestoreAgent() error {
 		}
 	}
 
+	/*
+	 * Each table is restored by the stream it was backed up to, which is the
+	 * same on every segment, so it is looked up in the first TOC read.
+	 */
+	segmentTOC := make(map[int]*toc.SegmentTOC)
+	streamsByIndex := make(map[int]*restoreStream)
+	restoreStreams = make([]*restoreStream, 0)
 	if *singleDataFile {
 		contentToRestore := *content
-		segmentTOC = make(map[int]*toc.SegmentTOC)
-		tocEntries = make(map[int]map[uint]toc.SegmentDataEntry)
-		start = make(map[int]uint64)
-		end = make(map[int]uint64)
-		lastByte = make(map[int]uint64)
 		for b := 0; b < batches; b++ {
 			// When performing a resize restore, if the content of the file we're being asked to read from
 			// is higher than any backup content, then no such file exists and we shouldn't try to open it.
@@ -141,18 +196,49 @@ func doRestoreAgent() error {
 			}
 			tocFileForContent := replaceContentInFilename(*tocFile, contentToRestore)
 			segmentTOC[contentToRestore] = toc.NewSegmentTOC(tocFileForContent)
-			tocEntries[contentToRestore] = segmentTOC[contentToRestore].DataEntries
+			if b == 0 {
+				for _, oid := range oidList {
+					stream := segmentTOC[contentToRestore].DataEntries[uint(oid)].Stream
+					if streamsByIndex[stream] == nil {
+						streamsByIndex[stream] = newRestoreStream(stream)
+						restoreStreams = append(restoreStreams, streamsByIndex[stream])
+					}
+				}
+			}
+			contentToRestore += *destSize
+		}
+	}
+	if len(restoreStreams) == 0 {
+		streamsByIndex[0] = newRestoreStream(0)
+		restoreStreams = append(restoreStreams, streamsByIndex[0])
+	}
+	sort.Slice(restoreStreams, func(i, j int) bool { return restoreStreams[i].stream < restoreStreams[j].stream })
+	firstContent := *content
+	for i, oid := range oidList {
+		stream := restoreStreams[0]
+		if firstTOC, ok := segmentTOC[firstContent]; ok {
+			stream = streamsByIndex[firstTOC.DataEntries[uint(oid)].Stream]
+		}
+		stream.oidIndexes = append(stream.oidIndexes, i)
+	}
+
+	for contentToRestore, contentTOC := range segmentTOC {
+		for _, stream := range restoreStreams {
+			streamTOC := contentTOC.GetStreamTOC(stream.stream)
+			stream.tocEntries[contentToRestore] = streamTOC.DataEntries
 
-			filename := replaceContentInFilename(*dataFile, contentToRestore)
-			readers[contentToRestore], err = getRestoreDataReader(filename, segmentTOC[contentToRestore], oidList)
+			oidsToRestore := make([]int, 0, len(stream.oidIndexes))
+			for _, index := range stream.oidIndexes {
+				oidsToRestore = append(oidsToRestore, oidList[index])
+			}
+			filename := fp.GetDataStreamFilePath(replaceContentInFilename(*dataFile, contentToRestore), stream.stream)
+			stream.readers[contentToRestore], err = getRestoreDataReader(filename, streamTOC, oidsToRestore)
 
 			if err != nil {
 				logError(fmt.Sprintf("Error encountered getting restore data reader for single data file: %v", err))
 				return err
 			}
-			log(fmt.Sprintf("Using reader type: %s", readers[contentToRestore].readerType))
-
-			contentToRestore += *destSize
+			log(fmt.Sprintf("Using reader type for data stream %d: %s", stream.stream, stream.readers[contentToRestore].readerType))
 		}
 	}
 
@@ -167,33 +253,73 @@ func doRestoreAgent() error {
 			return err
 		}
 
-		replicatedTables = make(map[int]bool, len(replicatedOidList))
+		replicatedOids := make(map[int]bool, len(replicatedOidList))
 		for _, oid := range replicatedOidList {
-			replicatedTables[oid] = true
+			replicatedOids[oid] = true
+		}
+		// Each stream has a map of its own tables, so that the streams do not share one
+		for _, stream := range restoreStreams {
+			stream.replicatedTables = make(map[int]bool)
+			for _, index := range stream.oidIndexes {
+				if replicatedOids[oidList[index]] {
+					stream.replicatedTables[oidList[index]] = true
+				}
+			}
 		}
 	}
 
 	preloadCreatedPipes(oidList, *copyQueue)
+	pipes := newPipeQueue(oidList, *copyQueue)
+
+	errChan := make(chan error, len(restoreStreams))
+	for _, stream := range restoreStreams {
+		go func(stream *restoreStream) {
+			errChan <- stream.restoreTables(oidList, pipes, batches)
+		}(stream)
+	}
+	for range restoreStreams {
+		err = <-errChan
+		if err != nil {
+			// error logging handled in restoreTables
+			return err
+		}
+	}
+	for _, stream := range restoreStreams {
+		if stream.lastError != nil {
+			return stream.lastError
+		}
+	}
+	return nil
+}
+
+/*
+ * Restores the tables of the stream in the order of the oid list.  Errors
+ * restoring a table are recorded in lastError if --on-error-continue is set,
+ * while any other error is returned.
+ */
+func (s *restoreStream) restoreTables(oidList []int, pipes *pipeQueue, batches int) error {
+	var bytesRead int64
+	var checksum hash.Hash32
+	var expectedChecksum string
+	var start uint64
+	var end uint64
+	var err error
 
 	var currentPipe string
-	for i, oid := range oidList {
+	for _, index := range s.oidIndexes {
+		oid := oidList[index]
 		if wasTerminated {
 			logError("Terminated due to user request")
 			return errors.New("Terminated due to user request")
 		}
 
-		currentPipe = fmt.Sprintf("%s_%d", *pipeFile, oidList[i])
-		if i < len(oidList)-*copyQueue {
-			nextPipeToCreate := fmt.Sprintf("%s_%d", *pipeFile, oidList[i+*copyQueue])
-			log(fmt.Sprintf("Oid %d: Creating pipe %s\n", oidList[i+*copyQueue], nextPipeToCreate))
-			err := createPipe(nextPipeToCreate)
-			if err != nil {
-				logError(fmt.Sprintf("Oid %d: Failed to create pipe %s\n", oidList[i+*copyQueue], nextPipeToCreate))
-				// In the case this error is hit it means we have lost the
-				// ability to create pipes normally, so hard quit even if
-				// --on-error-continue is given
-				return err
-			}
+		currentPipe = fmt.Sprintf("%s_%d", *pipeFile, oid)
+		err = pipes.createPipesForTable(index)
+		if err != nil {
+			// In the case this error is hit it means we have lost the
+			// ability to create pipes normally, so hard quit even if
+			// --on-error-continue is given
+			return err
 		}
 
 		// The pipe creation queue goes before the below loop because that still happens just once per table;
@@ -202,20 +328,26 @@ func doRestoreAgent() error {
 		contentToRestore := *content
 
 		for b := 0; b < batches; b++ {
-			if replicatedTables != nil {
-				tableIsNotYetRestored, tableIsPresent := replicatedTables[oid]
+			if s.replicatedTables != nil {
+				tableIsNotYetRestored, tableIsPresent := s.replicatedTables[oid]
 				if tableIsPresent && tableIsNotYetRestored {
 					// this is the first batch encountering this replicated table.
 					// restore it and mark it for no further restoration
-					replicatedTables[oid] = false
+					s.replicatedTables[oid] = false
 				} else if !tableIsNotYetRestored {
 					// table was already restored to this segment in a previous batch
 					continue
 				}
 			}
+			checksum = nil
 			if *singleDataFile {
-				start[contentToRestore] = tocEntries[contentToRestore][uint(oid)].StartByte
-				end[contentToRestore] = tocEntries[contentToRestore][uint(oid)].EndByte
+				start = s.tocEntries[contentToRestore][uint(oid)].StartByte
+				end = s.tocEntries[contentToRestore][uint(oid)].EndByte
+				// Backups taken before checksums were recorded have nothing to verify
+				expectedChecksum = s.tocEntries[contentToRestore][uint(oid)].Checksum
+				if expectedChecksum != "" {
+					checksum = utils.NewChecksum()
+				}
 			} else if *isResizeRestore {
 				if contentToRestore < *origSize {
 					// We can only pass one filename to the helper, so we still pass in the single-data-file-style
@@ -225,7 +357,7 @@ func doRestoreAgent() error {
 					// We pre-create readers above for the sake of not re-opening SDF readers.  For MDF we can't
 					// re-use them but still having them in a map simplifies overall code flow.  We repeatedly assign
 					// to a map entry here intentionally.
-					readers[contentToRestore], err = getRestoreDataReader(filename, nil, nil)
+					s.readers[contentToRestore], err = getRestoreDataReader(filename, nil, nil)
 					if err != nil {
 						logError(fmt.Sprintf("Error encountered getting restore data reader: %v", err))
 						return err
@@ -237,7 +369,7 @@ func doRestoreAgent() error {
 			retries := 0
 			var elapsedWait time.Duration = 0
 			for {
-				writer, writeHandle, err = getRestorePipeWriter(currentPipe)
+				s.writer, s.writeHandle, err = getRestorePipeWriter(currentPipe)
 				if err != nil {
 					if errors.Is(err, unix.ENXIO) {
 						// COPY (the pipe reader) has not tried to access the pipe yet so our restore_helper
@@ -279,7 +411,7 @@ func doRestoreAgent() error {
 					// the writer for the pipe. To avoid having to write complex buffer
 					// logic for when os.write() returns EAGAIN due to full buffer, set
 					// the file descriptor to block on IO.
-					unix.SetNonblock(int(writeHandle.Fd()), false)
+					unix.SetNonblock(int(s.writeHandle.Fd()), false)
 					log(fmt.Sprintf("Oid %d: Reader connected to pipe %s", oid, path.Base(currentPipe)))
 					break
 				}
@@ -289,8 +421,8 @@ func doRestoreAgent() error {
 			// Further, in SDF case, map entries for contents that were not part of original backup will be nil,
 			// and calling methods on them errors silently.
 			if *singleDataFile && !(*isResizeRestore && contentToRestore >= *origSize) {
-				log(fmt.Sprintf("Oid %d: Data Reader - Start Byte: %d; End Byte: %d; Last Byte: %d", oid, start[contentToRestore], end[contentToRestore], lastByte[contentToRestore]))
-				err = readers[contentToRestore].positionReader(start[contentToRestore]-lastByte[contentToRestore], oid)
+				log(fmt.Sprintf("Oid %d: Data Reader - Start Byte: %d; End Byte: %d; Last Byte: %d", oid, start, end, s.lastByte[contentToRestore]))
+				err = s.readers[contentToRestore].positionReader(start-s.lastByte[contentToRestore], oid)
 				if err != nil {
 					logError(fmt.Sprintf("Oid %d: Error reading from pipe: %v", oid, err))
 					return err
@@ -301,24 +433,24 @@ func doRestoreAgent() error {
 			if *isResizeRestore {
 				if contentToRestore < *origSize {
 					if *singleDataFile {
-						bytesRead, err = readers[contentToRestore].copyData(int64(end[contentToRestore] - start[contentToRestore]))
+						bytesRead, err = s.readers[contentToRestore].copyData(int64(end-start), s.rateLimiter.Writer(s.writer), checksum)
 					} else {
-						bytesRead, err = readers[contentToRestore].copyAllData()
+						bytesRead, err = s.readers[contentToRestore].copyAllData(s.rateLimiter.Writer(s.writer))
 					}
 				} else {
 					// Write "empty" data to the pipe for COPY ON SEGMENT to read.
 					bytesRead = 0
-					writer.Write([]byte{})
+					s.writer.Write([]byte{})
 				}
 			} else {
-				bytesRead, err = readers[contentToRestore].copyData(int64(end[contentToRestore] - start[contentToRestore]))
+				bytesRead, err = s.readers[contentToRestore].copyData(int64(end-start), s.rateLimiter.Writer(s.writer), checksum)
 			}
 			if err != nil {
 				// In case COPY FROM or copyN fails in the middle of a load. We
 				// need to update the lastByte with the amount of bytes that was
 				// copied before it errored out
 				if *singleDataFile {
-					lastByte[contentToRestore] += uint64(bytesRead)
+					s.lastByte[contentToRestore] += uint64(bytesRead)
 				}
 				if errBuf.Len() > 0 {
 					err = errors.Wrap(err, strings.Trim(errBuf.String(), "\x00"))
@@ -329,13 +461,24 @@ func doRestoreAgent() error {
 			}
 
 			if *singleDataFile {
-				lastByte[contentToRestore] = end[contentToRestore]
+				s.lastByte[contentToRestore] = end
 			}
 			log(fmt.Sprintf("Oid %d: Copied %d bytes into the pipe", oid, bytesRead))
 
+			if checksum != nil {
+				// The marker file has to exist before the pipe is closed, as that is when COPY checks for it
+				err = checkChecksum(uint32(oid), expectedChecksum, utils.FormatChecksum(checksum))
+				if err != nil {
+					writeChecksumErrorFile(currentPipe, err)
+				}
+			}
+
 			log(fmt.Sprintf("Closing pipe for oid %d: %s", oid, currentPipe))
-			err = flushAndCloseRestoreWriter(currentPipe, oid)
+			closeErr := s.flushAndCloseWriter(currentPipe, oid)
 			if err != nil {
+				goto LoopEnd
+			} else if closeErr != nil {
+				err = closeErr
 				log(fmt.Sprintf("Oid %d: Failed to flush and close pipe", oid))
 				goto LoopEnd
 			}
@@ -367,7 +510,7 @@ func doRestoreAgent() error {
 		if err != nil {
 			if *onErrorContinue {
 				logError(fmt.Sprintf("Oid %d: Error encountered: %v", oid, err))
-				lastError = err
+				s.lastError = err
 				err = nil
 				continue
 			} else {
@@ -377,14 +520,14 @@ func doRestoreAgent() error {
 		}
 	}
 
-	return lastError
+	return nil
 }
 
 func constructSingleTableFilename(name string, contentToRestore int, oid int) string {
 	name = strings.ReplaceAll(name, fmt.Sprintf("gpbackup_%d", *content), fmt.Sprintf("gpbackup_%d", contentToRestore))
 	nameParts := strings.Split(name, ".")
 	filename := fmt.Sprintf("%s_%d", nameParts[0], oid)
-	if len(nameParts) > 1 { // We only expect filenames ending in ".gz" or ".zst", but they can contain dots so handle arbitrary numbers of dots
+	if len(nameParts) > 1 { // We only expect filenames ending in a compression extension, but they can contain dots so handle arbitrary numbers of dots
 		prefix := strings.Join(nameParts[0:len(nameParts)-1], ".")
 		suffix := nameParts[len(nameParts)-1]
 		filename = fmt.Sprintf("%s_%d.%s", prefix, oid, suffix)
@@ -402,13 +545,13 @@ func replaceContentInFilename(filename string, content int) string {
 func getRestoreDataReader(fileToRead string, objToc *toc.SegmentTOC, oidList []int) (*RestoreReader, error) {
 	var readHandle io.Reader
 	var seekHandle io.ReadSeeker
-	var isSubset bool
+	var subsetRanges []subsetRange
 	var err error = nil
 	restoreReader := new(RestoreReader)
 
 	if *pluginConfigFile != "" {
-		readHandle, isSubset, err = startRestorePluginCommand(fileToRead, objToc, oidList)
-		if isSubset {
+		readHandle, subsetRanges, err = startRestorePluginCommand(fileToRead, objToc, oidList)
+		if subsetRanges != nil {
 			// Reader that operates on subset data
 			restoreReader.readerType = SUBSET
 		} else {
@@ -416,9 +559,9 @@ func getRestoreDataReader(fileToRead string, objToc *toc.SegmentTOC, oidList []i
 			restoreReader.readerType = NONSEEKABLE
 		}
 	} else {
-		if *isFiltered && !strings.HasSuffix(fileToRead, ".gz") && !strings.HasSuffix(fileToRead, ".zst") {
-			// Seekable reader if backup is not compressed and filters are set
-			seekHandle, err = os.Open(fileToRead)
+		if *isFiltered && canSeekInFile(fileToRead, objToc) {
+			// Seekable reader if backup is not encrypted, is not compressed or is compressed in blocks, and filters are set
+			seekHandle, err = openSeekableFile(fileToRead, objToc)
 			restoreReader.readerType = SEEKABLE
 		} else {
 			// Regular reader which doesn't support seek
@@ -434,22 +577,21 @@ func getRestoreDataReader(fileToRead string, objToc *toc.SegmentTOC, oidList []i
 	// Set the underlying stream reader in restoreReader
 	if restoreReader.readerType == SEEKABLE {
 		restoreReader.seekReader = seekHandle
-	} else if strings.HasSuffix(fileToRead, ".gz") {
-		gzipReader, err := gzip.NewReader(readHandle)
+	} else {
+		readHandle, err = getDecryptionReader(readHandle)
 		if err != nil {
 			// error logging handled by calling functions
 			return nil, err
 		}
-		restoreReader.bufReader = bufio.NewReader(gzipReader)
-	} else if strings.HasSuffix(fileToRead, ".zst") {
-		zstdReader, err := zstd.NewReader(readHandle)
+		decompressionReader, err := getDecompressionReader(readHandle, getCompressionTypeForFile(fileToRead))
 		if err != nil {
 			// error logging handled by calling functions
 			return nil, err
 		}
-		restoreReader.bufReader = bufio.NewReader(zstdReader)
-	} else {
-		restoreReader.bufReader = bufio.NewReader(readHandle)
+		restoreReader.bufReader = bufio.NewReader(decompressionReader)
+		if restoreReader.readerType == SUBSET {
+			restoreReader.seekReader = newSubsetReader(restoreReader.bufReader, subsetRanges)
+		}
 	}
 
 	// Check that no error has occurred in plugin command
@@ -461,6 +603,47 @@ func getRestoreDataReader(fileToRead string, objToc *toc.SegmentTOC, oidList []i
 	return restoreReader, err
 }
 
+// The compression type of a data file is determined by its extension
+func getCompressionTypeForFile(filename string) string {
+	switch {
+	case strings.HasSuffix(filename, ".gz"):
+		return "gzip"
+	case strings.HasSuffix(filename, ".zst"):
+		return "zstd"
+	case strings.HasSuffix(filename, ".lz4"):
+		return "lz4"
+	case strings.HasSuffix(filename, ".sz"):
+		return "snappy"
+	default:
+		return "cat"
+	}
+}
+
+func getDecryptionReader(readHandle io.Reader) (io.Reader, error) {
+	if encryptionKey == nil {
+		return readHandle, nil
+	}
+	return utils.NewDecryptingReader(readHandle, encryptionKey)
+}
+
+func getDecompressionReader(readHandle io.Reader, compressionType string) (io.Reader, error) {
+	switch compressionType {
+	case "cat":
+		return readHandle, nil
+	case "gzip":
+		return gzip.NewReader(readHandle)
+	case "zstd":
+		return zstd.NewReader(readHandle)
+	case "lz4":
+		return lz4.NewReader(readHandle), nil
+	case "snappy":
+		return snappy.NewReader(readHandle), nil
+	default:
+		// error logging handled by calling functions
+		return nil, fmt.Errorf("unknown compression type '%s'", compressionType)
+	}
+}
+
 func getRestorePipeWriter(currentPipe string) (*bufio.Writer, *os.File, error) {
 	fileHandle, err := os.OpenFile(currentPipe, os.O_WRONLY|unix.O_NONBLOCK, os.ModeNamedPipe)
 	if err != nil {
@@ -479,28 +662,33 @@ func getRestorePipeWriter(currentPipe string) (*bufio.Writer, *os.File, error) {
 	return pipeWriter, fileHandle, nil
 }
 
-func startRestorePluginCommand(fileToRead string, objToc *toc.SegmentTOC, oidList []int) (io.Reader, bool, error) {
-	isSubset := false
+// Returns the ranges of the data file the plugin returns, or nil if it returns the whole file
+func startRestorePluginCommand(fileToRead string, objToc *toc.SegmentTOC, oidList []int) (io.Reader, []subsetRange, error) {
+	var subsetRanges []subsetRange
 	pluginConfig, err := utils.ReadPluginConfig(*pluginConfigFile)
 	if err != nil {
 		logError(fmt.Sprintf("Error encountered when reading plugin config: %v", err))
-		return nil, false, err
+		return nil, nil, err
 	}
 	cmdStr := ""
-	if objToc != nil && pluginConfig.CanRestoreSubset() && *isFiltered && !strings.HasSuffix(fileToRead, ".gz") && !strings.HasSuffix(fileToRead, ".zst") {
+	if objToc != nil && pluginConfig.CanRestoreSubset() && *isFiltered && canSeekInFile(fileToRead, objToc) {
 		offsetsFile, _ := ioutil.TempFile("/tmp", "gprestore_offsets_")
 		defer func() {
 			offsetsFile.Close()
 		}()
+		subsetRanges = getSubsetRanges(objToc, oidList)
 		w := bufio.NewWriter(offsetsFile)
-		w.WriteString(fmt.Sprintf("%v", len(oidList)))
+		w.WriteString(fmt.Sprintf("%v", len(subsetRanges)))
 
-		for _, oid := range oidList {
-			w.WriteString(fmt.Sprintf(" %v %v", objToc.DataEntries[uint(oid)].StartByte, objToc.DataEntries[uint(oid)].EndByte))
+		for _, subset := range subsetRanges {
+			w.WriteString(fmt.Sprintf(" %v %v", subset.fileStart, subset.fileEnd))
 		}
 		w.Flush()
 		cmdStr = fmt.Sprintf("%s restore_data_subset %s %s %s", pluginConfig.ExecutablePath, pluginConfig.ConfigPath, fileToRead, offsetsFile.Name())
-		isSubset = true
+	} else if pluginConfig.CanResumeRestoreData() {
+		// Only whole files are resumed, as a subset stream is not the file data at its offsets
+		readHandle, err := startPluginRestoreReader(pluginConfig, fileToRead)
+		return readHandle, nil, err
 	} else {
 		cmdStr = fmt.Sprintf("%s restore_data %s %s", pluginConfig.ExecutablePath, pluginConfig.ConfigPath, fileToRead)
 	}
@@ -509,10 +697,10 @@ func startRestorePluginCommand(fileToRead string, objToc *toc.SegmentTOC, oidLis
 
 	readHandle, err := cmd.StdoutPipe()
 	if err != nil {
-		return nil, false, err
+		return nil, nil, err
 	}
 	cmd.Stderr = &errBuf
 
 	err = cmd.Start()
-	return readHandle, isSubset, err
+	return readHandle, subsetRanges, err
 }
@@ -19,6 +19,12 @@ type RestorePlanEntry struct {
 	TableFQNs []string
 }
 
+type DataSnapshotEntry struct {
+	Timestamp  string
+	SnapshotID string
+	TableFQNs  []string
+}
+
 const (
     BackupStatusInProgress = "In Progress"
 	BackupStatusSucceed = "Success"
@@ -33,8 +39,13 @@ type BackupConfig struct {
 	DatabaseName          string
 	DatabaseVersion       string
 	SegmentCount          int
+	DataFormat            string
 	DataOnly              bool
+	DataSnapshots         []DataSnapshotEntry
+	DataStreams           int
 	DateDeleted           string
+	Differential          bool
+	EncryptionFingerprint string
 	ExcludeRelations      []string
 	ExcludeSchemaFiltered bool
 	ExcludeSchemas        []string
@@ -45,11 +56,14 @@ type BackupConfig struct {
 	IncludeTableFiltered  bool
 	Incremental           bool
 	LeafPartitionData     bool
+	MaskingRules          map[string]string
 	MetadataOnly          bool
 	Plugin                string
 	PluginVersion         string
+	Repository            bool
 	RestorePlan           []RestorePlanEntry
 	SingleDataFile        bool
+	TablePredicates       map[string]string
 	Timestamp             string
 	EndTime               string
 	WithoutGlobals        bool
@@ -61,6 +75,11 @@ func (backup *BackupConfig) Failed() bool {
 	return backup.Status == BackupStatusFailed
 }
 
+// Data snapshots are only recorded for backups resumed with --resume
+func (backup *BackupConfig) Resumed() bool {
+	return len(backup.DataSnapshots) > 0
+}
+
 func ReadConfigFile(filename string) *BackupConfig {
 	config := &BackupConfig{}
 	contents, err := ioutil.ReadFile(filename)
@@ -140,6 +159,13 @@ func InitializeHistoryDatabase(historyDBPath string) (*sql.DB, error) {
 		return nil, err
 	}
 
+	err = addMissingBackupsColumns(tx)
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+
 	createAuxTableQuery := `
 		CREATE TABLE IF NOT EXISTS %s (
 			timestamp TEXT NOT NULL,
@@ -187,6 +213,62 @@ func InitializeHistoryDatabase(historyDBPath string) (*sql.DB, error) {
 		return nil, err
 	}
 
+	createMaskingRulesTable := `
+		CREATE TABLE IF NOT EXISTS masking_rules (
+			timestamp TEXT NOT NULL,
+			column_fqn TEXT NOT NULL,
+			expression TEXT NOT NULL,
+			FOREIGN KEY(timestamp) REFERENCES backups(timestamp)
+		);`
+	_, err = tx.Exec(createMaskingRulesTable)
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+
+	createTablePredicatesTable := `
+		CREATE TABLE IF NOT EXISTS table_predicates (
+			timestamp TEXT NOT NULL,
+			table_fqn TEXT NOT NULL,
+			predicate TEXT NOT NULL,
+			FOREIGN KEY(timestamp) REFERENCES backups(timestamp)
+		);`
+	_, err = tx.Exec(createTablePredicatesTable)
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+
+	createDataSnapshotsTable := `
+		CREATE TABLE IF NOT EXISTS data_snapshots (
+			timestamp TEXT NOT NULL,
+			snapshot_timestamp TEXT NOT NULL,
+			snapshot_id TEXT NOT NULL,
+			FOREIGN KEY(timestamp) REFERENCES backups(timestamp)
+		);`
+	_, err = tx.Exec(createDataSnapshotsTable)
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+
+	createDataSnapshotTablesTable := `
+		CREATE TABLE IF NOT EXISTS data_snapshot_tables (
+			timestamp TEXT NOT NULL,
+			snapshot_timestamp TEXT NOT NULL,
+			table_fqn TEXT NOT NULL,
+			FOREIGN KEY(timestamp) REFERENCES backups(timestamp)
+		);`
+	_, err = tx.Exec(createDataSnapshotTablesTable)
+	if err != nil {
+		tx.Rollback()
+		db.Close()
+		return nil, err
+	}
+
 	err = tx.Commit()
 	if err != nil {
 		db.Close()
@@ -195,6 +277,65 @@ func InitializeHistoryDatabase(historyDBPath string) (*sql.DB, error) {
 	return db, nil
 }
 
+/*
+ * Opens an existing backup history database without creating or migrating it,
+ * for a dry run, which must not write anything.  It is the caller's
+ * responsibility to close the returned connection when done with it.
+ */
+func OpenHistoryDatabaseReadOnly(historyDBPath string) (*sql.DB, error) {
+	if _, err := os.Stat(historyDBPath); err != nil {
+		return nil, err
+	}
+	return sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=ro", historyDBPath))
+}
+
+/*
+ * Columns added to the backups table after it was first released.  A history
+ * database created by an older version lacks them, so they are added here, and
+ * their defaults describe how those older backups were taken.
+ */
+var addedBackupsColumns = []struct {
+	name       string
+	definition string
+}{
+	{"differential", "INT DEFAULT 0 CHECK (differential in (0,1))"},
+	{"encryption_fingerprint", "TEXT DEFAULT ''"},
+	{"data_streams", "INT DEFAULT 0"},
+	{"repository", "INT DEFAULT 0 CHECK (repository in (0,1))"},
+	{"data_format", "TEXT DEFAULT ''"},
+}
+
+func addMissingBackupsColumns(tx *sql.Tx) error {
+	columnRows, err := tx.Query("PRAGMA table_info(backups);")
+	if err != nil {
+		return err
+	}
+	existingColumns := make(map[string]bool)
+	for columnRows.Next() {
+		var cid, notNull, primaryKey int
+		var name, columnType string
+		var defaultValue sql.NullString
+		err = columnRows.Scan(&cid, &name, &columnType, &notNull, &defaultValue, &primaryKey)
+		if err != nil {
+			columnRows.Close()
+			return err
+		}
+		existingColumns[name] = true
+	}
+	columnRows.Close()
+
+	for _, column := range addedBackupsColumns {
+		if existingColumns[column.name] {
+			continue
+		}
+		_, err = tx.Exec(fmt.Sprintf("ALTER TABLE backups ADD COLUMN %s %s;", column.name, column.definition))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func CurrentTimestamp() string {
 	return operating.System.Now().Format("20060102150405")
 }
@@ -210,6 +351,17 @@ func storeAuxTable(tx *sql.Tx, arrayValues []string, tablename string, timestamp
 	return nil
 }
 
+func storeAuxMap(tx *sql.Tx, mapValues map[string]string, tablename string, timestamp string) error {
+	for key, value := range mapValues {
+		auxMapInsert := fmt.Sprintf("INSERT INTO %s VALUES (?, ?, ?);", tablename)
+		_, err := tx.Exec(auxMapInsert, timestamp, key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func StoreBackupHistory(db *sql.DB, currentBackupConfig *BackupConfig) error {
 	if currentBackupConfig.EndTime == "" {
 		// If we're migrating in prior backup records, we don't want to overwrite pre-existing EndTime values
@@ -222,9 +374,10 @@ func StoreBackupHistory(db *sql.DB, currentBackupConfig *BackupConfig) error {
 			database_version, segment_count, data_only, date_deleted, exclude_schema_filtered,
 			exclude_table_filtered, include_schema_filtered, include_table_filtered, incremental,
 			leaf_partition_data, metadata_only, plugin, plugin_version, single_data_file, end_time,
-			without_globals, with_statistics, status
+			without_globals, with_statistics, status, differential, encryption_fingerprint,
+			data_streams, repository, data_format
 			)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?);`,
 		currentBackupConfig.Timestamp, currentBackupConfig.BackupDir,
 		currentBackupConfig.BackupVersion, currentBackupConfig.Compressed,
 		currentBackupConfig.CompressionType, currentBackupConfig.DatabaseName,
@@ -236,7 +389,10 @@ func StoreBackupHistory(db *sql.DB, currentBackupConfig *BackupConfig) error {
 		currentBackupConfig.MetadataOnly, currentBackupConfig.Plugin,
 		currentBackupConfig.PluginVersion, currentBackupConfig.SingleDataFile,
 		currentBackupConfig.EndTime, currentBackupConfig.WithoutGlobals,
-		currentBackupConfig.WithStatistics, currentBackupConfig.Status)
+		currentBackupConfig.WithStatistics, currentBackupConfig.Status,
+		currentBackupConfig.Differential, currentBackupConfig.EncryptionFingerprint,
+		currentBackupConfig.DataStreams, currentBackupConfig.Repository,
+		currentBackupConfig.DataFormat)
 	if err != nil {
 		goto CleanupError
 	}
@@ -261,6 +417,16 @@ func StoreBackupHistory(db *sql.DB, currentBackupConfig *BackupConfig) error {
 		goto CleanupError
 	}
 
+	err = storeAuxMap(tx, currentBackupConfig.MaskingRules, "masking_rules", currentBackupConfig.Timestamp)
+	if err != nil {
+		goto CleanupError
+	}
+
+	err = storeAuxMap(tx, currentBackupConfig.TablePredicates, "table_predicates", currentBackupConfig.Timestamp)
+	if err != nil {
+		goto CleanupError
+	}
+
 	// unpack and store restore plan entries
 	for _, restorePlan := range currentBackupConfig.RestorePlan {
 		_, err = tx.Exec("INSERT INTO restore_plans VALUES (?, ?);",
@@ -278,6 +444,11 @@ func StoreBackupHistory(db *sql.DB, currentBackupConfig *BackupConfig) error {
 		}
 	}
 
+	err = storeDataSnapshots(tx, currentBackupConfig.Timestamp, currentBackupConfig.DataSnapshots)
+	if err != nil {
+		goto CleanupError
+	}
+
 	err = tx.Commit()
 	return err
 
@@ -286,6 +457,76 @@ CleanupError:
 	return err
 }
 
+func storeDataSnapshots(tx *sql.Tx, timestamp string, dataSnapshots []DataSnapshotEntry) error {
+	for _, dataSnapshot := range dataSnapshots {
+		_, err := tx.Exec("INSERT INTO data_snapshots VALUES (?, ?, ?);",
+			timestamp, dataSnapshot.Timestamp, dataSnapshot.SnapshotID)
+		if err != nil {
+			return err
+		}
+
+		for _, tableFQN := range dataSnapshot.TableFQNs {
+			_, err = tx.Exec("INSERT INTO data_snapshot_tables VALUES (?, ?, ?);",
+				timestamp, dataSnapshot.Timestamp, tableFQN)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Replace any data snapshot entries already stored for the backup with the given ones
+func StoreDataSnapshots(db *sql.DB, timestamp string, dataSnapshots []DataSnapshotEntry) error {
+	tx, _ := db.Begin()
+	for _, tableName := range []string{"data_snapshot_tables", "data_snapshots"} {
+		_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE timestamp = ?;", tableName), timestamp)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	err := storeDataSnapshots(tx, timestamp, dataSnapshots)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Remove all records of a backup, so that a resumed backup can store its entry again
+func DeleteBackupHistory(db *sql.DB, timestamp string) error {
+	tx, _ := db.Begin()
+	// The backups table must come last, as the other tables reference it
+	tableNames := []string{"exclude_relations", "exclude_schemas", "include_relations", "include_schemas",
+		"masking_rules", "table_predicates", "restore_plan_tables", "restore_plans", "data_snapshot_tables", "data_snapshots", "backups"}
+	for _, tableName := range tableNames {
+		_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE timestamp = ?;", tableName), timestamp)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Record that the files of a backup were deleted, keeping its entry for reference
+func MarkBackupDeleted(db *sql.DB, timestamp string, dateDeleted string) error {
+	result, err := db.Exec("UPDATE backups SET date_deleted = ? WHERE timestamp = ?;", dateDeleted, timestamp)
+	if err != nil {
+		return err
+	}
+	numRows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if numRows == 0 {
+		return errors.New("timestamp doesn't match any existing backups")
+	}
+	return nil
+}
+
 func GetMainBackupInfo(timestamp string, historyDB *sql.DB) (BackupConfig, error) {
 	// Retreive main backups information. SQLite doesn't have booleans so convert from ints
 	// TODO -- consider passing in a tx instead so that aux tables are coherent with main backups
@@ -296,7 +537,8 @@ func GetMainBackupInfo(timestamp string, historyDB *sql.DB) (BackupConfig, error
 			database_version, segment_count, data_only, date_deleted, exclude_schema_filtered,
 			exclude_table_filtered, include_schema_filtered, include_table_filtered, incremental,
 			leaf_partition_data, metadata_only, plugin, plugin_version, single_data_file, end_time,
-			without_globals, with_statistics, status
+			without_globals, with_statistics, status, differential, encryption_fingerprint,
+			data_streams, repository, data_format
 		FROM backups WHERE timestamp = '%s'`,
 		timestamp)
 	backupRow := historyDB.QueryRow(backupQuery)
@@ -314,6 +556,8 @@ func GetMainBackupInfo(timestamp string, historyDB *sql.DB) (BackupConfig, error
 	var isSingleDataFile int
 	var isWithoutGlobals int
 	var isWithStatistics int
+	var isDifferential int
+	var isRepository int
 	err := backupRow.Scan(
 		&backupConfig.Timestamp, &backupConfig.BackupDir, &backupConfig.BackupVersion,
 		&isCompressed, &backupConfig.CompressionType, &backupConfig.DatabaseName,
@@ -321,7 +565,9 @@ func GetMainBackupInfo(timestamp string, historyDB *sql.DB) (BackupConfig, error
 		&backupConfig.DateDeleted, &isExclSchemaFiltered, &isExclTableFiltered,
 		&isInclSchemaFiltered, &isInclTableFiltered, &isIncremental, &isLeafPartition,
 		&isMetadataOnly, &backupConfig.Plugin, &backupConfig.PluginVersion, &isSingleDataFile,
-		&backupConfig.EndTime, &isWithoutGlobals, &isWithStatistics, &backupConfig.Status)
+		&backupConfig.EndTime, &isWithoutGlobals, &isWithStatistics, &backupConfig.Status,
+		&isDifferential, &backupConfig.EncryptionFingerprint, &backupConfig.DataStreams, &isRepository,
+		&backupConfig.DataFormat)
 	if err == sql.ErrNoRows {
 		return backupConfig, errors.New("timestamp doesn't match any existing backups")
 	} else if err != nil {
@@ -340,6 +586,8 @@ func GetMainBackupInfo(timestamp string, historyDB *sql.DB) (BackupConfig, error
 	backupConfig.SingleDataFile = isSingleDataFile == 1
 	backupConfig.WithoutGlobals = isWithoutGlobals == 1
 	backupConfig.WithStatistics = isWithStatistics == 1
+	backupConfig.Differential = isDifferential == 1
+	backupConfig.Repository = isRepository == 1
 
 	return backupConfig, err
 }
@@ -392,6 +640,16 @@ func GetBackupConfig(timestamp string, historyDB *sql.DB) (*BackupConfig, error)
 		return nil, err
 	}
 
+	backupConfig.MaskingRules, err = getAuxMap(historyDB, timestamp, "masking_rules", "column_fqn", "expression")
+	if err != nil {
+		return nil, err
+	}
+
+	backupConfig.TablePredicates, err = getAuxMap(historyDB, timestamp, "table_predicates", "table_fqn", "predicate")
+	if err != nil {
+		return nil, err
+	}
+
 	// Retrieve restore plan information
 	restorePlanQuery := fmt.Sprintf("SELECT DISTINCT restore_plan_timestamp FROM restore_plans WHERE timestamp = '%s' ORDER BY restore_plan_timestamp", timestamp)
 	restorePlanRows, err := historyDB.Query(restorePlanQuery)
@@ -432,5 +690,74 @@ func GetBackupConfig(timestamp string, historyDB *sql.DB) (*BackupConfig, error)
 		backupConfig.RestorePlan = append(backupConfig.RestorePlan, restorePlan)
 	}
 
+	backupConfig.DataSnapshots, err = getDataSnapshots(historyDB, timestamp)
+	if err != nil {
+		return nil, err
+	}
+
 	return &backupConfig, err
 }
+
+func getAuxMap(historyDB *sql.DB, timestamp, tableName, keyColumn, valueColumn string) (map[string]string, error) {
+	getAuxMapQuery := fmt.Sprintf("SELECT %s, %s FROM %s WHERE timestamp = '%s'", keyColumn, valueColumn, tableName, timestamp)
+	auxMapRows, err := historyDB.Query(getAuxMapQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer auxMapRows.Close()
+
+	// Leave the map nil for backups taken without the corresponding file
+	var auxMap map[string]string
+	for auxMapRows.Next() {
+		var key, value string
+		err = auxMapRows.Scan(&key, &value)
+		if err != nil {
+			return nil, err
+		}
+		if auxMap == nil {
+			auxMap = make(map[string]string)
+		}
+		auxMap[key] = value
+	}
+	return auxMap, nil
+}
+
+func getDataSnapshots(historyDB *sql.DB, timestamp string) ([]DataSnapshotEntry, error) {
+	dataSnapshotQuery := fmt.Sprintf("SELECT snapshot_timestamp, snapshot_id FROM data_snapshots WHERE timestamp = '%s' ORDER BY snapshot_timestamp", timestamp)
+	dataSnapshotRows, err := historyDB.Query(dataSnapshotQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer dataSnapshotRows.Close()
+
+	// Leave the slice nil for backups that were never resumed
+	var dataSnapshots []DataSnapshotEntry
+	for dataSnapshotRows.Next() {
+		dataSnapshot := DataSnapshotEntry{TableFQNs: make([]string, 0)}
+		err = dataSnapshotRows.Scan(&dataSnapshot.Timestamp, &dataSnapshot.SnapshotID)
+		if err != nil {
+			return nil, err
+		}
+		dataSnapshots = append(dataSnapshots, dataSnapshot)
+	}
+
+	for i, dataSnapshot := range dataSnapshots {
+		dataSnapshotTablesQuery := fmt.Sprintf("SELECT table_fqn FROM data_snapshot_tables WHERE timestamp = '%s' and snapshot_timestamp = '%s'", timestamp, dataSnapshot.Timestamp)
+		dataSnapshotTableRows, err := historyDB.Query(dataSnapshotTablesQuery)
+		if err != nil {
+			return nil, err
+		}
+		for dataSnapshotTableRows.Next() {
+			var tableFQN string
+			err = dataSnapshotTableRows.Scan(&tableFQN)
+			if err != nil {
+				dataSnapshotTableRows.Close()
+				return nil, err
+			}
+			dataSnapshots[i].TableFQNs = append(dataSnapshots[i].TableFQNs, tableFQN)
+		}
+		dataSnapshotTableRows.Close()
+	}
+
+	return dataSnapshots, nil
+}
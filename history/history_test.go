@@ -80,12 +80,16 @@ var _ = Describe("backup/history tests", func() {
 			}
 
 			Expect(tableNames[0]).To(Equal("backups"))
-			Expect(tableNames[1]).To(Equal("exclude_relations"))
-			Expect(tableNames[2]).To(Equal("exclude_schemas"))
-			Expect(tableNames[3]).To(Equal("include_relations"))
-			Expect(tableNames[4]).To(Equal("include_schemas"))
-			Expect(tableNames[5]).To(Equal("restore_plan_tables"))
-			Expect(tableNames[6]).To(Equal("restore_plans"))
+			Expect(tableNames[1]).To(Equal("data_snapshot_tables"))
+			Expect(tableNames[2]).To(Equal("data_snapshots"))
+			Expect(tableNames[3]).To(Equal("exclude_relations"))
+			Expect(tableNames[4]).To(Equal("exclude_schemas"))
+			Expect(tableNames[5]).To(Equal("include_relations"))
+			Expect(tableNames[6]).To(Equal("include_schemas"))
+			Expect(tableNames[7]).To(Equal("masking_rules"))
+			Expect(tableNames[8]).To(Equal("restore_plan_tables"))
+			Expect(tableNames[9]).To(Equal("restore_plans"))
+			Expect(tableNames[10]).To(Equal("table_predicates"))
 
 		})
 
@@ -104,8 +108,49 @@ var _ = Describe("backup/history tests", func() {
 			Expect(tableName).To(Equal("dummy"))
 
 		})
+
+		It("adds the columns missing from a database created by an older version", func() {
+			db, _ := history.InitializeHistoryDatabase(historyDBPath)
+			err := history.StoreBackupHistory(db, &testConfig1)
+			Expect(err).To(BeNil())
+			for _, column := range []string{"differential", "encryption_fingerprint", "data_streams", "repository", "data_format"} {
+				_, err = db.Exec("ALTER TABLE backups DROP COLUMN " + column)
+				Expect(err).To(BeNil())
+			}
+			db.Close()
+
+			migratedDB, err := history.InitializeHistoryDatabase(historyDBPath)
+			Expect(err).To(BeNil())
+			defer migratedDB.Close()
+			config, err := history.GetBackupConfig(testConfig1.Timestamp, migratedDB)
+			Expect(err).To(BeNil())
+			Expect(config).To(structmatcher.MatchStruct(testConfig1))
+		})
 	})
 
+	Describe("OpenHistoryDatabaseReadOnly", func() {
+		It("returns a read-only handle to an existing database", func() {
+			db, _ := history.InitializeHistoryDatabase(historyDBPath)
+			err := history.StoreBackupHistory(db, &testConfig1)
+			Expect(err).To(BeNil())
+			db.Close()
+
+			readOnlyDB, err := history.OpenHistoryDatabaseReadOnly(historyDBPath)
+			Expect(err).To(BeNil())
+			defer readOnlyDB.Close()
+			config, err := history.GetBackupConfig(testConfig1.Timestamp, readOnlyDB)
+			Expect(err).To(BeNil())
+			Expect(config).To(structmatcher.MatchStruct(testConfig1))
+			err = history.StoreBackupHistory(readOnlyDB, &testConfig2)
+			Expect(err).ToNot(BeNil())
+		})
+		It("returns an error without creating a database if none is present", func() {
+			_, err := history.OpenHistoryDatabaseReadOnly(historyDBPath)
+			Expect(err).ToNot(BeNil())
+			_, err = os.Stat(historyDBPath)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
 	Describe("StoreBackupHistory", func() {
 		It("stores a config into the database", func() {
 			db, _ := history.InitializeHistoryDatabase(historyDBPath)
@@ -181,5 +226,105 @@ var _ = Describe("backup/history tests", func() {
 			Expect(err).To(BeNil())
 			Expect(config).To(structmatcher.MatchStruct(testConfig2))
 		})
+		It("gets a config from the database with data snapshot entries", func() {
+			db, _ := history.InitializeHistoryDatabase(historyDBPath)
+			defer db.Close()
+			testConfig1.DataSnapshots = []history.DataSnapshotEntry{
+				{Timestamp: "snapshot1", SnapshotID: "00000005-00000002-1", TableFQNs: []string{"testschema.testtable1"}},
+				{Timestamp: "snapshot2", SnapshotID: "00000007-00000003-1", TableFQNs: []string{"testschema.testtable2"}},
+			}
+			err := history.StoreBackupHistory(db, &testConfig1)
+			Expect(err).To(BeNil())
+
+			config, err := history.GetBackupConfig(testConfig1.Timestamp, db)
+			Expect(err).To(BeNil())
+			Expect(config).To(structmatcher.MatchStruct(testConfig1))
+			Expect(config.Resumed()).To(BeTrue())
+		})
+		It("gets a config from the database with the settings that affect its data", func() {
+			db, _ := history.InitializeHistoryDatabase(historyDBPath)
+			defer db.Close()
+			testConfig1.DataFormat = "binary"
+			testConfig1.DataStreams = 4
+			testConfig1.Differential = true
+			testConfig1.EncryptionFingerprint = "0123456789abcdef"
+			testConfig1.MaskingRules = map[string]string{"testschema.testtable1.ssn": "'xxx-xx-' || right(ssn, 4)"}
+			testConfig1.Repository = true
+			testConfig1.TablePredicates = map[string]string{"testschema.testtable2": "id > 100"}
+			err := history.StoreBackupHistory(db, &testConfig1)
+			Expect(err).To(BeNil())
+
+			config, err := history.GetBackupConfig(testConfig1.Timestamp, db)
+			Expect(err).To(BeNil())
+			Expect(config).To(structmatcher.MatchStruct(testConfig1))
+		})
+	})
+
+	Describe("StoreDataSnapshots", func() {
+		It("replaces the data snapshot entries of a backup", func() {
+			db, _ := history.InitializeHistoryDatabase(historyDBPath)
+			defer db.Close()
+			testConfig1.DataSnapshots = []history.DataSnapshotEntry{
+				{Timestamp: "snapshot1", SnapshotID: "", TableFQNs: []string{"testschema.testtable1"}},
+			}
+			err := history.StoreBackupHistory(db, &testConfig1)
+			Expect(err).To(BeNil())
+
+			testConfig1.DataSnapshots = []history.DataSnapshotEntry{
+				{Timestamp: "snapshot1", SnapshotID: "", TableFQNs: []string{"testschema.testtable1"}},
+				{Timestamp: "snapshot2", SnapshotID: "", TableFQNs: []string{"testschema.testtable2"}},
+			}
+			err = history.StoreDataSnapshots(db, testConfig1.Timestamp, testConfig1.DataSnapshots)
+			Expect(err).To(BeNil())
+
+			config, err := history.GetBackupConfig(testConfig1.Timestamp, db)
+			Expect(err).To(BeNil())
+			Expect(config.DataSnapshots).To(Equal(testConfig1.DataSnapshots))
+		})
+	})
+
+	Describe("DeleteBackupHistory", func() {
+		It("removes a backup from the database so that it can be stored again", func() {
+			db, _ := history.InitializeHistoryDatabase(historyDBPath)
+			defer db.Close()
+			err := history.StoreBackupHistory(db, &testConfig1)
+			Expect(err).To(BeNil())
+			err = history.StoreBackupHistory(db, &testConfig2)
+			Expect(err).To(BeNil())
+
+			err = history.DeleteBackupHistory(db, testConfig2.Timestamp)
+			Expect(err).To(BeNil())
+
+			_, err = history.GetBackupConfig(testConfig2.Timestamp, db)
+			Expect(err.Error()).To(Equal("timestamp doesn't match any existing backups"))
+			config, err := history.GetBackupConfig(testConfig1.Timestamp, db)
+			Expect(err).To(BeNil())
+			Expect(config).To(structmatcher.MatchStruct(testConfig1))
+
+			err = history.StoreBackupHistory(db, &testConfig2)
+			Expect(err).To(BeNil())
+		})
+	})
+	Describe("MarkBackupDeleted", func() {
+		It("records the date on which a backup was deleted", func() {
+			db, _ := history.InitializeHistoryDatabase(historyDBPath)
+			defer db.Close()
+			err := history.StoreBackupHistory(db, &testConfig1)
+			Expect(err).To(BeNil())
+
+			err = history.MarkBackupDeleted(db, testConfig1.Timestamp, "20260101010101")
+			Expect(err).To(BeNil())
+
+			config, err := history.GetBackupConfig(testConfig1.Timestamp, db)
+			Expect(err).To(BeNil())
+			Expect(config.DateDeleted).To(Equal("20260101010101"))
+		})
+		It("returns an error for a backup that is not in the database", func() {
+			db, _ := history.InitializeHistoryDatabase(historyDBPath)
+			defer db.Close()
+
+			err := history.MarkBackupDeleted(db, testConfig1.Timestamp, "20260101010101")
+			Expect(err.Error()).To(Equal("timestamp doesn't match any existing backups"))
+		})
 	})
 })
@@ -72,7 +72,7 @@ var _ = Describe("backup integration tests", func() {
 			backup.SetBackupSnapshot(testSnapshot)
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(func() { backup.BackupDataForAllTables(testTables) }).ShouldNot(Panic())
+			Expect(func() { backup.BackupDataForAllTables(testTables, map[uint32]int64{}) }).ShouldNot(Panic())
 
 			// Assert that at least one segment's worth of files for both tables were written out
 			_, err = os.Stat("/tmp/backup_data_test/backups/20170101/20170101010101/gpbackup_0_20170101010101_0")
@@ -85,10 +85,9 @@ var _ = Describe("backup integration tests", func() {
 			// error. This coverage is meant to prevent that from reocurring in future refactors,
 			// which that function needs.
 			dummyPipeThrough := utils.PipeThroughProgram{
-				Name:          "dummy",
-				OutputCommand: "doesnotexist",
-				InputCommand:  "doesnotexist",
-				Extension:     ".dne",
+				Name:      "dummy",
+				Level:     1,
+				Extension: ".dne",
 			}
 			utils.SetPipeThroughProgram(dummyPipeThrough)
 
@@ -110,7 +109,7 @@ var _ = Describe("backup integration tests", func() {
 			Expect(err).ToNot(HaveOccurred())
 			backup.SetBackupSnapshot(testSnapshot)
 
-			Expect(func() { backup.BackupDataForAllTables(testTables) }).Should(Panic())
+			Expect(func() { backup.BackupDataForAllTables(testTables, map[uint32]int64{}) }).Should(Panic())
 
 			// Terminate the hanging copy command or it breaks test suite cleanup. We do not need
 			// to worry about GPDB5- syntax here because these tests don't apply to that
@@ -145,7 +144,7 @@ var _ = Describe("backup integration tests", func() {
 			Expect(err).ToNot(HaveOccurred())
 			backup.SetBackupSnapshot(testSnapshot)
 
-			Expect(func() { backup.BackupDataForAllTables(testTables) }).Should(Panic())
+			Expect(func() { backup.BackupDataForAllTables(testTables, map[uint32]int64{}) }).Should(Panic())
 		})
 	})
 })
@@ -282,4 +282,61 @@ var _ = Describe("backup integration tests", func() {
 			})
 		})
 	})
+	Describe("GetHeapIncrementalMetadata", func() {
+		var heapTableFQN = "public.heap_foo"
+		var initialHeapIncrementalMetadata map[string]toc.HeapEntry
+		var heapIncrementalMetadata map[string]toc.HeapEntry
+		BeforeEach(func() {
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf("CREATE TABLE %s (i int) DISTRIBUTED BY (i)", heapTableFQN))
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf(insertSQL, heapTableFQN))
+			initialHeapIncrementalMetadata = backup.GetHeapIncrementalMetadata(connectionPool)
+		})
+		AfterEach(func() {
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf(dropTableSQL, heapTableFQN))
+		})
+		It("should only retrieve heap tables", func() {
+			Expect(initialHeapIncrementalMetadata).To(HaveKey(heapTableFQN))
+			Expect(initialHeapIncrementalMetadata).To(Not(HaveKey(aoTableFQN)))
+			Expect(initialHeapIncrementalMetadata).To(Not(HaveKey(aoCOTableFQN)))
+		})
+		It("should have a last DDL timestamp", func() {
+			Expect(initialHeapIncrementalMetadata[heapTableFQN].LastDDLTimestamp).To(Not(BeEmpty()))
+		})
+		It("should not change when the table is unmodified", func() {
+			heapIncrementalMetadata = backup.GetHeapIncrementalMetadata(connectionPool)
+			Expect(heapIncrementalMetadata[heapTableFQN]).To(Equal(initialHeapIncrementalMetadata[heapTableFQN]))
+		})
+		It("should change after a delete", func() {
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf(deleteSQL, heapTableFQN))
+			Eventually(func() toc.HeapEntry {
+				return backup.GetHeapIncrementalMetadata(connectionPool)[heapTableFQN]
+			}, "5s", "200ms").Should(Not(Equal(initialHeapIncrementalMetadata[heapTableFQN])))
+		})
+		It("should have a changed relfilenode after a truncate", func() {
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf("TRUNCATE TABLE %s", heapTableFQN))
+			heapIncrementalMetadata = backup.GetHeapIncrementalMetadata(connectionPool)
+			Expect(heapIncrementalMetadata[heapTableFQN].Relfilenode).
+				To(Not(Equal(initialHeapIncrementalMetadata[heapTableFQN].Relfilenode)))
+		})
+		It("should have a changed last DDL timestamp after a column add", func() {
+			testhelper.AssertQueryRuns(connectionPool, fmt.Sprintf(addColumnSQL, heapTableFQN))
+			heapIncrementalMetadata = backup.GetHeapIncrementalMetadata(connectionPool)
+			Expect(heapIncrementalMetadata[heapTableFQN].LastDDLTimestamp).
+				To(Not(Equal(initialHeapIncrementalMetadata[heapTableFQN].LastDDLTimestamp)))
+		})
+	})
+	Describe("GetHeapStatsEpoch", func() {
+		It("should not change while the statistics are not reset", func() {
+			initialEpoch := backup.GetHeapStatsEpoch(connectionPool)
+			Expect(initialEpoch).To(Not(BeEmpty()))
+			Expect(backup.GetHeapStatsEpoch(connectionPool)).To(Equal(initialEpoch))
+		})
+		It("should change after the statistics are reset", func() {
+			initialEpoch := backup.GetHeapStatsEpoch(connectionPool)
+			testhelper.AssertQueryRuns(connectionPool, "SELECT pg_stat_reset() FROM gp_dist_random('gp_id')")
+			Eventually(func() string {
+				return backup.GetHeapStatsEpoch(connectionPool)
+			}, "5s", "200ms").Should(Not(Equal(initialEpoch)))
+		})
+	})
 })
@@ -191,6 +191,50 @@ PARTITION BY LIST (gender)
 			structmatcher.ExpectStructsToMatchIncluding(&tableFoo, &tables[0], "Name", "Schema")
 		})
 	})
+	Describe("GetTableSizes", func() {
+		It("returns the size of the data in a table", func() {
+			testhelper.AssertQueryRuns(connectionPool, "CREATE TABLE public.foo(i int) DISTRIBUTED BY (i)")
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP TABLE public.foo")
+			testhelper.AssertQueryRuns(connectionPool, "INSERT INTO public.foo SELECT generate_series(1, 1000)")
+			testhelper.AssertQueryRuns(connectionPool, "CREATE TABLE public.empty_foo(i int) DISTRIBUTED BY (i)")
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP TABLE public.empty_foo")
+			fooOid := testutils.OidFromObjectName(connectionPool, "public", "foo", backup.TYPE_RELATION)
+			emptyFooOid := testutils.OidFromObjectName(connectionPool, "public", "empty_foo", backup.TYPE_RELATION)
+			tables := []backup.Table{
+				{Relation: backup.Relation{Oid: fooOid, Schema: "public", Name: "foo"}},
+				{Relation: backup.Relation{Oid: emptyFooOid, Schema: "public", Name: "empty_foo"}},
+			}
+
+			tableSizes := backup.GetTableSizes(connectionPool, tables)
+
+			Expect(tableSizes).To(HaveLen(2))
+			Expect(tableSizes[fooOid]).To(BeNumerically(">", 0))
+			Expect(tableSizes[emptyFooOid]).To(Equal(int64(0)))
+		})
+		It("includes the size of all partitions in the size of a parent partition table", func() {
+			createStmt := `CREATE TABLE public.rank (id int, rank int, year int, gender
+char(1), count int )
+DISTRIBUTED BY (id)
+PARTITION BY LIST (gender)
+( PARTITION girls VALUES ('F'),
+  PARTITION boys VALUES ('M'),
+  DEFAULT PARTITION other );`
+			testhelper.AssertQueryRuns(connectionPool, createStmt)
+			defer testhelper.AssertQueryRuns(connectionPool, "DROP TABLE public.rank")
+			testhelper.AssertQueryRuns(connectionPool, "INSERT INTO public.rank SELECT i, i, 2022, 'F', i FROM generate_series(1, 1000) i")
+			rankOid := testutils.OidFromObjectName(connectionPool, "public", "rank", backup.TYPE_RELATION)
+			girlsOid := testutils.OidFromObjectName(connectionPool, "public", "rank_1_prt_girls", backup.TYPE_RELATION)
+			tables := []backup.Table{
+				{Relation: backup.Relation{Oid: rankOid, Schema: "public", Name: "rank"}},
+				{Relation: backup.Relation{Oid: girlsOid, Schema: "public", Name: "rank_1_prt_girls"}},
+			}
+
+			tableSizes := backup.GetTableSizes(connectionPool, tables)
+
+			Expect(tableSizes[girlsOid]).To(BeNumerically(">", 0))
+			Expect(tableSizes[rankOid]).To(BeNumerically(">=", tableSizes[girlsOid]))
+		})
+	})
 	Describe("GetAllSequenceRelations", func() {
 		It("returns a slice of all sequences", func() {
 			testhelper.AssertQueryRuns(connectionPool, "CREATE SEQUENCE public.my_sequence START 10")
@@ -17,9 +17,17 @@ const (
 	BACKUP_DIR            = "backup-dir"
 	COMPRESSION_TYPE      = "compression-type"
 	COMPRESSION_LEVEL     = "compression-level"
+	DATA_FORMAT           = "data-format"
 	DATA_ONLY             = "data-only"
+	DATA_STREAMS          = "data-streams"
 	DBNAME                = "dbname"
 	DEBUG                 = "debug"
+	DELETE_BACKUP         = "delete-backup"
+	DIFFERENTIAL          = "differential"
+	DRY_RUN               = "dry-run"
+	DRY_RUN_FILE          = "dry-run-file"
+	DRY_RUN_FORMAT        = "dry-run-format"
+	ENCRYPTION_KEY_FILE   = "encryption-key-file"
 	EXCLUDE_RELATION      = "exclude-table"
 	EXCLUDE_RELATION_FILE = "exclude-table-file"
 	EXCLUDE_SCHEMA        = "exclude-schema"
@@ -32,12 +40,21 @@ const (
 	INCREMENTAL           = "incremental"
 	JOBS                  = "jobs"
 	LEAF_PARTITION_DATA   = "leaf-partition-data"
+	MASKING_RULES_FILE    = "masking-rules-file"
+	MAX_BANDWIDTH         = "max-bandwidth"
+	MAX_BANDWIDTH_SCOPE   = "max-bandwidth-scope"
 	METADATA_ONLY         = "metadata-only"
 	NO_COMPRESSION        = "no-compression"
 	NO_HISTORY            = "no-history"
 	PLUGIN_CONFIG         = "plugin-config"
+	PRIORITY_TABLE_FILE   = "priority-table-file"
 	QUIET                 = "quiet"
+	REPOSITORY            = "repository"
 	SINGLE_DATA_FILE      = "single-data-file"
+	STALL_RETRIES         = "stall-retries"
+	STALL_TIMEOUT         = "stall-timeout"
+	TABLE_PREDICATE_FILE  = "table-predicate-file"
+	TRACK_HEAP_CHANGES    = "track-heap-changes"
 	COPY_QUEUE_SIZE       = "copy-queue-size"
 	VERBOSE               = "verbose"
 	WITH_STATS            = "with-stats"
@@ -49,42 +66,70 @@ const (
 	TIMESTAMP             = "timestamp"
 	WITH_GLOBALS          = "with-globals"
 	REDIRECT_SCHEMA       = "redirect-schema"
+	REDIRECT_SCHEMA_MAP   = "redirect-schema-map"
+	RENAME_TABLE          = "rename-table"
+	RENAME_TABLE_FILE     = "rename-table-file"
+	ROLE_MAP              = "role-map"
+	NO_OWNER              = "no-owner"
+	NO_PRIVILEGES         = "no-privileges"
+	TABLESPACE_MAP        = "tablespace-map"
+	NO_TABLESPACES        = "no-tablespaces"
 	TRUNCATE_TABLE        = "truncate-table"
 	WITHOUT_GLOBALS       = "without-globals"
 	RESIZE_CLUSTER        = "resize-cluster"
+	RESIZE_ROUTE_DATA     = "resize-route-data"
 	NO_INHERITS           = "no-inherits"
 	REPORT_DIR            = "report-dir"
+	RESUME                = "resume"
 )
 
 func SetBackupFlagDefaults(flagSet *pflag.FlagSet) {
 	flagSet.String(BACKUP_DIR, "", "The absolute path of the directory to which all backup files will be written")
-	flagSet.String(COMPRESSION_TYPE, "gzip", "Type of compression to use during data backup. Valid values are 'gzip', 'zstd'")
+	flagSet.String(COMPRESSION_TYPE, "gzip", "Type of compression to use during data backup. Valid values are 'gzip', 'zstd', 'lz4', 'snappy'")
 	flagSet.Int(COMPRESSION_LEVEL, 1, "Level of compression to use during data backup. Range of valid values depends on compression type")
+	flagSet.String(DATA_FORMAT, "csv", "The format in which to back up table data. Valid values are 'csv', 'binary'")
 	flagSet.Bool(DATA_ONLY, false, "Only back up data, do not back up metadata")
+	flagSet.Int(DATA_STREAMS, 1, "The number of data files each segment writes at the same time when backing up using the --single-data-file option")
 	flagSet.String(DBNAME, "", "The database to be backed up")
 	flagSet.Bool(DEBUG, false, "Print verbose and debug log messages")
+	flagSet.String(DELETE_BACKUP, "", "The timestamp of a backup in the --backup-dir to delete instead of taking a backup. Repository chunks no remaining backup uses are removed")
+	flagSet.Bool(DIFFERENTIAL, false, "Only back up data for AO tables, and heap tables with --track-heap-changes, that have been modified since the last full backup")
+	flagSet.Bool(DRY_RUN, false, "Print the plan for the backup without writing any backup files or history entries")
+	flagSet.String(DRY_RUN_FILE, "", "A file to write the --dry-run plan to instead of printing it. Required with --dry-run-format json")
+	flagSet.String(DRY_RUN_FORMAT, "text", "The format in which to print the --dry-run plan. Valid values are 'text', 'json'")
+	flagSet.String(ENCRYPTION_KEY_FILE, "", "A file containing a 256-bit key with which to encrypt the data, metadata, TOC and statistics files of the backup")
 	flagSet.StringArray(EXCLUDE_SCHEMA, []string{}, "Back up all metadata except objects in the specified schema(s). --exclude-schema can be specified multiple times.")
 	flagSet.String(EXCLUDE_SCHEMA_FILE, "", "A file containing a list of schemas to be excluded from the backup")
 	flagSet.StringArray(EXCLUDE_RELATION, []string{}, "Back up all metadata except the specified table(s). --exclude-table can be specified multiple times.")
 	flagSet.String(EXCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified tables to be excluded from the backup")
-	flagSet.String(FROM_TIMESTAMP, "", "A timestamp to use to base the current incremental backup off")
+	flagSet.String(FROM_TIMESTAMP, "", "A timestamp to use to base the current incremental or differential backup off")
 	flagSet.Bool("help", false, "Help for gpbackup")
 	flagSet.StringArray(INCLUDE_SCHEMA, []string{}, "Back up only the specified schema(s). --include-schema can be specified multiple times.")
 	flagSet.String(INCLUDE_SCHEMA_FILE, "", "A file containing a list of schema(s) to be included in the backup")
 	flagSet.StringArray(INCLUDE_RELATION, []string{}, "Back up only the specified table(s). --include-table can be specified multiple times.")
 	flagSet.String(INCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified tables to be included in the backup")
-	flagSet.Bool(INCREMENTAL, false, "Only back up data for AO tables that have been modified since the last backup")
+	flagSet.Bool(INCREMENTAL, false, "Only back up data for AO tables, and heap tables with --track-heap-changes, that have been modified since the last backup")
 	flagSet.Int(JOBS, 1, "The number of parallel connections to use when backing up data")
 	flagSet.Bool(LEAF_PARTITION_DATA, false, "For partition tables, create one data file per leaf partition instead of one data file for the whole table")
+	flagSet.String(MASKING_RULES_FILE, "", "A YAML file mapping fully-qualified columns to SQL expressions. The data of those columns will be replaced by the result of their expression")
+	flagSet.String(MAX_BANDWIDTH, "", "The maximum bandwidth to use to back up table data, in bytes per second with an optional K, M, G or T suffix")
+	flagSet.String(MAX_BANDWIDTH_SCOPE, "segment", "Whether --max-bandwidth applies to each 'segment' or each segment 'host'")
 	flagSet.Bool(METADATA_ONLY, false, "Only back up metadata, do not back up data")
 	flagSet.Bool(NO_COMPRESSION, false, "Skip compression of data files")
 	flagSet.Bool(NO_HISTORY, false, "Do not write a backup entry to the gpbackup_history database")
 	flagSet.String(PLUGIN_CONFIG, "", "The configuration file to use for a plugin")
+	flagSet.String(PRIORITY_TABLE_FILE, "", "A file containing a list of fully-qualified tables whose data will be backed up before that of all other tables, in the order listed")
 	flagSet.Bool("version", false, "Print version number and exit")
 	flagSet.Bool(QUIET, false, "Suppress non-warning, non-error log messages")
+	flagSet.Bool(REPOSITORY, false, "Store table data in a deduplicated repository in the --backup-dir, which keeps each distinct chunk of data only once across backups")
+	flagSet.String(RESUME, "", "The timestamp of a failed backup to resume. Only data for tables that did not finish will be backed up")
 	flagSet.Bool(SINGLE_BACKUP_DIR, false, "Back up all data to a single directory instead of split by segment")
 	flagSet.Bool(SINGLE_DATA_FILE, false, "Back up all data to a single file instead of one per table")
+	flagSet.Int(STALL_RETRIES, 0, "The number of times to back up the data of a table again after its COPY is canceled for stalling, before the backup fails")
+	flagSet.Int(STALL_TIMEOUT, 0, "The number of seconds a segment may back up no data of a table before its COPY is canceled for stalling. 0 disables stall detection")
 	flagSet.Int(COPY_QUEUE_SIZE, 1, "number of COPY commands gpbackup should enqueue when backing up using the --single-data-file option")
+	flagSet.String(TABLE_PREDICATE_FILE, "", "A YAML file mapping fully-qualified tables to WHERE clauses. Only the rows of those tables matching their clause will be backed up")
+	flagSet.Bool(TRACK_HEAP_CHANGES, false, "Record which heap tables changed, so that incremental and differential backups based off of this one skip unchanged heap tables. Changes are detected from statistics collector counters, which can miss a change if the collector drops a message, so a changed heap table may be skipped")
 	flagSet.Bool(VERBOSE, false, "Print verbose log messages")
 	flagSet.Bool(WITH_STATS, false, "Back up query plan statistics")
 	flagSet.Bool(WITHOUT_GLOBALS, false, "Skip backup of global metadata")
@@ -96,6 +141,7 @@ func SetRestoreFlagDefaults(flagSet *pflag.FlagSet) {
 	flagSet.Bool(CREATE_DB, false, "Create the database before metadata restore")
 	flagSet.Bool(DATA_ONLY, false, "Only restore data, do not restore metadata")
 	flagSet.Bool(DEBUG, false, "Print verbose and debug log messages")
+	flagSet.String(ENCRYPTION_KEY_FILE, "", "A file containing the key with which the backup to be restored was encrypted")
 	flagSet.StringArray(EXCLUDE_SCHEMA, []string{}, "Restore all metadata except objects in the specified schema(s). --exclude-schema can be specified multiple times.")
 	flagSet.String(EXCLUDE_SCHEMA_FILE, "", "A file containing a list of schemas that will not be restored")
 	flagSet.StringArray(EXCLUDE_RELATION, []string{}, "Restore all metadata except the specified relation(s). --exclude-table can be specified multiple times.")
@@ -105,15 +151,26 @@ func SetRestoreFlagDefaults(flagSet *pflag.FlagSet) {
 	flagSet.String(INCLUDE_SCHEMA_FILE, "", "A file containing a list of schemas that will be restored")
 	flagSet.StringArray(INCLUDE_RELATION, []string{}, "Restore only the specified relation(s). --include-table can be specified multiple times.")
 	flagSet.String(INCLUDE_RELATION_FILE, "", "A file containing a list of fully-qualified relation(s) that will be restored")
-	flagSet.Bool(INCREMENTAL, false, "BETA FEATURE: Only restore data for all heap tables and only AO tables that have been modified since the last backup")
+	flagSet.Bool(INCREMENTAL, false, "BETA FEATURE: Only restore data for AO and heap tables that have been modified since the last backup")
+	flagSet.String(MAX_BANDWIDTH, "", "The maximum bandwidth to use to restore table data, in bytes per second with an optional K, M, G or T suffix")
+	flagSet.String(MAX_BANDWIDTH_SCOPE, "segment", "Whether --max-bandwidth applies to each 'segment' or each segment 'host'")
 	flagSet.Bool(METADATA_ONLY, false, "Only restore metadata, do not restore data")
 	flagSet.Int(JOBS, 1, "Number of parallel connections to use when restoring table data and post-data")
 	flagSet.Bool(ON_ERROR_CONTINUE, false, "Log errors and continue restore, instead of exiting on first error")
 	flagSet.String(PLUGIN_CONFIG, "", "The configuration file to use for a plugin")
+	flagSet.String(PRIORITY_TABLE_FILE, "", "A file containing a list of fully-qualified tables whose data will be restored before that of all other tables, in the order listed")
 	flagSet.Bool("version", false, "Print version number and exit")
 	flagSet.Bool(QUIET, false, "Suppress non-warning, non-error log messages")
 	flagSet.String(REDIRECT_DB, "", "Restore to the specified database instead of the database that was backed up")
 	flagSet.String(REDIRECT_SCHEMA, "", "Restore to the specified schema instead of the schema that was backed up")
+	flagSet.String(REDIRECT_SCHEMA_MAP, "", "A file containing a list of schema=new_schema mappings of schemas that will be restored to other schemas")
+	flagSet.StringArray(RENAME_TABLE, []string{}, "Restore a table under a new name, in the format schema.table=new_schema.new_table. --rename-table can be specified multiple times.")
+	flagSet.String(RENAME_TABLE_FILE, "", "A file containing a list of schema.table=new_schema.new_table mappings of tables that will be restored under new names")
+	flagSet.String(ROLE_MAP, "", "A file containing a list of role=new_role mappings of roles that will be replaced by other roles in restored metadata")
+	flagSet.Bool(NO_OWNER, false, "Do not restore the owners of objects, so that they are owned by the user running the restore")
+	flagSet.Bool(NO_PRIVILEGES, false, "Do not restore the privileges granted on objects")
+	flagSet.StringArray(TABLESPACE_MAP, []string{}, "Restore objects in a tablespace to another tablespace, in the format tablespace=new_tablespace. --tablespace-map can be specified multiple times.")
+	flagSet.Bool(NO_TABLESPACES, false, "Do not restore tablespaces, so that objects are restored to the default tablespace")
 	flagSet.Int(COPY_QUEUE_SIZE, 1, "Number of COPY commands gprestore should enqueue when restoring a backup taken using the --single-data-file option")
 	flagSet.Bool(WITH_GLOBALS, false, "Restore global metadata")
 	flagSet.String(TIMESTAMP, "", "The timestamp to be restored, in the format YYYYMMDDHHMMSS")
@@ -123,7 +180,9 @@ func SetRestoreFlagDefaults(flagSet *pflag.FlagSet) {
 	flagSet.Bool(LEAF_PARTITION_DATA, false, "For partition tables, create one data file per leaf partition instead of one data file for the whole table")
 	flagSet.Bool(RUN_ANALYZE, false, "Run ANALYZE on restored tables")
 	flagSet.Bool(RESIZE_CLUSTER, false, "Restore a backup taken on a cluster with more or fewer segments than the cluster to which it will be restored")
+	flagSet.Bool(RESIZE_ROUTE_DATA, false, "When restoring with --resize-cluster, load the data of hash distributed tables directly onto the segments it belongs on instead of redistributing it afterwards")
 	flagSet.String(REPORT_DIR, "", "The absolute path of the directory to which restore report and error tables will be written")
+	flagSet.String(RESUME, "", "The timestamp of a failed restore to resume. Only metadata and data that were not completely restored will be restored")
 	_ = flagSet.MarkHidden(LEAF_PARTITION_DATA)
 }
 
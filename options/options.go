@@ -2,6 +2,7 @@ package options
 
 import (
 	"fmt"
+	"io/ioutil"
 	"regexp"
 	"strings"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v2"
 )
 
 // This is meant to be a read only package. Values inside should only be
@@ -24,6 +26,15 @@ type Options struct {
 	IncludedSchemas           []string
 	originalIncludedRelations []string
 	RedirectSchema            string
+	RedirectSchemaMap         map[string]string
+	RoleMap                   map[string]string
+	TablespaceMap             map[string]string
+	RenamedRelations          map[string]string
+	PriorityRelations         []string
+	TablePredicates           map[string]string
+	MaskingRules              map[string]string
+	MaxBandwidth              int64
+	MaxBandwidthPerHost       bool
 }
 
 func NewOptions(initialFlags *pflag.FlagSet) (*Options, error) {
@@ -68,6 +79,53 @@ func NewOptions(initialFlags *pflag.FlagSet) (*Options, error) {
 		}
 	}
 
+	redirectSchemaMap, err := readNameMapFromFile(initialFlags, REDIRECT_SCHEMA_MAP, "Schema", "schema=new_schema")
+	if err != nil {
+		return nil, err
+	}
+
+	roleMap, err := readNameMapFromFile(initialFlags, ROLE_MAP, "Role", "role=new_role")
+	if err != nil {
+		return nil, err
+	}
+
+	tablespaceMap := make(map[string]string)
+	if initialFlags.Lookup(TABLESPACE_MAP) != nil {
+		tablespaceMappings, err := initialFlags.GetStringArray(TABLESPACE_MAP)
+		if err != nil {
+			return nil, err
+		}
+		tablespaceMap, err = parseNameMappings(tablespaceMappings, "Tablespace", "tablespace=new_tablespace")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	renamedRelations, err := readRenamedRelations(initialFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	priorityRelations, err := readPriorityRelationsFromFile(initialFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	tablePredicates, err := readTablePredicatesFromFile(initialFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	maskingRules, err := readMaskingRulesFromFile(initialFlags)
+	if err != nil {
+		return nil, err
+	}
+
+	maxBandwidth, maxBandwidthPerHost, err := getMaxBandwidth(initialFlags)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Options{
 		IncludedRelations:         includedRelations,
 		ExcludedRelations:         excludedRelations,
@@ -76,9 +134,142 @@ func NewOptions(initialFlags *pflag.FlagSet) (*Options, error) {
 		isLeafPartitionData:       leafPartitionData,
 		originalIncludedRelations: includedRelations,
 		RedirectSchema:            redirectSchema,
+		RedirectSchemaMap:         redirectSchemaMap,
+		RoleMap:                   roleMap,
+		TablespaceMap:             tablespaceMap,
+		RenamedRelations:          renamedRelations,
+		PriorityRelations:         priorityRelations,
+		TablePredicates:           tablePredicates,
+		MaskingRules:              maskingRules,
+		MaxBandwidth:              maxBandwidth,
+		MaxBandwidthPerHost:       maxBandwidthPerHost,
 	}, nil
 }
 
+func readPriorityRelationsFromFile(initialFlags *pflag.FlagSet) ([]string, error) {
+	priorityRelations := make([]string, 0)
+	if initialFlags.Lookup(PRIORITY_TABLE_FILE) == nil {
+		return priorityRelations, nil
+	}
+	filename, err := initialFlags.GetString(PRIORITY_TABLE_FILE)
+	if err != nil || filename == "" {
+		return priorityRelations, err
+	}
+	lines, err := iohelper.ReadLinesFromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	for _, fqn := range lines {
+		if fqn != "" {
+			priorityRelations = append(priorityRelations, fqn)
+		}
+	}
+	err = utils.ValidateFQNs(priorityRelations)
+	if err != nil {
+		return nil, err
+	}
+	return priorityRelations, nil
+}
+
+/*
+ * Each schema to restore to another schema, and each role or tablespace to
+ * replace with another one, is given as a mapping from its name to the name of
+ * the one to use instead, for example:
+ *
+ *   app_a=stage_a
+ */
+func readNameMapFromFile(initialFlags *pflag.FlagSet, flagName string, objectType string, format string) (map[string]string, error) {
+	nameMap := make(map[string]string)
+	if initialFlags.Lookup(flagName) == nil {
+		return nameMap, nil
+	}
+	filename, err := initialFlags.GetString(flagName)
+	if err != nil || filename == "" {
+		return nameMap, err
+	}
+	mappings, err := iohelper.ReadLinesFromFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return parseNameMappings(mappings, objectType, format)
+}
+
+func parseNameMappings(mappings []string, objectType string, format string) (map[string]string, error) {
+	nameMap := make(map[string]string)
+	for _, mapping := range mappings {
+		mapping = strings.TrimSpace(mapping)
+		if mapping == "" {
+			continue
+		}
+		names := strings.Split(mapping, "=")
+		oldName, newName := "", ""
+		if len(names) == 2 {
+			oldName, newName = strings.TrimSpace(names[0]), strings.TrimSpace(names[1])
+		}
+		if oldName == "" || newName == "" {
+			return nil, errors.Errorf(`%s mapping "%s" is invalid.  Please ensure it is in the format "%s".`, objectType, mapping, format)
+		}
+		if _, ok := nameMap[oldName]; ok {
+			return nil, errors.Errorf("%s %s cannot be mapped more than once", objectType, oldName)
+		}
+		nameMap[oldName] = newName
+	}
+	return nameMap, nil
+}
+
+/*
+ * Each table to restore under a new name is given as a mapping from its
+ * fully-qualified name to its new one, for example:
+ *
+ *   sales.orders=sales.orders_20261001
+ */
+func readRenamedRelations(initialFlags *pflag.FlagSet) (map[string]string, error) {
+	renamedRelations := make(map[string]string)
+	if initialFlags.Lookup(RENAME_TABLE) == nil {
+		return renamedRelations, nil
+	}
+	mappings, err := initialFlags.GetStringArray(RENAME_TABLE)
+	if err != nil {
+		return nil, err
+	}
+	filename, err := initialFlags.GetString(RENAME_TABLE_FILE)
+	if err != nil {
+		return nil, err
+	}
+	if filename != "" {
+		lines, err := iohelper.ReadLinesFromFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		mappings = append(mappings, lines...)
+	}
+	renamedFrom := make(map[string]string)
+	for _, mapping := range mappings {
+		mapping = strings.TrimSpace(mapping)
+		if mapping == "" {
+			continue
+		}
+		fqns := strings.Split(mapping, "=")
+		if len(fqns) != 2 {
+			return nil, errors.Errorf(`Table mapping "%s" is invalid.  Please ensure it is in the format "schema.table=new_schema.new_table".`, mapping)
+		}
+		oldFQN, newFQN := strings.TrimSpace(fqns[0]), strings.TrimSpace(fqns[1])
+		err = utils.ValidateFQNs([]string{oldFQN, newFQN})
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := renamedRelations[oldFQN]; ok {
+			return nil, errors.Errorf("Table %s cannot be renamed more than once", oldFQN)
+		}
+		if otherFQN, ok := renamedFrom[newFQN]; ok {
+			return nil, errors.Errorf("Tables %s and %s cannot both be renamed to %s", otherFQN, oldFQN, newFQN)
+		}
+		renamedRelations[oldFQN] = newFQN
+		renamedFrom[newFQN] = oldFQN
+	}
+	return renamedRelations, nil
+}
+
 func setFiltersFromFile(initialFlags *pflag.FlagSet, filterFlag string, filterFileFlag string) ([]string, error) {
 	filters, err := initialFlags.GetStringArray(filterFlag)
 	if err != nil {
@@ -112,6 +303,112 @@ func setFiltersFromFile(initialFlags *pflag.FlagSet, filterFlag string, filterFi
 	return filters, nil
 }
 
+/*
+ * The table predicate file is a YAML map from fully-qualified table names to
+ * the WHERE clauses used to filter their data, for example:
+ *
+ *   public.sales: sale_date >= '2022-01-01'
+ */
+func readTablePredicatesFromFile(initialFlags *pflag.FlagSet) (map[string]string, error) {
+	tablePredicates := make(map[string]string)
+	if initialFlags.Lookup(TABLE_PREDICATE_FILE) == nil {
+		return tablePredicates, nil
+	}
+	filename, err := initialFlags.GetString(TABLE_PREDICATE_FILE)
+	if err != nil || filename == "" {
+		return tablePredicates, err
+	}
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.UnmarshalStrict(contents, &tablePredicates)
+	if err != nil {
+		return nil, errors.Errorf("Unable to parse table predicate file %s: %v", filename, err)
+	}
+	fqns := make([]string, 0, len(tablePredicates))
+	for fqn, predicate := range tablePredicates {
+		if strings.TrimSpace(predicate) == "" {
+			return nil, errors.Errorf(`Table "%s" in table predicate file %s has an empty predicate`, fqn, filename)
+		}
+		fqns = append(fqns, fqn)
+	}
+	err = utils.ValidateFQNs(fqns)
+	if err != nil {
+		return nil, err
+	}
+	return tablePredicates, nil
+}
+
+/*
+ * The masking rules file is a YAML map from fully-qualified column names to
+ * the SQL expressions whose results replace the data of those columns, for
+ * example:
+ *
+ *   public.customers.email: md5(email)
+ *   public.customers.phone: "NULL"
+ */
+func readMaskingRulesFromFile(initialFlags *pflag.FlagSet) (map[string]string, error) {
+	maskingRules := make(map[string]string)
+	if initialFlags.Lookup(MASKING_RULES_FILE) == nil {
+		return maskingRules, nil
+	}
+	filename, err := initialFlags.GetString(MASKING_RULES_FILE)
+	if err != nil || filename == "" {
+		return maskingRules, err
+	}
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.UnmarshalStrict(contents, &maskingRules)
+	if err != nil {
+		return nil, errors.Errorf("Unable to parse masking rules file %s: %v", filename, err)
+	}
+	validFormat := regexp.MustCompile(`^.+\..+\..+$`)
+	for column, expression := range maskingRules {
+		if !validFormat.MatchString(column) {
+			return nil, errors.Errorf(`Column "%s" in masking rules file %s is not correctly fully-qualified.  Please ensure column is in the format "schema.table.column".`, column, filename)
+		}
+		if strings.TrimSpace(expression) == "" {
+			return nil, errors.Errorf(`Column "%s" in masking rules file %s has an empty expression`, column, filename)
+		}
+	}
+	return maskingRules, nil
+}
+
+// Returns the maximum bandwidth in bytes per second, and whether it applies to each host instead of each segment
+func getMaxBandwidth(initialFlags *pflag.FlagSet) (int64, bool, error) {
+	if initialFlags.Lookup(MAX_BANDWIDTH) == nil {
+		return 0, false, nil
+	}
+	maxBandwidthStr, err := initialFlags.GetString(MAX_BANDWIDTH)
+	if err != nil {
+		return 0, false, err
+	}
+	scope, err := initialFlags.GetString(MAX_BANDWIDTH_SCOPE)
+	if err != nil {
+		return 0, false, err
+	}
+	if scope != "segment" && scope != "host" {
+		return 0, false, errors.Errorf("Invalid --max-bandwidth-scope value %s.  Please specify 'segment' or 'host'.", scope)
+	}
+	if maxBandwidthStr == "" {
+		if initialFlags.Changed(MAX_BANDWIDTH_SCOPE) {
+			return 0, false, errors.Errorf("--max-bandwidth-scope must be specified with --max-bandwidth")
+		}
+		return 0, false, nil
+	}
+	maxBandwidth, err := utils.ParseByteSize(maxBandwidthStr)
+	if err != nil {
+		return 0, false, err
+	}
+	if maxBandwidth <= 0 {
+		return 0, false, errors.Errorf("--max-bandwidth must be greater than 0")
+	}
+	return maxBandwidth, scope == "host", nil
+}
+
 func (o Options) GetIncludedTables() []string {
 	return o.IncludedRelations
 }
@@ -245,6 +542,49 @@ func (o *Options) QuoteExcludeRelations(conn *dbconn.DBConn) error {
 	return nil
 }
 
+func (o *Options) QuoteRenamedRelations(conn *dbconn.DBConn) error {
+	oldFQNs := make([]string, 0, len(o.RenamedRelations))
+	newFQNs := make([]string, 0, len(o.RenamedRelations))
+	for oldFQN, newFQN := range o.RenamedRelations {
+		oldFQNs = append(oldFQNs, oldFQN)
+		newFQNs = append(newFQNs, newFQN)
+	}
+	quotedOldFQNs, err := QuoteTableNames(conn, oldFQNs)
+	if err != nil {
+		return err
+	}
+	quotedNewFQNs, err := QuoteTableNames(conn, newFQNs)
+	if err != nil {
+		return err
+	}
+	o.RenamedRelations = make(map[string]string, len(quotedOldFQNs))
+	for i := range quotedOldFQNs {
+		o.RenamedRelations[quotedOldFQNs[i]] = quotedNewFQNs[i]
+	}
+
+	return nil
+}
+
+func (o *Options) QuoteRoleMap(conn *dbconn.DBConn) {
+	o.RoleMap = quoteNameMap(conn, o.RoleMap)
+}
+
+func (o *Options) QuoteTablespaceMap(conn *dbconn.DBConn) {
+	o.TablespaceMap = quoteNameMap(conn, o.TablespaceMap)
+}
+
+func (o *Options) QuoteRedirectSchemaMap(conn *dbconn.DBConn) {
+	o.RedirectSchemaMap = quoteNameMap(conn, o.RedirectSchemaMap)
+}
+
+func quoteNameMap(conn *dbconn.DBConn, nameMap map[string]string) map[string]string {
+	quotedNameMap := make(map[string]string, len(nameMap))
+	for oldName, newName := range nameMap {
+		quotedNameMap[utils.QuoteIdent(conn, oldName)] = utils.QuoteIdent(conn, newName)
+	}
+	return quotedNameMap
+}
+
 // given a set of table oids, return a deduplicated set of other tables that EITHER depend
 // on them, OR that they depend on. The behavior for which is set with recurseDirection.
 func (o *Options) recurseTableDepend(conn *dbconn.DBConn, includeOids []string, tablesToRetrieve string, getLeafPartitions bool) ([]string, error) {
@@ -202,6 +202,342 @@ var _ = Describe("options", func() {
 			_, err = options.NewOptions(myflags)
 			Expect(err).To(HaveOccurred())
 		})
+		It("returns the priority tables from file in the order listed", func() {
+			file, err := ioutil.TempFile("/tmp", "gpbackup_test_options*.txt")
+			Expect(err).To(Not(HaveOccurred()))
+			defer func() {
+				_ = os.Remove(file.Name())
+			}()
+			_, err = file.WriteString("myschema.mytable2\n\nmyschema.mytable\n")
+			Expect(err).To(Not(HaveOccurred()))
+			err = file.Close()
+			Expect(err).To(Not(HaveOccurred()))
+
+			err = myflags.Set(options.PRIORITY_TABLE_FILE, file.Name())
+			Expect(err).ToNot(HaveOccurred())
+			subject, err := options.NewOptions(myflags)
+			Expect(err).To(Not(HaveOccurred()))
+
+			Expect(subject.PriorityRelations).To(Equal([]string{"myschema.mytable2", "myschema.mytable"}))
+		})
+		It("returns an error upon invalid priority tables", func() {
+			file, err := ioutil.TempFile("/tmp", "gpbackup_test_options*.txt")
+			Expect(err).To(Not(HaveOccurred()))
+			defer func() {
+				_ = os.Remove(file.Name())
+			}()
+			_, err = file.WriteString("mytable\n")
+			Expect(err).To(Not(HaveOccurred()))
+			err = file.Close()
+			Expect(err).To(Not(HaveOccurred()))
+
+			err = myflags.Set(options.PRIORITY_TABLE_FILE, file.Name())
+			Expect(err).ToNot(HaveOccurred())
+			_, err = options.NewOptions(myflags)
+			Expect(err).To(HaveOccurred())
+		})
+		Context("table predicate file", func() {
+			var file *os.File
+			BeforeEach(func() {
+				var err error
+				file, err = ioutil.TempFile("/tmp", "gpbackup_test_options*.yaml")
+				Expect(err).To(Not(HaveOccurred()))
+			})
+			AfterEach(func() {
+				_ = os.Remove(file.Name())
+			})
+			It("returns the predicate of each table", func() {
+				_, err := file.WriteString("public.sales: sale_date >= '2022-01-01'\nmyschema.tenants: \"tenant_id IN (1, 2)\"\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.TABLE_PREDICATE_FILE, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				subject, err := options.NewOptions(myflags)
+				Expect(err).To(Not(HaveOccurred()))
+
+				Expect(subject.TablePredicates).To(Equal(map[string]string{
+					"public.sales":     "sale_date >= '2022-01-01'",
+					"myschema.tenants": "tenant_id IN (1, 2)",
+				}))
+			})
+			It("returns an error if a table is not fully-qualified", func() {
+				_, err := file.WriteString("sales: sale_date >= '2022-01-01'\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.TABLE_PREDICATE_FILE, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(HaveOccurred())
+			})
+			It("returns an error if a predicate is empty", func() {
+				_, err := file.WriteString("public.sales: \"\"\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.TABLE_PREDICATE_FILE, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError(ContainSubstring(`Table "public.sales" in table predicate file`)))
+			})
+			It("returns an error if the file is not a YAML map", func() {
+				_, err := file.WriteString("- public.sales\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.TABLE_PREDICATE_FILE, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError(ContainSubstring("Unable to parse table predicate file")))
+			})
+		})
+		Context("masking rules file", func() {
+			var file *os.File
+			BeforeEach(func() {
+				var err error
+				file, err = ioutil.TempFile("/tmp", "gpbackup_test_options*.yaml")
+				Expect(err).To(Not(HaveOccurred()))
+			})
+			AfterEach(func() {
+				_ = os.Remove(file.Name())
+			})
+			It("returns the expression of each column", func() {
+				_, err := file.WriteString("public.customers.email: md5(email)\npublic.customers.phone: \"NULL\"\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.MASKING_RULES_FILE, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				subject, err := options.NewOptions(myflags)
+				Expect(err).To(Not(HaveOccurred()))
+
+				Expect(subject.MaskingRules).To(Equal(map[string]string{
+					"public.customers.email": "md5(email)",
+					"public.customers.phone": "NULL",
+				}))
+			})
+			It("returns an error if a column is not fully-qualified", func() {
+				_, err := file.WriteString("customers.email: md5(email)\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.MASKING_RULES_FILE, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError(ContainSubstring(`Column "customers.email" in masking rules file`)))
+			})
+			It("returns an error if an expression is empty", func() {
+				_, err := file.WriteString("public.customers.email: \"\"\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.MASKING_RULES_FILE, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError(ContainSubstring("has an empty expression")))
+			})
+		})
+		Context("redirect schema map", func() {
+			var file *os.File
+			BeforeEach(func() {
+				myflags = &pflag.FlagSet{}
+				options.SetRestoreFlagDefaults(myflags)
+				var err error
+				file, err = ioutil.TempFile("/tmp", "gpbackup_test_options*.txt")
+				Expect(err).To(Not(HaveOccurred()))
+			})
+			AfterEach(func() {
+				_ = os.Remove(file.Name())
+			})
+			It("returns the schema each schema is redirected to", func() {
+				_, err := file.WriteString("app_a=stage_a\n\napp_b = stage_b\napp_c=stage_a\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.REDIRECT_SCHEMA_MAP, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				subject, err := options.NewOptions(myflags)
+				Expect(err).To(Not(HaveOccurred()))
+
+				Expect(subject.RedirectSchemaMap).To(Equal(map[string]string{
+					"app_a": "stage_a",
+					"app_b": "stage_b",
+					"app_c": "stage_a",
+				}))
+			})
+			It("returns an error if a mapping is not in the right format", func() {
+				_, err := file.WriteString("app_a=\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.REDIRECT_SCHEMA_MAP, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError(ContainSubstring(`Schema mapping "app_a=" is invalid`)))
+			})
+			It("returns an error if a schema is mapped more than once", func() {
+				_, err := file.WriteString("app_a=stage_a\napp_a=stage_b\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.REDIRECT_SCHEMA_MAP, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError("Schema app_a cannot be mapped more than once"))
+			})
+		})
+		Context("role map", func() {
+			It("returns the role each role is replaced by", func() {
+				file, err := ioutil.TempFile("/tmp", "gpbackup_test_options*.txt")
+				Expect(err).To(Not(HaveOccurred()))
+				defer os.Remove(file.Name())
+				_, err = file.WriteString("app_owner=stage_owner\nApp Reader=stage_reader\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				myflags = &pflag.FlagSet{}
+				options.SetRestoreFlagDefaults(myflags)
+				err = myflags.Set(options.ROLE_MAP, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				subject, err := options.NewOptions(myflags)
+				Expect(err).To(Not(HaveOccurred()))
+
+				Expect(subject.RoleMap).To(Equal(map[string]string{
+					"app_owner":  "stage_owner",
+					"App Reader": "stage_reader",
+				}))
+			})
+		})
+		Context("tablespace map", func() {
+			BeforeEach(func() {
+				myflags = &pflag.FlagSet{}
+				options.SetRestoreFlagDefaults(myflags)
+			})
+			It("returns the tablespace each tablespace is replaced by", func() {
+				Expect(myflags.Set(options.TABLESPACE_MAP, "fast_disk=pg_default")).To(Succeed())
+				Expect(myflags.Set(options.TABLESPACE_MAP, "slow_disk=archive")).To(Succeed())
+				subject, err := options.NewOptions(myflags)
+				Expect(err).To(Not(HaveOccurred()))
+
+				Expect(subject.TablespaceMap).To(Equal(map[string]string{
+					"fast_disk": "pg_default",
+					"slow_disk": "archive",
+				}))
+			})
+			It("returns an error if a mapping is not in the right format", func() {
+				Expect(myflags.Set(options.TABLESPACE_MAP, "fast_disk")).To(Succeed())
+				_, err := options.NewOptions(myflags)
+				Expect(err).To(MatchError(`Tablespace mapping "fast_disk" is invalid.  Please ensure it is in the format "tablespace=new_tablespace".`))
+			})
+		})
+		Context("renamed tables", func() {
+			var file *os.File
+			BeforeEach(func() {
+				myflags = &pflag.FlagSet{}
+				options.SetRestoreFlagDefaults(myflags)
+				var err error
+				file, err = ioutil.TempFile("/tmp", "gpbackup_test_options*.txt")
+				Expect(err).To(Not(HaveOccurred()))
+			})
+			AfterEach(func() {
+				_ = os.Remove(file.Name())
+			})
+			It("returns the new name of each table from the flags and the file", func() {
+				_, err := file.WriteString("sales.orders=archive.orders_2026\n\npublic.a = public.b\n")
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(file.Close()).To(Succeed())
+
+				err = myflags.Set(options.RENAME_TABLE, "public.b=public.a")
+				Expect(err).ToNot(HaveOccurred())
+				err = myflags.Set(options.RENAME_TABLE_FILE, file.Name())
+				Expect(err).ToNot(HaveOccurred())
+				subject, err := options.NewOptions(myflags)
+				Expect(err).To(Not(HaveOccurred()))
+
+				Expect(subject.RenamedRelations).To(Equal(map[string]string{
+					"sales.orders": "archive.orders_2026",
+					"public.a":     "public.b",
+					"public.b":     "public.a",
+				}))
+			})
+			It("returns an error if a mapping is not in the right format", func() {
+				err := myflags.Set(options.RENAME_TABLE, "sales.orders")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError(ContainSubstring(`Table mapping "sales.orders" is invalid`)))
+			})
+			It("returns an error if a table is not fully-qualified", func() {
+				err := myflags.Set(options.RENAME_TABLE, "sales.orders=orders_2026")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(HaveOccurred())
+			})
+			It("returns an error if a table is renamed more than once", func() {
+				Expect(myflags.Set(options.RENAME_TABLE, "sales.orders=sales.orders1")).To(Succeed())
+				Expect(myflags.Set(options.RENAME_TABLE, "sales.orders=sales.orders2")).To(Succeed())
+				_, err := options.NewOptions(myflags)
+				Expect(err).To(MatchError("Table sales.orders cannot be renamed more than once"))
+			})
+			It("returns an error if two tables are renamed to the same name", func() {
+				Expect(myflags.Set(options.RENAME_TABLE, "sales.orders1=sales.orders")).To(Succeed())
+				Expect(myflags.Set(options.RENAME_TABLE, "sales.orders2=sales.orders")).To(Succeed())
+				_, err := options.NewOptions(myflags)
+				Expect(err).To(MatchError("Tables sales.orders1 and sales.orders2 cannot both be renamed to sales.orders"))
+			})
+		})
+		Context("max bandwidth", func() {
+			It("does not limit bandwidth by default", func() {
+				subject, err := options.NewOptions(myflags)
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(subject.MaxBandwidth).To(Equal(int64(0)))
+				Expect(subject.MaxBandwidthPerHost).To(BeFalse())
+			})
+			It("parses the maximum bandwidth for each segment", func() {
+				err := myflags.Set(options.MAX_BANDWIDTH, "100MB")
+				Expect(err).ToNot(HaveOccurred())
+				subject, err := options.NewOptions(myflags)
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(subject.MaxBandwidth).To(Equal(int64(100 * 1024 * 1024)))
+				Expect(subject.MaxBandwidthPerHost).To(BeFalse())
+			})
+			It("parses the maximum bandwidth for each host", func() {
+				err := myflags.Set(options.MAX_BANDWIDTH, "1G")
+				Expect(err).ToNot(HaveOccurred())
+				err = myflags.Set(options.MAX_BANDWIDTH_SCOPE, "host")
+				Expect(err).ToNot(HaveOccurred())
+				subject, err := options.NewOptions(myflags)
+				Expect(err).To(Not(HaveOccurred()))
+				Expect(subject.MaxBandwidth).To(Equal(int64(1024 * 1024 * 1024)))
+				Expect(subject.MaxBandwidthPerHost).To(BeTrue())
+			})
+			It("returns an error for an invalid bandwidth", func() {
+				err := myflags.Set(options.MAX_BANDWIDTH, "fast")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError(ContainSubstring("Invalid size fast")))
+			})
+			It("returns an error for a bandwidth of 0", func() {
+				err := myflags.Set(options.MAX_BANDWIDTH, "0")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError("--max-bandwidth must be greater than 0"))
+			})
+			It("returns an error for an invalid scope", func() {
+				err := myflags.Set(options.MAX_BANDWIDTH, "100M")
+				Expect(err).ToNot(HaveOccurred())
+				err = myflags.Set(options.MAX_BANDWIDTH_SCOPE, "cluster")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError(ContainSubstring("Invalid --max-bandwidth-scope value cluster")))
+			})
+			It("returns an error if the scope is given without a bandwidth", func() {
+				err := myflags.Set(options.MAX_BANDWIDTH_SCOPE, "host")
+				Expect(err).ToNot(HaveOccurred())
+				_, err = options.NewOptions(myflags)
+				Expect(err).To(MatchError("--max-bandwidth-scope must be specified with --max-bandwidth"))
+			})
+		})
 		Describe("AddIncludeRelation", func() {
 			It("it adds a relation", func() {
 				subject, err := options.NewOptions(myflags)
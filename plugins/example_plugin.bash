@@ -80,6 +80,14 @@ restore_data() {
 	cat /tmp/plugin_dest/$timestamp_day_dir/$timestamp_dir/$filename
 }
 
+restore_data_from_offset() {
+  echo "restore_data_from_offset $1 $2 $3" >> /tmp/plugin_out.txt
+  filename=`basename "$2"`
+  timestamp_dir=`basename $(dirname "$2")`
+  timestamp_day_dir=${timestamp_dir%??????}
+	tail -c +$(($3 + 1)) /tmp/plugin_dest/$timestamp_day_dir/$timestamp_dir/$filename
+}
+
 delete_backup() {
   echo "delete_backup $1 $2" >> /tmp/plugin_out.txt
   timestamp_day_dir=${2%??????}
@@ -204,6 +204,23 @@ echo "[PASSED] backup_data with no data"
 echo "[PASSED] restore_data with no data"
 cleanup_test_dir $testdir
 
+# ----------------------------------------------
+# Restore data from offset function
+# ----------------------------------------------
+if grep -q "restore_resume: *on" $plugin_config; then
+  echo "[RUNNING] backup_data for restore from offset"
+  echo $data | $plugin backup_data $plugin_config $testdata
+  echo "[RUNNING] restore_data_from_offset"
+  output=`$plugin restore_data_from_offset $plugin_config $testdata 3`
+  data_from_offset=$(echo $data | cut -c4-)
+  if [ "$output" != "$data_from_offset" ]; then
+    echo "Failure in restore_data_from_offset using plugin"
+    exit 1
+  fi
+  echo "[PASSED] restore_data_from_offset"
+  cleanup_test_dir $testdir
+fi
+
 # ----------------------------------------------
 # Restore subset data functions
 # ----------------------------------------------
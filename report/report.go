@@ -29,6 +29,8 @@ import (
 type Report struct {
 	BackupParamsString string
 	DatabaseSize       string
+	PartialTables      map[string]string // Predicates of the tables in the backup set, keyed by table FQN
+	SegmentDataBytes   map[int]int64
 	history.BackupConfig
 }
 
@@ -69,6 +71,10 @@ func (report *Report) ConstructBackupParamsString() {
 	if report.Compressed {
 		compressStr = program.Name
 	}
+	encryptionStr := "None"
+	if report.EncryptionFingerprint != "" {
+		encryptionStr = fmt.Sprintf("AES-256-GCM (key fingerprint %s)", report.EncryptionFingerprint)
+	}
 	pluginStr := "None"
 	if report.Plugin != "" {
 		pluginStr = report.Plugin
@@ -83,21 +89,26 @@ func (report *Report) ConstructBackupParamsString() {
 	filesStr := "Multiple Data Files Per Segment"
 	if report.MetadataOnly {
 		filesStr = "No Data Files"
+	} else if report.SingleDataFile && report.DataStreams > 1 {
+		filesStr = fmt.Sprintf("Single Data File Per Segment With %d Data Streams", report.DataStreams)
 	} else if report.SingleDataFile {
 		filesStr = "Single Data File Per Segment"
+	} else if report.Repository {
+		filesStr = "Deduplicated Repository"
 	}
 	statsStr := "No"
 	if report.WithStatistics {
 		statsStr = "Yes"
 	}
 	backupParamsTemplate := `compression: %s
+encryption: %s
 plugin executable: %s
 backup section: %s
 object filtering: %s
 includes statistics: %s
 data file format: %s
 %s`
-	report.BackupParamsString = fmt.Sprintf(backupParamsTemplate, compressStr, pluginStr, sectionStr, filterStr,
+	report.BackupParamsString = fmt.Sprintf(backupParamsTemplate, compressStr, encryptionStr, pluginStr, sectionStr, filterStr,
 		statsStr, filesStr, report.constructIncrementalSection())
 }
 
@@ -109,9 +120,13 @@ func (report *Report) constructIncrementalSection() string {
 	for _, restorePlanEntry := range report.RestorePlan {
 		backupTimestamps = append(backupTimestamps, restorePlanEntry.Timestamp)
 	}
-	return fmt.Sprintf(`incremental: True
+	differentialStr := ""
+	if report.Differential {
+		differentialStr = "\ndifferential: True"
+	}
+	return fmt.Sprintf(`incremental: True%s
 incremental backup set:
-%s`, strings.Join(backupTimestamps, "\n"))
+%s`, differentialStr, strings.Join(backupTimestamps, "\n"))
 }
 
 func (report *Report) WriteBackupReportFile(reportFilename string, timestamp string, endtime time.Time, objectCounts map[string]int, errMsg string) {
@@ -158,6 +173,7 @@ func (report *Report) WriteBackupReportFile(reportFilename string, timestamp str
 	}
 	reportInfo = append(reportInfo,
 		LineInfo{Key: "segment count:", Value: fmt.Sprintf("%d", report.SegmentCount)})
+	reportInfo = append(reportInfo, GetSegmentSkewInfo(report.SegmentDataBytes)...)
 
 	_, err = fmt.Fprint(reportFile, "Greenplum Database Backup Report\n\n")
 	if err != nil {
@@ -168,6 +184,8 @@ func (report *Report) WriteBackupReportFile(reportFilename string, timestamp str
 	logOutputReport(reportFile, reportInfo)
 
 	PrintObjectCounts(reportFile, objectCounts)
+	PrintTablePredicates(reportFile, report.PartialTables)
+	PrintMaskingRules(reportFile, report.MaskingRules)
 
 	err = reportFile.Close()
 	gplog.FatalOnError(err)
@@ -225,6 +243,44 @@ func WriteRestoreReportFile(reportFilename string, backupTimestamp string, start
 	_ = operating.System.Chmod(reportFilename, 0444)
 }
 
+/*
+ * Summarizes how evenly the table data backed up is spread over the segments,
+ * as the size of the largest and smallest segment data and the ratio of the
+ * largest to the average, which is 1 for data that is spread evenly.
+ */
+func GetSegmentSkewInfo(segmentDataBytes map[int]int64) []LineInfo {
+	if len(segmentDataBytes) == 0 {
+		return []LineInfo{}
+	}
+	contentIDs := make([]int, 0, len(segmentDataBytes))
+	for contentID := range segmentDataBytes {
+		contentIDs = append(contentIDs, contentID)
+	}
+	sort.Ints(contentIDs)
+	totalBytes := int64(0)
+	largest, smallest := contentIDs[0], contentIDs[0]
+	for _, contentID := range contentIDs {
+		numBytes := segmentDataBytes[contentID]
+		totalBytes += numBytes
+		if numBytes > segmentDataBytes[largest] {
+			largest = contentID
+		}
+		if numBytes < segmentDataBytes[smallest] {
+			smallest = contentID
+		}
+	}
+	skewInfo := []LineInfo{
+		{Key: "table data backed up:", Value: utils.FormatByteSize(totalBytes)},
+		{Key: "largest segment data:", Value: fmt.Sprintf("%s (segment %d)", utils.FormatByteSize(segmentDataBytes[largest]), largest)},
+		{Key: "smallest segment data:", Value: fmt.Sprintf("%s (segment %d)", utils.FormatByteSize(segmentDataBytes[smallest]), smallest)},
+	}
+	if totalBytes > 0 {
+		averageBytes := float64(totalBytes) / float64(len(contentIDs))
+		skewInfo = append(skewInfo, LineInfo{Key: "segment data skew:", Value: fmt.Sprintf("%.2f (largest / average)", float64(segmentDataBytes[largest])/averageBytes)})
+	}
+	return skewInfo
+}
+
 func logOutputReport(reportFile io.WriteCloser, reportInfo []LineInfo) {
 	maxSize := 0
 	for _, lineInfo := range reportInfo {
@@ -282,6 +338,36 @@ func PrintObjectCounts(reportFile io.WriteCloser, objectCounts map[string]int) {
 	utils.MustPrintf(reportFile, objectStr)
 }
 
+// Tables backed up with a predicate only have part of their data in the backup
+func PrintTablePredicates(reportFile io.WriteCloser, tablePredicates map[string]string) {
+	printSortedMap(reportFile, "tables with partial data, filtered by predicate:", "WHERE ", tablePredicates)
+}
+
+// Masked columns contain the result of their masking expression instead of their data
+func PrintMaskingRules(reportFile io.WriteCloser, maskingRules map[string]string) {
+	printSortedMap(reportFile, "masked columns:", "", maskingRules)
+}
+
+func printSortedMap(reportFile io.WriteCloser, header string, valuePrefix string, values map[string]string) {
+	if len(values) == 0 {
+		return
+	}
+	mapStr := fmt.Sprintf("\n%s\n", header)
+	keySlice := make([]string, 0)
+	maxSize := 0
+	for k := range values {
+		keySlice = append(keySlice, k)
+		if len(k) > maxSize {
+			maxSize = len(k)
+		}
+	}
+	sort.Strings(keySlice)
+	for _, key := range keySlice {
+		mapStr += fmt.Sprintf("%-*s%s%s\n", maxSize+3, key, valuePrefix, values[key])
+	}
+	utils.MustPrintf(reportFile, "%s", mapStr)
+}
+
 /*
  * This function will not error out if the user has gprestore X.Y.Z
  * and gpbackup X.Y.Z+dev, when technically the uncommitted code changes
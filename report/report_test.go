@@ -152,6 +152,33 @@ count of database objects in backup:
 sequences   1
 tables      42
 types       1000`))
+		})
+		It("writes a report listing the tables filtered by predicate", func() {
+			backupReport.PartialTables = map[string]string{
+				"public.sales":     "sale_date >= '2022-01-01'",
+				"public.customers": "name LIKE 'A%'",
+			}
+			backupReport.WriteBackupReportFile("filename", timestamp, endtime, objectCounts, "")
+			Expect(buffer).To(Say(`count of database objects in backup:
+sequences   1
+tables      42
+types       1000
+
+tables with partial data, filtered by predicate:
+public.customers   WHERE name LIKE 'A%'
+public.sales       WHERE sale_date >= '2022-01-01'`))
+		})
+		It("writes a report listing the masked columns", func() {
+			backupReport.MaskingRules = map[string]string{
+				"public.customers.email": "md5(email)",
+				"public.customers.ssn":   "NULL",
+			}
+			backupReport.WriteBackupReportFile("filename", timestamp, endtime, objectCounts, "")
+			Expect(buffer).To(Say(`types       1000
+
+masked columns:
+public.customers.email   md5\(email\)
+public.customers.ssn     NULL`))
 		})
 		It("writes a report without database size information", func() {
 			backupReport.DatabaseSize = ""
@@ -183,6 +210,36 @@ sequences   1
 tables      42
 types       1000`))
 		})
+		It("writes a report with the skew of the segment data", func() {
+			backupReport.SegmentDataBytes = map[int]int64{0: 3 * 1024 * 1024, 1: 1024 * 1024, 2: 2 * 1024 * 1024}
+			backupReport.WriteBackupReportFile("filename", timestamp, endtime, objectCounts, "")
+			Expect(buffer).To(Say(`segment count:           3
+table data backed up:    6\.0 MB
+largest segment data:    3\.0 MB \(segment 0\)
+smallest segment data:   1\.0 MB \(segment 1\)
+segment data skew:       1\.50 \(largest / average\)
+
+count of database objects in backup:`))
+		})
+	})
+	Describe("GetSegmentSkewInfo", func() {
+		It("returns no information without segment data", func() {
+			Expect(report.GetSegmentSkewInfo(nil)).To(BeEmpty())
+		})
+		It("reports the lowest segment of equally large segments", func() {
+			skewInfo := report.GetSegmentSkewInfo(map[int]int64{2: 1024, 0: 1024, 1: 1024})
+			Expect(skewInfo).To(Equal([]report.LineInfo{
+				{Key: "table data backed up:", Value: "3.0 KB"},
+				{Key: "largest segment data:", Value: "1.0 KB (segment 0)"},
+				{Key: "smallest segment data:", Value: "1.0 KB (segment 0)"},
+				{Key: "segment data skew:", Value: "1.00 (largest / average)"},
+			}))
+		})
+		It("does not report skew when no data was backed up", func() {
+			skewInfo := report.GetSegmentSkewInfo(map[int]int64{0: 0, 1: 0})
+			Expect(skewInfo).To(HaveLen(3))
+			Expect(skewInfo[2]).To(Equal(report.LineInfo{Key: "smallest segment data:", Value: "0 B (segment 0)"}))
+		})
 	})
 	Describe("AppendBackupParams", func() {
 		It("correctly parses the string and appends to the LineInfo array", func() {
@@ -350,6 +407,33 @@ restore status:          Success but non-fatal errors occurred. See log file .+
 				Status:               history.BackupStatusInProgress,
 			}, backupConfig)
 		})
+		It("records the fingerprint of the encryption key", func() {
+			key := []byte("0123456789abcdef0123456789abcdef")
+			utils.SetEncryptionKey(key)
+			defer utils.SetEncryptionKey(nil)
+			backupCmdFlags := pflag.NewFlagSet("gpbackup", pflag.ExitOnError)
+			backup.SetCmdFlags(backupCmdFlags)
+			opts, err := options.NewOptions(backupCmdFlags)
+			Expect(err).ToNot(HaveOccurred())
+
+			backupConfig := backup.NewBackupConfig("testdb",
+				"5.0.0 build test", "0.1.0",
+				"", "timestamp1", *opts)
+			Expect(backupConfig.EncryptionFingerprint).To(Equal(utils.GetEncryptionKeyFingerprint(key)))
+		})
+		It("records the number of data streams of a single data file backup", func() {
+			backupCmdFlags := pflag.NewFlagSet("gpbackup", pflag.ExitOnError)
+			backup.SetCmdFlags(backupCmdFlags)
+			Expect(backupCmdFlags.Set(options.SINGLE_DATA_FILE, "true")).To(Succeed())
+			Expect(backupCmdFlags.Set(options.DATA_STREAMS, "4")).To(Succeed())
+			opts, err := options.NewOptions(backupCmdFlags)
+			Expect(err).ToNot(HaveOccurred())
+
+			backupConfig := backup.NewBackupConfig("testdb",
+				"5.0.0 build test", "0.1.0",
+				"", "timestamp1", *opts)
+			Expect(backupConfig.DataStreams).To(Equal(4))
+		})
 	})
 	Describe("GetDurationInfo", func() {
 		timestamp := "20170101010101"
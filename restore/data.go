@@ -6,6 +6,8 @@ package restore
 
 import (
 	"fmt"
+	"path"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -25,29 +27,34 @@ var (
 	tableDelim = ","
 )
 
-func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttributes string, destinationToRead string, singleDataFile bool, whichConn int) (int64, error) {
-	whichConn = connectionPool.ValidateConnNum(whichConn)
-	copyCommand := ""
+// Returns the program that reads the data of a table on each segment
+func getReadTableDataProgram(destinationToRead string, singleDataFile bool, helperFilterCommand string) string {
 	readFromDestinationCommand := "cat"
-	customPipeThroughCommand := utils.GetPipeThroughProgram().InputCommand
-	origSize, destSize, resizeCluster := GetResizeClusterInfo()
+	customPipeThroughCommand := helperFilterCommand
+	checkChecksumCommand := ""
+	_, _, resizeCluster := GetResizeClusterInfo()
 
 	if singleDataFile || resizeCluster {
-		//helper.go handles compression, so we don't want to set it here
+		// The helper agent handles compression, so we don't want to set it here
 		customPipeThroughCommand = "cat -"
 	} else if MustGetFlagString(options.PLUGIN_CONFIG) != "" {
 		readFromDestinationCommand = fmt.Sprintf("%s restore_data %s", pluginConfig.ExecutablePath, pluginConfig.ConfigPath)
 	}
+	if singleDataFile {
+		// helper.go verifies the checksum and leaves a file with the error message if it does not match
+		checksumErrorFile := fmt.Sprintf("%s_checksum_error", destinationToRead)
+		checkChecksumCommand = fmt.Sprintf(" && if [ -e %[1]s ]; then cat %[1]s >&2; rm -f %[1]s; exit 1; fi", checksumErrorFile)
+	}
 
-	copyCommand = fmt.Sprintf("PROGRAM '%s %s | %s'", readFromDestinationCommand, destinationToRead, customPipeThroughCommand)
-
-	query := fmt.Sprintf("COPY %s%s FROM %s WITH CSV DELIMITER '%s' ON SEGMENT;", tableName, tableAttributes, copyCommand, tableDelim)
-
-	var numRows int64
-	var err error
+	return fmt.Sprintf("%s %s | %s%s", readFromDestinationCommand, destinationToRead, customPipeThroughCommand, checkChecksumCommand)
+}
 
-	// During a larger-to-smaller restore, we need multiple COPY passes to load all the data.
-	// One pass is sufficient for smaller-to-larger and normal restores.
+/*
+ * During a larger-to-smaller restore, we need multiple passes to load all the data.
+ * One pass is sufficient for smaller-to-larger and normal restores.
+ */
+func getNumLoadBatches() int {
+	origSize, destSize, resizeCluster := GetResizeClusterInfo()
 	batches := 1
 	if resizeCluster && origSize > destSize {
 		batches = origSize / destSize
@@ -55,6 +62,19 @@ func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttribute
 			batches += 1
 		}
 	}
+	return batches
+}
+
+func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttributes string, dataFormat string, destinationToRead string, singleDataFile bool, helperFilterCommand string, whichConn int) (int64, error) {
+	whichConn = connectionPool.ValidateConnNum(whichConn)
+	copyCommand := fmt.Sprintf("PROGRAM '%s'", getReadTableDataProgram(destinationToRead, singleDataFile, helperFilterCommand))
+
+	query := fmt.Sprintf("COPY %s%s FROM %s WITH %s ON SEGMENT;", tableName, tableAttributes, copyCommand, utils.GetCopyFormatOptions(dataFormat, tableDelim))
+
+	var numRows int64
+	var err error
+
+	batches := getNumLoadBatches()
 	for i := 0; i < batches; i++ {
 		if connectionPool.Version.AtLeast("7") {
 			gplog.Verbose(`Executing "%s" on coordinator`, query)
@@ -79,11 +99,108 @@ func CopyTableIn(connectionPool *dbconn.DBConn, tableName string, tableAttribute
 	return numRows, err
 }
 
+/*
+ * A resize restore normally loads the data each segment of the original
+ * cluster backed up onto one segment of the new cluster and then redistributes
+ * it, which writes all of the data twice.  With --resize-route-data, the data
+ * of a hash distributed table is instead read on the segments through an
+ * external web table and inserted into the table, so each row is sent to the
+ * segment its distribution policy assigns it to as it is loaded.
+ */
+type StagingColumn struct {
+	Name string
+	Type string
+}
+
+/*
+ * Returns the columns of a staging table for the data of a table, or nil if
+ * the table is not hash distributed or its columns do not match the columns
+ * its data was backed up with, in which case its data cannot be routed.
+ */
+func GetResizeStagingColumns(connectionPool *dbconn.DBConn, tableName string, tableAttributes string, whichConn int) ([]StagingColumn, error) {
+	if connectionPool.Version.Before("6") || tableAttributes == "" {
+		return nil, nil
+	}
+	query := fmt.Sprintf(`
+	SELECT quote_ident(a.attname) AS name,
+		pg_catalog.format_type(a.atttypid, a.atttypmod) AS type
+	FROM pg_attribute a
+		JOIN gp_distribution_policy p ON p.localoid = a.attrelid
+	WHERE a.attrelid = '%s'::regclass::oid
+		AND a.attnum > 0
+		AND NOT a.attisdropped
+		AND p.policytype = 'p'
+		AND p.distkey::text <> ''
+	ORDER BY a.attnum`, utils.EscapeSingleQuotes(tableName))
+	columns := make([]StagingColumn, 0)
+	err := connectionPool.Select(&columns, query, whichConn)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	if len(columns) == 0 || fmt.Sprintf("(%s)", strings.Join(names, ",")) != tableAttributes {
+		return nil, nil
+	}
+	return columns, nil
+}
+
+func LoadTableInThroughStaging(connectionPool *dbconn.DBConn, tableName string, oid uint32, columns []StagingColumn, destinationToRead string, singleDataFile bool, whichConn int) (int64, error) {
+	whichConn = connectionPool.ValidateConnNum(whichConn)
+	// The data is in the encoding of the backup, which is the client encoding of the restore
+	encoding, err := dbconn.SelectString(connectionPool, "SHOW client_encoding", whichConn)
+	if err != nil {
+		return 0, err
+	}
+	// Web table commands get the segment from the environment instead of from COPY placeholders
+	program := strings.NewReplacer("<SEG_DATA_DIR>", "$GP_SEG_DATADIR", "<SEGID>", "$GP_SEGMENT_ID").Replace(
+		getReadTableDataProgram(destinationToRead, singleDataFile, ""))
+	stagingTable := fmt.Sprintf("gpbackup_staging_%d", oid)
+	columnDefs := make([]string, len(columns))
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		columnDefs[i] = fmt.Sprintf("%s %s", column.Name, column.Type)
+		names[i] = column.Name
+	}
+	createQuery := fmt.Sprintf("CREATE READABLE EXTERNAL TEMPORARY WEB TABLE %s (%s) EXECUTE '%s' ON ALL FORMAT 'csv' (DELIMITER '%s') ENCODING '%s';",
+		stagingTable, strings.Join(columnDefs, ", "), program, tableDelim, encoding)
+	dropQuery := fmt.Sprintf("DROP EXTERNAL TABLE IF EXISTS %s;", stagingTable)
+	insertQuery := fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s;", tableName, strings.Join(names, ","), strings.Join(names, ","), stagingTable)
+
+	_, err = connectionPool.Exec(createQuery, whichConn)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Error creating staging table for table %s", tableName)
+	}
+	defer connectionPool.MustExec(dropQuery, whichConn)
+
+	var numRows int64
+	batches := getNumLoadBatches()
+	for i := 0; i < batches; i++ {
+		if connectionPool.Version.AtLeast("7") {
+			gplog.Verbose(`Executing "%s" on coordinator`, insertQuery)
+		} else {
+			gplog.Verbose(`Executing "%s" on master`, insertQuery)
+		}
+		result, err := connectionPool.Exec(insertQuery, whichConn)
+		if err != nil {
+			return 0, errors.Wrap(err, fmt.Sprintf("Error loading data into table %s", tableName))
+		}
+		rowsLoaded, _ := result.RowsAffected()
+		numRows += rowsLoaded
+	}
+	return numRows, nil
+}
+
 func restoreSingleTableData(fpInfo *filepath.FilePathInfo, entry toc.CoordinatorDataEntry, tableName string, whichConn int, origSize int, destSize int) error {
 	resizeCluster := MustGetFlagBool(options.RESIZE_CLUSTER)
 	destinationToRead := ""
 	if backupConfig.SingleDataFile || resizeCluster {
 		destinationToRead = fmt.Sprintf("%s_%d", fpInfo.GetSegmentPipePathForCopyCommand(), entry.Oid)
+	} else if entry.ChunkList != "" {
+		// The data of a table backed up with --repository is read through the list of the chunks it is stored in
+		destinationToRead = path.Join(path.Dir(fpInfo.GetTableBackupFilePathForCopyCommand(entry.Oid, "", false)), entry.ChunkList)
 	} else {
 		destinationToRead = fpInfo.GetTableBackupFilePathForCopyCommand(entry.Oid, utils.GetPipeThroughProgram().Extension, backupConfig.SingleDataFile)
 	}
@@ -95,7 +212,40 @@ func restoreSingleTableData(fpInfo *filepath.FilePathInfo, entry toc.Coordinator
 		defer connectionPool.MustExec("RESET gp_enable_segment_copy_checking;", whichConn)
 	}
 
-	numRowsRestored, err := CopyTableIn(connectionPool, tableName, entry.AttributeString, destinationToRead, backupConfig.SingleDataFile, whichConn)
+	// The helper agent decrypts and decompresses the data, verifies checksums and limits bandwidth for single data file and resize restores
+	helperFilterCommand := ""
+	if !backupConfig.SingleDataFile && !resizeCluster {
+		checksumFile := ""
+		if len(entry.Checksums) > 0 {
+			checksumFile = fpInfo.GetSegmentHelperFilePathForCopyCommand("checksums")
+		}
+		// Each job copies the data of a different table at the same time, so they share the bandwidth
+		copyBandwidth := utils.GetStreamBandwidth(globalCluster, opts.MaxBandwidth, opts.MaxBandwidthPerHost, MustGetFlagInt(options.JOBS))
+		chunkDir := ""
+		if entry.ChunkList != "" {
+			chunkDir = fpInfo.GetChunkDirForCopyCommand()
+		}
+		helperFilterCommand = utils.GetRestoreFilterCommand(checksumFile, utils.GetEncryptionKeyFileForCopyCommand(*fpInfo), chunkDir, entry.Oid, copyBandwidth)
+	}
+
+	var stagingColumns []StagingColumn
+	var err error
+	if resizeCluster && MustGetFlagBool(options.RESIZE_ROUTE_DATA) && !entry.IsReplicated && entry.DataFormat != utils.DATA_FORMAT_BINARY {
+		stagingColumns, err = GetResizeStagingColumns(connectionPool, tableName, entry.AttributeString, whichConn)
+		if err != nil {
+			return err
+		}
+		if stagingColumns == nil {
+			gplog.Verbose("Data for table %s cannot be routed to its segments as it is loaded, so it will be redistributed after it is loaded", tableName)
+		}
+	}
+
+	var numRowsRestored int64
+	if stagingColumns != nil {
+		numRowsRestored, err = LoadTableInThroughStaging(connectionPool, tableName, entry.Oid, stagingColumns, destinationToRead, backupConfig.SingleDataFile, whichConn)
+	} else {
+		numRowsRestored, err = CopyTableIn(connectionPool, tableName, entry.AttributeString, entry.DataFormat, destinationToRead, backupConfig.SingleDataFile, helperFilterCommand, whichConn)
+	}
 	if err != nil {
 		return err
 	}
@@ -114,7 +264,8 @@ func restoreSingleTableData(fpInfo *filepath.FilePathInfo, entry toc.Coordinator
 		return err
 	}
 
-	if resizeCluster || entry.DistByEnum {
+	// Rows loaded through a staging table are already on the segments they belong on
+	if (resizeCluster || entry.DistByEnum) && stagingColumns == nil {
 		// replicated tables cannot be redistributed, so instead expand them if needed
 		if entry.IsReplicated && (origSize < destSize) {
 			err = ExpandReplicatedTable(origSize, tableName, whichConn)
@@ -165,6 +316,45 @@ func RedistributeTableData(tableName string, whichConn int) error {
 	return err
 }
 
+/*
+ * The tables of a multiple-data-file backup are restored largest first, or
+ * the tables in the priority file first if there is one, to make the most of
+ * parallel jobs.  The data of a single-data-file backup has to be read in the
+ * order in which it was written, so its order cannot change.
+ */
+func ScheduleDataEntriesForRestore(dataEntries []toc.CoordinatorDataEntry, priorityTables []string) []toc.CoordinatorDataEntry {
+	copyTasks := make([]utils.CopyTask, len(dataEntries))
+	for i, entry := range dataEntries {
+		copyTasks[i] = utils.CopyTask{Schema: entry.Schema, Name: entry.Name, Size: entry.DataSize}
+	}
+	scheduledEntries := make([]toc.CoordinatorDataEntry, 0, len(dataEntries))
+	for _, i := range utils.ScheduleCopyTasks(copyTasks, priorityTables) {
+		scheduledEntries = append(scheduledEntries, dataEntries[i])
+	}
+	return scheduledEntries
+}
+
+/*
+ * Tables backed up with --table-predicate-file only contain the rows that
+ * matched their predicate, so make sure the user knows that the restored data
+ * is partial.
+ */
+func LogPartialTableData(filteredDataEntries map[string][]toc.CoordinatorDataEntry) {
+	numPartialTables := 0
+	for _, entries := range filteredDataEntries {
+		for _, entry := range entries {
+			if entry.Predicate != "" {
+				gplog.Verbose("Table %s was backed up with only the rows matching: %s", utils.MakeFQN(entry.Schema, entry.Name), entry.Predicate)
+				numPartialTables++
+			}
+		}
+	}
+	if numPartialTables > 0 {
+		gplog.Warn("%d table(s) were backed up with a table predicate and contain only part of their data. See %s for details.",
+			numPartialTables, gplog.GetLogFilePath())
+	}
+}
+
 func restoreDataFromTimestamp(fpInfo filepath.FilePathInfo, dataEntries []toc.CoordinatorDataEntry,
 	gucStatements []toc.StatementWithType, dataProgressBar utils.ProgressBar) int32 {
 	totalTables := len(dataEntries)
@@ -173,7 +363,20 @@ func restoreDataFromTimestamp(fpInfo filepath.FilePathInfo, dataEntries []toc.Co
 		return 0
 	}
 
+	if !backupConfig.SingleDataFile {
+		dataEntries = ScheduleDataEntriesForRestore(dataEntries, opts.PriorityRelations)
+	}
+
 	origSize, destSize, resizeCluster := GetResizeClusterInfo()
+	// All table data is piped through gpbackup_helper, either as an agent or as a filter in each COPY command
+	utils.VerifyHelperVersionOnSegments(version, globalCluster)
+	// The helper files of single data file restores are removed in DoCleanup instead
+	cleanUpHelperFiles := false
+	if utils.GetEncryptionKey() != nil {
+		gplog.Verbose("Writing encryption key to segments for decryption")
+		utils.WriteEncryptionKeyToSegments(globalCluster, fpInfo)
+		cleanUpHelperFiles = !backupConfig.SingleDataFile
+	}
 	if backupConfig.SingleDataFile || resizeCluster {
 		msg := ""
 		if backupConfig.SingleDataFile {
@@ -183,7 +386,6 @@ func restoreDataFromTimestamp(fpInfo filepath.FilePathInfo, dataEntries []toc.Co
 			msg += "resize "
 		}
 		gplog.Verbose("Initializing pipes and gpbackup_helper on segments for %srestore", msg)
-		utils.VerifyHelperVersionOnSegments(version, globalCluster)
 		oidList := make([]string, totalTables)
 		replicatedOidList := make([]string, 0)
 		for i, entry := range dataEntries {
@@ -209,7 +411,21 @@ func restoreDataFromTimestamp(fpInfo filepath.FilePathInfo, dataEntries []toc.Co
 		if backupConfig.Compressed {
 			compressStr = fmt.Sprintf(" --compression-type %s ", utils.GetPipeThroughProgram().Name)
 		}
-		utils.StartGpbackupHelpers(globalCluster, fpInfo, "--restore-agent", MustGetFlagString(options.PLUGIN_CONFIG), compressStr, MustGetFlagBool(options.ON_ERROR_CONTINUE), isFilter, &wasTerminated, initialPipes, backupConfig.SingleDataFile, resizeCluster, origSize, destSize)
+		utils.StartGpbackupHelpers(globalCluster, fpInfo, "--restore-agent", MustGetFlagString(options.PLUGIN_CONFIG), compressStr, MustGetFlagBool(options.ON_ERROR_CONTINUE), isFilter, &wasTerminated, initialPipes, backupConfig.SingleDataFile, resizeCluster, origSize, destSize,
+			utils.GetStreamBandwidth(globalCluster, opts.MaxBandwidth, opts.MaxBandwidthPerHost, backupConfig.DataStreams), 1)
+	} else {
+		checksums := GetChecksumsBySegment(dataEntries)
+		if len(checksums) > 0 {
+			gplog.Verbose("Writing table data checksums to segments for verification")
+			utils.WriteChecksumsToSegments(checksums, globalCluster, fpInfo)
+			cleanUpHelperFiles = true
+		}
+	}
+	if cleanUpHelperFiles {
+		defer utils.CleanUpHelperFilesOnAllHosts(globalCluster, fpInfo)
+	}
+	if resizeCluster && !backupConfig.SingleDataFile {
+		gplog.Verbose("Table data checksums are not verified when restoring a multiple data file backup to a different size cluster")
 	}
 	/*
 	 * We break when an interrupt is received and rely on
@@ -239,17 +455,17 @@ func restoreDataFromTimestamp(fpInfo filepath.FilePathInfo, dataEntries []toc.Co
 					dataProgressBar.(*pb.ProgressBar).NotPrint = true
 					return
 				}
-				tableName := utils.MakeFQN(entry.Schema, entry.Name)
-				if opts.RedirectSchema != "" {
-					tableName = utils.MakeFQN(opts.RedirectSchema, entry.Name)
-				}
-				// Truncate table before restore, if needed
+				tableName := getRestoreTableName(entry.Schema, entry.Name)
+				// Truncate table before restore, if needed; a resumed restore may have partly loaded it
 				var err error
-				if MustGetFlagBool(options.INCREMENTAL) || MustGetFlagBool(options.TRUNCATE_TABLE) {
+				if MustGetFlagBool(options.INCREMENTAL) || MustGetFlagBool(options.TRUNCATE_TABLE) || MustGetFlagString(options.RESUME) != "" {
 					err = TruncateTable(tableName, whichConn)
 				}
 				if err == nil {
 					err = restoreSingleTableData(&fpInfo, entry, tableName, whichConn, origSize, destSize)
+					if err == nil {
+						err = restoreStateFile.RecordTableLoaded(fpInfo.Timestamp, entry)
+					}
 
 					if gplog.GetVerbosity() > gplog.LOGINFO {
 						// No progress bar at this log level, so we note table count here
@@ -307,6 +523,20 @@ func restoreDataFromTimestamp(fpInfo filepath.FilePathInfo, dataEntries []toc.Co
 	return numErrors
 }
 
+// Backups taken before checksums were recorded have no checksums to verify
+func GetChecksumsBySegment(dataEntries []toc.CoordinatorDataEntry) map[int]map[uint32]string {
+	checksums := make(map[int]map[uint32]string)
+	for _, entry := range dataEntries {
+		for contentID, checksum := range entry.Checksums {
+			if checksums[contentID] == nil {
+				checksums[contentID] = make(map[uint32]string)
+			}
+			checksums[contentID][entry.Oid] = checksum
+		}
+	}
+	return checksums
+}
+
 func CreateInitialSegmentPipes(oidList []string, c *cluster.Cluster, connectionPool *dbconn.DBConn, fpInfo filepath.FilePathInfo) int {
 	// Create min(connections, tables) segment pipes on each host
 	var maxPipes int
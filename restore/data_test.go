@@ -8,83 +8,113 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/testhelper"
 	"github.com/greenplum-db/gpbackup/backup"
 	"github.com/greenplum-db/gpbackup/history"
 	"github.com/greenplum-db/gpbackup/options"
 	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
 	"github.com/jackc/pgconn"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
+	. "github.com/onsi/gomega/gbytes"
 )
 
 var _ = Describe("restore/data tests", func() {
 	Describe("CopyTableIn", func() {
 		BeforeEach(func() {
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", OutputCommand: "cat -", InputCommand: "cat -", Extension: ""})
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "cat", Level: 0, Extension: ""})
 			backup.SetPluginConfig(nil)
 			_ = cmdFlags.Set(options.PLUGIN_CONFIG, "")
 			backup.SetCluster(&cluster.Cluster{ContentIDs: []int{-1, 0, 1, 2}})
 			restore.SetBackupConfig(&history.BackupConfig{})
 		})
 		It("will restore a table from its own file with gzip compression", func() {
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", OutputCommand: "gzip -c -1", InputCommand: "gzip -d -c", Extension: ".gz"})
-			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.gz | gzip -d -c' WITH CSV DELIMITER ',' ON SEGMENT")
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", Level: 1, Extension: ".gz"})
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.gz | gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type gzip' WITH CSV DELIMITER ',' ON SEGMENT")
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.gz"
-			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "", filename, false, "gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type gzip", 0)
 
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 		It("will restore a table from its own file with zstd compression", func() {
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "zstd", OutputCommand: "zstd --compress -1 -c", InputCommand: "zstd --decompress -c", Extension: ".zst"})
-			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.zst | zstd --decompress -c' WITH CSV DELIMITER ',' ON SEGMENT")
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "zstd", Level: 1, Extension: ".zst"})
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.zst | gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type zstd' WITH CSV DELIMITER ',' ON SEGMENT")
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.zst"
-			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "", filename, false, "gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type zstd", 0)
 
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 		It("will restore a table from its own file without compression", func() {
-			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456 | cat -' WITH CSV DELIMITER ',' ON SEGMENT")
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456 | gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type cat' WITH CSV DELIMITER ',' ON SEGMENT")
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
-			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "", filename, false, "gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type cat", 0)
+
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+		It("will restore a table backed up in binary format", func() {
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456 | gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type cat' WITH BINARY ON SEGMENT")
+			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
+			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "binary", filename, false, "gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type cat", 0)
 
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 		It("will restore a table from a single data file", func() {
-			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456 | cat -' WITH CSV DELIMITER ',' ON SEGMENT")
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456 | cat - && if [ -e <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456_checksum_error ]; then cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456_checksum_error >&2; rm -f <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456_checksum_error; exit 1; fi' WITH CSV DELIMITER ',' ON SEGMENT")
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456"
-			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", filename, true, 0)
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "", filename, true, "", 0)
+
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+		It("will restore a table from its own file and verify its checksum", func() {
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", Level: 1, Extension: ".gz"})
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.gz | gpbackup_helper --restore-filter --checksum-file <SEG_DATA_DIR>/gpbackup_<SEGID>_20170101010101_checksums_1234 --oid 3456 --content <SEGID> --compression-type gzip' WITH CSV DELIMITER ',' ON SEGMENT")
+			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
+			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.gz"
+			helperFilterCommand := "gpbackup_helper --restore-filter --checksum-file <SEG_DATA_DIR>/gpbackup_<SEGID>_20170101010101_checksums_1234 --oid 3456 --content <SEGID> --compression-type gzip"
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "", filename, false, helperFilterCommand, 0)
+
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+		It("will restore a table from the chunks listed in its chunk list", func() {
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", Level: 1, Extension: ".gz"})
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.chunks | gpbackup_helper --restore-filter --chunk-dir /backups/gpseg<SEGID>/chunks --oid 3456 --content <SEGID> --compression-type gzip' WITH CSV DELIMITER ',' ON SEGMENT")
+			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
+			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456.chunks"
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "", filename, false, "gpbackup_helper --restore-filter --chunk-dir /backups/gpseg<SEGID>/chunks --oid 3456 --content <SEGID> --compression-type gzip", 0)
 
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 		It("will restore a table from its own file with gzip compression using a plugin", func() {
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", OutputCommand: "gzip -c -1", InputCommand: "gzip -d -c", Extension: ".gz"})
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "gzip", Level: 1, Extension: ".gz"})
 			_ = cmdFlags.Set(options.PLUGIN_CONFIG, "/tmp/plugin_config")
 			pluginConfig := utils.PluginConfig{ExecutablePath: "/tmp/fake-plugin.sh", ConfigPath: "/tmp/plugin_config"}
 			restore.SetPluginConfig(&pluginConfig)
-			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM '/tmp/fake-plugin.sh restore_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456.gz | gzip -d -c' WITH CSV DELIMITER ',' ON SEGMENT")
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM '/tmp/fake-plugin.sh restore_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456.gz | gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type gzip' WITH CSV DELIMITER ',' ON SEGMENT")
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456.gz"
-			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "", filename, false, "gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type gzip", 0)
 
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 		It("will restore a table from its own file with zstd compression using a plugin", func() {
-			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "zstd", OutputCommand: "zstd --compress -1 -c", InputCommand: "zstd --decompress -c", Extension: ".zst"})
+			utils.SetPipeThroughProgram(utils.PipeThroughProgram{Name: "zstd", Level: 1, Extension: ".zst"})
 			_ = cmdFlags.Set(options.PLUGIN_CONFIG, "/tmp/plugin_config")
 			pluginConfig := utils.PluginConfig{ExecutablePath: "/tmp/fake-plugin.sh", ConfigPath: "/tmp/plugin_config"}
 			restore.SetPluginConfig(&pluginConfig)
-			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM '/tmp/fake-plugin.sh restore_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456.zst | zstd --decompress -c' WITH CSV DELIMITER ',' ON SEGMENT")
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM '/tmp/fake-plugin.sh restore_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456.zst | gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type zstd' WITH CSV DELIMITER ',' ON SEGMENT")
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456.zst"
-			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "", filename, false, "gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type zstd", 0)
 
 			Expect(err).ShouldNot(HaveOccurred())
 		})
@@ -92,16 +122,16 @@ var _ = Describe("restore/data tests", func() {
 			_ = cmdFlags.Set(options.PLUGIN_CONFIG, "/tmp/plugin_config")
 			pluginConfig := utils.PluginConfig{ExecutablePath: "/tmp/fake-plugin.sh", ConfigPath: "/tmp/plugin_config"}
 			restore.SetPluginConfig(&pluginConfig)
-			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM '/tmp/fake-plugin.sh restore_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456.gz | cat -' WITH CSV DELIMITER ',' ON SEGMENT")
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM '/tmp/fake-plugin.sh restore_data /tmp/plugin_config <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456.gz | gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type cat' WITH CSV DELIMITER ',' ON SEGMENT")
 			mock.ExpectExec(execStr).WillReturnResult(sqlmock.NewResult(10, 0))
 
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_pipe_3456.gz"
-			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "", filename, false, "gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type cat", 0)
 
 			Expect(err).ShouldNot(HaveOccurred())
 		})
 		It("will output expected error string from COPY ON SEGMENT failure", func() {
-			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456 | cat -' WITH CSV DELIMITER ',' ON SEGMENT")
+			execStr := regexp.QuoteMeta("COPY public.foo(i,j) FROM PROGRAM 'cat <SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456 | gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type cat' WITH CSV DELIMITER ',' ON SEGMENT")
 			pgErr := &pgconn.PgError{
 				Severity: "ERROR",
 				Code:     "22P04",
@@ -110,7 +140,7 @@ var _ = Describe("restore/data tests", func() {
 			}
 			mock.ExpectExec(execStr).WillReturnError(pgErr)
 			filename := "<SEG_DATA_DIR>/backups/20170101/20170101010101/gpbackup_<SEGID>_20170101010101_3456"
-			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", filename, false, 0)
+			_, err := restore.CopyTableIn(connectionPool, "public.foo", "(i,j)", "", filename, false, "gpbackup_helper --restore-filter --oid 3456 --content <SEGID> --compression-type cat", 0)
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("Error loading data into table public.foo: " +
@@ -118,6 +148,76 @@ var _ = Describe("restore/data tests", func() {
 				"ERROR: value of distribution key doesn't belong to segment with ID 0, it belongs to segment with ID 1 (SQLSTATE 22P04)"))
 		})
 	})
+	Describe("GetResizeStagingColumns", func() {
+		header := []string{"name", "type"}
+		BeforeEach(func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+		})
+		It("returns the columns of a hash distributed table", func() {
+			rows := sqlmock.NewRows(header).AddRow("i", "integer").AddRow(`"J"`, "character varying(10)")
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(rows)
+			columns, err := restore.GetResizeStagingColumns(connectionPool, "public.foo", `(i,"J")`, 0)
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(columns).To(Equal([]restore.StagingColumn{{Name: "i", Type: "integer"}, {Name: `"J"`, Type: "character varying(10)"}}))
+		})
+		It("returns no columns for a table that is not hash distributed", func() {
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(sqlmock.NewRows(header))
+			columns, err := restore.GetResizeStagingColumns(connectionPool, "public.foo", "(i,j)", 0)
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(columns).To(BeNil())
+		})
+		It("returns no columns for a table whose columns do not match its data", func() {
+			rows := sqlmock.NewRows(header).AddRow("i", "integer").AddRow("j", "integer").AddRow("k", "integer")
+			mock.ExpectQuery("SELECT (.*)").WillReturnRows(rows)
+			columns, err := restore.GetResizeStagingColumns(connectionPool, "public.foo", "(i,j)", 0)
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(columns).To(BeNil())
+		})
+		It("returns no columns for a table with no columns", func() {
+			columns, err := restore.GetResizeStagingColumns(connectionPool, "public.foo", "", 0)
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(columns).To(BeNil())
+		})
+	})
+	Describe("LoadTableInThroughStaging", func() {
+		columns := []restore.StagingColumn{{Name: "i", Type: "integer"}, {Name: "j", Type: "text"}}
+		filename := "<SEG_DATA_DIR>/gpbackup_<SEGID>_20170101010101_pipe_3456"
+		createStr := regexp.QuoteMeta("CREATE READABLE EXTERNAL TEMPORARY WEB TABLE gpbackup_staging_3456 (i integer, j text) EXECUTE 'cat $GP_SEG_DATADIR/gpbackup_$GP_SEGMENT_ID_20170101010101_pipe_3456 | cat -' ON ALL FORMAT 'csv' (DELIMITER ',') ENCODING 'UTF8';")
+		insertStr := regexp.QuoteMeta("INSERT INTO public.foo (i,j) SELECT i,j FROM gpbackup_staging_3456;")
+		dropStr := regexp.QuoteMeta("DROP EXTERNAL TABLE IF EXISTS gpbackup_staging_3456;")
+		BeforeEach(func() {
+			testhelper.SetDBVersion(connectionPool, "6.0.0")
+			_ = cmdFlags.Set(options.RESIZE_CLUSTER, "true")
+			restore.SetBackupConfig(&history.BackupConfig{SegmentCount: 3})
+			mock.ExpectQuery("SHOW client_encoding").WillReturnRows(sqlmock.NewRows([]string{"client_encoding"}).AddRow("UTF8"))
+			mock.ExpectExec(createStr).WillReturnResult(sqlmock.NewResult(0, 0))
+		})
+		It("loads the data of a table in one pass when restoring to a larger cluster", func() {
+			restore.SetCluster(&cluster.Cluster{ContentIDs: []int{-1, 0, 1, 2, 3, 4, 5}})
+			mock.ExpectExec(insertStr).WillReturnResult(sqlmock.NewResult(0, 10))
+			mock.ExpectExec(dropStr).WillReturnResult(sqlmock.NewResult(0, 0))
+			numRows, err := restore.LoadTableInThroughStaging(connectionPool, "public.foo", 3456, columns, filename, false, 0)
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(numRows).To(Equal(int64(10)))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+		It("loads the data of a table in several passes when restoring to a smaller cluster", func() {
+			restore.SetCluster(&cluster.Cluster{ContentIDs: []int{-1, 0, 1}})
+			mock.ExpectExec(insertStr).WillReturnResult(sqlmock.NewResult(0, 10))
+			mock.ExpectExec(insertStr).WillReturnResult(sqlmock.NewResult(0, 5))
+			mock.ExpectExec(dropStr).WillReturnResult(sqlmock.NewResult(0, 0))
+			numRows, err := restore.LoadTableInThroughStaging(connectionPool, "public.foo", 3456, columns, filename, false, 0)
+
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(numRows).To(Equal(int64(15)))
+			Expect(mock.ExpectationsWereMet()).To(Succeed())
+		})
+	})
 	Describe("CheckRowsRestored", func() {
 		var (
 			expectedRows int64 = 10
@@ -133,6 +233,63 @@ var _ = Describe("restore/data tests", func() {
 			Expect(err.Error()).To(Equal("Expected to restore 10 rows to table public.foo, but restored 5 instead"))
 		})
 	})
+	Describe("GetChecksumsBySegment", func() {
+		It("groups the checksums of all tables by segment", func() {
+			dataEntries := []toc.CoordinatorDataEntry{
+				{Schema: "public", Name: "foo", Oid: 1, Checksums: map[int]string{0: "0a0a0a0a", 1: "1b1b1b1b"}},
+				{Schema: "public", Name: "bar", Oid: 2, Checksums: map[int]string{0: "2c2c2c2c", 1: "3d3d3d3d"}},
+			}
+			checksums := restore.GetChecksumsBySegment(dataEntries)
+			Expect(checksums).To(Equal(map[int]map[uint32]string{
+				0: {1: "0a0a0a0a", 2: "2c2c2c2c"},
+				1: {1: "1b1b1b1b", 2: "3d3d3d3d"},
+			}))
+		})
+		It("returns no checksums for a backup taken without checksums", func() {
+			dataEntries := []toc.CoordinatorDataEntry{{Schema: "public", Name: "foo", Oid: 1}}
+			Expect(restore.GetChecksumsBySegment(dataEntries)).To(BeEmpty())
+		})
+	})
+	Describe("ScheduleDataEntriesForRestore", func() {
+		small := toc.CoordinatorDataEntry{Schema: "public", Name: "small", Oid: 1, DataSize: 10}
+		large := toc.CoordinatorDataEntry{Schema: "public", Name: "large", Oid: 2, DataSize: 1000}
+		unsized := toc.CoordinatorDataEntry{Schema: "public", Name: "unsized", Oid: 3}
+
+		It("schedules the largest tables first", func() {
+			dataEntries := restore.ScheduleDataEntriesForRestore([]toc.CoordinatorDataEntry{small, unsized, large}, []string{})
+			Expect(dataEntries).To(Equal([]toc.CoordinatorDataEntry{large, small, unsized}))
+		})
+		It("schedules priority tables before all other tables", func() {
+			dataEntries := restore.ScheduleDataEntriesForRestore([]toc.CoordinatorDataEntry{small, unsized, large}, []string{"public.unsized"})
+			Expect(dataEntries).To(Equal([]toc.CoordinatorDataEntry{unsized, large, small}))
+		})
+		It("keeps the original order of tables from a backup without sizes", func() {
+			first := toc.CoordinatorDataEntry{Schema: "public", Name: "first", Oid: 4}
+			second := toc.CoordinatorDataEntry{Schema: "public", Name: "second", Oid: 5}
+			dataEntries := restore.ScheduleDataEntriesForRestore([]toc.CoordinatorDataEntry{first, second}, []string{})
+			Expect(dataEntries).To(Equal([]toc.CoordinatorDataEntry{first, second}))
+		})
+	})
+	Describe("LogPartialTableData", func() {
+		It("warns about tables that were backed up with a predicate", func() {
+			filteredDataEntries := map[string][]toc.CoordinatorDataEntry{
+				"20170101010101": {
+					{Schema: "public", Name: "full", Oid: 1},
+					{Schema: "public", Name: "partial", Oid: 2, Predicate: "a > 1"},
+				},
+			}
+			restore.LogPartialTableData(filteredDataEntries)
+			Expect(stdout).To(Say("1 table\\(s\\) were backed up with a table predicate and contain only part of their data"))
+			Expect(logfile).To(Say("Table public.partial was backed up with only the rows matching: a > 1"))
+		})
+		It("does not warn when no table was backed up with a predicate", func() {
+			filteredDataEntries := map[string][]toc.CoordinatorDataEntry{
+				"20170101010101": {{Schema: "public", Name: "full", Oid: 1}},
+			}
+			restore.LogPartialTableData(filteredDataEntries)
+			Expect(stdout).ToNot(Say("table predicate"))
+		})
+	})
 })
 
 func batchMapToString(m map[int]map[int]int) string {
@@ -34,7 +34,9 @@ var (
 	globalFPInfo        filepath.FilePathInfo
 	globalTOC           *toc.TOC
 	pluginConfig        *utils.PluginConfig
+	restoreProgress     *RestoreProgress
 	restoreStartTime    string
+	restoreStateFile    *RestoreStateFile
 	version             string
 	wasTerminated       bool
 	errorTablesMetadata map[string]Empty
@@ -52,6 +54,7 @@ func init() {
 	// Initialize global variables
 	errorTablesMetadata = make(map[string]Empty)
 	errorTablesData = make(map[string]Empty)
+	restoreProgress = NewRestoreProgress(nil)
 }
 
 /*
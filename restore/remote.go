@@ -60,7 +60,9 @@ func VerifyBackupFileCountOnSegments() {
 
 	// these are the file counts for non-resize restores.
 	fileCount := 2 // 1 for the actual data file, 1 for the segment TOC file
-	if !backupConfig.SingleDataFile {
+	if backupConfig.SingleDataFile && backupConfig.DataStreams > 1 {
+		fileCount = backupConfig.DataStreams + 1 // 1 data file per data stream
+	} else if !backupConfig.SingleDataFile {
 		fileCount = len(globalTOC.DataEntries)
 	}
 
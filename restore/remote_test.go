@@ -1,6 +1,9 @@
 package restore_test
 
 import (
+	"io/ioutil"
+	"os"
+	"os/exec"
 	"os/user"
 
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
@@ -116,5 +119,41 @@ var _ = Describe("restore/remote tests", func() {
 			// Expect is implied, does not need to be explicitly called here
 			restore.VerifyBackupFileCountOnSegments()
 		})
+		It("counts only the data files in the backup directories of a multiple-data-file backup", func() {
+			backupDir, err := ioutil.TempDir("", "gpbackup_remote_test")
+			Expect(err).ToNot(HaveOccurred())
+			defer os.RemoveAll(backupDir)
+			testFPInfo = filepath.NewFilePathInfo(testCluster, backupDir, "20170101010101", "gpseg", false)
+			restore.SetFPInfo(testFPInfo)
+			restore.SetBackupConfig(&history.BackupConfig{SingleDataFile: false})
+			restore.SetTOC(&toc.TOC{DataEntries: []toc.CoordinatorDataEntry{{Oid: 1}, {Oid: 2}}})
+			cmdFlags.Set(options.RESIZE_CLUSTER, "false")
+			for _, contentID := range []int{0, 1} {
+				_ = os.MkdirAll(testFPInfo.GetDirForContent(contentID), 0755)
+				backupFiles := []string{testFPInfo.GetTableBackupFilePath(contentID, 1, ".gz", false),
+					testFPInfo.GetTableBackupFilePath(contentID, 2, ".gz", false),
+					testFPInfo.GetSegmentChecksumFilePath(contentID)}
+				for _, backupFile := range backupFiles {
+					Expect(ioutil.WriteFile(backupFile, []byte{}, 0644)).To(Succeed())
+				}
+			}
+
+			// Capture the count commands, then run them locally against the backup directories
+			testExecutor.ClusterOutput = &cluster.RemoteOutput{}
+			restore.SetCluster(testCluster)
+			restore.VerifyBackupFileCountOnSegments()
+			commands := make([]cluster.ShellCommand, 0)
+			for _, command := range testExecutor.ClusterCommands[0] {
+				countCommand := command.Command.Args[len(command.Command.Args)-1]
+				output, err := exec.Command("bash", "-c", countCommand).Output()
+				Expect(err).ToNot(HaveOccurred())
+				commands = append(commands, cluster.ShellCommand{Content: command.Content, Stdout: string(output)})
+			}
+			Expect(commands).To(HaveLen(2))
+
+			testExecutor.ClusterOutput = &cluster.RemoteOutput{Commands: commands}
+			// LogFatalError in VerifyBackupFileCountOnSegments will fail test if the checksum files are counted
+			restore.VerifyBackupFileCountOnSegments()
+		})
 	})
 })
@@ -46,6 +46,10 @@ func DoValidation(cmd *cobra.Command) {
 	if providedTimestamp != "" && !filepath.IsValidTimestamp(providedTimestamp) {
 		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", providedTimestamp), "")
 	}
+	resumeTimestamp := MustGetFlagString(options.RESUME)
+	if resumeTimestamp != "" && !filepath.IsValidTimestamp(resumeTimestamp) {
+		gplog.Fatal(errors.Errorf("Timestamp %s is invalid.  Timestamps must be in the format YYYYMMDDHHMMSS.", resumeTimestamp), "")
+	}
 }
 
 // This function handles setup that must be done after parsing flags.
@@ -55,6 +59,9 @@ func DoSetup() {
 
 	utils.CheckGpexpandRunning(utils.RestorePreventedByGpexpandMessage)
 	restoreStartTime = history.CurrentTimestamp()
+	if resumeTimestamp := MustGetFlagString(options.RESUME); resumeTimestamp != "" {
+		restoreStartTime = resumeTimestamp
+	}
 
 	CreateConnectionPool("postgres")
 	segConfig := cluster.MustGetSegmentConfiguration(connectionPool)
@@ -81,6 +88,13 @@ func DoSetup() {
 	err = opts.QuoteExcludeRelations(connectionPool)
 	gplog.FatalOnError(err)
 
+	err = opts.QuoteRenamedRelations(connectionPool)
+	gplog.FatalOnError(err)
+
+	opts.QuoteRoleMap(connectionPool)
+	opts.QuoteTablespaceMap(connectionPool)
+	opts.QuoteRedirectSchemaMap(connectionPool)
+
 	segPrefix, singleBackupDir, err := filepath.ParseSegPrefix(MustGetFlagString(options.BACKUP_DIR), backupTimestamp)
 	gplog.FatalOnError(err)
 	globalFPInfo = filepath.NewFilePathInfo(globalCluster, MustGetFlagString(options.BACKUP_DIR), backupTimestamp, segPrefix, singleBackupDir)
@@ -89,6 +103,17 @@ func DoSetup() {
 		err = operating.System.MkdirAll(globalFPInfo.GetReportDirectoryPath(), 0775)
 		gplog.FatalOnError(err)
 	}
+	if MustGetFlagString(options.RESUME) != "" {
+		prepareRestoreForResume()
+	}
+	// As with the report file, a restore from a read-only backup directory is not failed for lack of a state file
+	restoreStateFilename := globalFPInfo.GetRestoreStateFilePath(restoreStartTime)
+	restoreStateFile, err = OpenRestoreStateFile(restoreStateFilename)
+	if err != nil {
+		gplog.Warn("Unable to open restore state file %s, so this restore cannot be resumed: %v", restoreStateFilename, err)
+	} else if MustGetFlagString(options.RESUME) == "" {
+		gplog.FatalOnError(restoreStateFile.RecordOptions(NewRestoreStateOptions()))
+	}
 
 	// Get restore metadata from plugin
 	if MustGetFlagString(options.PLUGIN_CONFIG) != "" {
@@ -112,11 +137,13 @@ func DoSetup() {
 	if MustGetFlagString(options.REDIRECT_DB) != "" {
 		unquotedRestoreDatabase = MustGetFlagString(options.REDIRECT_DB)
 	}
-	ValidateDatabaseExistence(unquotedRestoreDatabase, MustGetFlagBool(options.CREATE_DB), backupConfig.IncludeTableFiltered || backupConfig.DataOnly)
+	// The database already exists if the restore being resumed created it
+	createDB := MustGetFlagBool(options.CREATE_DB) && !restoreProgress.IsSectionCompleted("global") && !restoreProgress.IsSectionCompleted("database")
+	ValidateDatabaseExistence(unquotedRestoreDatabase, createDB, backupConfig.IncludeTableFiltered || backupConfig.DataOnly)
 	if MustGetFlagBool(options.WITH_GLOBALS) {
-		restoreGlobal(metadataFilename)
+		runRestoreSection("global", func() { restoreGlobal(metadataFilename) })
 	} else if MustGetFlagBool(options.CREATE_DB) {
-		createDatabase(metadataFilename)
+		runRestoreSection("database", func() { createDatabase(metadataFilename) })
 	}
 	if connectionPool != nil {
 		connectionPool.Close()
@@ -128,8 +155,9 @@ func DoSetup() {
 	 * should not error out for validation reasons once the restore database exists.
 	 * For on-error-continue, we will see the same errors later when we try to run SQL,
 	 * but since they will not stop the restore, it is not necessary to log them twice.
+	 * A resumed restore already created the relations it restores.
 	 */
-	if !MustGetFlagBool(options.CREATE_DB) && !MustGetFlagBool(options.ON_ERROR_CONTINUE) && !MustGetFlagBool(options.INCREMENTAL) {
+	if !MustGetFlagBool(options.CREATE_DB) && !MustGetFlagBool(options.ON_ERROR_CONTINUE) && !MustGetFlagBool(options.INCREMENTAL) && MustGetFlagString(options.RESUME) == "" {
 		relationsToRestore := GenerateRestoreRelationList(*opts)
 		if opts.RedirectSchema != "" {
 			fqns, err := options.SeparateSchemaAndTable(relationsToRestore)
@@ -139,6 +167,13 @@ func DoSetup() {
 				redirectRelationsToRestore = append(redirectRelationsToRestore, utils.MakeFQN(opts.RedirectSchema, fqn.Name))
 			}
 			relationsToRestore = redirectRelationsToRestore
+		} else if len(opts.RenamedRelations) > 0 || len(opts.RedirectSchemaMap) > 0 {
+			renamedRelationsToRestore := make([]string, 0, len(relationsToRestore))
+			for _, fqn := range relationsToRestore {
+				schema, name := splitQuotedFQN(fqn)
+				renamedRelationsToRestore = append(renamedRelationsToRestore, getRestoreTableName(schema, name))
+			}
+			relationsToRestore = renamedRelationsToRestore
 		}
 		ValidateRelationsInRestoreDatabase(connectionPool, relationsToRestore)
 	}
@@ -146,6 +181,9 @@ func DoSetup() {
 	if opts.RedirectSchema != "" {
 		ValidateRedirectSchema(connectionPool, opts.RedirectSchema)
 	}
+	for _, redirectSchema := range opts.RedirectSchemaMap {
+		ValidateRedirectSchema(connectionPool, utils.UnquoteIdent(redirectSchema))
+	}
 }
 
 func DoRestore() {
@@ -160,12 +198,12 @@ func DoRestore() {
 	}
 
 	if !isDataOnly && !isIncremental {
-		restorePredata(metadataFilename)
+		runRestoreSection("predata", func() { restorePredata(metadataFilename) })
 	} else if isDataOnly {
 		// The sequence setval commands need to be run during data only restores since
 		// they are arguably the data of the sequence relations and can affect user tables
 		// containing columns that reference those sequence relations.
-		restoreSequenceValues(metadataFilename)
+		runRestoreSection("sequences", func() { restoreSequenceValues(metadataFilename) })
 	}
 
 	totalTablesRestored := 0
@@ -177,13 +215,13 @@ func DoRestore() {
 	}
 
 	if !isDataOnly && !isIncremental {
-		restorePostdata(metadataFilename)
+		runRestoreSection("postdata", func() { restorePostdata(metadataFilename) })
 	}
 
 	if MustGetFlagBool(options.WITH_STATS) && backupConfig.WithStatistics {
-		restoreStatistics()
+		runRestoreSection("statistics", restoreStatistics)
 	} else if MustGetFlagBool(options.RUN_ANALYZE) && totalTablesRestored > 0 {
-		runAnalyze(filteredDataEntries)
+		runRestoreSection("analyze", func() { runAnalyze(filteredDataEntries) })
 	}
 }
 
@@ -197,6 +235,8 @@ func createDatabase(metadataFilename string) {
 		dbName = quotedDBName
 		statements = toc.SubstituteRedirectDatabaseInStatements(statements, backupConfig.DatabaseName, quotedDBName)
 	}
+	statements = editStatementsRoles(statements)
+	statements = editStatementsTablespaces(statements)
 	numErrors := ExecuteRestoreMetadataStatements("global", statements, "", nil, utils.PB_NONE, false)
 
 	if numErrors > 0 {
@@ -218,6 +258,8 @@ func restoreGlobal(metadataFilename string) {
 		quotedDBName := utils.QuoteIdent(connectionPool, MustGetFlagString(options.REDIRECT_DB))
 		statements = toc.SubstituteRedirectDatabaseInStatements(statements, backupConfig.DatabaseName, quotedDBName)
 	}
+	statements = editStatementsRoles(statements)
+	statements = editStatementsTablespaces(statements)
 	statements = toc.RemoveActiveRole(connectionPool.User, statements)
 	numErrors := ExecuteRestoreMetadataStatements("global", statements, "Global objects", nil, utils.PB_VERBOSE, false)
 
@@ -298,10 +340,21 @@ func restorePredata(metadataFilename string) {
 	var schemaStatements []toc.StatementWithType
 	if opts.RedirectSchema == "" {
 		schemaStatements = GetRestoreMetadataStatementsFiltered("predata", metadataFilename, []string{toc.OBJ_SCHEMA}, []string{}, filters)
+		// Schemas are redirected into schemas that already exist, so only the others are created
+		createdSchemaStatements := make([]toc.StatementWithType, 0, len(schemaStatements))
+		for _, statement := range schemaStatements {
+			if _, ok := opts.RedirectSchemaMap[statement.Schema]; !ok {
+				createdSchemaStatements = append(createdSchemaStatements, statement)
+			}
+		}
+		schemaStatements = editStatementsRoles(createdSchemaStatements)
 	}
 	statements := GetRestoreMetadataStatementsFiltered("predata", metadataFilename, []string{}, []string{toc.OBJ_SCHEMA}, filters)
+	statements = editStatementsRoles(statements)
+	statements = editStatementsTablespaces(statements)
 
-	editStatementsRedirectSchema(statements, opts.RedirectSchema)
+	editStatementsRenameTables(statements, getRenamedRelations(globalTOC, opts.RenamedRelations))
+	editStatementsRedirectSchema(statements, opts.RedirectSchema, opts.RedirectSchemaMap)
 	progressBar := utils.NewProgressBar(len(schemaStatements)+len(statements), "Pre-data objects restored: ", utils.PB_VERBOSE)
 	progressBar.Start()
 
@@ -384,6 +437,8 @@ func restoreSequenceValues(metadataFilename string) {
 			sequenceValueStatements = append(sequenceValueStatements, statement)
 		}
 	}
+	editStatementsRenameTables(sequenceValueStatements, getRenamedRelations(globalTOC, opts.RenamedRelations))
+	editStatementsRedirectSchema(sequenceValueStatements, opts.RedirectSchema, opts.RedirectSchemaMap)
 
 	numErrors := int32(0)
 	if len(sequenceValueStatements) == 0 {
@@ -404,38 +459,90 @@ func restoreSequenceValues(metadataFilename string) {
 	}
 }
 
-func editStatementsRedirectSchema(statements []toc.StatementWithType, redirectSchema string) {
-	if redirectSchema == "" {
+/*
+ * Replaces roles with the roles they are mapped to with --role-map, and removes
+ * the owner and privileges statements with --no-owner and --no-privileges.
+ */
+func editStatementsRoles(statements []toc.StatementWithType) []toc.StatementWithType {
+	statements = toc.SubstituteRolesInStatements(statements, opts.RoleMap)
+	if MustGetFlagBool(options.NO_OWNER) {
+		statements = toc.RemoveOwnerStatements(statements)
+	}
+	if MustGetFlagBool(options.NO_PRIVILEGES) {
+		statements = toc.RemovePrivilegesStatements(statements)
+	}
+	return statements
+}
+
+/*
+ * Replaces tablespaces with the tablespaces they are mapped to with
+ * --tablespace-map, and removes them with --no-tablespaces.
+ */
+func editStatementsTablespaces(statements []toc.StatementWithType) []toc.StatementWithType {
+	if MustGetFlagBool(options.NO_TABLESPACES) {
+		return toc.RemoveTablespacesFromStatements(statements)
+	}
+	return toc.SubstituteTablespacesInStatements(statements, opts.TablespaceMap)
+}
+
+/*
+ * Redirects every statement to redirectSchema, or only the statements of the
+ * schemas in redirectSchemaMap to the schemas they are mapped to.
+ */
+func editStatementsRedirectSchema(statements []toc.StatementWithType, redirectSchema string, redirectSchemaMap map[string]string) {
+	if redirectSchema == "" && len(redirectSchemaMap) == 0 {
 		return
 	}
+	getNewSchema := func(schema string) string {
+		if newSchema, ok := redirectSchemaMap[schema]; ok {
+			return newSchema
+		} else if redirectSchema != "" {
+			return redirectSchema
+		}
+		return schema
+	}
 
 	schemaMatch := `(?:".+?"|[^.]+?)` // matches either an unquoted schema with no dots or a quoted schema containing dots
 	// This expression matches a GRANT or REVOKE statement on any object and captures the old schema name
 	permissionsRE := regexp.MustCompile(fmt.Sprintf(`(?m)(^(?:REVOKE|GRANT) .+ ON .+?) (%s)((\..+)? (?:FROM|TO) .+)`, schemaMatch))
 	// This expression matches an ATTACH PARTITION statement and captures both the parent and child schema names
 	attachRE := regexp.MustCompile(fmt.Sprintf(`(ALTER TABLE(?: ONLY)?) (%[1]s)(\..+ ATTACH PARTITION) (%[1]s)(\..+)`, schemaMatch))
+	schemaPrefixes := make(map[string]string, len(redirectSchemaMap))
+	for schema, newSchema := range redirectSchemaMap {
+		schemaPrefixes[schema+"."] = newSchema + "."
+	}
 	for i := range statements {
+		newSchemaName := getNewSchema(statements[i].Schema)
 		oldSchema := fmt.Sprintf("%s.", statements[i].Schema)
-		newSchema := fmt.Sprintf("%s.", redirectSchema)
+		newSchema := fmt.Sprintf("%s.", newSchemaName)
 		statement := statements[i].Statement
 		// Schemas themselves are a special case, since they lack the trailing dot.
-		statement = strings.Replace(statement, fmt.Sprintf("CREATE SCHEMA %s", statements[i].Schema), fmt.Sprintf("CREATE SCHEMA %s", redirectSchema), 1)
+		statement = strings.Replace(statement, fmt.Sprintf("CREATE SCHEMA %s", statements[i].Schema), fmt.Sprintf("CREATE SCHEMA %s", newSchemaName), 1)
 
-		statements[i].Schema = redirectSchema
+		statements[i].Schema = newSchemaName
 		if statements[i].ObjectType == toc.OBJ_SCHEMA {
-			statements[i].Name = redirectSchema
+			statements[i].Name = newSchemaName
 		}
 		replaced := false
 
 		// Permission statements come in multi-line blocks, so ensure we replace all instances of the existing schema
 		if strings.Contains(statement, "GRANT") || strings.Contains(statement, "REVOKE") {
-			statement = permissionsRE.ReplaceAllString(statement, fmt.Sprintf("$1 %s$3", redirectSchema))
+			statement = permissionsRE.ReplaceAllString(statement, fmt.Sprintf("$1 %s$3", newSchemaName))
 			replaced = true
 		}
 
-		// ALTER TABLE schema.root ATTACH PARTITION schema.leaf needs two schema replacements
+		// ALTER TABLE schema.root ATTACH PARTITION schema.leaf needs two schema replacements, which may differ with a schema map
 		if connectionPool.Version.AtLeast("7") && statements[i].ObjectType == toc.OBJ_TABLE && statements[i].ReferenceObject != "" && strings.Contains(statement, "ATTACH") {
-			statement = attachRE.ReplaceAllString(statement, fmt.Sprintf("$1 %[1]s$3 %[1]s$5", redirectSchema))
+			statement = attachRE.ReplaceAllStringFunc(statement, func(match string) string {
+				groups := attachRE.FindStringSubmatch(match)
+				return fmt.Sprintf("%s %s%s %s%s", groups[1], getNewSchema(groups[2]), groups[3], getNewSchema(groups[4]), groups[5])
+			})
+			replaced = true
+		}
+
+		// Every object of a mapped schema moves with it, so references to it from foreign keys, defaults, and views move too
+		if len(redirectSchemaMap) > 0 && !replaced {
+			statement = replaceRelationFQNs(statement, schemaPrefixes)
 			replaced = true
 		}
 
@@ -443,13 +550,283 @@ func editStatementsRedirectSchema(statements []toc.StatementWithType, redirectSc
 		if !replaced {
 			statement = strings.Replace(statement, oldSchema, newSchema, 1)
 		}
+		// The value of a sequence is set by name after it is created, so that name needs replacing as well
+		if statements[i].ObjectType == toc.OBJ_SEQUENCE && len(redirectSchemaMap) == 0 {
+			statement = strings.Replace(statement, fmt.Sprintf("setval('%s", oldSchema), fmt.Sprintf("setval('%s", newSchema), 1)
+		}
 		statements[i].Statement = statement
 
 		// only postdata will have a reference object
 		if statements[i].ReferenceObject != "" {
-			statements[i].ReferenceObject = strings.Replace(statements[i].ReferenceObject, oldSchema, newSchema, 1)
+			referenceSchema, _ := splitQuotedFQN(statements[i].ReferenceObject)
+			if referenceSchema != "" {
+				statements[i].ReferenceObject = strings.Replace(statements[i].ReferenceObject, fmt.Sprintf("%s.", referenceSchema), fmt.Sprintf("%s.", getNewSchema(referenceSchema)), 1)
+			}
+		}
+	}
+}
+
+/*
+ * Returns the name a table is restored under, which differs from the name it
+ * was backed up under with --rename-table, --redirect-schema, or
+ * --redirect-schema-map.
+ */
+func getRestoreTableName(schema string, name string) string {
+	fqn := utils.MakeFQN(schema, name)
+	if newFQN, ok := opts.RenamedRelations[fqn]; ok {
+		return newFQN
+	}
+	if newSchema, ok := opts.RedirectSchemaMap[schema]; ok {
+		return utils.MakeFQN(newSchema, name)
+	}
+	if opts.RedirectSchema != "" {
+		return utils.MakeFQN(opts.RedirectSchema, name)
+	}
+	return fqn
+}
+
+/*
+ * Returns the tables renamed with --rename-table along with the new names of
+ * their indexes and owned sequences.  These share a namespace with tables, so
+ * restoring them under their old names fails if the table they were backed up
+ * with still exists.
+ */
+func getRenamedRelations(tocfile *toc.TOC, renamedTables map[string]string) map[string]string {
+	renamedRelations := make(map[string]string, len(renamedTables))
+	for fqn, newFQN := range renamedTables {
+		renamedRelations[fqn] = newFQN
+	}
+	if len(renamedTables) == 0 || tocfile == nil {
+		return renamedRelations
+	}
+
+	entries := append(append([]toc.MetadataEntry{}, tocfile.PredataEntries...), tocfile.PostdataEntries...)
+	for _, entry := range entries {
+		newTableFQN, isTableRenamed := renamedTables[entry.ReferenceObject]
+		if !isTableRenamed || (entry.ObjectType != toc.OBJ_INDEX && entry.ObjectType != toc.OBJ_SEQUENCE_OWNER) {
+			continue
+		}
+		fqn := utils.MakeFQN(entry.Schema, entry.Name)
+		if _, ok := renamedRelations[fqn]; ok {
+			continue
 		}
+		// Indexes always live in the schema of their table, and owned sequences are kept alongside it
+		_, tableName := splitQuotedFQN(entry.ReferenceObject)
+		newSchema, newTableName := splitQuotedFQN(newTableFQN)
+		renamedRelations[fqn] = utils.MakeFQN(newSchema, getDependentObjectName(entry.Name, tableName, newTableName))
+	}
+	return renamedRelations
+}
+
+/*
+ * Derives the new name of an object that belongs to a renamed table, such as
+ * an index or constraint, by replacing the name of the table at its start, so
+ * that orders_pkey becomes orders_2026_pkey, or else by appending the new name
+ * of the table.
+ */
+func getDependentObjectName(name string, tableName string, newTableName string) string {
+	name = utils.UnquoteIdent(name)
+	tableName = utils.UnquoteIdent(tableName)
+	newTableName = utils.UnquoteIdent(newTableName)
+	if strings.HasPrefix(name, tableName) {
+		name = newTableName + strings.TrimPrefix(name, tableName)
+	} else {
+		name = fmt.Sprintf("%s_%s", name, newTableName)
+	}
+	if unquotedIdentRE.MatchString(name) {
+		return name
 	}
+	return fmt.Sprintf(`"%s"`, strings.Replace(name, `"`, `""`, -1))
+}
+
+/*
+ * Rewrites the statements of each renamed table, and those of the objects that
+ * belong to it such as its indexes, constraints, owned sequences, and
+ * statistics, to use its new name and those of its objects.  Other objects
+ * that refer to a renamed table, such as views, are restored as they were
+ * backed up.
+ */
+func editStatementsRenameTables(statements []toc.StatementWithType, renamedRelations map[string]string) {
+	if len(renamedRelations) == 0 {
+		return
+	}
+	renamedObjectTypes := map[string]bool{toc.OBJ_TABLE: true, toc.OBJ_FOREIGN_TABLE: true, toc.OBJ_STATISTICS: true,
+		toc.OBJ_SEQUENCE: true, toc.OBJ_SEQUENCE_OWNER: true, toc.OBJ_INDEX: true, "INDEX METADATA": true}
+
+	for i := range statements {
+		fqn := utils.MakeFQN(statements[i].Schema, statements[i].Name)
+		newFQN, isRenamed := renamedRelations[fqn]
+		isRenamed = isRenamed && renamedObjectTypes[statements[i].ObjectType]
+		newReferenceObject, isReferenceRenamed := renamedRelations[statements[i].ReferenceObject]
+		if !isRenamed && !isReferenceRenamed {
+			continue
+		}
+
+		// A partition is attached to its parent by name, so every renamed relation in the statement is replaced
+		statement := replaceRelationFQNs(statements[i].Statement, renamedRelations)
+		// Indexes and constraints are created and referred to by their unqualified names
+		oldName := statements[i].Name
+		switch {
+		case isRenamed && statements[i].ObjectType == toc.OBJ_INDEX:
+			_, newName := splitQuotedFQN(newFQN)
+			statement = strings.Replace(statement, fmt.Sprintf(" INDEX %s ON ", oldName), fmt.Sprintf(" INDEX %s ON ", newName), 1)
+		case isRenamed && statements[i].ObjectType == "INDEX METADATA":
+			_, newName := splitQuotedFQN(newFQN)
+			statement = strings.Replace(statement, fmt.Sprintf(" CLUSTER ON %s;", oldName), fmt.Sprintf(" CLUSTER ON %s;", newName), 1)
+			statement = strings.Replace(statement, fmt.Sprintf(" USING INDEX %s;", oldName), fmt.Sprintf(" USING INDEX %s;", newName), 1)
+		case isReferenceRenamed && statements[i].ObjectType == toc.OBJ_CONSTRAINT:
+			_, tableName := splitQuotedFQN(statements[i].ReferenceObject)
+			newSchema, newTableName := splitQuotedFQN(newReferenceObject)
+			newName := getDependentObjectName(oldName, tableName, newTableName)
+			statement = strings.Replace(statement, fmt.Sprintf("ADD CONSTRAINT %s ", oldName), fmt.Sprintf("ADD CONSTRAINT %s ", newName), 1)
+			statement = strings.Replace(statement, fmt.Sprintf("COMMENT ON CONSTRAINT %s ON ", oldName), fmt.Sprintf("COMMENT ON CONSTRAINT %s ON ", newName), 1)
+			statements[i].Schema, statements[i].Name = newSchema, newName
+		}
+		statements[i].Statement = statement
+		if isRenamed {
+			statements[i].Schema, statements[i].Name = splitQuotedFQN(newFQN)
+		}
+		if isReferenceRenamed {
+			statements[i].ReferenceObject = newReferenceObject
+		}
+	}
+}
+
+/*
+ * Replaces each whole occurrence of a renamed relation's name in a statement in
+ * a single pass, so that relations whose names are swapped are each renamed
+ * once and a relation whose name starts with that of a renamed relation is left
+ * alone.  A name ending in a dot is a schema, and replaces the schema of every
+ * name qualified with it.  Comments and string literals, including dollar-quoted
+ * function bodies, are left alone as well, except for literals that name a
+ * relation, as in nextval('schema.seq'::regclass) and setval('schema.seq', 1, true).
+ */
+func replaceRelationFQNs(statement string, renamedRelations map[string]string) string {
+	var builder strings.Builder
+	for i := 0; i < len(statement); {
+		if statement[i] == '\'' {
+			end := findQuoteEnd(statement, i)
+			builder.WriteString(replaceRelationLiteral(statement, i, end, renamedRelations))
+			i = end
+			continue
+		}
+		if statement[i] == '$' && (i == 0 || !isIdentifierChar(statement[i-1])) {
+			if tag := dollarQuoteRE.FindString(statement[i:]); tag != "" {
+				end := len(statement)
+				if bodyEnd := strings.Index(statement[i+len(tag):], tag); bodyEnd >= 0 {
+					end = i + len(tag) + bodyEnd + len(tag)
+				}
+				builder.WriteString(statement[i:end])
+				i = end
+				continue
+			}
+		}
+		if strings.HasPrefix(statement[i:], "--") || strings.HasPrefix(statement[i:], "/*") {
+			end := len(statement)
+			if statement[i] == '-' {
+				if newline := strings.IndexByte(statement[i:], '\n'); newline >= 0 {
+					end = i + newline
+				}
+			} else if commentEnd := strings.Index(statement[i+2:], "*/"); commentEnd >= 0 {
+				end = i + 2 + commentEnd + 2
+			}
+			builder.WriteString(statement[i:end])
+			i = end
+			continue
+		}
+
+		oldFQN := ""
+		if i == 0 || (!isIdentifierChar(statement[i-1]) && statement[i-1] != '.') {
+			for fqn := range renamedRelations {
+				end := i + len(fqn)
+				if len(fqn) > len(oldFQN) && strings.HasPrefix(statement[i:], fqn) &&
+					(strings.HasSuffix(fqn, ".") || end == len(statement) || !isIdentifierChar(statement[end])) {
+					oldFQN = fqn
+				}
+			}
+		}
+		if oldFQN != "" {
+			builder.WriteString(renamedRelations[oldFQN])
+			i += len(oldFQN)
+		} else if statement[i] == '"' {
+			// A quoted identifier may contain quotes or comment markers of its own
+			end := findQuoteEnd(statement, i)
+			builder.WriteString(statement[i:end])
+			i = end
+		} else {
+			builder.WriteByte(statement[i])
+			i++
+		}
+	}
+	return builder.String()
+}
+
+// Returns the index just past the quote closing the one at start, skipping doubled quotes
+func findQuoteEnd(statement string, start int) int {
+	quote := statement[start]
+	for end := start + 1; end < len(statement); end++ {
+		if statement[end] == quote {
+			if end+1 < len(statement) && statement[end+1] == quote {
+				end++
+				continue
+			}
+			return end + 1
+		}
+	}
+	return len(statement)
+}
+
+/*
+ * Returns the string literal between start and end, replaced with the new name
+ * of the relation it names, or of the schema that relation is in, if it is cast
+ * to regclass or names a sequence whose value is set.
+ */
+func replaceRelationLiteral(statement string, start int, end int, renamedRelations map[string]string) string {
+	literal := statement[start:end]
+	namesRelation := strings.HasPrefix(statement[end:], "::regclass") || strings.HasSuffix(statement[:start], "setval(")
+	if !namesRelation || len(literal) < 2 {
+		return literal
+	}
+	for fqn, newFQN := range renamedRelations {
+		quotedFQN := fmt.Sprintf("'%s", utils.EscapeSingleQuotes(fqn))
+		if literal == quotedFQN+"'" || (strings.HasSuffix(fqn, ".") && strings.HasPrefix(literal, quotedFQN)) {
+			return fmt.Sprintf("'%s", utils.EscapeSingleQuotes(newFQN)) + literal[len(quotedFQN):]
+		}
+	}
+	return literal
+}
+
+var dollarQuoteRE = regexp.MustCompile(`^\$(?:[A-Za-z_][A-Za-z_0-9]*)?\$`)
+
+// Names matching this need no quotes
+var unquotedIdentRE = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+func isIdentifierChar(char byte) bool {
+	return char == '_' || char == '$' || char == '"' || char >= 0x80 ||
+		(char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9')
+}
+
+// Splits a fully-qualified name whose schema may be quoted and contain dots
+func splitQuotedFQN(fqn string) (string, string) {
+	end := 0
+	if strings.HasPrefix(fqn, `"`) {
+		for end = 1; end < len(fqn); end++ {
+			if fqn[end] == '"' {
+				if end+1 < len(fqn) && fqn[end+1] == '"' {
+					end++
+					continue
+				}
+				break
+			}
+		}
+		end++
+	} else {
+		end = strings.Index(fqn, ".")
+	}
+	if end < 0 || end >= len(fqn) {
+		return "", fqn
+	}
+	return fqn[:end], fqn[end+1:]
 }
 
 func restoreData() (int, map[string][]toc.CoordinatorDataEntry) {
@@ -475,15 +852,29 @@ func restoreData() (int, map[string][]toc.CoordinatorDataEntry) {
 		restorePlanTableFQNs := entry.TableFQNs
 		filteredDataEntriesForTimestamp := tocfile.GetDataEntriesMatching(opts.IncludedSchemas,
 			opts.ExcludedSchemas, opts.IncludedRelations, opts.ExcludedRelations, restorePlanTableFQNs)
+		// The data entries of earlier backups in an incremental backup set are only read here
+		ValidateDataFormatsForRestore(filteredDataEntriesForTimestamp)
 		filteredDataEntries[entry.Timestamp] = filteredDataEntriesForTimestamp
 		totalTables += len(filteredDataEntriesForTimestamp)
 	}
-	dataProgressBar := utils.NewProgressBar(totalTables, "Tables restored: ", utils.PB_INFO)
+	LogPartialTableData(filteredDataEntries)
+
+	// The tables loaded by the restore being resumed are still returned, so that they are analyzed
+	dataEntriesToLoad := make(map[string][]toc.CoordinatorDataEntry, len(filteredDataEntries))
+	numTablesToLoad := 0
+	for timestamp, entries := range filteredDataEntries {
+		dataEntriesToLoad[timestamp] = restoreProgress.FilterLoadedTables(timestamp, entries)
+		numTablesToLoad += len(dataEntriesToLoad[timestamp])
+	}
+	if numTablesToLoad < totalTables {
+		gplog.Info("Skipping data for %d table(s) restored by the resumed restore", totalTables-numTablesToLoad)
+	}
+	dataProgressBar := utils.NewProgressBar(numTablesToLoad, "Tables restored: ", utils.PB_INFO)
 	dataProgressBar.Start()
 
 	gucStatements := setGUCsForConnection(nil, 0)
 	numErrors := int32(0)
-	for timestamp, entries := range filteredDataEntries {
+	for timestamp, entries := range dataEntriesToLoad {
 		gplog.Verbose("Restoring data for %d tables from backup with timestamp: %s", len(entries), timestamp)
 		numErrors = restoreDataFromTimestamp(GetBackupFPInfoForTimestamp(timestamp), entries, gucStatements, dataProgressBar)
 	}
@@ -509,7 +900,10 @@ func restorePostdata(metadataFilename string) {
 	filters := NewFilters(opts.IncludedSchemas, opts.ExcludedSchemas, opts.IncludedRelations, opts.ExcludedRelations)
 
 	statements := GetRestoreMetadataStatementsFiltered("postdata", metadataFilename, []string{}, []string{}, filters)
-	editStatementsRedirectSchema(statements, opts.RedirectSchema)
+	statements = editStatementsRoles(statements)
+	statements = editStatementsTablespaces(statements)
+	editStatementsRenameTables(statements, getRenamedRelations(globalTOC, opts.RenamedRelations))
+	editStatementsRedirectSchema(statements, opts.RedirectSchema, opts.RedirectSchemaMap)
 	firstBatch, secondBatch, thirdBatch := BatchPostdataStatements(statements)
 	progressBar := utils.NewProgressBar(len(statements), "Post-data objects restored: ", utils.PB_VERBOSE)
 	progressBar.Start()
@@ -538,7 +932,8 @@ func restoreStatistics() {
 	filters := NewFilters(opts.IncludedSchemas, opts.ExcludedSchemas, opts.IncludedRelations, opts.ExcludedRelations)
 
 	statements := GetRestoreMetadataStatementsFiltered("statistics", statisticsFilename, []string{}, []string{}, filters)
-	editStatementsRedirectSchema(statements, opts.RedirectSchema)
+	editStatementsRenameTables(statements, getRenamedRelations(globalTOC, opts.RenamedRelations))
+	editStatementsRedirectSchema(statements, opts.RedirectSchema, opts.RedirectSchemaMap)
 	numErrors := ExecuteRestoreMetadataStatements("statistics", statements, "Table statistics", nil, utils.PB_VERBOSE, false)
 
 	if numErrors > 0 {
@@ -557,16 +952,13 @@ func runAnalyze(filteredDataEntries map[string][]toc.CoordinatorDataEntry) {
 	var analyzeStatements []toc.StatementWithType
 	for _, dataEntries := range filteredDataEntries {
 		for _, entry := range dataEntries {
-			tableSchema := entry.Schema
-			if opts.RedirectSchema != "" {
-				tableSchema = opts.RedirectSchema
-			}
-			tableFQN := utils.MakeFQN(tableSchema, entry.Name)
+			tableFQN := getRestoreTableName(entry.Schema, entry.Name)
 			analyzeCommand := fmt.Sprintf("ANALYZE %s", tableFQN)
 
+			tableSchema, tableName := splitQuotedFQN(tableFQN)
 			newAnalyzeStatement := toc.StatementWithType{
 				Schema:    tableSchema,
-				Name:      entry.Name,
+				Name:      tableName,
 				Statement: analyzeCommand,
 			}
 			analyzeStatements = append(analyzeStatements, newAnalyzeStatement)
@@ -721,6 +1113,9 @@ func DoCleanup(restoreFailed bool) {
 		}
 	}
 
+	if restoreStateFile != nil {
+		_ = restoreStateFile.Close()
+	}
 	if connectionPool != nil {
 		connectionPool.Close()
 	}
@@ -64,7 +64,7 @@ var _ = Describe("restore internal tests", func() {
 			originalStatements := make([]toc.StatementWithType, len(statements))
 			copy(originalStatements, statements)
 
-			editStatementsRedirectSchema(statements, "")
+			editStatementsRedirectSchema(statements, "", map[string]string{})
 
 			// Loop through statements individually instead of comparing the whole arrays directly,
 			// to make it easier to find the statements with issues
@@ -78,7 +78,7 @@ var _ = Describe("restore internal tests", func() {
 			connectionPool.Version = dbconn.NewVersion("7.0.0")
 			defer func() { connectionPool.Version = oldVersion }()
 
-			editStatementsRedirectSchema(statements, "foo2")
+			editStatementsRedirectSchema(statements, "foo2", map[string]string{})
 
 			expectedStatements := []toc.StatementWithType{
 				{
@@ -136,5 +136,319 @@ var _ = Describe("restore internal tests", func() {
 				Expect(statements[i]).To(Equal(expectedStatements[i]))
 			}
 		})
+		It("changes schema in the sql statement for each mapped schema", func() {
+			oldVersion := connectionPool.Version
+			connectionPool.Version = dbconn.NewVersion("7.0.0")
+			defer func() { connectionPool.Version = oldVersion }()
+
+			mapStatements := []toc.StatementWithType{
+				{ // table in a mapped schema
+					Schema: "app_a", Name: "bar", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE app_a.bar (\n\ti integer\n) DISTRIBUTED BY (i);\n",
+				},
+				{ // table in a schema that is not mapped
+					Schema: "public", Name: "bar", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE public.bar (\n\ti integer\n) DISTRIBUTED BY (i);\n",
+				},
+				{ // sequence whose value is set by name
+					Schema: "app_b", Name: "myseq", ObjectType: toc.OBJ_SEQUENCE,
+					Statement: "\n\nCREATE SEQUENCE app_b.myseq\n\tSTART WITH 1;\n\nSELECT pg_catalog.setval('app_b.myseq', 5, true);\n",
+				},
+				{ // multi-line permissions block for a table in a mapped schema
+					Schema: "app_b", Name: "baz", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nREVOKE ALL ON TABLE app_b.baz FROM PUBLIC;\nGRANT ALL ON TABLE app_b.baz TO testuser;\n",
+				},
+				{ // index on a table in a mapped schema
+					Schema: "app_a", Name: "bar_idx", ObjectType: toc.OBJ_INDEX, ReferenceObject: "app_a.bar",
+					Statement: "\n\nCREATE INDEX bar_idx ON app_a.bar USING btree (i);\n",
+				},
+				{ // partition attached to a parent in another mapped schema
+					Schema: "app_b", Name: "foopart_p1", ObjectType: toc.OBJ_TABLE, ReferenceObject: "app_a.foopart",
+					Statement: "\n\nALTER TABLE ONLY app_a.foopart ATTACH PARTITION app_b.foopart_p1 FOR VALUES FROM (0) TO (1);\n",
+				},
+				{ // partition attached to a parent in a schema that is not mapped
+					Schema: "app_a", Name: "foopart_p2", ObjectType: toc.OBJ_TABLE, ReferenceObject: "public.foopart",
+					Statement: "\n\nALTER TABLE ONLY public.foopart ATTACH PARTITION app_a.foopart_p2 FOR VALUES FROM (1) TO (2);\n",
+				},
+			}
+
+			editStatementsRedirectSchema(mapStatements, "", map[string]string{"app_a": "stage_a", "app_b": "stage_b"})
+
+			expectedStatements := []toc.StatementWithType{
+				{
+					Schema: "stage_a", Name: "bar", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE stage_a.bar (\n\ti integer\n) DISTRIBUTED BY (i);\n",
+				},
+				{
+					Schema: "public", Name: "bar", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE public.bar (\n\ti integer\n) DISTRIBUTED BY (i);\n",
+				},
+				{
+					Schema: "stage_b", Name: "myseq", ObjectType: toc.OBJ_SEQUENCE,
+					Statement: "\n\nCREATE SEQUENCE stage_b.myseq\n\tSTART WITH 1;\n\nSELECT pg_catalog.setval('stage_b.myseq', 5, true);\n",
+				},
+				{
+					Schema: "stage_b", Name: "baz", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nREVOKE ALL ON TABLE stage_b.baz FROM PUBLIC;\nGRANT ALL ON TABLE stage_b.baz TO testuser;\n",
+				},
+				{
+					Schema: "stage_a", Name: "bar_idx", ObjectType: toc.OBJ_INDEX, ReferenceObject: "stage_a.bar",
+					Statement: "\n\nCREATE INDEX bar_idx ON stage_a.bar USING btree (i);\n",
+				},
+				{
+					Schema: "stage_b", Name: "foopart_p1", ObjectType: toc.OBJ_TABLE, ReferenceObject: "stage_a.foopart",
+					Statement: "\n\nALTER TABLE ONLY stage_a.foopart ATTACH PARTITION stage_b.foopart_p1 FOR VALUES FROM (0) TO (1);\n",
+				},
+				{
+					Schema: "stage_a", Name: "foopart_p2", ObjectType: toc.OBJ_TABLE, ReferenceObject: "public.foopart",
+					Statement: "\n\nALTER TABLE ONLY public.foopart ATTACH PARTITION stage_a.foopart_p2 FOR VALUES FROM (1) TO (2);\n",
+				},
+			}
+			for i := range mapStatements {
+				Expect(mapStatements[i]).To(Equal(expectedStatements[i]))
+			}
+		})
+		It("changes every reference to a mapped schema in the sql statement", func() {
+			mapStatements := []toc.StatementWithType{
+				{ // table whose default refers to a sequence in a mapped schema
+					Schema: "app_a", Name: "orders", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE app_a.orders (\n\tid integer DEFAULT nextval('app_a.orders_id_seq'::regclass) NOT NULL,\n\tnote text DEFAULT 'app_a.note'\n) INHERITS (app_b.base) DISTRIBUTED BY (id);\n",
+				},
+				{ // foreign key referring to a table in another mapped schema
+					Schema: "app_a", Name: "orders_customer_fkey", ObjectType: toc.OBJ_CONSTRAINT, ReferenceObject: "app_a.orders",
+					Statement: "\n\nALTER TABLE ONLY app_a.orders ADD CONSTRAINT orders_customer_fkey FOREIGN KEY (customer_id) REFERENCES app_b.customers(id);\n",
+				},
+				{ // view in a schema that is not mapped
+					Schema: "public", Name: "myview", ObjectType: toc.OBJ_VIEW,
+					Statement: "\n\nCREATE VIEW public.myview AS  SELECT orders.id\n   FROM app_a.orders -- app_a.orders\n   JOIN public.bar ON true;\n",
+				},
+			}
+
+			editStatementsRedirectSchema(mapStatements, "", map[string]string{"app_a": "stage_a", "app_b": "stage_b"})
+
+			expectedStatements := []toc.StatementWithType{
+				{
+					Schema: "stage_a", Name: "orders", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE stage_a.orders (\n\tid integer DEFAULT nextval('stage_a.orders_id_seq'::regclass) NOT NULL,\n\tnote text DEFAULT 'app_a.note'\n) INHERITS (stage_b.base) DISTRIBUTED BY (id);\n",
+				},
+				{
+					Schema: "stage_a", Name: "orders_customer_fkey", ObjectType: toc.OBJ_CONSTRAINT, ReferenceObject: "stage_a.orders",
+					Statement: "\n\nALTER TABLE ONLY stage_a.orders ADD CONSTRAINT orders_customer_fkey FOREIGN KEY (customer_id) REFERENCES stage_b.customers(id);\n",
+				},
+				{
+					Schema: "public", Name: "myview", ObjectType: toc.OBJ_VIEW,
+					Statement: "\n\nCREATE VIEW public.myview AS  SELECT orders.id\n   FROM stage_a.orders -- app_a.orders\n   JOIN public.bar ON true;\n",
+				},
+			}
+			for i := range mapStatements {
+				Expect(mapStatements[i]).To(Equal(expectedStatements[i]))
+			}
+		})
+		It("changes schema in a sequence value statement", func() {
+			sequenceStatements := []toc.StatementWithType{{
+				Schema: "app_a", Name: "myseq", ObjectType: toc.OBJ_SEQUENCE,
+				Statement: "SELECT pg_catalog.setval('app_a.myseq', 5, true);",
+			}}
+
+			editStatementsRedirectSchema(sequenceStatements, "", map[string]string{"app_a": "stage_a"})
+
+			Expect(sequenceStatements[0].Statement).To(Equal("SELECT pg_catalog.setval('stage_a.myseq', 5, true);"))
+		})
+	})
+	Describe("editStatementsRenameTables", func() {
+		renamedRelations := map[string]string{
+			"sales.orders":      "archive.orders_2026",
+			"public.foopart_p1": "public.foopart_old",
+			"public.a":          "public.b",
+			"public.b":          "public.a",
+			`"my.schema".t`:     `"my.schema"."T2"`,
+		}
+		It("does not alter statements if no tables were renamed", func() {
+			renameStatements := []toc.StatementWithType{{
+				Schema: "sales", Name: "orders", ObjectType: toc.OBJ_TABLE,
+				Statement: "\n\nCREATE TABLE sales.orders (\n\ti integer\n) DISTRIBUTED BY (i);\n",
+			}}
+			originalStatements := make([]toc.StatementWithType, len(renameStatements))
+			copy(originalStatements, renameStatements)
+
+			editStatementsRenameTables(renameStatements, map[string]string{})
+
+			Expect(renameStatements).To(Equal(originalStatements))
+		})
+		It("renames tables and the objects that belong to them", func() {
+			renameStatements := []toc.StatementWithType{
+				{ // renamed table
+					Schema: "sales", Name: "orders", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE sales.orders (\n\ti integer\n) DISTRIBUTED BY (i);\n\nCOMMENT ON COLUMN sales.orders.i IS 'id';\n",
+				},
+				{ // table whose name starts with that of a renamed table
+					Schema: "sales", Name: "orders_archive", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE sales.orders_archive (\n\ti integer\n) DISTRIBUTED BY (i);\n",
+				},
+				{ // index on a renamed table
+					Schema: "sales", Name: "orders_idx", ObjectType: toc.OBJ_INDEX, ReferenceObject: "sales.orders",
+					Statement: "\n\nCREATE INDEX orders_idx ON sales.orders USING btree (i);\n",
+				},
+				{ // statistics of a renamed table
+					Schema: "sales", Name: "orders", ObjectType: toc.OBJ_STATISTICS,
+					Statement: "\n\nUPDATE pg_class\nSET\n\trelpages = 1::int,\n\treltuples = 1::real\nWHERE oid = 'sales.orders'::regclass::oid;\n",
+				},
+				{ // view of a renamed table
+					Schema: "sales", Name: "orders_view", ObjectType: toc.OBJ_VIEW,
+					Statement: "\n\nCREATE VIEW sales.orders_view AS  SELECT orders.i\n   FROM sales.orders;\n",
+				},
+				{ // renamed partition
+					Schema: "public", Name: "foopart_p1", ObjectType: toc.OBJ_TABLE, ReferenceObject: "public.foopart",
+					Statement: "\n\nALTER TABLE ONLY public.foopart ATTACH PARTITION public.foopart_p1 FOR VALUES FROM (0) TO (1);\n",
+				},
+				{ // tables whose names are swapped
+					Schema: "public", Name: "a", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE public.a (\n\ti integer\n) INHERITS (public.b) DISTRIBUTED BY (i);\n",
+				},
+				{ // table with a schema containing dots
+					Schema: `"my.schema"`, Name: "t", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE \"my.schema\".t (\n\ti integer\n) DISTRIBUTED BY (i);\n",
+				},
+			}
+
+			editStatementsRenameTables(renameStatements, renamedRelations)
+
+			expectedStatements := []toc.StatementWithType{
+				{
+					Schema: "archive", Name: "orders_2026", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE archive.orders_2026 (\n\ti integer\n) DISTRIBUTED BY (i);\n\nCOMMENT ON COLUMN archive.orders_2026.i IS 'id';\n",
+				},
+				{
+					Schema: "sales", Name: "orders_archive", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE sales.orders_archive (\n\ti integer\n) DISTRIBUTED BY (i);\n",
+				},
+				{
+					Schema: "sales", Name: "orders_idx", ObjectType: toc.OBJ_INDEX, ReferenceObject: "archive.orders_2026",
+					Statement: "\n\nCREATE INDEX orders_idx ON archive.orders_2026 USING btree (i);\n",
+				},
+				{
+					Schema: "archive", Name: "orders_2026", ObjectType: toc.OBJ_STATISTICS,
+					Statement: "\n\nUPDATE pg_class\nSET\n\trelpages = 1::int,\n\treltuples = 1::real\nWHERE oid = 'archive.orders_2026'::regclass::oid;\n",
+				},
+				{
+					Schema: "sales", Name: "orders_view", ObjectType: toc.OBJ_VIEW,
+					Statement: "\n\nCREATE VIEW sales.orders_view AS  SELECT orders.i\n   FROM sales.orders;\n",
+				},
+				{
+					Schema: "public", Name: "foopart_old", ObjectType: toc.OBJ_TABLE, ReferenceObject: "public.foopart",
+					Statement: "\n\nALTER TABLE ONLY public.foopart ATTACH PARTITION public.foopart_old FOR VALUES FROM (0) TO (1);\n",
+				},
+				{
+					Schema: "public", Name: "b", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE public.b (\n\ti integer\n) INHERITS (public.a) DISTRIBUTED BY (i);\n",
+				},
+				{
+					Schema: `"my.schema"`, Name: `"T2"`, ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE \"my.schema\".\"T2\" (\n\ti integer\n) DISTRIBUTED BY (i);\n",
+				},
+			}
+			for i := range renameStatements {
+				Expect(renameStatements[i]).To(Equal(expectedStatements[i]))
+			}
+		})
+		It("renames the indexes, constraints, and owned sequences of a renamed table with a primary key and a serial column", func() {
+			tocfile := &toc.TOC{
+				PredataEntries: []toc.MetadataEntry{
+					{Schema: "sales", Name: "orders_id_seq", ObjectType: toc.OBJ_SEQUENCE},
+					{Schema: "sales", Name: "orders", ObjectType: toc.OBJ_TABLE},
+					{Schema: "sales", Name: "orders_id_seq", ObjectType: toc.OBJ_SEQUENCE_OWNER, ReferenceObject: "sales.orders"},
+					{Schema: "sales", Name: "orders_pkey", ObjectType: toc.OBJ_CONSTRAINT, ReferenceObject: "sales.orders"},
+				},
+				PostdataEntries: []toc.MetadataEntry{
+					{Schema: "sales", Name: "idx_customer", ObjectType: toc.OBJ_INDEX, ReferenceObject: "sales.orders"},
+				},
+			}
+			renamedRelations := getRenamedRelations(tocfile, map[string]string{"sales.orders": "sales.orders_20261001"})
+			Expect(renamedRelations).To(Equal(map[string]string{
+				"sales.orders":        "sales.orders_20261001",
+				"sales.orders_id_seq": "sales.orders_20261001_id_seq",
+				"sales.idx_customer":  "sales.idx_customer_orders_20261001",
+			}))
+			renameStatements := []toc.StatementWithType{
+				{
+					Schema: "sales", Name: "orders_id_seq", ObjectType: toc.OBJ_SEQUENCE,
+					Statement: "\n\nCREATE SEQUENCE sales.orders_id_seq\n\tSTART WITH 1;\n\nSELECT pg_catalog.setval('sales.orders_id_seq', 5, true);\n",
+				},
+				{
+					Schema: "sales", Name: "orders", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE sales.orders (\n\tid integer DEFAULT nextval('sales.orders_id_seq'::regclass) NOT NULL\n) DISTRIBUTED BY (id);\n",
+				},
+				{
+					Schema: "sales", Name: "orders_id_seq", ObjectType: toc.OBJ_SEQUENCE_OWNER, ReferenceObject: "sales.orders",
+					Statement: "\n\nALTER SEQUENCE sales.orders_id_seq OWNED BY sales.orders.id;\n",
+				},
+				{
+					Schema: "sales", Name: "orders_pkey", ObjectType: toc.OBJ_CONSTRAINT, ReferenceObject: "sales.orders",
+					Statement: "\n\nALTER TABLE ONLY sales.orders ADD CONSTRAINT orders_pkey PRIMARY KEY (id);\n",
+				},
+				{
+					Schema: "sales", Name: "orders_pkey", ObjectType: toc.OBJ_CONSTRAINT, ReferenceObject: "sales.orders",
+					Statement: "\n\nCOMMENT ON CONSTRAINT orders_pkey ON sales.orders IS 'key';\n",
+				},
+				{
+					Schema: "sales", Name: "idx_customer", ObjectType: toc.OBJ_INDEX, ReferenceObject: "sales.orders",
+					Statement: "\n\nCREATE INDEX idx_customer ON sales.orders USING btree (id);",
+				},
+				{
+					Schema: "sales", Name: "idx_customer", ObjectType: "INDEX METADATA", ReferenceObject: "sales.idx_customer",
+					Statement: "\nALTER TABLE sales.orders CLUSTER ON idx_customer;",
+				},
+			}
+
+			editStatementsRenameTables(renameStatements, renamedRelations)
+
+			expectedStatements := []toc.StatementWithType{
+				{
+					Schema: "sales", Name: "orders_20261001_id_seq", ObjectType: toc.OBJ_SEQUENCE,
+					Statement: "\n\nCREATE SEQUENCE sales.orders_20261001_id_seq\n\tSTART WITH 1;\n\nSELECT pg_catalog.setval('sales.orders_20261001_id_seq', 5, true);\n",
+				},
+				{
+					Schema: "sales", Name: "orders_20261001", ObjectType: toc.OBJ_TABLE,
+					Statement: "\n\nCREATE TABLE sales.orders_20261001 (\n\tid integer DEFAULT nextval('sales.orders_20261001_id_seq'::regclass) NOT NULL\n) DISTRIBUTED BY (id);\n",
+				},
+				{
+					Schema: "sales", Name: "orders_20261001_id_seq", ObjectType: toc.OBJ_SEQUENCE_OWNER, ReferenceObject: "sales.orders_20261001",
+					Statement: "\n\nALTER SEQUENCE sales.orders_20261001_id_seq OWNED BY sales.orders_20261001.id;\n",
+				},
+				{
+					Schema: "sales", Name: "orders_20261001_pkey", ObjectType: toc.OBJ_CONSTRAINT, ReferenceObject: "sales.orders_20261001",
+					Statement: "\n\nALTER TABLE ONLY sales.orders_20261001 ADD CONSTRAINT orders_20261001_pkey PRIMARY KEY (id);\n",
+				},
+				{
+					Schema: "sales", Name: "orders_20261001_pkey", ObjectType: toc.OBJ_CONSTRAINT, ReferenceObject: "sales.orders_20261001",
+					Statement: "\n\nCOMMENT ON CONSTRAINT orders_20261001_pkey ON sales.orders_20261001 IS 'key';\n",
+				},
+				{
+					Schema: "sales", Name: "idx_customer_orders_20261001", ObjectType: toc.OBJ_INDEX, ReferenceObject: "sales.orders_20261001",
+					Statement: "\n\nCREATE INDEX idx_customer_orders_20261001 ON sales.orders_20261001 USING btree (id);",
+				},
+				{
+					Schema: "sales", Name: "idx_customer_orders_20261001", ObjectType: "INDEX METADATA", ReferenceObject: "sales.idx_customer_orders_20261001",
+					Statement: "\nALTER TABLE sales.orders_20261001 CLUSTER ON idx_customer_orders_20261001;",
+				},
+			}
+			for i := range renameStatements {
+				Expect(renameStatements[i]).To(Equal(expectedStatements[i]))
+			}
+		})
+		It("quotes the derived names of objects that need quoting", func() {
+			Expect(getDependentObjectName("orders_pkey", "orders", `"Orders"`)).To(Equal(`"Orders_pkey"`))
+			Expect(getDependentObjectName(`"Idx"`, "orders", "orders_2026")).To(Equal(`"Idx_orders_2026"`))
+		})
+		It("does not rename tables in comments or string literals", func() {
+			renameStatements := []toc.StatementWithType{{
+				Schema: "sales", Name: "orders", ObjectType: toc.OBJ_TABLE,
+				Statement: "\n\nCREATE TABLE sales.orders (\n\tnote text DEFAULT 'see sales.orders' -- sales.orders\n) DISTRIBUTED RANDOMLY;\n\nCOMMENT ON TABLE sales.orders IS 'copy of sales.orders /* sales.orders */';\n",
+			}}
+
+			editStatementsRenameTables(renameStatements, map[string]string{"sales.orders": "sales.orders_2026"})
+
+			Expect(renameStatements[0].Statement).To(Equal("\n\nCREATE TABLE sales.orders_2026 (\n\tnote text DEFAULT 'see sales.orders' -- sales.orders\n) DISTRIBUTED RANDOMLY;\n\nCOMMENT ON TABLE sales.orders_2026 IS 'copy of sales.orders /* sales.orders */';\n"))
+		})
 	})
 })
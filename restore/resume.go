@@ -0,0 +1,368 @@
+package restore
+
+/*
+ * This file contains structs and functions related to resuming a failed restore
+ * with the --resume flag.
+ */
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gpbackup/options"
+	"github.com/greenplum-db/gpbackup/toc"
+	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
+)
+
+/*
+ * Each section of a restore is recorded once when it starts and once when it
+ * completes.  The data section is instead recorded table by table, once the
+ * data of a table has been loaded and its row count checked.
+ */
+type RestoreStateEntry struct {
+	Section   string
+	Completed bool
+	Timestamp string               `json:",omitempty"`
+	Oid       uint32               `json:",omitempty"`
+	TableFQN  string               `json:",omitempty"`
+	Options   *RestoreStateOptions `json:",omitempty"`
+}
+
+/*
+ * The options that decide which objects a restore restores and where it
+ * restores them to.  They are recorded when a restore starts, since resuming
+ * it with different ones would mix two different restores in one database.
+ */
+type RestoreStateOptions struct {
+	RedirectDB        string            `json:",omitempty"`
+	IncludedSchemas   []string          `json:",omitempty"`
+	ExcludedSchemas   []string          `json:",omitempty"`
+	IncludedRelations []string          `json:",omitempty"`
+	ExcludedRelations []string          `json:",omitempty"`
+	RedirectSchema    string            `json:",omitempty"`
+	RedirectSchemaMap map[string]string `json:",omitempty"`
+	RenamedRelations  map[string]string `json:",omitempty"`
+	RoleMap           map[string]string `json:",omitempty"`
+	TablespaceMap     map[string]string `json:",omitempty"`
+	NoTablespaces     bool              `json:",omitempty"`
+	DataOnly          bool              `json:",omitempty"`
+	MetadataOnly      bool              `json:",omitempty"`
+	Incremental       bool              `json:",omitempty"`
+}
+
+func NewRestoreStateOptions() RestoreStateOptions {
+	return RestoreStateOptions{
+		RedirectDB:        MustGetFlagString(options.REDIRECT_DB),
+		IncludedSchemas:   opts.IncludedSchemas,
+		ExcludedSchemas:   opts.ExcludedSchemas,
+		IncludedRelations: opts.IncludedRelations,
+		ExcludedRelations: opts.ExcludedRelations,
+		RedirectSchema:    opts.RedirectSchema,
+		RedirectSchemaMap: opts.RedirectSchemaMap,
+		RenamedRelations:  opts.RenamedRelations,
+		RoleMap:           opts.RoleMap,
+		TablespaceMap:     opts.TablespaceMap,
+		NoTablespaces:     MustGetFlagBool(options.NO_TABLESPACES),
+		DataOnly:          MustGetFlagBool(options.DATA_ONLY),
+		MetadataOnly:      MustGetFlagBool(options.METADATA_ONLY),
+		Incremental:       MustGetFlagBool(options.INCREMENTAL),
+	}
+}
+
+// Returns the flags whose values differ between the two sets of options
+func (stateOptions RestoreStateOptions) GetMismatchedFlags(other RestoreStateOptions) []string {
+	mismatchedFlags := make([]string, 0)
+	checkFlag := func(flag string, matches bool) {
+		if !matches {
+			mismatchedFlags = append(mismatchedFlags, "--"+flag)
+		}
+	}
+	checkFlag(options.REDIRECT_DB, stateOptions.RedirectDB == other.RedirectDB)
+	checkFlag(options.INCLUDE_SCHEMA, matchesStringSets(stateOptions.IncludedSchemas, other.IncludedSchemas))
+	checkFlag(options.EXCLUDE_SCHEMA, matchesStringSets(stateOptions.ExcludedSchemas, other.ExcludedSchemas))
+	checkFlag(options.INCLUDE_RELATION, matchesStringSets(stateOptions.IncludedRelations, other.IncludedRelations))
+	checkFlag(options.EXCLUDE_RELATION, matchesStringSets(stateOptions.ExcludedRelations, other.ExcludedRelations))
+	checkFlag(options.REDIRECT_SCHEMA, stateOptions.RedirectSchema == other.RedirectSchema)
+	checkFlag(options.REDIRECT_SCHEMA_MAP, matchesStringMaps(stateOptions.RedirectSchemaMap, other.RedirectSchemaMap))
+	checkFlag(options.RENAME_TABLE, matchesStringMaps(stateOptions.RenamedRelations, other.RenamedRelations))
+	checkFlag(options.ROLE_MAP, matchesStringMaps(stateOptions.RoleMap, other.RoleMap))
+	checkFlag(options.TABLESPACE_MAP, matchesStringMaps(stateOptions.TablespaceMap, other.TablespaceMap))
+	checkFlag(options.NO_TABLESPACES, stateOptions.NoTablespaces == other.NoTablespaces)
+	checkFlag(options.DATA_ONLY, stateOptions.DataOnly == other.DataOnly)
+	checkFlag(options.METADATA_ONLY, stateOptions.MetadataOnly == other.MetadataOnly)
+	checkFlag(options.INCREMENTAL, stateOptions.Incremental == other.Incremental)
+	return mismatchedFlags
+}
+
+// Filters may be given in any order, and an empty list is recorded the same as no list at all
+func matchesStringSets(list1 []string, list2 []string) bool {
+	if len(list1) != len(list2) {
+		return false
+	}
+	set := make(map[string]bool, len(list1))
+	for _, item := range list1 {
+		set[item] = true
+	}
+	for _, item := range list2 {
+		if !set[item] {
+			return false
+		}
+	}
+	return true
+}
+
+func matchesStringMaps(map1 map[string]string, map2 map[string]string) bool {
+	if len(map1) != len(map2) {
+		return false
+	}
+	for key, value := range map1 {
+		if otherValue, ok := map2[key]; !ok || otherValue != value {
+			return false
+		}
+	}
+	return true
+}
+
+var restoreSectionDescriptions = map[string]string{
+	"global":     "global metadata",
+	"database":   "database creation",
+	"predata":    "pre-data metadata",
+	"sequences":  "sequence values",
+	"postdata":   "post-data metadata",
+	"statistics": "query planner statistics",
+	"analyze":    "ANALYZE on restored tables",
+}
+
+/*
+ * Running these sections again only repeats what was already done, so they
+ * can be resumed even if they were interrupted partway through.  The others
+ * create objects that would then already exist.
+ */
+var rerunnableRestoreSections = map[string]bool{
+	"sequences":  true,
+	"statistics": true,
+	"analyze":    true,
+}
+
+/*
+ * The restore state file holds one JSON-encoded RestoreStateEntry per line.
+ * The file is only ever appended to, so if gprestore dies while writing an
+ * entry, only the last line can be incomplete and it is ignored when the file
+ * is read back in.
+ */
+type RestoreStateFile struct {
+	file  *os.File
+	mutex sync.Mutex
+}
+
+func OpenRestoreStateFile(filename string) (*RestoreStateFile, error) {
+	file, err := os.OpenFile(filename, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &RestoreStateFile{file: file}, nil
+}
+
+// A restore that could not open its state file is not recorded, and cannot be resumed
+func (stateFile *RestoreStateFile) record(entry RestoreStateEntry) error {
+	if stateFile == nil {
+		return nil
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	stateFile.mutex.Lock()
+	defer stateFile.mutex.Unlock()
+	_, err = stateFile.file.Write(append(line, '\n'))
+	if err != nil {
+		return err
+	}
+	return stateFile.file.Sync()
+}
+
+func (stateFile *RestoreStateFile) RecordOptions(stateOptions RestoreStateOptions) error {
+	return stateFile.record(RestoreStateEntry{Section: "options", Options: &stateOptions})
+}
+
+func (stateFile *RestoreStateFile) RecordSectionStarted(section string) error {
+	return stateFile.record(RestoreStateEntry{Section: section})
+}
+
+func (stateFile *RestoreStateFile) RecordSectionCompleted(section string) error {
+	return stateFile.record(RestoreStateEntry{Section: section, Completed: true})
+}
+
+func (stateFile *RestoreStateFile) RecordTableLoaded(timestamp string, dataEntry toc.CoordinatorDataEntry) error {
+	return stateFile.record(RestoreStateEntry{
+		Section:   "data",
+		Completed: true,
+		Timestamp: timestamp,
+		Oid:       dataEntry.Oid,
+		TableFQN:  utils.MakeFQN(dataEntry.Schema, dataEntry.Name),
+	})
+}
+
+func (stateFile *RestoreStateFile) Close() error {
+	if stateFile == nil {
+		return nil
+	}
+	return stateFile.file.Close()
+}
+
+func ReadRestoreStateFile(filename string) ([]RestoreStateEntry, error) {
+	contents, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]RestoreStateEntry, 0)
+	for _, line := range strings.Split(string(contents), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry RestoreStateEntry
+		err = json.Unmarshal([]byte(line), &entry)
+		if err != nil {
+			gplog.Verbose("Ignoring incomplete restore state entry: %s", line)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// The data files of each backup in a restore plan are named by oid, so a table is identified by both
+type loadedTable struct {
+	timestamp string
+	oid       uint32
+}
+
+/*
+ * The progress of the restore being resumed, as read back from its restore
+ * state file.  A restore that is not being resumed has no progress.
+ */
+type RestoreProgress struct {
+	options           *RestoreStateOptions
+	startedSections   []string
+	completedSections map[string]bool
+	loadedTables      map[loadedTable]bool
+}
+
+func NewRestoreProgress(entries []RestoreStateEntry) *RestoreProgress {
+	progress := &RestoreProgress{
+		startedSections:   make([]string, 0),
+		completedSections: make(map[string]bool),
+		loadedTables:      make(map[loadedTable]bool),
+	}
+	for _, entry := range entries {
+		if entry.Section == "options" {
+			progress.options = entry.Options
+		} else if entry.Section == "data" {
+			progress.loadedTables[loadedTable{timestamp: entry.Timestamp, oid: entry.Oid}] = true
+		} else if entry.Completed {
+			progress.completedSections[entry.Section] = true
+		} else {
+			progress.startedSections = append(progress.startedSections, entry.Section)
+		}
+	}
+	return progress
+}
+
+// Returns the flags the restore being resumed was started with a different value of
+func (progress *RestoreProgress) GetMismatchedFlags(stateOptions RestoreStateOptions) []string {
+	if progress.options == nil {
+		return []string{}
+	}
+	return progress.options.GetMismatchedFlags(stateOptions)
+}
+
+func (progress *RestoreProgress) IsSectionCompleted(section string) bool {
+	return progress.completedSections[section]
+}
+
+// Returns the sections that were started but never completed, in the order they were started
+func (progress *RestoreProgress) GetInterruptedSections() []string {
+	interruptedSections := make([]string, 0)
+	for _, section := range progress.startedSections {
+		if !progress.completedSections[section] && !utils.Exists(interruptedSections, section) {
+			interruptedSections = append(interruptedSections, section)
+		}
+	}
+	return interruptedSections
+}
+
+func (progress *RestoreProgress) NumLoadedTables() int {
+	return len(progress.loadedTables)
+}
+
+// Returns the data entries of the tables whose data was not completely loaded
+func (progress *RestoreProgress) FilterLoadedTables(timestamp string, dataEntries []toc.CoordinatorDataEntry) []toc.CoordinatorDataEntry {
+	remainingEntries := make([]toc.CoordinatorDataEntry, 0, len(dataEntries))
+	for _, entry := range dataEntries {
+		if !progress.loadedTables[loadedTable{timestamp: timestamp, oid: entry.Oid}] {
+			remainingEntries = append(remainingEntries, entry)
+		}
+	}
+	return remainingEntries
+}
+
+/*
+ * Read back the progress of the restore being resumed, check that it is being
+ * resumed with the options it was started with and that none of the sections
+ * it was interrupted in would create objects that already exist, and remove
+ * the report files that will be written again by this run.
+ */
+func prepareRestoreForResume() {
+	entries, err := ReadRestoreStateFile(globalFPInfo.GetRestoreStateFilePath(restoreStartTime))
+	if os.IsNotExist(err) {
+		gplog.Fatal(errors.Errorf("Unable to find a restore of backup %s with timestamp %s to resume.", globalFPInfo.Timestamp, restoreStartTime), "")
+	}
+	gplog.FatalOnError(err)
+	restoreProgress = NewRestoreProgress(entries)
+	if restoreProgress.options == nil {
+		gplog.Fatal(errors.Errorf("The restore with timestamp %s did not record the options it was started with, so it cannot be resumed.", restoreStartTime), "")
+	}
+	if mismatchedFlags := restoreProgress.GetMismatchedFlags(NewRestoreStateOptions()); len(mismatchedFlags) > 0 {
+		gplog.Fatal(errors.Errorf("The restore with timestamp %s was started with different values of %s.  A restore must be resumed with the options it was started with.",
+			restoreStartTime, strings.Join(mismatchedFlags, ", ")), "")
+	}
+	for _, section := range restoreProgress.GetInterruptedSections() {
+		if !rerunnableRestoreSections[section] {
+			gplog.Fatal(errors.Errorf("The restore with timestamp %s failed partway through restoring %s, so it cannot be resumed.",
+				restoreStartTime, restoreSectionDescriptions[section]), "")
+		}
+	}
+	gplog.Info("Resuming restore with timestamp = %s, data for %d table(s) was previously restored", restoreStartTime, restoreProgress.NumLoadedTables())
+
+	staleFilenames := []string{globalFPInfo.GetRestoreReportFilePath(restoreStartTime),
+		globalFPInfo.GetErrorTablesMetadataFilePath(restoreStartTime), globalFPInfo.GetErrorTablesDataFilePath(restoreStartTime)}
+	for _, filename := range staleFilenames {
+		err = os.Remove(filename)
+		if err != nil && !os.IsNotExist(err) {
+			gplog.FatalOnError(err)
+		}
+	}
+}
+
+/*
+ * Runs a section of the restore unless the restore being resumed completed
+ * it, and records when it starts and when it completes.
+ */
+func runRestoreSection(section string, restoreFunc func()) {
+	if restoreProgress.IsSectionCompleted(section) {
+		gplog.Info("Skipping %s, which the resumed restore completed", restoreSectionDescriptions[section])
+		return
+	}
+	gplog.FatalOnError(restoreStateFile.RecordSectionStarted(section))
+	restoreFunc()
+	if !wasTerminated {
+		gplog.FatalOnError(restoreStateFile.RecordSectionCompleted(section))
+	}
+}
@@ -0,0 +1,133 @@
+package restore_test
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/greenplum-db/gpbackup/restore"
+	"github.com/greenplum-db/gpbackup/toc"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("restore/resume tests", func() {
+	entry1 := toc.CoordinatorDataEntry{Schema: "public", Name: "table1", Oid: 1}
+	entry2 := toc.CoordinatorDataEntry{Schema: "public", Name: "table2", Oid: 2}
+	entry3 := toc.CoordinatorDataEntry{Schema: "public", Name: "table3", Oid: 3}
+
+	Describe("RestoreStateFile", func() {
+		stateFilename := "/tmp/gprestore_restore_state"
+
+		BeforeEach(func() {
+			_ = os.Remove(stateFilename)
+		})
+		AfterEach(func() {
+			_ = os.Remove(stateFilename)
+		})
+
+		It("reads back the entries recorded by previous runs", func() {
+			stateFile, err := restore.OpenRestoreStateFile(stateFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateFile.RecordSectionStarted("predata")).To(Succeed())
+			Expect(stateFile.RecordSectionCompleted("predata")).To(Succeed())
+			Expect(stateFile.Close()).To(Succeed())
+
+			stateFile, err = restore.OpenRestoreStateFile(stateFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateFile.RecordTableLoaded("20220101010101", entry1)).To(Succeed())
+			Expect(stateFile.Close()).To(Succeed())
+
+			entries, err := restore.ReadRestoreStateFile(stateFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(Equal([]restore.RestoreStateEntry{
+				{Section: "predata"},
+				{Section: "predata", Completed: true},
+				{Section: "data", Completed: true, Timestamp: "20220101010101", Oid: 1, TableFQN: "public.table1"},
+			}))
+		})
+		It("ignores an entry that was only partially written", func() {
+			stateFile, err := restore.OpenRestoreStateFile(stateFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateFile.RecordTableLoaded("20220101010101", entry1)).To(Succeed())
+			Expect(stateFile.Close()).To(Succeed())
+			contents, _ := ioutil.ReadFile(stateFilename)
+			_ = ioutil.WriteFile(stateFilename, append(contents, []byte(`{"Section":"data","Completed":true,"Timesta`)...), 0644)
+
+			entries, err := restore.ReadRestoreStateFile(stateFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(Equal([]restore.RestoreStateEntry{
+				{Section: "data", Completed: true, Timestamp: "20220101010101", Oid: 1, TableFQN: "public.table1"},
+			}))
+		})
+		It("reads back the options the restore was started with", func() {
+			stateOptions := restore.RestoreStateOptions{
+				RedirectDB:        "restoredb",
+				IncludedSchemas:   []string{"sales"},
+				RedirectSchemaMap: map[string]string{"sales": "stage"},
+				Incremental:       true,
+			}
+			stateFile, err := restore.OpenRestoreStateFile(stateFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stateFile.RecordOptions(stateOptions)).To(Succeed())
+			Expect(stateFile.Close()).To(Succeed())
+
+			entries, err := restore.ReadRestoreStateFile(stateFilename)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(entries).To(Equal([]restore.RestoreStateEntry{{Section: "options", Options: &stateOptions}}))
+		})
+		It("returns an error if there is no restore to resume", func() {
+			_, err := restore.ReadRestoreStateFile(stateFilename)
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+	Describe("RestoreProgress", func() {
+		progress := restore.NewRestoreProgress([]restore.RestoreStateEntry{
+			{Section: "predata"},
+			{Section: "predata", Completed: true},
+			{Section: "data", Completed: true, Timestamp: "20220101010101", Oid: 1, TableFQN: "public.table1"},
+			{Section: "data", Completed: true, Timestamp: "20220101020202", Oid: 2, TableFQN: "public.table2"},
+			{Section: "postdata"},
+		})
+
+		It("reports the sections that were completed", func() {
+			Expect(progress.IsSectionCompleted("predata")).To(BeTrue())
+			Expect(progress.IsSectionCompleted("postdata")).To(BeFalse())
+			Expect(progress.IsSectionCompleted("statistics")).To(BeFalse())
+		})
+		It("reports the sections that were started but never completed", func() {
+			Expect(progress.GetInterruptedSections()).To(Equal([]string{"postdata"}))
+		})
+		It("skips the tables whose data was loaded from the same backup", func() {
+			Expect(progress.NumLoadedTables()).To(Equal(2))
+			Expect(progress.FilterLoadedTables("20220101010101", []toc.CoordinatorDataEntry{entry1, entry2, entry3})).To(Equal([]toc.CoordinatorDataEntry{entry2, entry3}))
+		})
+		It("reports the flags whose values differ from those the restore was started with", func() {
+			stateOptions := restore.RestoreStateOptions{
+				RedirectDB:        "restoredb",
+				IncludedRelations: []string{"sales.orders", "sales.customers"},
+				RenamedRelations:  map[string]string{"sales.orders": "sales.orders_2026"},
+				RoleMap:           map[string]string{"alice": "bob"},
+			}
+			optionsProgress := restore.NewRestoreProgress([]restore.RestoreStateEntry{{Section: "options", Options: &stateOptions}})
+
+			sameOptions := stateOptions
+			sameOptions.IncludedRelations = []string{"sales.customers", "sales.orders"}
+			Expect(optionsProgress.GetMismatchedFlags(sameOptions)).To(BeEmpty())
+
+			Expect(optionsProgress.GetMismatchedFlags(restore.RestoreStateOptions{
+				IncludedRelations: []string{"sales.orders"},
+				RenamedRelations:  map[string]string{"sales.orders": "sales.orders_2027"},
+				RoleMap:           map[string]string{"alice": "bob"},
+				TablespaceMap:     map[string]string{"ts1": "ts2"},
+				Incremental:       true,
+			})).To(Equal([]string{"--redirect-db", "--include-table", "--rename-table", "--tablespace-map", "--incremental"}))
+		})
+		It("has no progress if no restore is resumed", func() {
+			emptyProgress := restore.NewRestoreProgress(nil)
+			Expect(emptyProgress.IsSectionCompleted("predata")).To(BeFalse())
+			Expect(emptyProgress.GetInterruptedSections()).To(BeEmpty())
+			Expect(emptyProgress.FilterLoadedTables("20220101010101", []toc.CoordinatorDataEntry{entry1})).To(Equal([]toc.CoordinatorDataEntry{entry1}))
+		})
+	})
+})
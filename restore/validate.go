@@ -23,6 +23,7 @@ func validateFilterListsInBackupSet() {
 	ValidateExcludeSchemasInBackupSet(opts.ExcludedSchemas)
 	ValidateIncludeRelationsInBackupSet(opts.IncludedRelations)
 	ValidateExcludeRelationsInBackupSet(opts.ExcludedRelations)
+	ValidateRenamedRelationsInBackupSet(opts.RenamedRelations)
 }
 
 func ValidateIncludeSchemasInBackupSet(schemaList []string) {
@@ -163,6 +164,16 @@ func ValidateExcludeRelationsInBackupSet(schemaList []string) {
 	}
 }
 
+func ValidateRenamedRelationsInBackupSet(renamedRelations map[string]string) {
+	relationList := make([]string, 0, len(renamedRelations))
+	for fqn := range renamedRelations {
+		relationList = append(relationList, fqn)
+	}
+	if keys := getFilterRelationsInBackupSet(relationList); len(keys) != 0 {
+		gplog.Fatal(errors.Errorf("Could not find the following renamed table(s) in the backup set: %s", strings.Join(keys, ", ")), "")
+	}
+}
+
 func getFilterRelationsInBackupSet(relationList []string) []string {
 	if len(relationList) == 0 {
 		return []string{}
@@ -241,9 +252,32 @@ func ValidateBackupFlagCombinations() {
 	if !backupConfig.SingleDataFile && FlagChanged(options.COPY_QUEUE_SIZE) {
 		gplog.Fatal(errors.Errorf("The --copy-queue-size flag can only be used if the backup was taken with --single-data-file"), "")
 	}
+	if backupConfig.SingleDataFile && FlagChanged(options.PRIORITY_TABLE_FILE) {
+		gplog.Fatal(errors.Errorf("The --priority-table-file flag cannot be used if the backup was taken with --single-data-file"), "")
+	}
+	if backupConfig.Repository && MustGetFlagBool(options.RESIZE_CLUSTER) {
+		// The helper agent of a resize restore reads data files, and cannot read table data stored in repository chunks
+		gplog.Fatal(errors.Errorf("Backups taken with --repository cannot be restored using the --resize-cluster flag."), "")
+	}
 	validateBackupFlagPluginCombinations()
 }
 
+/*
+ * A resize restore has segments read data files that were not written by a
+ * segment of their own, or no data file at all, which only works for CSV table
+ * data, so table data backed up in binary format cannot be resize restored.
+ */
+func ValidateDataFormatsForRestore(dataEntries []toc.CoordinatorDataEntry) {
+	if !MustGetFlagBool(options.RESIZE_CLUSTER) {
+		return
+	}
+	for _, entry := range dataEntries {
+		if entry.DataFormat == utils.DATA_FORMAT_BINARY {
+			gplog.Fatal(errors.Errorf("Table %s was backed up with --data-format binary and cannot be restored using the --resize-cluster flag.", utils.MakeFQN(entry.Schema, entry.Name)), "")
+		}
+	}
+}
+
 func validateBackupFlagPluginCombinations() {
 	if backupConfig.Plugin != "" && MustGetFlagString(options.PLUGIN_CONFIG) == "" {
 		gplog.Fatal(errors.Errorf("Backup was taken with plugin %s. The --plugin-config flag must be used to restore.", backupConfig.Plugin), "")
@@ -264,18 +298,23 @@ func ValidateFlagCombinations(flags *pflag.FlagSet) {
 	options.CheckExclusiveFlags(flags, options.METADATA_ONLY, options.DATA_ONLY)
 	options.CheckExclusiveFlags(flags, options.PLUGIN_CONFIG, options.BACKUP_DIR)
 	options.CheckExclusiveFlags(flags, options.TRUNCATE_TABLE, options.METADATA_ONLY, options.INCREMENTAL)
-	options.CheckExclusiveFlags(flags, options.TRUNCATE_TABLE, options.REDIRECT_SCHEMA)
+	options.CheckExclusiveFlags(flags, options.TRUNCATE_TABLE, options.REDIRECT_SCHEMA, options.REDIRECT_SCHEMA_MAP)
+	options.CheckExclusiveFlags(flags, options.REDIRECT_SCHEMA, options.REDIRECT_SCHEMA_MAP, options.RENAME_TABLE, options.RENAME_TABLE_FILE)
+	options.CheckExclusiveFlags(flags, options.TABLESPACE_MAP, options.NO_TABLESPACES)
 
-	if flags.Changed(options.REDIRECT_SCHEMA) {
+	for _, redirectFlag := range []string{options.REDIRECT_SCHEMA, options.REDIRECT_SCHEMA_MAP} {
+		if !flags.Changed(redirectFlag) {
+			continue
+		}
 		// Redirect schema not compatible with any exclude flags
 		if flags.Changed(options.EXCLUDE_SCHEMA) || flags.Changed(options.EXCLUDE_SCHEMA_FILE) ||
 			flags.Changed(options.EXCLUDE_RELATION) || flags.Changed(options.EXCLUDE_RELATION_FILE) {
-			gplog.Fatal(errors.Errorf("Cannot use --redirect-schema with exclude flags"), "")
+			gplog.Fatal(errors.Errorf("Cannot use --%s with exclude flags", redirectFlag), "")
 		}
 		// Redirect schema requires an include flag
 		if !(flags.Changed(options.INCLUDE_RELATION) || flags.Changed(options.INCLUDE_RELATION_FILE) ||
 			flags.Changed(options.INCLUDE_SCHEMA) || flags.Changed(options.INCLUDE_SCHEMA_FILE)) {
-			gplog.Fatal(errors.Errorf("Cannot use --redirect-schema without --include-table, --include-table-file, --include-schema, or --include-schema-file"), "")
+			gplog.Fatal(errors.Errorf("Cannot use --%s without --include-table, --include-table-file, --include-schema, or --include-schema-file", redirectFlag), "")
 		}
 	}
 	if flags.Changed(options.TRUNCATE_TABLE) &&
@@ -283,6 +322,9 @@ func ValidateFlagCombinations(flags *pflag.FlagSet) {
 		!flags.Changed(options.DATA_ONLY) {
 		gplog.Fatal(errors.Errorf("Cannot use --truncate-table without --include-table or --include-table-file and without --data-only"), "")
 	}
+	if flags.Changed(options.RESIZE_ROUTE_DATA) && !flags.Changed(options.RESIZE_CLUSTER) {
+		gplog.Fatal(errors.Errorf("Cannot use --resize-route-data without --resize-cluster"), "")
+	}
 	if flags.Changed(options.INCREMENTAL) && !flags.Changed(options.DATA_ONLY) {
 		gplog.Fatal(errors.Errorf("Cannot use --incremental without --data-only"), "")
 	}
@@ -290,6 +332,11 @@ func ValidateFlagCombinations(flags *pflag.FlagSet) {
 		gplog.Fatal(errors.Errorf("Must provide --backup-dir if --timestamp is not provided"), "")
 	}
 	options.CheckExclusiveFlags(flags, options.RUN_ANALYZE, options.WITH_STATS)
+	// A restore always starts after the backup it restores, so a restore timestamp matching it was mistaken for it
+	backupTimestamp, _ := flags.GetString(options.TIMESTAMP)
+	if resumeTimestamp, _ := flags.GetString(options.RESUME); resumeTimestamp != "" && resumeTimestamp == backupTimestamp {
+		gplog.Fatal(errors.Errorf("Cannot use the timestamp of the backup with --resume, which takes the timestamp of the restore to resume"), "")
+	}
 }
 
 func ValidateSafeToResizeCluster() {
@@ -372,6 +372,12 @@ var _ = Describe("restore/validate tests", func() {
 			Entry("incremental combos", "--timestamp=0 --incremental", false),
 			Entry("incremental combos", "--timestamp=0 --incremental --data-only", true),
 
+			/*
+			 * Below are various different resize combinations
+			 */
+			Entry("resize combos", "--timestamp=0 --resize-route-data", false),
+			Entry("resize combos", "--timestamp=0 --resize-cluster --resize-route-data", true),
+
 			/*
 			 * Below are various different truncate combinations
 			 */
@@ -395,6 +401,41 @@ var _ = Describe("restore/validate tests", func() {
 			Entry("--redirect-schema combos", "--timestamp=0 --redirect-schema schema1 --exclude-schema-file /tmp/file2", false),
 			Entry("--redirect-schema combos", "--timestamp=0 --redirect-schema schema1 --include-table schema.table2 --metadata-only", true),
 			Entry("--redirect-schema combos", "--timestamp=0 --redirect-schema schema1 --include-table schema.table2 --data-only", true),
+
+			/*
+			 * Below are various different redirect-schema-map combinations
+			 */
+			Entry("--redirect-schema-map combos", "--timestamp=0 --redirect-schema-map /tmp/file", false),
+			Entry("--redirect-schema-map combos", "--timestamp=0 --redirect-schema-map /tmp/file --include-schema schema1 --include-schema schema2", true),
+			Entry("--redirect-schema-map combos", "--timestamp=0 --redirect-schema-map /tmp/file --include-table schema.table2", true),
+			Entry("--redirect-schema-map combos", "--timestamp=0 --redirect-schema-map /tmp/file --include-schema schema1 --exclude-table schema1.table2", false),
+			Entry("--redirect-schema-map combos", "--timestamp=0 --redirect-schema-map /tmp/file --include-schema schema1 --redirect-schema schema2", false),
+			Entry("--redirect-schema-map combos", "--timestamp=0 --redirect-schema-map /tmp/file --include-table schema.table2 --truncate-table", false),
+			Entry("--redirect-schema-map combos", "--timestamp=0 --redirect-schema-map /tmp/file --include-table schema.table2 --rename-table schema.table2=schema.table3", false),
+
+			/*
+			 * Below are various different tablespace combinations
+			 */
+			Entry("tablespace combos", "--timestamp=0 --tablespace-map ts1=ts2 --tablespace-map ts3=ts2", true),
+			Entry("tablespace combos", "--timestamp=0 --no-tablespaces", true),
+			Entry("tablespace combos", "--timestamp=0 --tablespace-map ts1=ts2 --no-tablespaces", false),
+
+			/*
+			 * Below are various different rename-table combinations
+			 */
+			Entry("--rename-table combos", "--timestamp=0 --rename-table schema.table=schema.table2", true),
+			Entry("--rename-table combos", "--timestamp=0 --rename-table schema.table=schema.table2 --include-table schema.table", true),
+			Entry("--rename-table combos", "--timestamp=0 --rename-table schema.table=schema.table2 --data-only", true),
+			Entry("--rename-table combos", "--timestamp=0 --rename-table schema.table=schema.table2 --rename-table-file /tmp/file2", false),
+			Entry("--rename-table combos", "--timestamp=0 --rename-table schema.table=schema.table2 --include-table schema.table --redirect-schema schema2", false),
+			Entry("--rename-table-file combos", "--timestamp=0 --rename-table-file /tmp/file --include-table schema.table --redirect-schema schema2", false),
+
+			/*
+			 * Below are various different resume combinations
+			 */
+			Entry("--resume combos", "--timestamp=20220101010101 --resume 20220102010101", true),
+			Entry("--resume combos", "--timestamp=20220101010101 --resume 20220101010101", false),
+			Entry("--resume combos", "--backup-dir /tmp --resume 20220101010101", true),
 		)
 	})
 	Describe("ValidateBackupFlagCombinations", func() {
@@ -415,5 +456,60 @@ var _ = Describe("restore/validate tests", func() {
 				Fail("invalid flag combination passed validation check")
 			}
 		})
+		It("restore with priority-table-file should fatal if backup was taken with single-data-file", func() {
+			restore.SetBackupConfig(&history.BackupConfig{SingleDataFile: true})
+			testCmd := &cobra.Command{
+				Use:  "flag validation",
+				Args: cobra.NoArgs,
+				Run: func(cmd *cobra.Command, args []string) {
+					restore.ValidateBackupFlagCombinations()
+				}}
+			testCmd.SetArgs([]string{"--priority-table-file", "/tmp/file"})
+			restore.SetCmdFlags(testCmd.Flags())
+
+			defer testhelper.ShouldPanicWithMessage("CRITICAL")
+			err := testCmd.Execute()
+			if err == nil {
+				Fail("invalid flag combination passed validation check")
+			}
+		})
+		It("restore with resize-cluster should fatal if backup was taken with repository", func() {
+			restore.SetBackupConfig(&history.BackupConfig{Repository: true})
+			testCmd := &cobra.Command{
+				Use:  "flag validation",
+				Args: cobra.NoArgs,
+				Run: func(cmd *cobra.Command, args []string) {
+					restore.ValidateBackupFlagCombinations()
+				}}
+			testCmd.SetArgs([]string{"--resize-cluster"})
+			restore.SetCmdFlags(testCmd.Flags())
+
+			defer testhelper.ShouldPanicWithMessage("Backups taken with --repository cannot be restored using the --resize-cluster flag.")
+			err := testCmd.Execute()
+			if err == nil {
+				Fail("invalid flag combination passed validation check")
+			}
+		})
+	})
+	Describe("ValidateDataFormatsForRestore", func() {
+		dataEntries := []toc.CoordinatorDataEntry{
+			{Schema: "public", Name: "foo", Oid: 1},
+			{Schema: "public", Name: "bar", Oid: 2, DataFormat: "binary"},
+		}
+		AfterEach(func() {
+			_ = cmdFlags.Set(options.RESIZE_CLUSTER, "false")
+		})
+		It("allows table data in any format to be restored", func() {
+			restore.ValidateDataFormatsForRestore(dataEntries)
+		})
+		It("allows table data in CSV format to be resize restored", func() {
+			_ = cmdFlags.Set(options.RESIZE_CLUSTER, "true")
+			restore.ValidateDataFormatsForRestore(dataEntries[:1])
+		})
+		It("panics if table data in binary format is resize restored", func() {
+			_ = cmdFlags.Set(options.RESIZE_CLUSTER, "true")
+			defer testhelper.ShouldPanicWithMessage("Table public.bar was backed up with --data-format binary and cannot be restored using the --resize-cluster flag.")
+			restore.ValidateDataFormatsForRestore(dataEntries)
+		})
 	})
 })
@@ -8,7 +8,6 @@ import (
 
 	"github.com/greenplum-db/gp-common-go-libs/dbconn"
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
-	"github.com/greenplum-db/gp-common-go-libs/iohelper"
 	"github.com/greenplum-db/gp-common-go-libs/operating"
 	"github.com/greenplum-db/gpbackup/filepath"
 	"github.com/greenplum-db/gpbackup/history"
@@ -16,6 +15,7 @@ import (
 	"github.com/greenplum-db/gpbackup/report"
 	"github.com/greenplum-db/gpbackup/toc"
 	"github.com/greenplum-db/gpbackup/utils"
+	"github.com/pkg/errors"
 )
 
 /*
@@ -66,6 +66,9 @@ func CreateConnectionPool(unquotedDBName string) {
 	connectionPool = dbconn.NewDBConnFromEnvironment(unquotedDBName)
 	if FlagChanged(options.COPY_QUEUE_SIZE) {
 		connectionPool.MustConnect(MustGetFlagInt(options.COPY_QUEUE_SIZE))
+	} else if backupConfig != nil && backupConfig.DataStreams > 1 {
+		// Each data stream needs a COPY command of its own to be read at the same time as the others
+		connectionPool.MustConnect(backupConfig.DataStreams)
 	} else {
 		connectionPool.MustConnect(MustGetFlagInt(options.JOBS))
 	}
@@ -154,10 +157,32 @@ func SetMaxCsvLineLengthQuery(connectionPool *dbconn.DBConn) string {
 func InitializeBackupConfig() {
 	backupConfig = history.ReadConfigFile(globalFPInfo.GetConfigFilePath())
 	utils.InitializePipeThroughParameters(backupConfig.Compressed, backupConfig.CompressionType, 0)
+	InitializeEncryption(MustGetFlagString(options.ENCRYPTION_KEY_FILE))
 	report.EnsureBackupVersionCompatibility(backupConfig.BackupVersion, version)
 	report.EnsureDatabaseVersionCompatibility(backupConfig.DatabaseVersion, connectionPool.Version)
 }
 
+/*
+ * The fingerprint of the key is checked before any backup file is read, so
+ * that a wrong or missing key is reported as such rather than as corrupt data.
+ */
+func InitializeEncryption(keyFile string) {
+	if backupConfig.EncryptionFingerprint == "" {
+		if keyFile != "" {
+			gplog.Warn("Backup %s is not encrypted, ignoring --%s", globalFPInfo.Timestamp, options.ENCRYPTION_KEY_FILE)
+		}
+		gplog.FatalOnError(utils.InitializeEncryption(""))
+		return
+	}
+	if keyFile == "" {
+		gplog.Fatal(errors.Errorf("Backup %s is encrypted.  Please specify the key with which it was encrypted with --%s.", globalFPInfo.Timestamp, options.ENCRYPTION_KEY_FILE), "")
+	}
+	gplog.FatalOnError(utils.InitializeEncryption(keyFile))
+	if fingerprint := utils.GetEncryptionKeyFingerprint(utils.GetEncryptionKey()); fingerprint != backupConfig.EncryptionFingerprint {
+		gplog.Fatal(errors.Errorf("The key in %s is not the key with which backup %s was encrypted: expected key fingerprint %s, found %s", keyFile, globalFPInfo.Timestamp, backupConfig.EncryptionFingerprint, fingerprint), "")
+	}
+}
+
 func BackupConfigurationValidation() {
 	if !backupConfig.MetadataOnly {
 		gplog.Verbose("Gathering information on backup directories")
@@ -176,6 +201,7 @@ func BackupConfigurationValidation() {
 	}
 
 	ValidateBackupFlagCombinations()
+	ValidateDataFormatsForRestore(globalTOC.DataEntries)
 
 	validateFilterListsInBackupSet()
 }
@@ -275,7 +301,7 @@ func GetRestoreMetadataStatements(section string, filename string, includeObject
 }
 
 func GetRestoreMetadataStatementsFiltered(section string, filename string, includeObjectTypes []string, excludeObjectTypes []string, filters Filters) []toc.StatementWithType {
-	metadataFile := iohelper.MustOpenFileForReading(filename)
+	metadataFile := utils.MustOpenFileForReadingAt(filename)
 	var statements []toc.StatementWithType
 	var inSchemas, exSchemas, inRelations, exRelations []string
 	if !filtersEmpty(filters) {
@@ -8,6 +8,7 @@ import (
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/greenplum-db/gp-common-go-libs/cluster"
 	"github.com/greenplum-db/gp-common-go-libs/testhelper"
+	fp "github.com/greenplum-db/gpbackup/filepath"
 	"github.com/greenplum-db/gpbackup/history"
 	"github.com/greenplum-db/gpbackup/options"
 	"github.com/greenplum-db/gpbackup/restore"
@@ -116,6 +117,43 @@ var _ = Describe("wrapper tests", func() {
 		})
 
 	})
+	Describe("InitializeEncryption", func() {
+		var keyFile string
+		key := []byte("0123456789abcdef0123456789abcdef")
+		BeforeEach(func() {
+			keyDir, err := ioutil.TempDir("", "gprestore-encryption")
+			Expect(err).ToNot(HaveOccurred())
+			keyFile = filepath.Join(keyDir, "key")
+			Expect(ioutil.WriteFile(keyFile, key, 0600)).To(Succeed())
+			restore.SetFPInfo(fp.FilePathInfo{Timestamp: "20170101010101"})
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(filepath.Dir(keyFile))
+			restore.SetBackupConfig(&history.BackupConfig{})
+			utils.SetEncryptionKey(nil)
+		})
+		It("uses the key with which the backup was encrypted", func() {
+			restore.SetBackupConfig(&history.BackupConfig{EncryptionFingerprint: utils.GetEncryptionKeyFingerprint(key)})
+			restore.InitializeEncryption(keyFile)
+			Expect(utils.GetEncryptionKey()).To(Equal(key))
+		})
+		It("panics if the backup is encrypted and no key is given", func() {
+			restore.SetBackupConfig(&history.BackupConfig{EncryptionFingerprint: utils.GetEncryptionKeyFingerprint(key)})
+			defer testhelper.ShouldPanicWithMessage("Backup 20170101010101 is encrypted.  Please specify the key with which it was encrypted with --encryption-key-file.")
+			restore.InitializeEncryption("")
+		})
+		It("panics if the backup was encrypted with a different key", func() {
+			restore.SetBackupConfig(&history.BackupConfig{EncryptionFingerprint: "0123456789abcdef0123456789abcdef"})
+			defer testhelper.ShouldPanicWithMessage("is not the key with which backup 20170101010101 was encrypted")
+			restore.InitializeEncryption(keyFile)
+		})
+		It("ignores the key if the backup is not encrypted", func() {
+			restore.SetBackupConfig(&history.BackupConfig{})
+			restore.InitializeEncryption(keyFile)
+			Expect(utils.GetEncryptionKey()).To(BeNil())
+			testhelper.ExpectRegexp(stdout, "Backup 20170101010101 is not encrypted, ignoring --encryption-key-file")
+		})
+	})
 	Describe("restore history tests", func() {
 		sampleConfigContents := `
 executablepath: /bin/echo
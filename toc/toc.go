@@ -5,6 +5,7 @@ import (
 	"io"
 	"io/ioutil"
 	"regexp"
+	"sort"
 	"strings"
 
 	"github.com/greenplum-db/gp-common-go-libs/gplog"
@@ -24,6 +25,7 @@ type TOC struct {
 
 type SegmentTOC struct {
 	DataEntries map[uint]SegmentDataEntry
+	Blocks      []SegmentBlock // Index of the independently compressed blocks of the data file, if any
 }
 
 type MetadataEntry struct {
@@ -45,15 +47,47 @@ type CoordinatorDataEntry struct {
 	PartitionRoot   string
 	IsReplicated    bool
 	DistByEnum      bool
+	Checksums       map[int]string // Checksums of multiple-data-file backups, keyed by content ID
+	DataSize        int64          // Estimated size in bytes of the table data at backup time
+	Predicate       string         // WHERE clause that the backed up data was filtered by, if any
+	DataFormat      string         // Format of the backed up data, which is csv if not set
+	ChunkList       string         // Name of the chunk list holding the data of each segment, for backups taken with --repository
 }
 
+/*
+ * A single data file backup taken with --data-streams writes the table data of
+ * each segment to several data files at once, so the offsets of an entry are
+ * offsets into the data of the stream it was written to.
+ */
 type SegmentDataEntry struct {
 	StartByte uint64
 	EndByte   uint64
+	Checksum  string
+	Stream    int
+}
+
+/*
+ * A compressed single data file may be written as a series of independently
+ * compressed blocks, each starting at a table boundary, so that a restore can
+ * start decompressing at the block holding the table it wants.  The last
+ * block marks the end of the data file and holds no data.
+ */
+type SegmentBlock struct {
+	DataOffset uint64 // Offset of the block in the uncompressed table data
+	FileOffset uint64 // Offset of the block in the compressed data file
+	Stream     int    // Data stream whose data file the block is in
 }
 
 type IncrementalEntries struct {
-	AO map[string]AOEntry
+	AO   map[string]AOEntry
+	Heap map[string]HeapEntry
+	/*
+	 * The tuple counters in the heap entries are only comparable between
+	 * backups taken while the statistics collector on every segment kept
+	 * counting, so this identifies when each segment's counters were last
+	 * reset.  It is empty if the counters cannot be trusted at all.
+	 */
+	HeapStatsEpoch string
 }
 
 type AOEntry struct {
@@ -61,6 +95,14 @@ type AOEntry struct {
 	LastDDLTimestamp string
 }
 
+type HeapEntry struct {
+	Relfilenode      uint32
+	TuplesInserted   int64
+	TuplesUpdated    int64
+	TuplesDeleted    int64
+	LastDDLTimestamp string
+}
+
 type UniqueID struct {
 	ClassID uint32
 	Oid     uint32
@@ -126,6 +168,8 @@ func NewTOC(filename string) *TOC {
 	toc := &TOC{}
 	contents, err := ioutil.ReadFile(filename)
 	gplog.FatalOnError(err)
+	contents, err = utils.DecryptContents(contents)
+	gplog.FatalOnError(err, filename)
 	err = yaml.Unmarshal(contents, toc)
 	gplog.FatalOnError(err)
 	return toc
@@ -143,6 +187,8 @@ func NewSegmentTOC(filename string) *SegmentTOC {
 func (toc *TOC) WriteToFileAndMakeReadOnly(filename string) {
 	contents, err := yaml.Marshal(toc)
 	gplog.FatalOnError(err)
+	contents, err = utils.EncryptContents(contents)
+	gplog.FatalOnError(err)
 	err = utils.WriteToFileAndMakeReadOnly(filename, contents)
 	gplog.FatalOnError(err)
 }
@@ -317,6 +363,176 @@ func RemoveActiveRole(activeUser string, statements []StatementWithType) []State
 	return newStatements
 }
 
+/*
+ * Role names are written on their own lines in owner, privileges, role,
+ * user mapping, and policy statements, so these expressions match whole lines
+ * to avoid rewriting a role name that appears in e.g. a function body.
+ */
+var (
+	// Matches a quoted identifier or an identifier that needs no quotes
+	identifierMatch  = `(?:"(?:[^"]|"")+"|[^\s,;"()]+)`
+	roleRE           = regexp.MustCompile(identifierMatch)
+	rolesMatch       = fmt.Sprintf(`%[1]s(?:, ?%[1]s)*`, identifierMatch)
+	roleStatementREs = []*regexp.Regexp{
+		regexp.MustCompile(fmt.Sprintf(`(?m)^(ALTER .+ OWNER TO )(%s)(;)$`, identifierMatch)),
+		regexp.MustCompile(fmt.Sprintf(`(?m)^((?:CREATE|ALTER|COMMENT ON) ROLE |SECURITY LABEL FOR \S+ ON ROLE |CREATE USER MAPPING FOR |ALTER DEFAULT PRIVILEGES FOR ROLE | TO )(%s)()`, rolesMatch)),
+		regexp.MustCompile(fmt.Sprintf(`(?m)^(GRANT )(%s)( TO )`, rolesMatch)),
+		regexp.MustCompile(fmt.Sprintf(`(?m)^((?:ALTER DEFAULT PRIVILEGES .*?)?(?:GRANT|REVOKE) .* (?:TO|FROM) )(%s)((?: WITH (?:GRANT|ADMIN) OPTION)?(?: GRANTED BY .+)?;)$`, rolesMatch)),
+		regexp.MustCompile(fmt.Sprintf(`(?m)^(GRANT .* GRANTED BY )(%s)(;)$`, identifierMatch)),
+	}
+	ownerLineRE      = regexp.MustCompile(`^ALTER .+ OWNER TO .+;$`)
+	privilegesLineRE = regexp.MustCompile(`^(?:ALTER DEFAULT PRIVILEGES .*)?(?:GRANT|REVOKE) .+;$`)
+)
+
+/*
+ * Rewrites the name of each role in roleMap to the name of the role it is
+ * mapped to.  The roles that are mapped to other roles are not created, as
+ * the roles they are mapped to are expected to already exist.
+ */
+func SubstituteRolesInStatements(statements []StatementWithType, roleMap map[string]string) []StatementWithType {
+	if len(roleMap) == 0 {
+		return statements
+	}
+	replaceRoles := func(roles string) string {
+		return roleRE.ReplaceAllStringFunc(roles, func(role string) string {
+			if newRole, ok := roleMap[role]; ok {
+				return newRole
+			}
+			return role
+		})
+	}
+	newStatements := make([]StatementWithType, 0)
+	for _, statement := range statements {
+		if _, ok := roleMap[statement.Name]; ok && statement.ObjectType == OBJ_ROLE {
+			continue
+		}
+		if isRoutineDefinition(statement.Statement) {
+			newStatements = append(newStatements, statement)
+			continue
+		}
+		for _, statementRE := range roleStatementREs {
+			statement.Statement = statementRE.ReplaceAllStringFunc(statement.Statement, func(match string) string {
+				groups := statementRE.FindStringSubmatch(match)
+				return groups[1] + replaceRoles(groups[2]) + groups[3]
+			})
+		}
+		if newRole, ok := roleMap[statement.Name]; ok && (statement.ObjectType == OBJ_ROLE_GUC || statement.ObjectType == OBJ_ROLE_GRANT) {
+			statement.Name = newRole
+		}
+		newStatements = append(newStatements, statement)
+	}
+	return newStatements
+}
+
+// Removes the ALTER ... OWNER TO statements, so that objects are owned by the user running the restore
+func RemoveOwnerStatements(statements []StatementWithType) []StatementWithType {
+	return removeStatementLines(statements, func(statement StatementWithType, line string) bool {
+		return ownerLineRE.MatchString(line)
+	})
+}
+
+// Removes the GRANT and REVOKE statements of object privileges, but not those of role membership
+func RemovePrivilegesStatements(statements []StatementWithType) []StatementWithType {
+	return removeStatementLines(statements, func(statement StatementWithType, line string) bool {
+		return statement.ObjectType != OBJ_ROLE_GRANT && privilegesLineRE.MatchString(line)
+	})
+}
+
+func removeStatementLines(statements []StatementWithType, shouldRemove func(StatementWithType, string) bool) []StatementWithType {
+	newStatements := make([]StatementWithType, 0)
+	for _, statement := range statements {
+		if isRoutineDefinition(statement.Statement) {
+			newStatements = append(newStatements, statement)
+			continue
+		}
+		lines := strings.Split(statement.Statement, "\n")
+		keptLines := make([]string, 0, len(lines))
+		for _, line := range lines {
+			if !shouldRemove(statement, strings.TrimSpace(line)) {
+				keptLines = append(keptLines, line)
+			}
+		}
+		statement.Statement = strings.Join(keptLines, "\n")
+		if strings.TrimSpace(statement.Statement) == "" {
+			continue
+		}
+		newStatements = append(newStatements, statement)
+	}
+	return newStatements
+}
+
+// The lines of a function body could look like any other statement, so function definitions are left alone
+func isRoutineDefinition(statement string) bool {
+	statement = strings.TrimSpace(statement)
+	return strings.HasPrefix(statement, "CREATE FUNCTION") || strings.HasPrefix(statement, "CREATE PROCEDURE")
+}
+
+/*
+ * Tablespaces are only named in the definitions of these objects outside of
+ * the tablespace globals themselves.
+ */
+var (
+	tablespaceObjectTypes = map[string]bool{OBJ_DATABASE: true, OBJ_TABLE: true, OBJ_MATERIALIZED_VIEW: true,
+		OBJ_INDEX: true, "INDEX METADATA": true, OBJ_CONSTRAINT: true}
+	tablespaceRE       = regexp.MustCompile(fmt.Sprintf(`(\bTABLESPACE )(%s)`, identifierMatch))
+	tablespaceClauseRE = regexp.MustCompile(fmt.Sprintf(` ?(?:USING INDEX )?\bTABLESPACE %s`, identifierMatch))
+	setTablespaceRE    = regexp.MustCompile(`^ALTER .+ SET TABLESPACE .+;$`)
+)
+
+/*
+ * Rewrites the name of each tablespace in tablespaceMap to the name of the
+ * tablespace it is mapped to.  The tablespaces that are mapped to other
+ * tablespaces are not created, as the tablespaces they are mapped to are
+ * expected to already exist.
+ */
+func SubstituteTablespacesInStatements(statements []StatementWithType, tablespaceMap map[string]string) []StatementWithType {
+	if len(tablespaceMap) == 0 {
+		return statements
+	}
+	newStatements := make([]StatementWithType, 0)
+	for _, statement := range statements {
+		if _, ok := tablespaceMap[statement.Name]; ok && statement.ObjectType == OBJ_TABLESPACE {
+			continue
+		}
+		if tablespaceObjectTypes[statement.ObjectType] {
+			statement.Statement = tablespaceRE.ReplaceAllStringFunc(statement.Statement, func(match string) string {
+				groups := tablespaceRE.FindStringSubmatch(match)
+				if newTablespace, ok := tablespaceMap[groups[2]]; ok {
+					return groups[1] + newTablespace
+				}
+				return match
+			})
+		}
+		newStatements = append(newStatements, statement)
+	}
+	return newStatements
+}
+
+// Removes the tablespace globals and the tablespaces of objects, so that objects are restored to the default tablespace
+func RemoveTablespacesFromStatements(statements []StatementWithType) []StatementWithType {
+	newStatements := make([]StatementWithType, 0)
+	for _, statement := range statements {
+		if statement.ObjectType == OBJ_TABLESPACE {
+			continue
+		}
+		if tablespaceObjectTypes[statement.ObjectType] {
+			lines := strings.Split(statement.Statement, "\n")
+			keptLines := make([]string, 0, len(lines))
+			for _, line := range lines {
+				if !setTablespaceRE.MatchString(strings.TrimSpace(line)) {
+					keptLines = append(keptLines, tablespaceClauseRE.ReplaceAllString(line, ""))
+				}
+			}
+			statement.Statement = strings.Join(keptLines, "\n")
+			if strings.TrimSpace(statement.Statement) == "" {
+				continue
+			}
+		}
+		newStatements = append(newStatements, statement)
+	}
+	return newStatements
+}
+
 func (toc *TOC) InitializeMetadataEntryMap() {
 	toc.metadataEntryMap = make(map[string]*[]MetadataEntry, 4)
 	toc.metadataEntryMap["global"] = &toc.GlobalEntries
@@ -341,12 +557,68 @@ func (toc *TOC) AddMetadataEntry(section string, entry MetadataEntry, start, end
 	*toc.metadataEntryMap[section] = append(*toc.metadataEntryMap[section], entry)
 }
 
-func (toc *TOC) AddCoordinatorDataEntry(schema string, name string, oid uint32, attributeString string, rowsCopied int64, PartitionRoot string, distPolicy string, distByEnum bool) {
+func NewCoordinatorDataEntry(schema string, name string, oid uint32, attributeString string, rowsCopied int64, PartitionRoot string, distPolicy string, distByEnum bool) CoordinatorDataEntry {
 	isReplicated := strings.Contains(distPolicy, "REPLICATED")
-	toc.DataEntries = append(toc.DataEntries, CoordinatorDataEntry{schema, name, oid, attributeString, rowsCopied, PartitionRoot, isReplicated, distByEnum})
+	return CoordinatorDataEntry{schema, name, oid, attributeString, rowsCopied, PartitionRoot, isReplicated, distByEnum, nil, 0, "", "", ""}
+}
+
+func (toc *TOC) AddCoordinatorDataEntry(schema string, name string, oid uint32, attributeString string, rowsCopied int64, PartitionRoot string, distPolicy string, distByEnum bool) {
+	toc.DataEntries = append(toc.DataEntries, NewCoordinatorDataEntry(schema, name, oid, attributeString, rowsCopied, PartitionRoot, distPolicy, distByEnum))
 }
 
-func (toc *SegmentTOC) AddSegmentDataEntry(oid uint, startByte uint64, endByte uint64) {
+func (toc *SegmentTOC) AddSegmentDataEntry(oid uint, startByte uint64, endByte uint64, checksum string, stream int) {
 	// We use uint for oid since the flags package does not have a uint32 flag
-	toc.DataEntries[oid] = SegmentDataEntry{startByte, endByte}
+	toc.DataEntries[oid] = SegmentDataEntry{startByte, endByte, checksum, stream}
+}
+
+func (toc *SegmentTOC) AddSegmentBlock(dataOffset uint64, fileOffset uint64, stream int) {
+	toc.Blocks = append(toc.Blocks, SegmentBlock{dataOffset, fileOffset, stream})
+}
+
+/*
+ * Returns the entries and blocks of a single data stream, so that the data file
+ * of the stream can be read as if it were the only data file of the segment.
+ */
+func (toc *SegmentTOC) GetStreamTOC(stream int) *SegmentTOC {
+	streamTOC := &SegmentTOC{DataEntries: make(map[uint]SegmentDataEntry)}
+	for oid, entry := range toc.DataEntries {
+		if entry.Stream == stream {
+			streamTOC.DataEntries[oid] = entry
+		}
+	}
+	for _, block := range toc.Blocks {
+		if block.Stream == stream {
+			streamTOC.Blocks = append(streamTOC.Blocks, block)
+		}
+	}
+	return streamTOC
+}
+
+// A block index needs at least one block of data and the end marker
+func (toc *SegmentTOC) HasBlocks() bool {
+	return toc != nil && len(toc.Blocks) > 1
+}
+
+// Returns the block holding the table data at the given offset
+func (toc *SegmentTOC) GetBlockForOffset(offset uint64) SegmentBlock {
+	dataBlocks := toc.Blocks[:len(toc.Blocks)-1]
+	i := sort.Search(len(dataBlocks), func(i int) bool { return dataBlocks[i].DataOffset > offset })
+	if i == 0 {
+		return dataBlocks[0]
+	}
+	return dataBlocks[i-1]
+}
+
+/*
+ * Returns the first block starting at or after the given offset, or the end
+ * marker if there is no such block, so the blocks from
+ * GetBlockForOffset(startByte) up to GetBlockBoundaryAtOrAfter(endByte) are
+ * the fewest blocks holding the table data between the two offsets.
+ */
+func (toc *SegmentTOC) GetBlockBoundaryAtOrAfter(offset uint64) SegmentBlock {
+	i := sort.Search(len(toc.Blocks), func(i int) bool { return toc.Blocks[i].DataOffset >= offset })
+	if i == len(toc.Blocks) {
+		return toc.Blocks[len(toc.Blocks)-1]
+	}
+	return toc.Blocks[i]
 }
@@ -400,6 +400,173 @@ COMMENT ON DATABASE "db-special-chär$" IS 'this is a database comment';`}
 			Expect(resultStatements).To(Equal([]toc.StatementWithType{user1, user2}))
 		})
 	})
+	Describe("SubstituteRolesInStatements", func() {
+		roleMap := map[string]string{"app_owner": "stage_owner", `"App Reader"`: "stage_reader"}
+		It("replaces mapped roles in owner and privileges statements", func() {
+			statements := []toc.StatementWithType{
+				{Schema: "public", Name: "foo", ObjectType: toc.OBJ_TABLE, Statement: "\n\nALTER TABLE public.foo OWNER TO app_owner;\n"},
+				{Schema: "public", Name: "foo", ObjectType: toc.OBJ_TABLE, Statement: "\n\nREVOKE ALL ON TABLE public.foo FROM PUBLIC;\nREVOKE ALL ON TABLE public.foo FROM app_owner;\nGRANT ALL ON TABLE public.foo TO app_owner;\nGRANT SELECT ON TABLE public.foo TO \"App Reader\" WITH GRANT OPTION;\nGRANT SELECT ON TABLE public.foo TO other_role;\n"},
+				{ObjectType: "DEFAULT PRIVILEGES", Statement: "\n\nALTER DEFAULT PRIVILEGES FOR ROLE app_owner IN SCHEMA public GRANT SELECT ON TABLES TO \"App Reader\";\n"},
+			}
+
+			resultStatements := toc.SubstituteRolesInStatements(statements, roleMap)
+
+			Expect(resultStatements[0].Statement).To(Equal("\n\nALTER TABLE public.foo OWNER TO stage_owner;\n"))
+			Expect(resultStatements[1].Statement).To(Equal("\n\nREVOKE ALL ON TABLE public.foo FROM PUBLIC;\nREVOKE ALL ON TABLE public.foo FROM stage_owner;\nGRANT ALL ON TABLE public.foo TO stage_owner;\nGRANT SELECT ON TABLE public.foo TO stage_reader WITH GRANT OPTION;\nGRANT SELECT ON TABLE public.foo TO other_role;\n"))
+			Expect(resultStatements[2].Statement).To(Equal("\n\nALTER DEFAULT PRIVILEGES FOR ROLE stage_owner IN SCHEMA public GRANT SELECT ON TABLES TO stage_reader;\n"))
+		})
+		It("replaces mapped roles in role statements and removes the definitions of mapped roles", func() {
+			statements := []toc.StatementWithType{
+				{Name: "app_owner", ObjectType: toc.OBJ_ROLE, Statement: "\n\nCREATE ROLE app_owner;\nALTER ROLE app_owner WITH NOSUPERUSER;\n"},
+				{Name: "other_role", ObjectType: toc.OBJ_ROLE, Statement: "\n\nCREATE ROLE other_role;\nALTER ROLE other_role WITH NOCREATEROLE INHERIT;\n"},
+				{Name: "app_owner", ObjectType: toc.OBJ_ROLE_GUC, Statement: "\n\nALTER ROLE app_owner SET search_path TO app;"},
+				{Name: `"App Reader"`, ObjectType: toc.OBJ_ROLE_GRANT, Statement: "\nGRANT app_owner TO \"App Reader\" WITH ADMIN OPTION GRANTED BY app_owner;"},
+			}
+
+			resultStatements := toc.SubstituteRolesInStatements(statements, roleMap)
+
+			Expect(resultStatements).To(Equal([]toc.StatementWithType{
+				{Name: "other_role", ObjectType: toc.OBJ_ROLE, Statement: "\n\nCREATE ROLE other_role;\nALTER ROLE other_role WITH NOCREATEROLE INHERIT;\n"},
+				{Name: "stage_owner", ObjectType: toc.OBJ_ROLE_GUC, Statement: "\n\nALTER ROLE stage_owner SET search_path TO app;"},
+				{Name: "stage_reader", ObjectType: toc.OBJ_ROLE_GRANT, Statement: "\nGRANT stage_owner TO stage_reader WITH ADMIN OPTION GRANTED BY stage_owner;"},
+			}))
+		})
+		It("replaces mapped roles in user mapping and policy statements", func() {
+			statements := []toc.StatementWithType{
+				{Name: "app_owner", ObjectType: toc.OBJ_USER_MAPPING, Statement: "\n\nCREATE USER MAPPING FOR app_owner\n\tSERVER foreign_server;"},
+				{Schema: "public", Name: "foo_policy", ObjectType: "POLICY", Statement: "\n\nCREATE POLICY foo_policy\nON public.foo\n TO app_owner, other_role, \"App Reader\";"},
+			}
+
+			resultStatements := toc.SubstituteRolesInStatements(statements, roleMap)
+
+			Expect(resultStatements[0].Statement).To(Equal("\n\nCREATE USER MAPPING FOR stage_owner\n\tSERVER foreign_server;"))
+			Expect(resultStatements[1].Statement).To(Equal("\n\nCREATE POLICY foo_policy\nON public.foo\n TO stage_owner, other_role, stage_reader;"))
+		})
+		It("does not replace roles in a function body", func() {
+			function := toc.StatementWithType{Schema: "public", Name: "grant_all", ObjectType: toc.OBJ_FUNCTION, Statement: "\n\nCREATE FUNCTION public.grant_all() RETURNS void AS $$\nBEGIN\nGRANT ALL ON TABLE public.foo TO app_owner;\nEND\n$$\nLANGUAGE plpgsql;"}
+
+			resultStatements := toc.SubstituteRolesInStatements([]toc.StatementWithType{function}, roleMap)
+
+			Expect(resultStatements).To(Equal([]toc.StatementWithType{function}))
+		})
+	})
+	Describe("RemoveOwnerStatements", func() {
+		It("removes owner statements and leaves the rest", func() {
+			owner := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: toc.OBJ_TABLE, Statement: "\n\nALTER TABLE public.foo OWNER TO app_owner;\n"}
+			comment := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: toc.OBJ_TABLE, Statement: "\n\nCOMMENT ON TABLE public.foo IS 'owner';\n"}
+			database := toc.StatementWithType{Name: "somedatabase", ObjectType: toc.OBJ_DATABASE_METADATA, Statement: "ALTER DATABASE somedatabase OWNER TO testrole;\n\nREVOKE ALL ON DATABASE somedatabase FROM public;"}
+
+			resultStatements := toc.RemoveOwnerStatements([]toc.StatementWithType{owner, comment, database})
+
+			Expect(resultStatements).To(Equal([]toc.StatementWithType{
+				comment,
+				{Name: "somedatabase", ObjectType: toc.OBJ_DATABASE_METADATA, Statement: "\nREVOKE ALL ON DATABASE somedatabase FROM public;"},
+			}))
+		})
+	})
+	Describe("RemovePrivilegesStatements", func() {
+		It("removes privileges statements but not role memberships", func() {
+			privileges := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: toc.OBJ_TABLE, Statement: "\n\nREVOKE ALL ON TABLE public.foo FROM PUBLIC;\nGRANT ALL ON TABLE public.foo TO app_owner;\n"}
+			defaultPrivileges := toc.StatementWithType{ObjectType: "DEFAULT PRIVILEGES", Statement: "\n\nALTER DEFAULT PRIVILEGES FOR ROLE app_owner REVOKE ALL ON TABLES FROM PUBLIC;\n"}
+			owner := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: toc.OBJ_TABLE, Statement: "\n\nALTER TABLE public.foo OWNER TO app_owner;\n"}
+			roleGrant := toc.StatementWithType{Name: "app_reader", ObjectType: toc.OBJ_ROLE_GRANT, Statement: "\nGRANT app_owner TO app_reader;"}
+
+			resultStatements := toc.RemovePrivilegesStatements([]toc.StatementWithType{privileges, defaultPrivileges, owner, roleGrant})
+
+			Expect(resultStatements).To(Equal([]toc.StatementWithType{owner, roleGrant}))
+		})
+	})
+	Describe("tablespaces", func() {
+		table := toc.StatementWithType{Schema: "public", Name: "foo", ObjectType: toc.OBJ_TABLE, Statement: "\n\nCREATE TABLE public.foo (\n\ti integer\n) WITH (appendonly=true) TABLESPACE fast_disk DISTRIBUTED BY (i);\n"}
+		matview := toc.StatementWithType{Schema: "public", Name: "foo_view", ObjectType: toc.OBJ_MATERIALIZED_VIEW, Statement: "\n\nCREATE MATERIALIZED VIEW public.foo_view TABLESPACE \"Fast Disk\" AS SELECT i FROM public.foo\nWITH NO DATA\nDISTRIBUTED BY (i);\n"}
+		index := toc.StatementWithType{Schema: "public", Name: "foo_idx", ObjectType: "INDEX METADATA", ReferenceObject: "public.foo_idx", Statement: "\nALTER INDEX public.foo_idx SET TABLESPACE fast_disk;"}
+		constraint := toc.StatementWithType{Schema: "public", Name: "foo_pkey", ObjectType: toc.OBJ_CONSTRAINT, ReferenceObject: "public.foo", Statement: "\n\nALTER TABLE ONLY public.foo ADD CONSTRAINT foo_pkey PRIMARY KEY (i) USING INDEX TABLESPACE fast_disk;"}
+		database := toc.StatementWithType{Name: "somedatabase", ObjectType: toc.OBJ_DATABASE, Statement: "\n\nCREATE DATABASE somedatabase TEMPLATE template0 TABLESPACE fast_disk;"}
+		tablespace := toc.StatementWithType{Name: "fast_disk", ObjectType: toc.OBJ_TABLESPACE, Statement: "\n\nCREATE TABLESPACE fast_disk LOCATION '/data/fast';"}
+		otherTablespace := toc.StatementWithType{Name: "slow_disk", ObjectType: toc.OBJ_TABLESPACE, Statement: "\n\nCREATE TABLESPACE slow_disk LOCATION '/data/slow';"}
+		function := toc.StatementWithType{Schema: "public", Name: "myfunc", ObjectType: toc.OBJ_FUNCTION, Statement: "\n\nCREATE FUNCTION public.myfunc() RETURNS text AS $$SELECT 'TABLESPACE fast_disk'$$\nLANGUAGE sql;"}
+		statements := []toc.StatementWithType{table, matview, index, constraint, database, tablespace, otherTablespace, function}
+		Describe("SubstituteTablespacesInStatements", func() {
+			It("replaces mapped tablespaces and removes the definitions of mapped tablespaces", func() {
+				resultStatements := toc.SubstituteTablespacesInStatements(statements, map[string]string{"fast_disk": "pg_default", `"Fast Disk"`: "ssd"})
+
+				Expect(resultStatements).To(HaveLen(7))
+				Expect(resultStatements[0].Statement).To(Equal("\n\nCREATE TABLE public.foo (\n\ti integer\n) WITH (appendonly=true) TABLESPACE pg_default DISTRIBUTED BY (i);\n"))
+				Expect(resultStatements[1].Statement).To(Equal("\n\nCREATE MATERIALIZED VIEW public.foo_view TABLESPACE ssd AS SELECT i FROM public.foo\nWITH NO DATA\nDISTRIBUTED BY (i);\n"))
+				Expect(resultStatements[2].Statement).To(Equal("\nALTER INDEX public.foo_idx SET TABLESPACE pg_default;"))
+				Expect(resultStatements[3].Statement).To(Equal("\n\nALTER TABLE ONLY public.foo ADD CONSTRAINT foo_pkey PRIMARY KEY (i) USING INDEX TABLESPACE pg_default;"))
+				Expect(resultStatements[4].Statement).To(Equal("\n\nCREATE DATABASE somedatabase TEMPLATE template0 TABLESPACE pg_default;"))
+				Expect(resultStatements[5]).To(Equal(otherTablespace))
+				Expect(resultStatements[6]).To(Equal(function))
+			})
+		})
+		Describe("RemoveTablespacesFromStatements", func() {
+			It("removes tablespaces from definitions and removes the tablespace globals", func() {
+				resultStatements := toc.RemoveTablespacesFromStatements(statements)
+
+				Expect(resultStatements).To(HaveLen(5))
+				Expect(resultStatements[0].Statement).To(Equal("\n\nCREATE TABLE public.foo (\n\ti integer\n) WITH (appendonly=true) DISTRIBUTED BY (i);\n"))
+				Expect(resultStatements[1].Statement).To(Equal("\n\nCREATE MATERIALIZED VIEW public.foo_view AS SELECT i FROM public.foo\nWITH NO DATA\nDISTRIBUTED BY (i);\n"))
+				Expect(resultStatements[2].Statement).To(Equal("\n\nALTER TABLE ONLY public.foo ADD CONSTRAINT foo_pkey PRIMARY KEY (i);"))
+				Expect(resultStatements[3].Statement).To(Equal("\n\nCREATE DATABASE somedatabase TEMPLATE template0;"))
+				Expect(resultStatements[4]).To(Equal(function))
+			})
+		})
+	})
+	Describe("SegmentTOC blocks", func() {
+		segmentTOC := &toc.SegmentTOC{}
+		BeforeEach(func() {
+			segmentTOC = &toc.SegmentTOC{DataEntries: make(map[uint]toc.SegmentDataEntry)}
+			segmentTOC.AddSegmentBlock(0, 0, 0)
+			segmentTOC.AddSegmentBlock(100, 40, 0)
+			segmentTOC.AddSegmentBlock(250, 90, 0)
+			segmentTOC.AddSegmentBlock(400, 150, 0)
+		})
+		It("has no blocks without a block index", func() {
+			Expect((&toc.SegmentTOC{}).HasBlocks()).To(BeFalse())
+			Expect(segmentTOC.HasBlocks()).To(BeTrue())
+		})
+		DescribeTable("GetBlockForOffset returns the block holding the data at an offset",
+			func(offset uint64, expected toc.SegmentBlock) {
+				Expect(segmentTOC.GetBlockForOffset(offset)).To(Equal(expected))
+			},
+			Entry("the start of the first block", uint64(0), toc.SegmentBlock{DataOffset: 0, FileOffset: 0}),
+			Entry("the middle of a block", uint64(150), toc.SegmentBlock{DataOffset: 100, FileOffset: 40}),
+			Entry("the start of a block", uint64(250), toc.SegmentBlock{DataOffset: 250, FileOffset: 90}),
+			Entry("the end of the data", uint64(400), toc.SegmentBlock{DataOffset: 250, FileOffset: 90}),
+		)
+		DescribeTable("GetBlockBoundaryAtOrAfter returns the first block boundary at or after an offset",
+			func(offset uint64, expected toc.SegmentBlock) {
+				Expect(segmentTOC.GetBlockBoundaryAtOrAfter(offset)).To(Equal(expected))
+			},
+			Entry("the start of a block", uint64(100), toc.SegmentBlock{DataOffset: 100, FileOffset: 40}),
+			Entry("the middle of a block", uint64(150), toc.SegmentBlock{DataOffset: 250, FileOffset: 90}),
+			Entry("the end of the data", uint64(400), toc.SegmentBlock{DataOffset: 400, FileOffset: 150}),
+		)
+	})
+	Describe("GetStreamTOC", func() {
+		It("returns only the entries and blocks of the stream", func() {
+			segmentTOC := &toc.SegmentTOC{DataEntries: make(map[uint]toc.SegmentDataEntry)}
+			segmentTOC.AddSegmentDataEntry(1, 0, 100, "00000001", 0)
+			segmentTOC.AddSegmentDataEntry(2, 0, 50, "00000002", 1)
+			segmentTOC.AddSegmentDataEntry(3, 100, 300, "00000003", 0)
+			segmentTOC.AddSegmentBlock(0, 0, 0)
+			segmentTOC.AddSegmentBlock(0, 0, 1)
+			segmentTOC.AddSegmentBlock(50, 20, 1)
+			segmentTOC.AddSegmentBlock(300, 120, 0)
+
+			streamTOC := segmentTOC.GetStreamTOC(1)
+
+			Expect(streamTOC.DataEntries).To(Equal(map[uint]toc.SegmentDataEntry{
+				2: {StartByte: 0, EndByte: 50, Checksum: "00000002", Stream: 1},
+			}))
+			Expect(streamTOC.Blocks).To(Equal([]toc.SegmentBlock{
+				{DataOffset: 0, FileOffset: 0, Stream: 1},
+				{DataOffset: 50, FileOffset: 20, Stream: 1},
+			}))
+			Expect(segmentTOC.GetStreamTOC(0).DataEntries).To(HaveLen(2))
+		})
+	})
 	Describe("GetIncludedPartitionRoots", func() {
 		It("does not return anything if relations are not leaf partitions", func() {
 			tocfile.AddCoordinatorDataEntry("schema0", "name0", 0, "attribute0", 1, "", "", false)
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	path "path/filepath"
+	"sort"
 	"strings"
 	"sync"
 
@@ -73,6 +74,62 @@ func WriteOidListToSegments(oidList []string, c *cluster.Cluster, fpInfo filepat
 	c.CheckClusterError(remoteOutput, errMsg, errFunc, false)
 }
 
+/*
+ * Unlike the oid list, the checksums differ between segments, so a separate
+ * file is written for each segment and copied to that segment only.
+ */
+func WriteChecksumsToSegments(checksums map[int]map[uint32]string, c *cluster.Cluster, fpInfo filepath.FilePathInfo) {
+	rsync_exists := CommandExists("rsync")
+	if !rsync_exists {
+		gplog.Fatal(errors.New("Failed to find rsync on PATH. Please ensure rsync is installed."), "")
+	}
+
+	localChecksumFiles := make(map[int]string)
+	defer func() {
+		for _, filename := range localChecksumFiles {
+			err := operating.System.Remove(filename)
+			if err != nil {
+				gplog.Warn("Cannot remove temporary checksum file: %s, Err: %s", filename, err.Error())
+			}
+		}
+	}()
+	for _, contentID := range c.ContentIDs {
+		if contentID == -1 {
+			continue
+		}
+		localChecksumFile, err := operating.System.TempFile("", "gpbackup-checksums")
+		gplog.FatalOnError(err, "Cannot open temporary file to write checksums")
+		localChecksumFiles[contentID] = localChecksumFile.Name()
+
+		oids := make([]uint32, 0, len(checksums[contentID]))
+		for oid := range checksums[contentID] {
+			oids = append(oids, oid)
+		}
+		sort.Slice(oids, func(i, j int) bool { return oids[i] < oids[j] })
+		for _, oid := range oids {
+			err = WriteChecksumEntry(localChecksumFile, oid, checksums[contentID][oid])
+			gplog.FatalOnError(err, localChecksumFile.Name())
+		}
+		err = localChecksumFile.Close()
+		gplog.FatalOnError(err, localChecksumFile.Name())
+	}
+
+	generateScpCmd := func(contentID int) string {
+		sourceFile := localChecksumFiles[contentID]
+		hostname := c.GetHostForContent(contentID)
+		dest := fpInfo.GetSegmentHelperFilePath(contentID, "checksums")
+
+		return fmt.Sprintf(`rsync -e ssh %s %s:%s`, sourceFile, hostname, dest)
+	}
+	remoteOutput := c.GenerateAndExecuteCommand("rsync checksum files to segments", cluster.ON_LOCAL|cluster.ON_SEGMENTS, generateScpCmd)
+
+	errMsg := "Failed to rsync checksum file"
+	errFunc := func(contentID int) string {
+		return "Failed to run rsync"
+	}
+	c.CheckClusterError(remoteOutput, errMsg, errFunc, false)
+}
+
 func WriteOidsToFile(filename string, oidList []string) {
 	oidFp, err := iohelper.OpenFileForWriting(filename)
 	gplog.FatalOnError(err, filename)
@@ -127,7 +184,7 @@ func VerifyHelperVersionOnSegments(version string, c *cluster.Cluster) {
 	}
 }
 
-func StartGpbackupHelpers(c *cluster.Cluster, fpInfo filepath.FilePathInfo, operation string, pluginConfigFile string, compressStr string, onErrorContinue bool, isFilter bool, wasTerminated *bool, copyQueue int, isSingleDataFile bool, resizeCluster bool, origSize int, destSize int) {
+func StartGpbackupHelpers(c *cluster.Cluster, fpInfo filepath.FilePathInfo, operation string, pluginConfigFile string, compressStr string, onErrorContinue bool, isFilter bool, wasTerminated *bool, copyQueue int, isSingleDataFile bool, resizeCluster bool, origSize int, destSize int, maxBandwidth int64, dataStreams int) {
 	// A mutex lock for cleaning up and starting gpbackup helpers prevents a
 	// race condition that causes gpbackup_helpers to be orphaned if
 	// gpbackup_helper cleanup happens before they are started.
@@ -167,8 +224,21 @@ func StartGpbackupHelpers(c *cluster.Cluster, fpInfo filepath.FilePathInfo, oper
 		pipeFile := fpInfo.GetSegmentPipeFilePath(contentID)
 		backupFile := fpInfo.GetTableBackupFilePath(contentID, 0, GetPipeThroughProgram().Extension, true)
 		replicatedOidFile := fpInfo.GetSegmentHelperFilePath(contentID, "replicated_oid")
-		helperCmdStr := fmt.Sprintf(`gpbackup_helper %s --toc-file %s --oid-file %s --pipe-file %s --data-file "%s" --content %d%s%s%s%s%s%s --copy-queue-size %d --replication-file %s`,
-			operation, tocFile, oidFile, pipeFile, backupFile, contentID, pluginStr, compressStr, onErrorContinueStr, filterStr, singleDataFileStr, resizeStr, copyQueue, replicatedOidFile)
+		encryptionKeyStr := ""
+		if encryptionKey != nil {
+			encryptionKeyStr = getEncryptionKeyFileArg(fpInfo.GetSegmentHelperFilePath(contentID, "key"))
+		}
+		// Only backups report their progress in bytes
+		progressStr := ""
+		if operation == "--backup-agent" {
+			progressStr = fmt.Sprintf(" --progress-file %s", fpInfo.GetSegmentHelperFilePath(contentID, "progress"))
+		}
+		dataStreamsStr := ""
+		if dataStreams > 1 {
+			dataStreamsStr = fmt.Sprintf(" --data-streams %d --stream-file %s", dataStreams, fpInfo.GetSegmentHelperFilePath(contentID, "stream"))
+		}
+		helperCmdStr := fmt.Sprintf(`gpbackup_helper %s --toc-file %s --oid-file %s --pipe-file %s --data-file "%s" --content %d%s%s%s%s%s%s --copy-queue-size %d --replication-file %s%s%s%s%s`,
+			operation, tocFile, oidFile, pipeFile, backupFile, contentID, pluginStr, compressStr, onErrorContinueStr, filterStr, singleDataFileStr, resizeStr, copyQueue, replicatedOidFile, encryptionKeyStr, dataStreamsStr, progressStr, getMaxBandwidthArg(maxBandwidth))
 		// we run these commands in sequence to ensure that any failure is critical; the last command ensures the agent process was successfully started
 		return fmt.Sprintf(`cat << HEREDOC > %[1]s && chmod +x %[1]s && ( nohup %[1]s &> /dev/null &)
 #!/bin/bash
@@ -189,7 +259,12 @@ func CleanUpHelperFilesOnAllHosts(c *cluster.Cluster, fpInfo filepath.FilePathIn
 		errorFile := fmt.Sprintf("%s_error", fpInfo.GetSegmentPipeFilePath(contentID))
 		oidFile := fpInfo.GetSegmentHelperFilePath(contentID, "oid")
 		scriptFile := fpInfo.GetSegmentHelperFilePath(contentID, "script")
-		return fmt.Sprintf("rm -f %s && rm -f %s && rm -f %s", errorFile, oidFile, scriptFile)
+		checksumFile := fpInfo.GetSegmentHelperFilePath(contentID, "checksums")
+		checksumErrorFiles := fmt.Sprintf("%s_*_checksum_error", fpInfo.GetSegmentPipeFilePath(contentID))
+		keyFile := fpInfo.GetSegmentHelperFilePath(contentID, "key")
+		streamFile := fpInfo.GetSegmentHelperFilePath(contentID, "stream")
+		progressFile := fpInfo.GetSegmentHelperFilePath(contentID, "progress")
+		return fmt.Sprintf("rm -f %s && rm -f %s && rm -f %s && rm -f %s && rm -f %s && rm -f %s && rm -f %s && rm -f %s", errorFile, oidFile, scriptFile, checksumFile, checksumErrorFiles, keyFile, streamFile, progressFile)
 	})
 	errMsg := fmt.Sprintf("Unable to remove segment helper file(s). See %s for a complete list of segments with errors and remove manually.",
 		gplog.GetLogFilePath())
@@ -252,3 +327,53 @@ func CreateSkipFileOnSegments(oid string, tableName string, c *cluster.Cluster,
 		return fmt.Sprintf("Could not create skip file %s_skip_%s on segments", fpInfo.GetSegmentPipeFilePath(contentID), oid)
 	})
 }
+
+// The progress of the table data of a table on a segment
+type TableProgress struct {
+	Bytes int64
+	Done  bool
+}
+
+/*
+ * Gathers the latest progress that gpbackup_helper has recorded for each table
+ * on each segment, keyed by content and oid.  The progress files of all of the
+ * segments of a host are read with one command, so that gathering progress
+ * does not take a connection to every segment, and a host that cannot be
+ * reached is skipped, as its progress is gathered again soon after.
+ */
+func GetDataProgressOnSegments(c *cluster.Cluster, fpInfo filepath.FilePathInfo) map[int]map[uint32]TableProgress {
+	commandList := c.GenerateSSHCommandList(cluster.ON_HOSTS, func(host string) string {
+		commands := make([]string, 0)
+		for _, contentID := range c.GetContentsForHost(host) {
+			if contentID == -1 {
+				continue
+			}
+			progressFile := fpInfo.GetSegmentHelperFilePath(contentID, "progress")
+			commands = append(commands, fmt.Sprintf(`if [[ -f %[2]s ]]; then awk '{bytes[$1] = $2; done[$1] = $3} END {for (oid in bytes) print %[1]d, oid, bytes[oid], done[oid]}' %[2]s; fi`, contentID, progressFile))
+		}
+		return strings.Join(commands, "; ")
+	})
+	remoteOutput := c.ExecuteClusterCommand(cluster.ON_HOSTS, commandList)
+
+	segmentProgress := make(map[int]map[uint32]TableProgress)
+	for _, cmd := range remoteOutput.Commands {
+		if cmd.Error != nil {
+			gplog.Debug("Unable to gather data progress on host %s: %v", cmd.Host, cmd.Error)
+			continue
+		}
+		for _, line := range strings.Split(strings.TrimSpace(cmd.Stdout), "\n") {
+			var contentID int
+			var oid uint32
+			var numBytes int64
+			_, err := fmt.Sscanf(line, "%d %d %d", &contentID, &oid, &numBytes)
+			if err != nil {
+				continue
+			}
+			if segmentProgress[contentID] == nil {
+				segmentProgress[contentID] = make(map[uint32]TableProgress)
+			}
+			segmentProgress[contentID][oid] = TableProgress{Bytes: numBytes, Done: strings.HasSuffix(line, " done")}
+		}
+	}
+	return segmentProgress
+}
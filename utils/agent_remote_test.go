@@ -126,18 +126,53 @@ var _ = Describe("agent remote", func() {
 	Describe("StartGpbackupHelpers()", func() {
 		It("Correctly propagates --on-error-continue flag to gpbackup_helper", func() {
 			wasTerminated := false
-			utils.StartGpbackupHelpers(testCluster, fpInfo, "operation", "/tmp/pluginConfigFile.yml", " compressStr", true, false, &wasTerminated, 1, true, false, 0, 0)
+			utils.StartGpbackupHelpers(testCluster, fpInfo, "operation", "/tmp/pluginConfigFile.yml", " compressStr", true, false, &wasTerminated, 1, true, false, 0, 0, 0, 1)
 
 			cc := testExecutor.ClusterCommands[0]
 			Expect(cc[1].CommandString).To(ContainSubstring(" --on-error-continue"))
 		})
 		It("Correctly propagates --copy-queue-size value to gpbackup_helper", func() {
 			wasTerminated := false
-			utils.StartGpbackupHelpers(testCluster, fpInfo, "operation", "/tmp/pluginConfigFile.yml", " compressStr", false, false, &wasTerminated, 4, true, false, 0, 0)
+			utils.StartGpbackupHelpers(testCluster, fpInfo, "operation", "/tmp/pluginConfigFile.yml", " compressStr", false, false, &wasTerminated, 4, true, false, 0, 0, 0, 1)
 
 			cc := testExecutor.ClusterCommands[0]
 			Expect(cc[1].CommandString).To(ContainSubstring(" --copy-queue-size 4"))
 		})
+		It("Correctly propagates --max-bandwidth value to gpbackup_helper", func() {
+			wasTerminated := false
+			utils.StartGpbackupHelpers(testCluster, fpInfo, "operation", "/tmp/pluginConfigFile.yml", " compressStr", false, false, &wasTerminated, 1, true, false, 0, 0, 1048576, 1)
+
+			cc := testExecutor.ClusterCommands[0]
+			Expect(cc[1].CommandString).To(ContainSubstring(" --max-bandwidth 1048576"))
+		})
+		It("Correctly propagates --data-streams value and stream file to gpbackup_helper", func() {
+			wasTerminated := false
+			utils.StartGpbackupHelpers(testCluster, fpInfo, "operation", "/tmp/pluginConfigFile.yml", " compressStr", false, false, &wasTerminated, 4, true, false, 0, 0, 0, 4)
+
+			cc := testExecutor.ClusterCommands[0]
+			Expect(cc[1].CommandString).To(ContainSubstring(fmt.Sprintf(" --data-streams 4 --stream-file /data/gpseg1/gpbackup_1_11112233445566_stream_%d", fpInfo.PID)))
+		})
+		It("Does not pass --data-streams to gpbackup_helper for a single data stream", func() {
+			wasTerminated := false
+			utils.StartGpbackupHelpers(testCluster, fpInfo, "operation", "/tmp/pluginConfigFile.yml", " compressStr", false, false, &wasTerminated, 1, true, false, 0, 0, 0, 1)
+
+			cc := testExecutor.ClusterCommands[0]
+			Expect(cc[1].CommandString).ToNot(ContainSubstring("--data-streams"))
+		})
+		It("Passes a progress file to a backup agent", func() {
+			wasTerminated := false
+			utils.StartGpbackupHelpers(testCluster, fpInfo, "--backup-agent", "/tmp/pluginConfigFile.yml", " compressStr", false, false, &wasTerminated, 1, true, false, 0, 0, 0, 1)
+
+			cc := testExecutor.ClusterCommands[0]
+			Expect(cc[1].CommandString).To(ContainSubstring(fmt.Sprintf(" --progress-file /data/gpseg1/gpbackup_1_11112233445566_progress_%d", fpInfo.PID)))
+		})
+		It("Does not pass a progress file to a restore agent", func() {
+			wasTerminated := false
+			utils.StartGpbackupHelpers(testCluster, fpInfo, "--restore-agent", "/tmp/pluginConfigFile.yml", " compressStr", false, false, &wasTerminated, 1, true, false, 0, 0, 0, 1)
+
+			cc := testExecutor.ClusterCommands[0]
+			Expect(cc[1].CommandString).ToNot(ContainSubstring("--progress-file"))
+		})
 	})
 	Describe("CheckAgentErrorsOnSegments", func() {
 		It("constructs the correct ssh call to check for the existance of an error file on each segment", func() {
@@ -155,6 +190,27 @@ var _ = Describe("agent remote", func() {
 		})
 
 	})
+	Describe("GetDataProgressOnSegments", func() {
+		It("reads the progress files of all of the segments of each host with one command", func() {
+			_ = utils.GetDataProgressOnSegments(testCluster, fpInfo)
+
+			cc := testExecutor.ClusterCommands[0]
+			Expect(cc).To(HaveLen(2))
+			Expect(cc[0].CommandString).To(ContainSubstring(fmt.Sprintf(`if [[ -f /data/gpseg0/gpbackup_0_11112233445566_progress_%[1]d ]]; then awk '{bytes[$1] = $2; done[$1] = $3} END {for (oid in bytes) print 0, oid, bytes[oid], done[oid]}' /data/gpseg0/gpbackup_0_11112233445566_progress_%[1]d; fi`, fpInfo.PID)))
+			Expect(cc[0].CommandString).ToNot(ContainSubstring("gpseg-1"))
+			Expect(cc[1].CommandString).To(ContainSubstring(fmt.Sprintf("/data/gpseg1/gpbackup_1_11112233445566_progress_%d", fpInfo.PID)))
+		})
+		It("returns the progress of each table on each segment, skipping hosts with errors", func() {
+			testExecutor.ClusterOutput = &cluster.RemoteOutput{
+				Commands: []cluster.ShellCommand{
+					{Host: "localhost", Stdout: "0 16384 1024 \n0 16385 0 done\n"},
+					{Host: "remotehost1", Stdout: "1 16384 2048 \n", Error: errors.New("exit status 255")},
+				},
+			}
+			segmentProgress := utils.GetDataProgressOnSegments(testCluster, fpInfo)
+			Expect(segmentProgress).To(Equal(map[int]map[uint32]utils.TableProgress{0: {16384: {Bytes: 1024}, 16385: {Bytes: 0, Done: true}}}))
+		})
+	})
 })
 
 type testWriter struct {
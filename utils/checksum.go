@@ -0,0 +1,65 @@
+package utils
+
+/*
+ * This file contains functions related to the checksums used to verify that
+ * table data was not corrupted between backup and restore.
+ */
+
+import (
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+)
+
+var checksumTable = crc32.MakeTable(crc32.Castagnoli)
+
+/*
+ * Checksums are computed over the uncompressed data of a table on a single
+ * segment, so the same value is produced regardless of the compression type.
+ */
+func NewChecksum() hash.Hash32 {
+	return crc32.New(checksumTable)
+}
+
+func FormatChecksum(checksum hash.Hash32) string {
+	return fmt.Sprintf("%08x", checksum.Sum32())
+}
+
+/*
+ * A checksum file holds one "<oid> <checksum>" line per table.  The backup
+ * side only ever appends to it, so if a table appears more than once the last
+ * line is the one that applies.
+ */
+func WriteChecksumEntry(writer io.Writer, oid uint32, checksum string) error {
+	_, err := fmt.Fprintf(writer, "%d %s\n", oid, checksum)
+	return err
+}
+
+func ParseChecksumEntries(contents string) map[uint32]string {
+	checksums := make(map[uint32]string)
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		oid, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			continue
+		}
+		checksums[uint32(oid)] = fields[1]
+	}
+	return checksums
+}
+
+func ReadChecksumFile(filename string) (map[uint32]string, error) {
+	contents, err := operating.System.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return ParseChecksumEntries(string(contents)), nil
+}
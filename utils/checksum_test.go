@@ -0,0 +1,45 @@
+package utils_test
+
+import (
+	"bytes"
+
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/checksum tests", func() {
+	Describe("FormatChecksum", func() {
+		It("formats the CRC-32C checksum of the data as hex", func() {
+			checksum := utils.NewChecksum()
+			_, _ = checksum.Write([]byte("123456789"))
+			Expect(utils.FormatChecksum(checksum)).To(Equal("e3069283"))
+		})
+		It("formats the checksum of no data", func() {
+			Expect(utils.FormatChecksum(utils.NewChecksum())).To(Equal("00000000"))
+		})
+	})
+	Describe("WriteChecksumEntry", func() {
+		It("writes one line per table", func() {
+			buffer := &bytes.Buffer{}
+			Expect(utils.WriteChecksumEntry(buffer, 1234, "e3069283")).To(Succeed())
+			Expect(utils.WriteChecksumEntry(buffer, 5678, "00000000")).To(Succeed())
+			Expect(buffer.String()).To(Equal("1234 e3069283\n5678 00000000\n"))
+		})
+	})
+	Describe("ParseChecksumEntries", func() {
+		It("parses the checksum of each table", func() {
+			checksums := utils.ParseChecksumEntries("1234 e3069283\n5678 00000000\n")
+			Expect(checksums).To(Equal(map[uint32]string{1234: "e3069283", 5678: "00000000"}))
+		})
+		It("uses the last checksum recorded for a table", func() {
+			checksums := utils.ParseChecksumEntries("1234 e3069283\n1234 0a0a0a0a\n")
+			Expect(checksums).To(Equal(map[uint32]string{1234: "0a0a0a0a"}))
+		})
+		It("ignores malformed lines", func() {
+			checksums := utils.ParseChecksumEntries("1234 e3069283\nfoo 0a0a0a0a\n5678\n")
+			Expect(checksums).To(Equal(map[uint32]string{1234: "e3069283"}))
+		})
+	})
+})
@@ -0,0 +1,128 @@
+package utils
+
+/*
+ * This file contains functions related to splitting table data into chunks
+ * for backups taken with --repository, which store each distinct chunk only
+ * once.
+ */
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	ChunkMinSize = 512 * 1024
+	ChunkMaxSize = 8 * 1024 * 1024
+	// Chunks end where the top 20 bits of the hash are zero, about every MB after the minimum size
+	chunkBoundaryMask = uint64(0xFFFFF) << 44
+
+	ChunkListExtension = ".chunks"
+)
+
+var chunkGearTable = makeChunkGearTable()
+
+/*
+ * The gear table maps each byte to a pseudo-random value.  It is generated
+ * with splitmix64 from a fixed seed, so that the same data is always split at
+ * the same places by every version of gpbackup_helper.
+ */
+func makeChunkGearTable() [256]uint64 {
+	var table [256]uint64
+	state := uint64(0x9E3779B97F4A7C15)
+	for i := range table {
+		state += 0x9E3779B97F4A7C15
+		value := state
+		value = (value ^ (value >> 30)) * 0xBF58476D1CE4E5B9
+		value = (value ^ (value >> 27)) * 0x94D049BB133111EB
+		table[i] = value ^ (value >> 31)
+	}
+	return table
+}
+
+/*
+ * A Chunker splits data into content-defined chunks using a gear rolling hash,
+ * so that a change to part of the data only changes the chunks around it and
+ * the chunks of the data before and after it stay the same.
+ */
+type Chunker struct {
+	reader *bufio.Reader
+	buffer []byte
+}
+
+func NewChunker(reader io.Reader) *Chunker {
+	return &Chunker{reader: bufio.NewReader(reader), buffer: make([]byte, 0, ChunkMaxSize)}
+}
+
+// Returns the next chunk, which is only valid until the next call, or io.EOF once all of the data is chunked
+func (c *Chunker) Next() ([]byte, error) {
+	c.buffer = c.buffer[:0]
+	hash := uint64(0)
+	for {
+		b, err := c.reader.ReadByte()
+		if err == io.EOF && len(c.buffer) > 0 {
+			return c.buffer, nil
+		} else if err != nil {
+			return nil, err
+		}
+		c.buffer = append(c.buffer, b)
+		hash = (hash << 1) + chunkGearTable[b]
+		if (len(c.buffer) >= ChunkMinSize && hash&chunkBoundaryMask == 0) || len(c.buffer) >= ChunkMaxSize {
+			return c.buffer, nil
+		}
+	}
+}
+
+/*
+ * A chunk is named for the SHA-256 hash of its uncompressed data, followed by
+ * the extension of its compression type, as chunks compressed differently
+ * cannot be shared.
+ */
+func GetChunkName(chunk []byte, extension string) string {
+	sum := sha256.Sum256(chunk)
+	return hex.EncodeToString(sum[:]) + extension
+}
+
+/*
+ * A chunk list holds the name of each chunk of the data of a table on a
+ * segment, one per line, in order.
+ */
+func WriteChunkListEntry(writer io.Writer, chunkName string) error {
+	_, err := fmt.Fprintf(writer, "%s\n", chunkName)
+	return err
+}
+
+func ReadChunkList(reader io.Reader) ([]string, error) {
+	chunkNames := make([]string, 0)
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		chunkName := strings.TrimSpace(scanner.Text())
+		if chunkName == "" {
+			continue
+		}
+		if !IsValidChunkName(chunkName) {
+			return nil, fmt.Errorf("Invalid chunk name %q in chunk list", chunkName)
+		}
+		chunkNames = append(chunkNames, chunkName)
+	}
+	return chunkNames, scanner.Err()
+}
+
+// Chunk names are used in file paths, so anything but a hash and an extension is rejected
+func IsValidChunkName(chunkName string) bool {
+	hash := strings.SplitN(chunkName, ".", 2)[0]
+	if len(hash) != sha256.Size*2 || strings.ContainsAny(chunkName, "/ ") {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+// Chunks are spread over subdirectories named for the first two characters of their hash
+func GetChunkPath(chunkDir string, chunkName string) string {
+	return fmt.Sprintf("%s/%s/%s", chunkDir, chunkName[0:2], chunkName)
+}
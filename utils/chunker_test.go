@@ -0,0 +1,86 @@
+package utils_test
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"strings"
+
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func chunkData(data []byte) [][]byte {
+	chunks := make([][]byte, 0)
+	chunker := utils.NewChunker(bytes.NewReader(data))
+	for {
+		chunk, err := chunker.Next()
+		if err == io.EOF {
+			return chunks
+		}
+		Expect(err).ToNot(HaveOccurred())
+		chunks = append(chunks, append([]byte{}, chunk...))
+	}
+}
+
+var _ = Describe("utils/chunker tests", func() {
+	Describe("Chunker", func() {
+		data := make([]byte, 20*1024*1024)
+		rand.New(rand.NewSource(1)).Read(data)
+
+		It("splits data into chunks between the minimum and maximum sizes", func() {
+			chunks := chunkData(data)
+			Expect(len(chunks)).To(BeNumerically(">", 2))
+			Expect(bytes.Join(chunks, nil)).To(Equal(data))
+			for _, chunk := range chunks[:len(chunks)-1] {
+				Expect(len(chunk)).To(BeNumerically(">=", utils.ChunkMinSize))
+				Expect(len(chunk)).To(BeNumerically("<=", utils.ChunkMaxSize))
+			}
+		})
+		It("splits data that never matches a boundary at the maximum size", func() {
+			chunks := chunkData(make([]byte, utils.ChunkMaxSize+10))
+			Expect(chunks).To(HaveLen(2))
+			Expect(chunks[0]).To(HaveLen(utils.ChunkMaxSize))
+			Expect(chunks[1]).To(HaveLen(10))
+		})
+		It("returns a single chunk for data smaller than the minimum size", func() {
+			Expect(chunkData([]byte("1,a\n2,b\n"))).To(Equal([][]byte{[]byte("1,a\n2,b\n")}))
+		})
+		It("returns no chunks for no data", func() {
+			Expect(chunkData([]byte{})).To(BeEmpty())
+		})
+		It("keeps the chunks after a change to the data the same", func() {
+			chunks := chunkData(data)
+			changedData := append(append(append([]byte{}, data[:1000]...), []byte("inserted row\n")...), data[1000:]...)
+			changedChunks := chunkData(changedData)
+			Expect(changedChunks[0]).ToNot(Equal(chunks[0]))
+			Expect(changedChunks[1:]).To(Equal(chunks[1:]))
+		})
+	})
+	Describe("GetChunkName", func() {
+		It("names a chunk for the hash of its data and its compression", func() {
+			Expect(utils.GetChunkName([]byte("abc"), ".gz")).To(Equal("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad.gz"))
+			Expect(utils.GetChunkName([]byte("abc"), "")).To(Equal("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"))
+		})
+	})
+	Describe("ReadChunkList", func() {
+		chunkName := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad.gz"
+		It("reads the chunk names written to a chunk list", func() {
+			buffer := &bytes.Buffer{}
+			Expect(utils.WriteChunkListEntry(buffer, chunkName)).To(Succeed())
+			Expect(utils.WriteChunkListEntry(buffer, chunkName)).To(Succeed())
+			Expect(utils.ReadChunkList(buffer)).To(Equal([]string{chunkName, chunkName}))
+		})
+		It("rejects a chunk name that is not a hash", func() {
+			_, err := utils.ReadChunkList(strings.NewReader("../../etc/passwd\n"))
+			Expect(err).To(MatchError(`Invalid chunk name "../../etc/passwd" in chunk list`))
+		})
+	})
+	Describe("GetChunkPath", func() {
+		It("stores a chunk in the subdirectory for the start of its hash", func() {
+			Expect(utils.GetChunkPath("/backups/gpseg0/chunks", "ba7816bf.gz")).To(Equal("/backups/gpseg0/chunks/ba/ba7816bf.gz"))
+		})
+	})
+})
@@ -1,21 +1,28 @@
 package utils
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+)
 
 var (
 	pipeThroughProgram PipeThroughProgram
 )
 
+/*
+ * Table data is compressed and decompressed by gpbackup_helper, so this only
+ * describes how the helper should do so and the extension of the data files.
+ */
 type PipeThroughProgram struct {
-	Name          string
-	OutputCommand string
-	InputCommand  string
-	Extension     string
+	Name      string
+	Level     int
+	Extension string
 }
 
 func InitializePipeThroughParameters(compress bool, compressionType string, compressionLevel int) {
 	if !compress {
-		pipeThroughProgram = PipeThroughProgram{Name: "cat", OutputCommand: "cat -", InputCommand: "cat -", Extension: ""}
+		pipeThroughProgram = PipeThroughProgram{Name: "cat", Level: 0, Extension: ""}
 		return
 	}
 
@@ -25,12 +32,22 @@ func InitializePipeThroughParameters(compress bool, compressionType string, comp
 	}
 
 	if compressionType == "gzip" {
-		pipeThroughProgram = PipeThroughProgram{Name: "gzip", OutputCommand: fmt.Sprintf("gzip -c -%d", compressionLevel), InputCommand: "gzip -d -c", Extension: ".gz"}
+		pipeThroughProgram = PipeThroughProgram{Name: "gzip", Level: compressionLevel, Extension: ".gz"}
 		return
 	}
 
 	if compressionType == "zstd" {
-		pipeThroughProgram = PipeThroughProgram{Name: "zstd", OutputCommand: fmt.Sprintf("zstd --compress -%d -c", compressionLevel), InputCommand: "zstd --decompress -c", Extension: ".zst"}
+		pipeThroughProgram = PipeThroughProgram{Name: "zstd", Level: compressionLevel, Extension: ".zst"}
+		return
+	}
+
+	if compressionType == "lz4" {
+		pipeThroughProgram = PipeThroughProgram{Name: "lz4", Level: compressionLevel, Extension: ".lz4"}
+		return
+	}
+
+	if compressionType == "snappy" {
+		pipeThroughProgram = PipeThroughProgram{Name: "snappy", Level: compressionLevel, Extension: ".sz"}
 		return
 	}
 }
@@ -42,3 +59,41 @@ func GetPipeThroughProgram() PipeThroughProgram {
 func SetPipeThroughProgram(compression PipeThroughProgram) {
 	pipeThroughProgram = compression
 }
+
+/*
+ * The multiple-data-file COPY commands pipe table data through gpbackup_helper,
+ * which compresses or decompresses it, encrypts or decrypts it, computes its
+ * checksum and limits its bandwidth in a single pass over the data.
+ */
+func GetBackupFilterCommand(checksumFile string, progressFile string, encryptionKeyFile string, chunkDir string, oid uint32, maxBandwidth int64) string {
+	progressStr := ""
+	if progressFile != "" {
+		progressStr = fmt.Sprintf(" --progress-file %s", progressFile)
+	}
+	progressStr += getChunkDirArg(chunkDir)
+	compressStr := " --compression-level 0"
+	if pipeThroughProgram.Name != "cat" {
+		compressStr = fmt.Sprintf(" --compression-type %s --compression-level %d", pipeThroughProgram.Name, pipeThroughProgram.Level)
+	}
+	return fmt.Sprintf("%s/bin/gpbackup_helper --backup-filter --checksum-file %s%s --oid %d --content <SEGID>%s%s%s",
+		operating.System.Getenv("GPHOME"), checksumFile, progressStr, oid, compressStr, getEncryptionKeyFileArg(encryptionKeyFile), getMaxBandwidthArg(maxBandwidth))
+}
+
+// The checksum file is empty if the backup has no checksums to verify
+func GetRestoreFilterCommand(checksumFile string, encryptionKeyFile string, chunkDir string, oid uint32, maxBandwidth int64) string {
+	checksumStr := ""
+	if checksumFile != "" {
+		checksumStr = fmt.Sprintf(" --checksum-file %s", checksumFile)
+	}
+	checksumStr += getChunkDirArg(chunkDir)
+	return fmt.Sprintf("%s/bin/gpbackup_helper --restore-filter%s --oid %d --content <SEGID> --compression-type %s%s%s",
+		operating.System.Getenv("GPHOME"), checksumStr, oid, pipeThroughProgram.Name, getEncryptionKeyFileArg(encryptionKeyFile), getMaxBandwidthArg(maxBandwidth))
+}
+
+// Table data backed up with --repository is stored in, and restored from, the chunk directory
+func getChunkDirArg(chunkDir string) string {
+	if chunkDir == "" {
+		return ""
+	}
+	return fmt.Sprintf(" --chunk-dir %s", chunkDir)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/greenplum-db/gpbackup/utils"
 
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
 )
 
 var _ = Describe("utils/compression tests", func() {
@@ -34,10 +35,9 @@ var _ = Describe("utils/compression tests", func() {
 			originalProgram := utils.GetPipeThroughProgram()
 			defer utils.SetPipeThroughProgram(originalProgram)
 			expectedProgram := utils.PipeThroughProgram{
-				Name:          "cat",
-				OutputCommand: "cat -",
-				InputCommand:  "cat -",
-				Extension:     "",
+				Name:      "cat",
+				Level:     0,
+				Extension: "",
 			}
 			utils.InitializePipeThroughParameters(false, "", 3)
 			resultProgram := utils.GetPipeThroughProgram()
@@ -47,10 +47,9 @@ var _ = Describe("utils/compression tests", func() {
 			originalProgram := utils.GetPipeThroughProgram()
 			defer utils.SetPipeThroughProgram(originalProgram)
 			expectedProgram := utils.PipeThroughProgram{
-				Name:          "gzip",
-				OutputCommand: "gzip -c -7",
-				InputCommand:  "gzip -d -c",
-				Extension:     ".gz",
+				Name:      "gzip",
+				Level:     7,
+				Extension: ".gz",
 			}
 			utils.InitializePipeThroughParameters(true, "gzip", 7)
 			resultProgram := utils.GetPipeThroughProgram()
@@ -60,14 +59,76 @@ var _ = Describe("utils/compression tests", func() {
 			originalProgram := utils.GetPipeThroughProgram()
 			defer utils.SetPipeThroughProgram(originalProgram)
 			expectedProgram := utils.PipeThroughProgram{
-				Name:          "zstd",
-				OutputCommand: "zstd --compress -7 -c",
-				InputCommand:  "zstd --decompress -c",
-				Extension:     ".zst",
+				Name:      "zstd",
+				Level:     7,
+				Extension: ".zst",
 			}
 			utils.InitializePipeThroughParameters(true, "zstd", 7)
 			resultProgram := utils.GetPipeThroughProgram()
 			structmatcher.ExpectStructsToMatch(&expectedProgram, &resultProgram)
 		})
+		It("initializes to use lz4 when passed compression type lz4 and a level", func() {
+			originalProgram := utils.GetPipeThroughProgram()
+			defer utils.SetPipeThroughProgram(originalProgram)
+			expectedProgram := utils.PipeThroughProgram{
+				Name:      "lz4",
+				Level:     2,
+				Extension: ".lz4",
+			}
+			utils.InitializePipeThroughParameters(true, "lz4", 2)
+			resultProgram := utils.GetPipeThroughProgram()
+			structmatcher.ExpectStructsToMatch(&expectedProgram, &resultProgram)
+		})
+		It("initializes to use snappy when passed compression type snappy", func() {
+			originalProgram := utils.GetPipeThroughProgram()
+			defer utils.SetPipeThroughProgram(originalProgram)
+			expectedProgram := utils.PipeThroughProgram{
+				Name:      "snappy",
+				Level:     1,
+				Extension: ".sz",
+			}
+			utils.InitializePipeThroughParameters(true, "snappy", 1)
+			resultProgram := utils.GetPipeThroughProgram()
+			structmatcher.ExpectStructsToMatch(&expectedProgram, &resultProgram)
+		})
+	})
+	Describe("GetBackupFilterCommand", func() {
+		AfterEach(func() {
+			utils.InitializePipeThroughParameters(false, "", 0)
+		})
+		It("compresses table data with the helper", func() {
+			utils.InitializePipeThroughParameters(true, "zstd", 3)
+			Expect(utils.GetBackupFilterCommand("/data/checksums", "", "", "", 1234, 0)).To(HaveSuffix("/bin/gpbackup_helper --backup-filter --checksum-file /data/checksums --oid 1234 --content <SEGID> --compression-type zstd --compression-level 3"))
+		})
+		It("does not compress table data for an uncompressed backup", func() {
+			Expect(utils.GetBackupFilterCommand("/data/checksums", "", "", "", 1234, 1024)).To(HaveSuffix("/bin/gpbackup_helper --backup-filter --checksum-file /data/checksums --oid 1234 --content <SEGID> --compression-level 0 --max-bandwidth 1024"))
+		})
+		It("encrypts table data with the helper", func() {
+			Expect(utils.GetBackupFilterCommand("/data/checksums", "", "/data/key", "", 1234, 0)).To(HaveSuffix("/bin/gpbackup_helper --backup-filter --checksum-file /data/checksums --oid 1234 --content <SEGID> --compression-level 0 --encryption-key-file /data/key"))
+		})
+		It("records the progress of the table data with the helper", func() {
+			Expect(utils.GetBackupFilterCommand("/data/checksums", "/data/progress", "", "", 1234, 0)).To(HaveSuffix("/bin/gpbackup_helper --backup-filter --checksum-file /data/checksums --progress-file /data/progress --oid 1234 --content <SEGID> --compression-level 0"))
+		})
+		It("stores table data in the chunk directory of a repository with the helper", func() {
+			Expect(utils.GetBackupFilterCommand("/data/checksums", "", "", "/backups/gpseg<SEGID>/chunks", 1234, 0)).To(HaveSuffix("/bin/gpbackup_helper --backup-filter --checksum-file /data/checksums --chunk-dir /backups/gpseg<SEGID>/chunks --oid 1234 --content <SEGID> --compression-level 0"))
+		})
+	})
+	Describe("GetRestoreFilterCommand", func() {
+		AfterEach(func() {
+			utils.InitializePipeThroughParameters(false, "", 0)
+		})
+		It("decompresses table data and verifies its checksum with the helper", func() {
+			utils.InitializePipeThroughParameters(true, "gzip", 0)
+			Expect(utils.GetRestoreFilterCommand("/data/checksums", "", "", 1234, 0)).To(HaveSuffix("/bin/gpbackup_helper --restore-filter --checksum-file /data/checksums --oid 1234 --content <SEGID> --compression-type gzip"))
+		})
+		It("does not verify checksums if the backup has none", func() {
+			Expect(utils.GetRestoreFilterCommand("", "", "", 1234, 1024)).To(HaveSuffix("/bin/gpbackup_helper --restore-filter --oid 1234 --content <SEGID> --compression-type cat --max-bandwidth 1024"))
+		})
+		It("decrypts table data with the helper", func() {
+			Expect(utils.GetRestoreFilterCommand("", "/data/key", "", 1234, 0)).To(HaveSuffix("/bin/gpbackup_helper --restore-filter --oid 1234 --content <SEGID> --compression-type cat --encryption-key-file /data/key"))
+		})
+		It("restores table data from the chunk directory of a repository with the helper", func() {
+			Expect(utils.GetRestoreFilterCommand("/data/checksums", "", "/backups/gpseg<SEGID>/chunks", 1234, 0)).To(HaveSuffix("/bin/gpbackup_helper --restore-filter --checksum-file /data/checksums --chunk-dir /backups/gpseg<SEGID>/chunks --oid 1234 --content <SEGID> --compression-type cat"))
+		})
 	})
 })
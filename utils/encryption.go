@@ -0,0 +1,379 @@
+package utils
+
+/*
+ * This file contains structs and functions related to encrypting backup files
+ * with --encryption-key-file.
+ *
+ * Encrypted files start with a header of a magic string and a random salt, from
+ * which a key for the file is derived, followed by chunks of AES-256-GCM
+ * encrypted data.  Each chunk starts with a 4-byte length, the top bit of which
+ * marks the final chunk, so a file that has been truncated or extended fails to
+ * decrypt just as one that has been modified does.
+ */
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+	"github.com/greenplum-db/gp-common-go-libs/iohelper"
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/filepath"
+	"github.com/pkg/errors"
+)
+
+const (
+	encryptionMagic     = "GPBKENC1"
+	encryptionSaltSize  = 32
+	encryptionKeySize   = 32
+	encryptionChunkSize = 64 * 1024
+	finalChunkFlag      = uint32(1) << 31
+)
+
+var (
+	encryptionKey     []byte
+	encryptionKeyFile string
+)
+
+/*
+ * The key file contains either the 32 bytes of the key or those bytes encoded
+ * as 64 hexadecimal digits, such as the output of "openssl rand -hex 32".
+ */
+func ReadEncryptionKeyFile(filename string) ([]byte, error) {
+	contents, err := operating.System.ReadFile(filename)
+	if err != nil {
+		return nil, errors.Errorf("Unable to read encryption key file %s: %v", filename, err)
+	}
+	if len(contents) == encryptionKeySize {
+		return contents, nil
+	}
+	key, err := hex.DecodeString(strings.TrimSpace(string(contents)))
+	if err != nil || len(key) != encryptionKeySize {
+		return nil, errors.Errorf("Encryption key file %s must contain a %d-byte key, either as raw bytes or as %d hexadecimal digits", filename, encryptionKeySize, 2*encryptionKeySize)
+	}
+	return key, nil
+}
+
+func InitializeEncryption(keyFile string) error {
+	if keyFile == "" {
+		encryptionKey, encryptionKeyFile = nil, ""
+		return nil
+	}
+	key, err := ReadEncryptionKeyFile(keyFile)
+	if err != nil {
+		return err
+	}
+	encryptionKey, encryptionKeyFile = key, keyFile
+	return nil
+}
+
+// Returns nil if backup files are not encrypted
+func GetEncryptionKey() []byte {
+	return encryptionKey
+}
+
+func SetEncryptionKey(key []byte) {
+	encryptionKey = key
+}
+
+/*
+ * The fingerprint identifies a key without revealing it, so it can be stored
+ * with a backup to check that the right key is used to restore it.
+ */
+func GetEncryptionKeyFingerprint(key []byte) string {
+	if key == nil {
+		return ""
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("gpbackup encryption key fingerprint"))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+func newFileCipher(key []byte, salt []byte) (cipher.AEAD, error) {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(salt)
+	block, err := aes.NewCipher(mac.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func getChunkNonce(aead cipher.AEAD, chunkNum uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], chunkNum)
+	return nonce
+}
+
+/*
+ * An encryptingWriter must be closed to write the final chunk of the file;
+ * closing it also closes the writer it wraps.
+ */
+type encryptingWriter struct {
+	writer   io.WriteCloser
+	aead     cipher.AEAD
+	buffer   []byte
+	chunkNum uint64
+}
+
+func NewEncryptingWriter(writer io.WriteCloser, key []byte) (io.WriteCloser, error) {
+	salt := make([]byte, encryptionSaltSize)
+	_, err := rand.Read(salt)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newFileCipher(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	_, err = writer.Write(append([]byte(encryptionMagic), salt...))
+	if err != nil {
+		return nil, err
+	}
+	return &encryptingWriter{writer: writer, aead: aead, buffer: make([]byte, 0, encryptionChunkSize)}, nil
+}
+
+func (w *encryptingWriter) Write(p []byte) (int, error) {
+	numBytes := 0
+	for len(p) > 0 {
+		if len(w.buffer) == encryptionChunkSize {
+			err := w.writeChunk(false)
+			if err != nil {
+				return numBytes, err
+			}
+		}
+		copied := copy(w.buffer[len(w.buffer):encryptionChunkSize], p)
+		w.buffer = w.buffer[:len(w.buffer)+copied]
+		p = p[copied:]
+		numBytes += copied
+	}
+	return numBytes, nil
+}
+
+func (w *encryptingWriter) writeChunk(final bool) error {
+	header := make([]byte, 4)
+	length := uint32(len(w.buffer))
+	if final {
+		length |= finalChunkFlag
+	}
+	binary.BigEndian.PutUint32(header, length)
+	chunk := w.aead.Seal(header, getChunkNonce(w.aead, w.chunkNum), w.buffer, header)
+	w.chunkNum++
+	w.buffer = w.buffer[:0]
+	_, err := w.writer.Write(chunk)
+	return err
+}
+
+func (w *encryptingWriter) Close() error {
+	err := w.writeChunk(true)
+	closeErr := w.writer.Close()
+	if err != nil {
+		return err
+	}
+	return closeErr
+}
+
+type decryptingReader struct {
+	reader   io.Reader
+	aead     cipher.AEAD
+	buffer   []byte
+	chunkNum uint64
+	done     bool
+}
+
+func NewDecryptingReader(reader io.Reader, key []byte) (io.Reader, error) {
+	header := make([]byte, len(encryptionMagic)+encryptionSaltSize)
+	_, err := io.ReadFull(reader, header)
+	if err != nil || string(header[:len(encryptionMagic)]) != encryptionMagic {
+		return nil, errors.New("Data is not encrypted or its encryption header is corrupt")
+	}
+	aead, err := newFileCipher(key, header[len(encryptionMagic):])
+	if err != nil {
+		return nil, err
+	}
+	return &decryptingReader{reader: reader, aead: aead}, nil
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.buffer) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		err := r.readChunk()
+		if err != nil {
+			return 0, err
+		}
+	}
+	numBytes := copy(p, r.buffer)
+	r.buffer = r.buffer[numBytes:]
+	return numBytes, nil
+}
+
+func (r *decryptingReader) readChunk() error {
+	header := make([]byte, 4)
+	_, err := io.ReadFull(r.reader, header)
+	if err != nil {
+		return getEncryptedReadError(err)
+	}
+	length := binary.BigEndian.Uint32(header)
+	final := length&finalChunkFlag != 0
+	length &^= finalChunkFlag
+	if length > encryptionChunkSize {
+		return errors.New("Encrypted data is corrupt")
+	}
+	chunk := make([]byte, int(length)+r.aead.Overhead())
+	_, err = io.ReadFull(r.reader, chunk)
+	if err != nil {
+		return getEncryptedReadError(err)
+	}
+	r.buffer, err = r.aead.Open(chunk[:0], getChunkNonce(r.aead, r.chunkNum), chunk, header)
+	if err != nil {
+		return errors.New("Unable to decrypt data: the encryption key is wrong or the data is corrupt")
+	}
+	r.chunkNum++
+	if final {
+		r.done = true
+		numBytes, _ := r.reader.Read(make([]byte, 1))
+		if numBytes > 0 {
+			return errors.New("Encrypted data has unexpected data after its end")
+		}
+	}
+	return nil
+}
+
+func getEncryptedReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return errors.New("Encrypted data is truncated")
+	}
+	return err
+}
+
+func IsEncrypted(contents []byte) bool {
+	return bytes.HasPrefix(contents, []byte(encryptionMagic))
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// Encrypts the contents of a backup file if an encryption key is set
+func EncryptContents(contents []byte) ([]byte, error) {
+	if encryptionKey == nil {
+		return contents, nil
+	}
+	var buffer bytes.Buffer
+	writer, err := NewEncryptingWriter(nopWriteCloser{&buffer}, encryptionKey)
+	if err == nil {
+		_, err = writer.Write(contents)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+/*
+ * Backup files written without an encryption key are returned as they are.  A
+ * key is only set when the backup being read was encrypted, so a file without
+ * the encryption header then has been replaced or tampered with.
+ */
+func DecryptContents(contents []byte) ([]byte, error) {
+	if encryptionKey == nil {
+		if IsEncrypted(contents) {
+			return nil, errors.New("Backup file is encrypted, but no encryption key was specified")
+		}
+		return contents, nil
+	}
+	if !IsEncrypted(contents) {
+		return nil, errors.New("Backup file is not encrypted, but the backup was encrypted")
+	}
+	reader, err := NewDecryptingReader(bytes.NewReader(contents), encryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func ReadAndDecryptFile(filename string) ([]byte, error) {
+	contents, err := operating.System.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	contents, err = DecryptContents(contents)
+	if err != nil {
+		return nil, errors.Errorf("%v: %s", err, filename)
+	}
+	return contents, nil
+}
+
+/*
+ * The metadata and statistics files are read at the offsets recorded in the
+ * TOC, which are offsets into the decrypted file, so an encrypted file is
+ * decrypted in memory to be read.
+ */
+func MustOpenFileForReadingAt(filename string) io.ReaderAt {
+	if encryptionKey == nil {
+		return iohelper.MustOpenFileForReading(filename)
+	}
+	contents, err := ReadAndDecryptFile(filename)
+	gplog.FatalOnError(err)
+	return bytes.NewReader(contents)
+}
+
+/*
+ * gpbackup_helper needs the key to encrypt or decrypt table data on the
+ * segments, so the key file is copied next to the other helper files of each
+ * segment, readable only by its owner, and removed with them.
+ */
+func WriteEncryptionKeyToSegments(c *cluster.Cluster, fpInfo filepath.FilePathInfo) {
+	rsync_exists := CommandExists("rsync")
+	if !rsync_exists {
+		gplog.Fatal(errors.New("Failed to find rsync on PATH. Please ensure rsync is installed."), "")
+	}
+
+	generateScpCmd := func(contentID int) string {
+		hostname := c.GetHostForContent(contentID)
+		dest := fpInfo.GetSegmentHelperFilePath(contentID, "key")
+
+		return fmt.Sprintf(`rsync -e ssh --chmod=F600 %s %s:%s`, encryptionKeyFile, hostname, dest)
+	}
+	remoteOutput := c.GenerateAndExecuteCommand("rsync encryption key file to segments", cluster.ON_LOCAL|cluster.ON_SEGMENTS, generateScpCmd)
+
+	errMsg := "Failed to rsync encryption key file"
+	errFunc := func(contentID int) string {
+		return "Failed to run rsync"
+	}
+	c.CheckClusterError(remoteOutput, errMsg, errFunc, false)
+}
+
+// Returns the empty string if backup files are not encrypted
+func GetEncryptionKeyFileForCopyCommand(fpInfo filepath.FilePathInfo) string {
+	if encryptionKey == nil {
+		return ""
+	}
+	return fpInfo.GetSegmentHelperFilePathForCopyCommand("key")
+}
+
+func getEncryptionKeyFileArg(keyFile string) string {
+	if keyFile == "" {
+		return ""
+	}
+	return fmt.Sprintf(" --encryption-key-file %s", keyFile)
+}
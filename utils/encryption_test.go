@@ -0,0 +1,157 @@
+package utils_test
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/greenplum-db/gp-common-go-libs/operating"
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type bufferWriteCloser struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (w *bufferWriteCloser) Close() error {
+	w.closed = true
+	return nil
+}
+
+var _ = Describe("utils/encryption tests", func() {
+	key := bytes.Repeat([]byte{0x42}, 32)
+	otherKey := bytes.Repeat([]byte{0x24}, 32)
+
+	encrypt := func(contents []byte, key []byte) []byte {
+		buffer := &bufferWriteCloser{}
+		writer, err := utils.NewEncryptingWriter(buffer, key)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = writer.Write(contents)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(writer.Close()).To(Succeed())
+		Expect(buffer.closed).To(BeTrue())
+		return buffer.Bytes()
+	}
+	decrypt := func(contents []byte, key []byte) ([]byte, error) {
+		reader, err := utils.NewDecryptingReader(bytes.NewReader(contents), key)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(reader)
+	}
+
+	AfterEach(func() {
+		utils.SetEncryptionKey(nil)
+		operating.System = operating.InitializeSystemFunctions()
+	})
+	Describe("ReadEncryptionKeyFile", func() {
+		It("reads a key of raw bytes", func() {
+			operating.System.ReadFile = func(filename string) ([]byte, error) { return key, nil }
+			Expect(utils.ReadEncryptionKeyFile("/tmp/key")).To(Equal(key))
+		})
+		It("reads a key of hexadecimal digits followed by a newline", func() {
+			operating.System.ReadFile = func(filename string) ([]byte, error) {
+				return []byte(strings.Repeat("42", 32) + "\n"), nil
+			}
+			Expect(utils.ReadEncryptionKeyFile("/tmp/key")).To(Equal(key))
+		})
+		It("returns an error for a key of the wrong length", func() {
+			operating.System.ReadFile = func(filename string) ([]byte, error) { return []byte(strings.Repeat("42", 20)), nil }
+			_, err := utils.ReadEncryptionKeyFile("/tmp/key")
+			Expect(err).To(MatchError("Encryption key file /tmp/key must contain a 32-byte key, either as raw bytes or as 64 hexadecimal digits"))
+		})
+		It("returns an error if the key file cannot be read", func() {
+			operating.System.ReadFile = func(filename string) ([]byte, error) { return nil, os.ErrNotExist }
+			_, err := utils.ReadEncryptionKeyFile("/tmp/key")
+			Expect(err).To(MatchError(ContainSubstring("Unable to read encryption key file /tmp/key")))
+		})
+	})
+	Describe("GetEncryptionKeyFingerprint", func() {
+		It("returns the same fingerprint for the same key", func() {
+			fingerprint := utils.GetEncryptionKeyFingerprint(key)
+			Expect(fingerprint).To(HaveLen(32))
+			Expect(utils.GetEncryptionKeyFingerprint(append([]byte{}, key...))).To(Equal(fingerprint))
+		})
+		It("returns different fingerprints for different keys", func() {
+			Expect(utils.GetEncryptionKeyFingerprint(key)).ToNot(Equal(utils.GetEncryptionKeyFingerprint(otherKey)))
+		})
+		It("returns no fingerprint without a key", func() {
+			Expect(utils.GetEncryptionKeyFingerprint(nil)).To(Equal(""))
+		})
+	})
+	Describe("NewEncryptingWriter and NewDecryptingReader", func() {
+		It("decrypts data spanning several chunks", func() {
+			contents := bytes.Repeat([]byte("0123456789"), 20000)
+			encrypted := encrypt(contents, key)
+			Expect(utils.IsEncrypted(encrypted)).To(BeTrue())
+			Expect(bytes.Contains(encrypted, []byte("0123456789"))).To(BeFalse())
+			Expect(decrypt(encrypted, key)).To(Equal(contents))
+		})
+		It("decrypts empty data", func() {
+			Expect(decrypt(encrypt([]byte{}, key), key)).To(BeEmpty())
+		})
+		It("encrypts the same data differently each time", func() {
+			Expect(encrypt([]byte("data"), key)).ToNot(Equal(encrypt([]byte("data"), key)))
+		})
+		It("returns an error for the wrong key", func() {
+			_, err := decrypt(encrypt([]byte("data"), key), otherKey)
+			Expect(err).To(MatchError("Unable to decrypt data: the encryption key is wrong or the data is corrupt"))
+		})
+		It("returns an error for modified data", func() {
+			encrypted := encrypt([]byte("data"), key)
+			encrypted[len(encrypted)-1] ^= 1
+			_, err := decrypt(encrypted, key)
+			Expect(err).To(MatchError("Unable to decrypt data: the encryption key is wrong or the data is corrupt"))
+		})
+		It("returns an error for truncated data", func() {
+			encrypted := encrypt(bytes.Repeat([]byte("0123456789"), 20000), key)
+			_, err := decrypt(encrypted[:len(encrypted)/2], key)
+			Expect(err).To(MatchError("Encrypted data is truncated"))
+		})
+		It("returns an error for data missing its final chunk", func() {
+			contents := bytes.Repeat([]byte("0"), 64*1024)
+			encrypted := encrypt(contents, key)
+			// The final chunk is empty, so it is only its header and tag
+			_, err := decrypt(encrypted[:len(encrypted)-4-16], key)
+			Expect(err).To(MatchError("Encrypted data is truncated"))
+		})
+		It("returns an error for data with trailing data", func() {
+			_, err := decrypt(append(encrypt([]byte("data"), key), 'x'), key)
+			Expect(err).To(MatchError("Encrypted data has unexpected data after its end"))
+		})
+		It("returns an error for unencrypted data", func() {
+			_, err := decrypt([]byte("plain data that is long enough to fill the header"), key)
+			Expect(err).To(MatchError("Data is not encrypted or its encryption header is corrupt"))
+		})
+	})
+	Describe("EncryptContents and DecryptContents", func() {
+		It("does not encrypt contents without a key", func() {
+			Expect(utils.EncryptContents([]byte("data"))).To(Equal([]byte("data")))
+		})
+		It("returns unencrypted contents as they are without a key", func() {
+			Expect(utils.DecryptContents([]byte("data"))).To(Equal([]byte("data")))
+		})
+		It("returns an error for unencrypted contents with a key", func() {
+			utils.SetEncryptionKey(key)
+			_, err := utils.DecryptContents([]byte("data"))
+			Expect(err).To(MatchError("Backup file is not encrypted, but the backup was encrypted"))
+		})
+		It("decrypts encrypted contents", func() {
+			utils.SetEncryptionKey(key)
+			encrypted, err := utils.EncryptContents([]byte("data"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(utils.IsEncrypted(encrypted)).To(BeTrue())
+			Expect(utils.DecryptContents(encrypted)).To(Equal([]byte("data")))
+		})
+		It("returns an error for encrypted contents without a key", func() {
+			encrypted := encrypt([]byte("data"), key)
+			_, err := utils.DecryptContents(encrypted)
+			Expect(err).To(MatchError("Backup file is encrypted, but no encryption key was specified"))
+		})
+	})
+})
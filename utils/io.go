@@ -43,19 +43,33 @@ type FileWithByteCount struct {
 	Writer    io.Writer
 	File      *os.File
 	ByteCount uint64
+	encrypter io.WriteCloser
 }
 
 func NewFileWithByteCount(writer io.Writer) *FileWithByteCount {
-	return &FileWithByteCount{"", writer, nil, 0}
+	return &FileWithByteCount{"", writer, nil, 0, nil}
 }
 
+/*
+ * The byte count is that of the data written, which is what the offsets in the
+ * TOC refer to, even if the file is encrypted as it is written.
+ */
 func NewFileWithByteCountFromFile(filename string) *FileWithByteCount {
 	file, err := OpenFileForWrite(filename)
 	gplog.FatalOnError(err)
-	return &FileWithByteCount{filename, file, file, 0}
+	if encryptionKey == nil {
+		return &FileWithByteCount{filename, file, file, 0, nil}
+	}
+	encrypter, err := NewEncryptingWriter(nopWriteCloser{file}, encryptionKey)
+	gplog.FatalOnError(err, filename)
+	return &FileWithByteCount{filename, encrypter, file, 0, encrypter}
 }
 
 func (file *FileWithByteCount) Close() {
+	if file.encrypter != nil {
+		err := file.encrypter.Close()
+		gplog.FatalOnError(err, "Unable to write to file")
+	}
 	if file.File != nil {
 		err := file.File.Sync()
 		gplog.FatalOnError(err)
@@ -506,3 +506,12 @@ func (plugin *PluginConfig) CanRestoreSubset() bool {
 		(strings.HasSuffix(plugin.ExecutablePath, "ddboost_plugin") &&
 			plugin.Options["restore_subset"] != "off")
 }
+
+/*
+ * A plugin that can return the data of a file from a byte offset, with the
+ * restore_data_from_offset command, lets a restore resume a restore_data
+ * stream that breaks partway through a file instead of failing.
+ */
+func (plugin *PluginConfig) CanResumeRestoreData() bool {
+	return plugin.Options["restore_resume"] == "on"
+}
@@ -281,6 +281,15 @@ options:
 			Expect(subject.UsesEncryption()).To(BeTrue())
 		})
 	})
+	Describe("CanResumeRestoreData", func() {
+		It("returns false when restore_resume is not in config", func() {
+			Expect(subject.CanResumeRestoreData()).To(BeFalse())
+		})
+		It("returns true when restore_resume is on in config", func() {
+			subject.Options["restore_resume"] = "on"
+			Expect(subject.CanResumeRestoreData()).To(BeTrue())
+		})
+	})
 	Describe("GetSecretKey", func() {
 		It("returns a secret key when one exists for the given name", func() {
 			mdd := testCluster.GetDirForContent(-1)
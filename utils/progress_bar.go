@@ -5,6 +5,7 @@ package utils
  */
 
 import (
+	"fmt"
 	"sync"
 	"time"
 
@@ -95,3 +96,71 @@ func (vpb *VerboseProgressBar) checkPercent() {
 		vpb.nextPercentToPrint += INCR_PERCENT
 	}
 }
+
+/*
+ * A DataProgress tracks the number of bytes of table data processed on each
+ * segment, as gathered from the segments, against an estimate of the total,
+ * to report the throughput and estimate the time left.
+ */
+type DataProgress struct {
+	totalBytes   int64
+	start        time.Time
+	segmentBytes map[int]map[uint32]int64
+	mu           sync.Mutex
+}
+
+func NewDataProgress(totalBytes int64) *DataProgress {
+	return &DataProgress{totalBytes: totalBytes, start: time.Now(), segmentBytes: make(map[int]map[uint32]int64)}
+}
+
+// Updates the counts of the tables in segmentProgress, keeping the counts of any others
+func (progress *DataProgress) Update(segmentProgress map[int]map[uint32]TableProgress) {
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+	for contentID, tables := range segmentProgress {
+		if progress.segmentBytes[contentID] == nil {
+			progress.segmentBytes[contentID] = make(map[uint32]int64)
+		}
+		for oid, tableProgress := range tables {
+			progress.segmentBytes[contentID][oid] = tableProgress.Bytes
+		}
+	}
+}
+
+// Returns the number of bytes processed on each segment, keyed by content
+func (progress *DataProgress) GetSegmentBytes() map[int]int64 {
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+	totals := make(map[int]int64, len(progress.segmentBytes))
+	for contentID, tableBytes := range progress.segmentBytes {
+		for _, numBytes := range tableBytes {
+			totals[contentID] += numBytes
+		}
+	}
+	return totals
+}
+
+func (progress *DataProgress) String() string {
+	numBytes := int64(0)
+	for _, segmentBytes := range progress.GetSegmentBytes() {
+		numBytes += segmentBytes
+	}
+	return FormatDataProgress(numBytes, progress.totalBytes, time.Since(progress.start))
+}
+
+/*
+ * The total is an estimate from the on-disk size of the tables, so the time
+ * left is only shown while fewer bytes than that have been processed.
+ */
+func FormatDataProgress(numBytes int64, totalBytes int64, elapsed time.Duration) string {
+	bytesPerSecond := float64(0)
+	if elapsed > 0 {
+		bytesPerSecond = float64(numBytes) / elapsed.Seconds()
+	}
+	progressStr := fmt.Sprintf("%s, %s/s", FormatByteSize(numBytes), FormatByteSize(int64(bytesPerSecond)))
+	if totalBytes > numBytes && bytesPerSecond > 0 {
+		timeLeft := time.Duration(float64(totalBytes-numBytes) / bytesPerSecond * float64(time.Second))
+		progressStr = fmt.Sprintf("%s of about %s, %s/s, about %s left", FormatByteSize(numBytes), FormatByteSize(totalBytes), FormatByteSize(int64(bytesPerSecond)), timeLeft.Round(time.Second))
+	}
+	return progressStr
+}
@@ -150,4 +150,26 @@ var _ = Describe("utils/log tests", func() {
 			testhelper.NotExpectRegexp(logfile, expectedMessage)
 		})
 	})
+	Describe("DataProgress", func() {
+		It("keeps the latest count of each table on each segment", func() {
+			progress := utils.NewDataProgress(0)
+			progress.Update(map[int]map[uint32]utils.TableProgress{0: {1: {Bytes: 100}, 2: {Bytes: 50, Done: true}}, 1: {1: {Bytes: 10}}})
+			progress.Update(map[int]map[uint32]utils.TableProgress{0: {1: {Bytes: 200}}})
+			Expect(progress.GetSegmentBytes()).To(Equal(map[int]int64{0: 250, 1: 10}))
+		})
+	})
+	Describe("FormatDataProgress", func() {
+		It("shows the throughput and the time left", func() {
+			progressStr := utils.FormatDataProgress(1024*1024*1024, 4*1024*1024*1024, 10*time.Second)
+			Expect(progressStr).To(Equal("1.0 GB of about 4.0 GB, 102.4 MB/s, about 30s left"))
+		})
+		It("does not show the time left once the estimated total is exceeded", func() {
+			progressStr := utils.FormatDataProgress(5*1024*1024, 4*1024*1024, 5*time.Second)
+			Expect(progressStr).To(Equal("5.0 MB, 1.0 MB/s"))
+		})
+		It("does not show the time left before any data is processed", func() {
+			progressStr := utils.FormatDataProgress(0, 4*1024*1024, 0)
+			Expect(progressStr).To(Equal("0 B, 0 B/s"))
+		})
+	})
 })
@@ -0,0 +1,65 @@
+package utils
+
+/*
+ * This file contains functions related to choosing the order in which the
+ * data of tables is copied during a backup or restore.
+ */
+
+import (
+	"sort"
+
+	"github.com/greenplum-db/gp-common-go-libs/gplog"
+)
+
+type CopyTask struct {
+	Schema string
+	Name   string
+	Size   int64
+}
+
+/*
+ * Returns the indices of the given tasks in the order in which they should be
+ * run.  Tables listed in the priority list go first, in the order listed, and
+ * the rest follow from largest to smallest so that with multiple jobs a large
+ * table is not left copying on its own after all the others are done.  Tables
+ * of the same size keep their original order.
+ */
+func ScheduleCopyTasks(tasks []CopyTask, priorityTables []string) []int {
+	priorityRanks := make(map[string]int, len(priorityTables))
+	for rank, fqn := range priorityTables {
+		if _, ok := priorityRanks[fqn]; !ok {
+			priorityRanks[fqn] = rank
+		}
+	}
+
+	taskRanks := make([]int, len(tasks))
+	foundTables := make(map[string]bool, len(priorityTables))
+	for i, task := range tasks {
+		taskRanks[i] = len(priorityTables)
+		for _, fqn := range UserFQNVariants(task.Schema, task.Name) {
+			if rank, ok := priorityRanks[fqn]; ok {
+				taskRanks[i] = rank
+				foundTables[priorityTables[rank]] = true
+				break
+			}
+		}
+	}
+	for _, fqn := range priorityTables {
+		if !foundTables[fqn] {
+			gplog.Verbose("Priority table %s does not have any data to copy", fqn)
+		}
+	}
+
+	order := make([]int, len(tasks))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i int, j int) bool {
+		first, second := order[i], order[j]
+		if taskRanks[first] != taskRanks[second] {
+			return taskRanks[first] < taskRanks[second]
+		}
+		return tasks[first].Size > tasks[second].Size
+	})
+	return order
+}
@@ -0,0 +1,39 @@
+package utils_test
+
+import (
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/schedule tests", func() {
+	Describe("ScheduleCopyTasks", func() {
+		tasks := []utils.CopyTask{
+			{Schema: "public", Name: "small", Size: 10},
+			{Schema: "public", Name: "large", Size: 1000},
+			{Schema: "public", Name: "medium", Size: 100},
+			{Schema: "public", Name: "other_medium", Size: 100},
+			{Schema: `"Schema"`, Name: `"Table"`, Size: 1},
+		}
+
+		It("schedules tables from largest to smallest", func() {
+			Expect(utils.ScheduleCopyTasks(tasks, []string{})).To(Equal([]int{1, 2, 3, 0, 4}))
+		})
+		It("keeps the original order of tables of the same size", func() {
+			unsizedTasks := []utils.CopyTask{{Schema: "public", Name: "foo"}, {Schema: "public", Name: "bar"}}
+			Expect(utils.ScheduleCopyTasks(unsizedTasks, []string{})).To(Equal([]int{0, 1}))
+		})
+		It("schedules priority tables first in the order listed", func() {
+			order := utils.ScheduleCopyTasks(tasks, []string{"public.small", "public.other_medium"})
+			Expect(order).To(Equal([]int{0, 3, 1, 2, 4}))
+		})
+		It("matches priority tables with or without quoted identifiers", func() {
+			Expect(utils.ScheduleCopyTasks(tasks, []string{"Schema.Table"})).To(Equal([]int{4, 1, 2, 3, 0}))
+			Expect(utils.ScheduleCopyTasks(tasks, []string{`"Schema"."Table"`})).To(Equal([]int{4, 1, 2, 3, 0}))
+		})
+		It("ignores priority tables that are not scheduled", func() {
+			Expect(utils.ScheduleCopyTasks(tasks, []string{"public.nonexistent", "public.medium"})).To(Equal([]int{2, 1, 3, 0, 4}))
+		})
+	})
+})
@@ -0,0 +1,180 @@
+package utils
+
+/*
+ * This file contains structs and functions related to limiting the bandwidth
+ * used to back up and restore table data with --max-bandwidth.
+ */
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/pkg/errors"
+)
+
+// The most bandwidth that may be saved up while a stream is idle, so that it cannot burst for long afterwards
+const maxBurstDuration = time.Second
+
+var byteSizeRE = regexp.MustCompile(`^(\d+)\s*([KMGT]?)B?$`)
+
+/*
+ * Parses a number of bytes with an optional K, M, G or T suffix, such as
+ * "500K" or "100MB".  Units are powers of 1024.
+ */
+func ParseByteSize(size string) (int64, error) {
+	matches := byteSizeRE.FindStringSubmatch(strings.ToUpper(strings.TrimSpace(size)))
+	if matches == nil {
+		return 0, errors.Errorf("Invalid size %s.  Please specify a number of bytes, optionally followed by K, M, G or T.", size)
+	}
+	numBytes, err := strconv.ParseInt(matches[1], 10, 64)
+	if err != nil {
+		return 0, errors.Errorf("Invalid size %s: %v", size, err)
+	}
+	for _, unit := range []string{"K", "M", "G", "T"} {
+		if matches[2] == "" {
+			break
+		}
+		if numBytes > math.MaxInt64/1024 {
+			return 0, errors.Errorf("Invalid size %s: value out of range", size)
+		}
+		numBytes *= 1024
+		if matches[2] == unit {
+			break
+		}
+	}
+	return numBytes, nil
+}
+
+// Formats a number of bytes in the largest whole unit, in powers of 1024
+func FormatByteSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	value := float64(size) / unit
+	for _, suffix := range []string{"KB", "MB", "GB", "TB"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f %s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1f PB", value)
+}
+
+/*
+ * A RateLimiter paces the data passing through the readers and writers it
+ * wraps so that together they stay under a number of bytes per second.  A nil
+ * RateLimiter does not limit anything, so callers need not check whether a
+ * limit was given.
+ */
+type RateLimiter struct {
+	bytesPerSecond int64
+	numBytes       int64
+	start          time.Time
+}
+
+func NewRateLimiter(bytesPerSecond int64) *RateLimiter {
+	if bytesPerSecond <= 0 {
+		return nil
+	}
+	return &RateLimiter{bytesPerSecond: bytesPerSecond, start: time.Now()}
+}
+
+func (limiter *RateLimiter) Wait(numBytes int) {
+	if limiter == nil || numBytes <= 0 {
+		return
+	}
+	limiter.numBytes += int64(numBytes)
+	expected := time.Duration(float64(limiter.numBytes) / float64(limiter.bytesPerSecond) * float64(time.Second))
+	elapsed := time.Since(limiter.start)
+	if expected > elapsed {
+		time.Sleep(expected - elapsed)
+	} else if elapsed-expected > maxBurstDuration {
+		limiter.start = time.Now().Add(-expected - maxBurstDuration)
+	}
+}
+
+func (limiter *RateLimiter) Reader(reader io.Reader) io.Reader {
+	if limiter == nil {
+		return reader
+	}
+	return &rateLimitedReader{reader: reader, limiter: limiter}
+}
+
+func (limiter *RateLimiter) Writer(writer io.Writer) io.Writer {
+	if limiter == nil {
+		return writer
+	}
+	return &rateLimitedWriter{writer: writer, limiter: limiter}
+}
+
+type rateLimitedReader struct {
+	reader  io.Reader
+	limiter *RateLimiter
+}
+
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	numBytes, err := r.reader.Read(p)
+	r.limiter.Wait(numBytes)
+	return numBytes, err
+}
+
+type rateLimitedWriter struct {
+	writer  io.Writer
+	limiter *RateLimiter
+}
+
+func (w *rateLimitedWriter) Write(p []byte) (int, error) {
+	numBytes, err := w.writer.Write(p)
+	w.limiter.Wait(numBytes)
+	return numBytes, err
+}
+
+/*
+ * Returns the bandwidth that each stream of table data on a segment may use.
+ * COPY commands on every segment share one command string, so a limit for
+ * each host is divided by the number of primary segments on the host with the
+ * most of them, and the limit for a segment is then divided between the
+ * streams that copy data to or from it at the same time.
+ */
+func GetStreamBandwidth(c *cluster.Cluster, maxBandwidth int64, perHost bool, numStreams int) int64 {
+	if maxBandwidth <= 0 {
+		return 0
+	}
+	bandwidth := maxBandwidth
+	if perHost {
+		maxSegmentsPerHost := 1
+		for _, segments := range c.ByHost {
+			numSegments := 0
+			for _, segment := range segments {
+				if segment.ContentID >= 0 {
+					numSegments++
+				}
+			}
+			if numSegments > maxSegmentsPerHost {
+				maxSegmentsPerHost = numSegments
+			}
+		}
+		bandwidth /= int64(maxSegmentsPerHost)
+	}
+	if numStreams > 1 {
+		bandwidth /= int64(numStreams)
+	}
+	if bandwidth < 1 {
+		bandwidth = 1
+	}
+	return bandwidth
+}
+
+func getMaxBandwidthArg(maxBandwidth int64) string {
+	if maxBandwidth <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" --max-bandwidth %d", maxBandwidth)
+}
@@ -0,0 +1,110 @@
+package utils_test
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/greenplum-db/gp-common-go-libs/cluster"
+	"github.com/greenplum-db/gpbackup/utils"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("utils/throttle tests", func() {
+	Describe("ParseByteSize", func() {
+		DescribeTable("parses sizes with and without units",
+			func(size string, expected int64) {
+				numBytes, err := utils.ParseByteSize(size)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numBytes).To(Equal(expected))
+			},
+			Entry("bytes", "1000", int64(1000)),
+			Entry("bytes with a unit", "1000B", int64(1000)),
+			Entry("kilobytes", "500K", int64(500*1024)),
+			Entry("megabytes", "100MB", int64(100*1024*1024)),
+			Entry("lowercase megabytes", "100mb", int64(100*1024*1024)),
+			Entry("gigabytes", "2G", int64(2*1024*1024*1024)),
+			Entry("terabytes", "1 TB", int64(1024*1024*1024*1024)),
+			Entry("largest number of terabytes", "8388607T", int64(8388607)*1024*1024*1024*1024),
+		)
+		DescribeTable("returns an error for invalid sizes",
+			func(size string) {
+				_, err := utils.ParseByteSize(size)
+				Expect(err).To(MatchError(ContainSubstring("Invalid size")))
+			},
+			Entry("empty", ""),
+			Entry("negative", "-100M"),
+			Entry("fractional", "1.5G"),
+			Entry("unknown unit", "100X"),
+			Entry("too large in terabytes", "8388608T"),
+			Entry("too large in kilobytes", "9007199254740992K"),
+			Entry("too large without a unit", "9223372036854775808"),
+		)
+	})
+	Describe("FormatByteSize", func() {
+		DescribeTable("formats sizes in the largest whole unit",
+			func(size int64, expected string) {
+				Expect(utils.FormatByteSize(size)).To(Equal(expected))
+			},
+			Entry("bytes", int64(0), "0 B"),
+			Entry("bytes", int64(1023), "1023 B"),
+			Entry("kilobytes", int64(1536), "1.5 KB"),
+			Entry("megabytes", int64(5*1024*1024), "5.0 MB"),
+			Entry("gigabytes", int64(1024*1024*1024), "1.0 GB"),
+			Entry("terabytes", int64(2*1024*1024*1024*1024), "2.0 TB"),
+			Entry("petabytes", int64(3*1024*1024*1024*1024*1024), "3.0 PB"),
+		)
+	})
+	Describe("RateLimiter", func() {
+		It("does not limit a nil rate limiter", func() {
+			limiter := utils.NewRateLimiter(0)
+			Expect(limiter).To(BeNil())
+			reader := strings.NewReader("data")
+			Expect(limiter.Reader(reader)).To(Equal(reader))
+		})
+		It("limits the rate of data read", func() {
+			limiter := utils.NewRateLimiter(100 * 1024)
+			start := time.Now()
+			numBytes, err := io.Copy(io.Discard, limiter.Reader(bytes.NewReader(make([]byte, 20*1024))))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(numBytes).To(Equal(int64(20 * 1024)))
+			Expect(time.Since(start)).To(BeNumerically(">=", 190*time.Millisecond))
+		})
+		It("limits the rate of data written", func() {
+			limiter := utils.NewRateLimiter(100 * 1024)
+			buffer := &bytes.Buffer{}
+			start := time.Now()
+			_, err := limiter.Writer(buffer).Write(make([]byte, 20*1024))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(buffer.Len()).To(Equal(20 * 1024))
+			Expect(time.Since(start)).To(BeNumerically(">=", 190*time.Millisecond))
+		})
+	})
+	Describe("GetStreamBandwidth", func() {
+		testCluster := cluster.NewCluster([]cluster.SegConfig{
+			{ContentID: -1, Hostname: "coordinator"},
+			{ContentID: 0, Hostname: "host1"},
+			{ContentID: 1, Hostname: "host1"},
+			{ContentID: 2, Hostname: "host2"},
+			{ContentID: 3, Hostname: "host2"},
+			{ContentID: 4, Hostname: "host2"},
+			{ContentID: 5, Hostname: "host2"},
+		})
+		It("does not limit bandwidth if no limit is given", func() {
+			Expect(utils.GetStreamBandwidth(testCluster, 0, true, 4)).To(Equal(int64(0)))
+		})
+		It("uses the limit for a segment as is for a single stream", func() {
+			Expect(utils.GetStreamBandwidth(testCluster, 1000, false, 1)).To(Equal(int64(1000)))
+		})
+		It("divides the limit for a segment between its streams", func() {
+			Expect(utils.GetStreamBandwidth(testCluster, 1000, false, 4)).To(Equal(int64(250)))
+		})
+		It("divides the limit for a host between the segments of the largest host", func() {
+			Expect(utils.GetStreamBandwidth(testCluster, 1000, true, 1)).To(Equal(int64(250)))
+			Expect(utils.GetStreamBandwidth(testCluster, 1000, true, 2)).To(Equal(int64(125)))
+		})
+	})
+})
@@ -113,6 +113,11 @@ func MakeFQN(schema string, object string) string {
 	return fmt.Sprintf("%s.%s", schema, object)
 }
 
+// Users may list tables with or without quoting their identifiers, so match either form
+func UserFQNVariants(schema string, object string) []string {
+	return []string{MakeFQN(schema, object), MakeFQN(UnquoteIdent(schema), UnquoteIdent(object))}
+}
+
 // We require users to include schema and table, separated by a dot.  So, check that there's at
 // least one dot with stuff on either side of it.  The remainder of validation is done in the
 // ValidateTablesExist routine
@@ -141,8 +146,10 @@ type CompressionLevelsDescription struct {
 
 func ValidateCompressionTypeAndLevel(compressionType string, compressionLevel int) error {
 	compressionLevelsForType := map[string]CompressionLevelsDescription{
-		"gzip": {Min: 1, Max: 9},
-		"zstd": {Min: 1, Max: 19},
+		"gzip":   {Min: 1, Max: 9},
+		"zstd":   {Min: 1, Max: 19},
+		"lz4":    {Min: 1, Max: 9},
+		"snappy": {Min: 1, Max: 1},
 	}
 
 	if levelsDescription, ok := compressionLevelsForType[compressionType]; ok {
@@ -174,6 +181,24 @@ func InitializeSignalHandler(cleanupFunc func(bool), procDesc string, termFlag *
 	}()
 }
 
+const (
+	DATA_FORMAT_CSV    = "csv"
+	DATA_FORMAT_BINARY = "binary"
+)
+
+/*
+ * Returns the format options of a COPY command for table data in the given
+ * format.  Table data is CSV unless it was backed up with --data-format
+ * binary, and data entries of backups taken before that option existed do not
+ * record a format.
+ */
+func GetCopyFormatOptions(dataFormat string, delimiter string) string {
+	if dataFormat == DATA_FORMAT_BINARY {
+		return "BINARY"
+	}
+	return fmt.Sprintf("CSV DELIMITER '%s'", delimiter)
+}
+
 // TODO: Uniquely identify COPY commands in the multiple data file case to allow terminating sessions
 func TerminateHangingCopySessions(connectionPool *dbconn.DBConn, fpInfo filepath.FilePathInfo, appName string) {
 	var query string
@@ -149,6 +149,49 @@ var _ = Describe("utils/util tests", func() {
 			err := utils.ValidateCompressionTypeAndLevel(compressType, compressLevel)
 			Expect(err).To(MatchError("compression type 'zstd' only allows compression levels between 1 and 19, but the provided level is 20"))
 		})
+		It("validates a compression type 'lz4' and a level between 1 and 9", func() {
+			compressType := "lz4"
+			compressLevel := 9
+			err := utils.ValidateCompressionTypeAndLevel(compressType, compressLevel)
+			Expect(err).To(Not(HaveOccurred()))
+		})
+		It("panics if given a compression type 'lz4' and a compression level > 9", func() {
+			compressType := "lz4"
+			compressLevel := 10
+			err := utils.ValidateCompressionTypeAndLevel(compressType, compressLevel)
+			Expect(err).To(MatchError("compression type 'lz4' only allows compression levels between 1 and 9, but the provided level is 10"))
+		})
+		It("validates a compression type 'snappy' and a level of 1", func() {
+			compressType := "snappy"
+			compressLevel := 1
+			err := utils.ValidateCompressionTypeAndLevel(compressType, compressLevel)
+			Expect(err).To(Not(HaveOccurred()))
+		})
+		It("panics if given a compression type 'snappy' and a compression level other than 1", func() {
+			compressType := "snappy"
+			compressLevel := 2
+			err := utils.ValidateCompressionTypeAndLevel(compressType, compressLevel)
+			Expect(err).To(MatchError("compression type 'snappy' only allows compression levels between 1 and 1, but the provided level is 2"))
+		})
+	})
+	Describe("GetCopyFormatOptions", func() {
+		It("returns CSV options for CSV data", func() {
+			Expect(utils.GetCopyFormatOptions("csv", ",")).To(Equal("CSV DELIMITER ','"))
+		})
+		It("returns CSV options for data of a backup that did not record its format", func() {
+			Expect(utils.GetCopyFormatOptions("", ",")).To(Equal("CSV DELIMITER ','"))
+		})
+		It("returns binary options for binary data", func() {
+			Expect(utils.GetCopyFormatOptions("binary", ",")).To(Equal("BINARY"))
+		})
+	})
+	Describe("UserFQNVariants", func() {
+		It("returns the quoted and unquoted forms of a table name", func() {
+			Expect(utils.UserFQNVariants(`"Schema"`, `"Table"`)).To(Equal([]string{`"Schema"."Table"`, "Schema.Table"}))
+		})
+		It("returns the same form twice for a table name that needs no quoting", func() {
+			Expect(utils.UserFQNVariants("public", "foo")).To(Equal([]string{"public.foo", "public.foo"}))
+		})
 	})
 	Describe("UnquoteIdent", func() {
 		It("returns unchanged ident when passed a single char", func() {